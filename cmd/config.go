@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/daemon"
+)
+
+// configFetchTimeout the timeout for fetching the configuration from an URL
+const configFetchTimeout = 30 * time.Second
+
+// isURL returns true if the configuration location is an http(s) URL
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// fetchConfig downloads the configuration from an URL
+func fetchConfig(location string) ([]byte, error) {
+	client := http.Client{
+		Timeout: configFetchTimeout,
+	}
+	response, err := client.Get(location)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to fetch the configuration from %s", location)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fail to fetch the configuration from %s: status %d", location, response.StatusCode)
+	}
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to read the configuration from %s", location)
+	}
+	return body, nil
+}
+
+// parseConfig parses and validates the configuration
+func parseConfig(content []byte) (*daemon.Configuration, error) {
+	var config daemon.Configuration
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the yaml config file")
+	}
+	return &config, nil
+}
+
+// loadURLConfig fetches and validates the configuration from an URL.
+// The last valid configuration is written to the cache file if set, and the
+// cached configuration is used if the URL can't be fetched.
+func loadURLConfig(logger *zap.Logger, location string, cache string) (*daemon.Configuration, error) {
+	content, err := fetchConfig(location)
+	if err != nil {
+		if cache == "" {
+			return nil, err
+		}
+		logger.Error(fmt.Sprintf("%s, using the cached configuration %s", err.Error(), cache))
+		cached, cacheErr := os.ReadFile(cache)
+		if cacheErr != nil {
+			return nil, err
+		}
+		return parseConfig(cached)
+	}
+	config, err := parseConfig(content)
+	if err != nil {
+		return nil, err
+	}
+	if cache != "" {
+		err = os.WriteFile(cache, content, 0600)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Fail to write the configuration cache file %s: %s", cache, err.Error()))
+		}
+	}
+	return config, nil
+}
+
+// loadConfig reads and validates the configuration from a file path or
+// an http(s) URL
+func loadConfig(logger *zap.Logger, location string, cache string) (*daemon.Configuration, error) {
+	if isURL(location) {
+		return loadURLConfig(logger, location, cache)
+	}
+	content, err := os.ReadFile(location)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to read the configuration file")
+	}
+	return parseConfig(content)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestLoadConfig(t *testing.T) {
+	body := "http:\n  host: 127.0.0.1\n  port: 2000\n"
+	available := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer ts.Close()
+	logger := zap.NewExample()
+	dir := t.TempDir()
+	cache := filepath.Join(dir, "cache.yaml")
+	config, err := loadConfig(logger, ts.URL, cache)
+	if err != nil {
+		t.Fatalf("Fail to load the configuration\n%v", err)
+	}
+	if config.HTTP.Host != "127.0.0.1" || config.HTTP.Port != 2000 {
+		t.Fatalf("Invalid configuration %v", config)
+	}
+	cached, err := os.ReadFile(cache)
+	if err != nil || string(cached) != body {
+		t.Fatalf("Invalid cached configuration %s\n%v", cached, err)
+	}
+	// a cache write failure doesn't prevent the configuration to be loaded
+	_, err = loadConfig(logger, ts.URL, filepath.Join(dir, "missing", "cache.yaml"))
+	if err != nil {
+		t.Fatalf("The configuration should be loaded if the cache can't be written\n%v", err)
+	}
+	available = false
+	config, err = loadConfig(logger, ts.URL, cache)
+	if err != nil {
+		t.Fatalf("The cached configuration should be used\n%v", err)
+	}
+	if config.HTTP.Port != 2000 {
+		t.Fatalf("Invalid configuration %v", config)
+	}
+	_, err = loadConfig(logger, ts.URL, "")
+	if err == nil {
+		t.Fatalf("Was expecting an error because the configuration can't be fetched")
+	}
+	file := filepath.Join(dir, "config.yaml")
+	err = os.WriteFile(file, []byte(body), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the configuration\n%v", err)
+	}
+	config, err = loadConfig(logger, file, "")
+	if err != nil {
+		t.Fatalf("Fail to load the configuration\n%v", err)
+	}
+	if config.HTTP.Port != 2000 {
+		t.Fatalf("Invalid configuration %v", config)
+	}
+}
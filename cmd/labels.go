@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/appclacks/cabourotte/daemon"
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// parseLabels parses labels in the key=value format
+func parseLabels(values []string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid label %s (format key=value)", value)
+		}
+		labels[parts[0]] = parts[1]
+	}
+	return labels, nil
+}
+
+// copyLabels returns a copy of the labels, so healthchecks don't share the
+// same labels map
+func copyLabels(labels map[string]string) map[string]string {
+	result := make(map[string]string, len(labels))
+	for k, v := range labels {
+		result[k] = v
+	}
+	return result
+}
+
+// addLabels merges the labels into the labels of all the healthchecks
+// defined in the configuration
+func addLabels(config *daemon.Configuration, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	for i := range config.CommandChecks {
+		healthcheck.MergeLabels(&config.CommandChecks[i].Base, copyLabels(labels))
+	}
+	for i := range config.DNSChecks {
+		healthcheck.MergeLabels(&config.DNSChecks[i].Base, copyLabels(labels))
+	}
+	for i := range config.TCPChecks {
+		healthcheck.MergeLabels(&config.TCPChecks[i].Base, copyLabels(labels))
+	}
+	for i := range config.HTTPChecks {
+		healthcheck.MergeLabels(&config.HTTPChecks[i].Base, copyLabels(labels))
+	}
+	for i := range config.TLSChecks {
+		healthcheck.MergeLabels(&config.TLSChecks[i].Base, copyLabels(labels))
+	}
+	for i := range config.GRPCChecks {
+		healthcheck.MergeLabels(&config.GRPCChecks[i].Base, copyLabels(labels))
+	}
+	for i := range config.PostgresChecks {
+		healthcheck.MergeLabels(&config.PostgresChecks[i].Base, copyLabels(labels))
+	}
+	for i := range config.UDPChecks {
+		healthcheck.MergeLabels(&config.UDPChecks[i].Base, copyLabels(labels))
+	}
+	if config.SelfCheck != nil {
+		healthcheck.MergeLabels(&config.SelfCheck.Base, copyLabels(labels))
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/appclacks/cabourotte/daemon"
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestParseLabels(t *testing.T) {
+	labels, err := parseLabels([]string{"commit=abc", "branch=main", "list=a,b", "empty="})
+	if err != nil {
+		t.Fatalf("Fail to parse the labels\n%v", err)
+	}
+	if len(labels) != 4 || labels["commit"] != "abc" || labels["list"] != "a,b" || labels["empty"] != "" {
+		t.Fatalf("Invalid labels %v", labels)
+	}
+	for _, value := range []string{"commit", "=abc"} {
+		_, err = parseLabels([]string{value})
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", value)
+		}
+	}
+}
+
+func TestAddLabels(t *testing.T) {
+	config := daemon.Configuration{
+		TCPChecks: []healthcheck.TCPHealthcheckConfiguration{
+			{Base: healthcheck.Base{Name: "foo", Labels: map[string]string{"env": "prod", "commit": "old"}}},
+			{Base: healthcheck.Base{Name: "bar"}},
+		},
+		HTTPChecks: []healthcheck.HTTPHealthcheckConfiguration{
+			{Base: healthcheck.Base{Name: "baz"}},
+		},
+	}
+	addLabels(&config, map[string]string{"commit": "abc"})
+	foo := config.TCPChecks[0].Base.Labels
+	if len(foo) != 2 || foo["env"] != "prod" || foo["commit"] != "abc" {
+		t.Fatalf("Invalid labels %v", foo)
+	}
+	if config.TCPChecks[1].Base.Labels["commit"] != "abc" || config.HTTPChecks[0].Base.Labels["commit"] != "abc" {
+		t.Fatalf("The labels were not added")
+	}
+	config.TCPChecks[1].Base.Labels["commit"] = "modified"
+	if config.HTTPChecks[0].Base.Labels["commit"] != "abc" {
+		t.Fatalf("The healthchecks should not share the labels")
+	}
+}
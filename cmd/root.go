@@ -1,13 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
-
-	"gopkg.in/yaml.v2"
+	"time"
 
 	"github.com/appclacks/cabourotte/daemon"
 
@@ -16,37 +16,69 @@ import (
 	"go.uber.org/zap"
 )
 
+// BuildInfo the Cabourotte build information
+type BuildInfo struct {
+	Version string
+	Commit  string
+	Date    string
+}
+
 // Main the main entrypoint
-func Main() {
+func Main(buildInfo BuildInfo) {
 	app := &cli.App{
-		Usage: "Cabourotte, a monitoring tool to execute healthchecks on your infrastructure",
+		Usage:   "Cabourotte, a monitoring tool to execute healthchecks on your infrastructure",
+		Version: buildInfo.Version,
+		// labels values can contain commas
+		DisableSliceFlagSeparator: true,
 		Commands: []*cli.Command{
+			{
+				Name:  "version",
+				Usage: "prints the Cabourotte version",
+				Action: func(c *cli.Context) error {
+					fmt.Printf("version: %s\ncommit: %s\ndate: %s\n", buildInfo.Version, buildInfo.Commit, buildInfo.Date)
+					return nil
+				},
+			},
 			{
 				Name:  "daemon",
 				Usage: "starts the Cabourotte daemon",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
 						Name:     "config",
-						Usage:    "Path to the configuration file",
+						Usage:    "Path or http(s) URL of the configuration file",
 						Required: true,
 					},
+					&cli.StringFlag{
+						Name:     "config-cache",
+						Usage:    "Path of the file caching the last valid configuration fetched from an URL, used if the URL can't be fetched",
+						Required: false,
+					},
 					&cli.BoolFlag{
 						Name:     "debug",
 						Usage:    "Enable debug logging",
 						Required: false,
 					},
+					&cli.BoolFlag{
+						Name:     "oneshot-metrics",
+						Usage:    "Wait for all healthchecks to be executed once, print the metrics and exit. The healthchecks scheduled using a cron expression are not waited for",
+						Required: false,
+					},
+					&cli.DurationFlag{
+						Name:     "oneshot-timeout",
+						Usage:    "The maximum time to wait for the healthchecks executions in oneshot mode",
+						Value:    time.Minute,
+						Required: false,
+					},
+					&cli.StringSliceFlag{
+						Name:     "label",
+						Usage:    "Label (key=value) added to the healthchecks defined in the configuration file. Can be repeated",
+						Required: false,
+					},
 				},
 				Action: func(c *cli.Context) error {
-					file, err := os.ReadFile(c.String("config"))
+					labels, err := parseLabels(c.StringSlice("label"))
 					if err != nil {
-						return errors.Wrapf(err, "fail to read the configuration file")
-					}
-					var config daemon.Configuration
-					if err := yaml.Unmarshal(file, &config); err != nil {
-						return errors.Wrapf(err, "Fail to read the yaml config file")
-					}
-					if config.HTTP.Host == "" {
-						return errors.New("Invalid HTTP server configuration")
+						return err
 					}
 					zapConfig := zap.NewProductionConfig()
 					if c.Bool("debug") {
@@ -58,10 +90,29 @@ func Main() {
 					}
 					// nolint
 					defer logger.Sync()
-					daemonComponent, err := daemon.New(logger, &config)
+					config, err := loadConfig(logger, c.String("config"), c.String("config-cache"))
+					if err != nil {
+						return err
+					}
+					addLabels(config, labels)
+					if config.HTTP.Host == "" && config.HTTP.Socket == "" {
+						return errors.New("Invalid HTTP server configuration")
+					}
+					daemonComponent, err := daemon.New(logger, config)
 					if err != nil {
 						return errors.Wrapf(err, "Fail to creae the daemon")
 					}
+					err = daemonComponent.Prometheus.RegisterBuildInfo(buildInfo.Version, buildInfo.Commit, buildInfo.Date)
+					if err != nil {
+						stopErr := daemonComponent.Stop()
+						if stopErr != nil {
+							logger.Error(fmt.Sprintf("Fail to stop: %s", stopErr.Error()))
+						}
+						return errors.Wrapf(err, "Fail to register the build information")
+					}
+					if c.Bool("oneshot-metrics") {
+						return oneshotMetrics(daemonComponent, c.Duration("oneshot-timeout"))
+					}
 					signals := make(chan os.Signal, 1)
 					errChan := make(chan error)
 
@@ -84,19 +135,15 @@ func Main() {
 								errChan <- nil
 							case syscall.SIGHUP:
 								logger.Info(fmt.Sprintf("Received signal %s, reload", sig))
-								newFile, err := os.ReadFile(c.String("config"))
+								newConfig, err := loadConfig(logger, c.String("config"), c.String("config-cache"))
 								if err != nil {
 									logger.Error(err.Error())
 								} else {
-									var newConfig daemon.Configuration
-									if err := yaml.Unmarshal(newFile, &newConfig); err != nil {
-										logger.Error(err.Error())
-									} else {
-										err := daemonComponent.Reload(&newConfig)
-										if err != nil {
-											logger.Error(fmt.Sprintf("Fail to reload: %s", err.Error()))
-											errChan <- err
-										}
+									addLabels(newConfig, labels)
+									err := daemonComponent.Reload(newConfig)
+									if err != nil {
+										logger.Error(fmt.Sprintf("Fail to reload: %s", err.Error()))
+										errChan <- err
 									}
 								}
 							}
@@ -114,3 +161,23 @@ func Main() {
 		log.Fatal(err)
 	}
 }
+
+// oneshotMetrics waits for all healthchecks to be executed once, stops the
+// daemon and prints the metrics on stdout
+func oneshotMetrics(daemonComponent *daemon.Component, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	waitErr := daemonComponent.WaitExecuted(ctx)
+	err := daemonComponent.Stop()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to stop the daemon")
+	}
+	if waitErr != nil {
+		return waitErr
+	}
+	err = daemonComponent.Prometheus.Write(os.Stdout)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to write the metrics")
+	}
+	return nil
+}
@@ -7,25 +7,69 @@ import (
 	"github.com/appclacks/cabourotte/exporter"
 	"github.com/appclacks/cabourotte/healthcheck"
 	"github.com/appclacks/cabourotte/http"
+	"github.com/appclacks/cabourotte/memorystore"
 )
 
 // Configuration the HTTP server configuration
 type Configuration struct {
-	ResultBuffer       uint `yaml:"result-buffer"`
-	HTTP               http.Configuration
-	HealthchecksLabels []string                                      `yaml:"healthchecks-labels"`
-	CommandChecks      []healthcheck.CommandHealthcheckConfiguration `yaml:"command-checks"`
-	DNSChecks          []healthcheck.DNSHealthcheckConfiguration     `yaml:"dns-checks"`
-	TCPChecks          []healthcheck.TCPHealthcheckConfiguration     `yaml:"tcp-checks"`
-	HTTPChecks         []healthcheck.HTTPHealthcheckConfiguration    `yaml:"http-checks"`
-	TLSChecks          []healthcheck.TLSHealthcheckConfiguration     `yaml:"tls-checks"`
-	Exporters          exporter.Configuration
-	Discovery          discovery.Configuration
+	ResultBuffer           uint `yaml:"result-buffer"`
+	HTTP                   http.Configuration
+	HealthchecksLabels     []string                    `yaml:"healthchecks-labels"`
+	MetricsNamespace       string                      `yaml:"metrics-namespace"`
+	MaxAnnotations         int                         `yaml:"max-annotations"`
+	MaxAnnotationsSize     int                         `yaml:"max-annotations-size"`
+	MaxExecutionEvents     int                         `yaml:"max-execution-events"`
+	HistorySize            int                         `yaml:"history-size"`
+	MaxLabelValues         int                         `yaml:"max-label-values"`
+	DiscoveryMetricsLabels []string                    `yaml:"discovery-metrics-labels"`
+	RetentionTiers         []memorystore.RetentionTier `yaml:"retention-tiers"`
+	// PersistPath the file where the healthchecks results are persisted
+	// across restarts
+	PersistPath string `yaml:"persist-path"`
+	// Maintenance starts the daemon with the global maintenance enabled
+	Maintenance bool `yaml:"maintenance"`
+	// MaxConcurrentChecks the maximum number of healthchecks executed at
+	// the same time (no limit if 0)
+	MaxConcurrentChecks int `yaml:"max-concurrent-checks"`
+	// StartupJitter the maximum random delay before the first execution of
+	// the healthchecks (4 seconds by default)
+	StartupJitter  healthcheck.Duration                           `yaml:"startup-jitter"`
+	Resolver       healthcheck.ResolverConfiguration              `yaml:"resolver"`
+	CommandChecks  []healthcheck.CommandHealthcheckConfiguration  `yaml:"command-checks"`
+	DNSChecks      []healthcheck.DNSHealthcheckConfiguration      `yaml:"dns-checks"`
+	TCPChecks      []healthcheck.TCPHealthcheckConfiguration      `yaml:"tcp-checks"`
+	HTTPChecks     []healthcheck.HTTPHealthcheckConfiguration     `yaml:"http-checks"`
+	TLSChecks      []healthcheck.TLSHealthcheckConfiguration      `yaml:"tls-checks"`
+	GRPCChecks     []healthcheck.GRPCHealthcheckConfiguration     `yaml:"grpc-checks"`
+	PostgresChecks []healthcheck.PostgresHealthcheckConfiguration `yaml:"postgres-checks"`
+	UDPChecks      []healthcheck.UDPHealthcheckConfiguration      `yaml:"udp-checks"`
+	// SelfCheck enables the healthcheck monitoring Cabourotte itself
+	SelfCheck *healthcheck.SelfHealthcheckConfiguration `yaml:"self-check"`
+	Exporters exporter.Configuration
+	Discovery discovery.Configuration
 }
 
 // DefaultBufferSize the default siez for the buffer containing healthchecks results
 const DefaultBufferSize = 20000
 
+// validateExporters verifies that the exporters of an healthcheck are
+// configured
+func validateExporters(exporters []string, base healthcheck.Base) error {
+	for _, name := range base.Exporters {
+		found := false
+		for _, configured := range exporters {
+			if configured == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf("The exporter %s of the healthcheck %s is not configured", name, base.Name)
+		}
+	}
+	return nil
+}
+
 // UnmarshalYAML Parse a configuration from YAML.
 func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	chanSize := uint(DefaultBufferSize)
@@ -34,12 +78,17 @@ func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) er
 	if err := unmarshal(&raw); err != nil {
 		return errors.Wrap(err, "Unable to read Cabourotte configuration")
 	}
+	exporters := raw.Exporters.Names()
 	for i := range raw.CommandChecks {
 		check := raw.CommandChecks[i]
 		err := check.Validate()
 		if err != nil {
 			return errors.Wrap(err, "Invalid healthcheck configuration")
 		}
+		err = validateExporters(exporters, check.Base)
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
 	}
 	for i := range raw.DNSChecks {
 		check := raw.DNSChecks[i]
@@ -47,6 +96,10 @@ func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) er
 		if err != nil {
 			return errors.Wrap(err, "Invalid healthcheck configuration")
 		}
+		err = validateExporters(exporters, check.Base)
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
 	}
 	for i := range raw.TCPChecks {
 		check := raw.TCPChecks[i]
@@ -54,6 +107,10 @@ func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) er
 		if err != nil {
 			return errors.Wrap(err, "Invalid healthcheck configuration")
 		}
+		err = validateExporters(exporters, check.Base)
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
 	}
 	for i := range raw.HTTPChecks {
 		check := raw.HTTPChecks[i]
@@ -61,6 +118,10 @@ func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) er
 		if err != nil {
 			return errors.Wrap(err, "Invalid healthcheck configuration")
 		}
+		err = validateExporters(exporters, check.Base)
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
 	}
 	for i := range raw.TLSChecks {
 		check := raw.TLSChecks[i]
@@ -68,6 +129,93 @@ func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) er
 		if err != nil {
 			return errors.Wrap(err, "Invalid healthcheck configuration")
 		}
+		err = validateExporters(exporters, check.Base)
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
+	}
+	for i := range raw.GRPCChecks {
+		check := raw.GRPCChecks[i]
+		err := check.Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
+		err = validateExporters(exporters, check.Base)
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
+	}
+	for i := range raw.PostgresChecks {
+		check := raw.PostgresChecks[i]
+		err := check.Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
+		err = validateExporters(exporters, check.Base)
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
+	}
+	for i := range raw.UDPChecks {
+		check := raw.UDPChecks[i]
+		err := check.Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
+		err = validateExporters(exporters, check.Base)
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
+	}
+	if raw.SelfCheck != nil {
+		if raw.SelfCheck.Base.Name == "" {
+			raw.SelfCheck.Base.Name = healthcheck.DefaultSelfHealthcheckName
+		}
+		err := raw.SelfCheck.Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid self healthcheck configuration")
+		}
+		err = validateExporters(exporters, raw.SelfCheck.Base)
+		if err != nil {
+			return errors.Wrap(err, "Invalid self healthcheck configuration")
+		}
+	}
+	err := raw.Resolver.Validate()
+	if err != nil {
+		return err
+	}
+	if raw.MaxAnnotations < healthcheck.UnlimitedAnnotations || raw.MaxAnnotationsSize < healthcheck.UnlimitedAnnotations {
+		return errors.New("The maximum number and size of annotations should be positive, or -1 for no limit")
+	}
+	if raw.MaxExecutionEvents < 0 {
+		return errors.New("The maximum number of execution events should be positive")
+	}
+	if raw.MaxLabelValues < 0 {
+		return errors.New("The maximum number of label values should be positive")
+	}
+	if raw.MaxConcurrentChecks < 0 {
+		return errors.New("The maximum number of concurrent healthchecks should be positive")
+	}
+	if raw.StartupJitter < 0 {
+		return errors.New("The startup jitter should be positive")
+	}
+	healthchecksLabels := make(map[string]bool)
+	for _, label := range raw.HealthchecksLabels {
+		healthchecksLabels[label] = true
+	}
+	for _, label := range raw.DiscoveryMetricsLabels {
+		if !healthchecksLabels[label] {
+			return errors.Errorf("The discovery metrics label %s is not in healthchecks-labels", label)
+		}
+	}
+	if raw.HistorySize < 0 {
+		return errors.New("The history size should be positive")
+	}
+	for i := range raw.RetentionTiers {
+		err := raw.RetentionTiers[i].Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid retention tier configuration")
+		}
 	}
 	if raw.ResultBuffer == 0 {
 		raw.ResultBuffer = chanSize
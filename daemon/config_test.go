@@ -49,7 +49,7 @@ discovery:
 							Host:     "127.0.0.1",
 							Port:     30000,
 							Path:     "/",
-							Interval: 10,
+							Interval: healthcheck.Duration(10 * time.Second),
 						},
 					},
 				},
@@ -197,6 +197,8 @@ http-checks:
     port: 443
     body-regexp:
       - "foo*"
+    forbidden-body-regexp:
+      - "foo*"
     interval: 10s
     timeout: 5s
     path: "/foo"
@@ -355,15 +357,16 @@ exporters:
 								"environment": "prod",
 							},
 						},
-						Cacert:     "/tmp/foo",
-						Insecure:   true,
-						Body:       "foobar",
-						Path:       "/foo",
-						BodyRegexp: []healthcheck.Regexp{regexp},
-						SourceIP:   healthcheck.IP(net.ParseIP("127.0.0.3")),
-						Target:     "mcorbin.fr",
-						Port:       443,
-						Redirect:   true,
+						Cacert:              "/tmp/foo",
+						Insecure:            true,
+						Body:                "foobar",
+						Path:                "/foo",
+						BodyRegexp:          []healthcheck.Regexp{regexp},
+						ForbiddenBodyRegexp: []healthcheck.Regexp{regexp},
+						SourceIP:            healthcheck.IP(net.ParseIP("127.0.0.3")),
+						Target:              "mcorbin.fr",
+						Port:                443,
+						Redirect:            true,
 						Headers: map[string]string{
 							"foo": "bar",
 						},
@@ -375,6 +378,48 @@ exporters:
 				},
 			},
 		},
+		{
+			in: `
+http:
+  host: "127.0.0.1"
+  port: 2000
+self-check:
+  interval: 10s
+  max-goroutines: 1000
+`,
+			want: Configuration{
+				ResultBuffer: DefaultBufferSize,
+				HTTP: http.Configuration{
+					Host: "127.0.0.1",
+					Port: 2000,
+				},
+				SelfCheck: &healthcheck.SelfHealthcheckConfiguration{
+					Base: healthcheck.Base{
+						Name:     healthcheck.DefaultSelfHealthcheckName,
+						Interval: healthcheck.Duration(time.Second * 10),
+					},
+					MaxGoroutines: 1000,
+				},
+			},
+		},
+		{
+			in: `
+http:
+  host: "127.0.0.1"
+  port: 2000
+max-annotations: -1
+max-annotations-size: -1
+`,
+			want: Configuration{
+				ResultBuffer: DefaultBufferSize,
+				HTTP: http.Configuration{
+					Host: "127.0.0.1",
+					Port: 2000,
+				},
+				MaxAnnotations:     healthcheck.UnlimitedAnnotations,
+				MaxAnnotationsSize: healthcheck.UnlimitedAnnotations,
+			},
+		},
 	}
 	for _, c := range cases {
 		var result Configuration
@@ -513,6 +558,35 @@ http-checks:
       - 201
     labels:
       environment: prod
+`,
+		`
+http:
+  host: "127.0.0.1"
+  port: 2000
+healthchecks-labels:
+  - env
+discovery-metrics-labels:
+  - pod
+`,
+		`
+http:
+  host: "127.0.0.1"
+  port: 2000
+max-annotations: -2
+`,
+		`
+http:
+  host: "127.0.0.1"
+  port: 2000
+tcp-checks:
+  - name: foo
+    description: bar
+    target: 127.0.0.1
+    port: 2000
+    interval: 10s
+    timeout: 5s
+    exporters:
+      - unknown
 `,
 	}
 	for _, c := range cases {
@@ -1,8 +1,11 @@
 package daemon
 
 import (
+	"context"
+	"fmt"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -28,6 +31,8 @@ type Component struct {
 	Discovery   *discovery.Component
 	lock        sync.RWMutex
 	ChanResult  chan *healthcheck.Result
+	// configLock protects the configuration returned by RunningConfig
+	configLock sync.RWMutex
 }
 
 // New creates and start a new daemon component
@@ -37,12 +42,35 @@ func New(logger *zap.Logger, config *Configuration) (*Component, error) {
 	if err != nil {
 		return nil, err
 	}
+	prom.Config = &prometheus.Configuration{
+		Namespace: config.MetricsNamespace,
+	}
 	chanResult := make(chan *healthcheck.Result, config.ResultBuffer)
 	checkComponent, err := healthcheck.New(logger, chanResult, prom, config.HealthchecksLabels)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fail to create the healthcheck component")
 	}
+	checkComponent.Resolver = healthcheck.NewResolver(&config.Resolver)
+	checkComponent.SetAnnotationsLimits(annotationsLimits(config))
+	if config.MaxExecutionEvents != 0 {
+		checkComponent.MaxExecutionEvents = config.MaxExecutionEvents
+	}
+	if config.StartupJitter != 0 {
+		checkComponent.StartupJitter = config.StartupJitter
+	}
+	checkComponent.MaxLabelValues = config.MaxLabelValues
+	checkComponent.SetMaxConcurrentChecks(config.MaxConcurrentChecks)
+	checkComponent.DiscoveryMetricsLabels = config.DiscoveryMetricsLabels
+	checkComponent.ExporterNames = config.Exporters.Names()
+	if config.Maintenance {
+		checkComponent.SetMaintenance(true)
+	}
 	memstore := memorystore.NewMemoryStore(logger)
+	if config.HistorySize != 0 {
+		memstore.HistorySize = config.HistorySize
+	}
+	memstore.RetentionTiers = config.RetentionTiers
+	memstore.PersistPath = config.PersistPath
 	memstore.Start()
 	err = checkComponent.Start()
 	if err != nil {
@@ -52,10 +80,6 @@ func New(logger *zap.Logger, config *Configuration) (*Component, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fail to create the HTTP server")
 	}
-	err = http.Start()
-	if err != nil {
-		return nil, errors.Wrapf(err, "Fail to start the HTTP server")
-	}
 	exporterComponent, err := exporter.New(logger, memstore, chanResult, prom, &config.Exporters)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fail to create the exporter component")
@@ -83,13 +107,103 @@ func New(logger *zap.Logger, config *Configuration) (*Component, error) {
 		Discovery:   discoveryComponent,
 		Healthcheck: checkComponent,
 	}
+	http.RunningConfig = component.RunningConfig
+	err = http.Start()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to start the HTTP server")
+	}
 	err = component.ReloadHealthchecks(config)
 	if err != nil {
 		return nil, err
 	}
+	err = component.reloadSelfCheck(config)
+	if err != nil {
+		return nil, err
+	}
 	return &component, nil
 }
 
+// annotationsLimits returns the maximum number and size of the annotations
+// in a result, using the default limits if not configured
+func annotationsLimits(config *Configuration) (int, int) {
+	maxCount := healthcheck.DefaultMaxAnnotations
+	if config.MaxAnnotations != 0 {
+		maxCount = config.MaxAnnotations
+	}
+	maxSize := healthcheck.DefaultMaxAnnotationsSize
+	if config.MaxAnnotationsSize != 0 {
+		maxSize = config.MaxAnnotationsSize
+	}
+	return maxCount, maxSize
+}
+
+// warnRestartSetting logs that a setting can't be reloaded
+func (c *Component) warnRestartSetting(name string) {
+	c.Logger.Warn(fmt.Sprintf("The %s setting can't be reloaded, restart the daemon to apply it", name))
+}
+
+// keepRestartSettings replaces the settings of a new configuration which
+// can't be reloaded by the running ones, so the running configuration
+// only contains the settings in effect.
+func (c *Component) keepRestartSettings(daemonConfig *Configuration) {
+	running := c.Config
+	if !reflect.DeepEqual(running.ResultBuffer, daemonConfig.ResultBuffer) {
+		c.warnRestartSetting("ResultBuffer")
+		daemonConfig.ResultBuffer = running.ResultBuffer
+	}
+	if !reflect.DeepEqual(running.HealthchecksLabels, daemonConfig.HealthchecksLabels) {
+		c.warnRestartSetting("HealthchecksLabels")
+		daemonConfig.HealthchecksLabels = running.HealthchecksLabels
+	}
+	if !reflect.DeepEqual(running.MetricsNamespace, daemonConfig.MetricsNamespace) {
+		c.warnRestartSetting("MetricsNamespace")
+		daemonConfig.MetricsNamespace = running.MetricsNamespace
+	}
+	if !reflect.DeepEqual(running.MaxExecutionEvents, daemonConfig.MaxExecutionEvents) {
+		c.warnRestartSetting("MaxExecutionEvents")
+		daemonConfig.MaxExecutionEvents = running.MaxExecutionEvents
+	}
+	if !reflect.DeepEqual(running.HistorySize, daemonConfig.HistorySize) {
+		c.warnRestartSetting("HistorySize")
+		daemonConfig.HistorySize = running.HistorySize
+	}
+	if !reflect.DeepEqual(running.MaxLabelValues, daemonConfig.MaxLabelValues) {
+		c.warnRestartSetting("MaxLabelValues")
+		daemonConfig.MaxLabelValues = running.MaxLabelValues
+	}
+	if !reflect.DeepEqual(running.DiscoveryMetricsLabels, daemonConfig.DiscoveryMetricsLabels) {
+		c.warnRestartSetting("DiscoveryMetricsLabels")
+		daemonConfig.DiscoveryMetricsLabels = running.DiscoveryMetricsLabels
+	}
+	if !reflect.DeepEqual(running.RetentionTiers, daemonConfig.RetentionTiers) {
+		c.warnRestartSetting("RetentionTiers")
+		daemonConfig.RetentionTiers = running.RetentionTiers
+	}
+	if !reflect.DeepEqual(running.PersistPath, daemonConfig.PersistPath) {
+		c.warnRestartSetting("PersistPath")
+		daemonConfig.PersistPath = running.PersistPath
+	}
+	if !reflect.DeepEqual(running.StartupJitter, daemonConfig.StartupJitter) {
+		c.warnRestartSetting("StartupJitter")
+		daemonConfig.StartupJitter = running.StartupJitter
+	}
+	if !reflect.DeepEqual(running.Exporters, daemonConfig.Exporters) {
+		c.warnRestartSetting("Exporters")
+		daemonConfig.Exporters = running.Exporters
+	}
+	if !reflect.DeepEqual(running.Discovery, daemonConfig.Discovery) {
+		c.warnRestartSetting("Discovery")
+		daemonConfig.Discovery = running.Discovery
+	}
+}
+
+// RunningConfig returns the configuration of the last successful reload
+func (c *Component) RunningConfig() interface{} {
+	c.configLock.RLock()
+	defer c.configLock.RUnlock()
+	return c.Config
+}
+
 // Stop stops the Cabourotte daemon
 func (c *Component) Stop() error {
 	c.Logger.Info("Stopping the Cabourotte daemon")
@@ -112,6 +226,24 @@ func (c *Component) Stop() error {
 	if err != nil {
 		return errors.Wrapf(err, "Fail to stop the exporter component")
 	}
+	err = c.MemoryStore.Stop()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to stop the memory store")
+	}
+	return nil
+}
+
+// WaitExecuted waits for all healthchecks to be executed at least once
+func (c *Component) WaitExecuted(ctx context.Context) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for !c.Healthcheck.Executed() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "Fail to wait for the healthchecks executions")
+		}
+	}
 	return nil
 }
 
@@ -124,20 +256,62 @@ func (c *Component) ReloadHealthchecks(daemonConfig *Configuration) error {
 		daemonConfig.DNSChecks,
 		daemonConfig.TCPChecks,
 		daemonConfig.HTTPChecks,
-		daemonConfig.TLSChecks)
+		daemonConfig.TLSChecks,
+		daemonConfig.GRPCChecks,
+		daemonConfig.PostgresChecks,
+		daemonConfig.UDPChecks)
+}
+
+// reloadSelfCheck removes the existing self healthcheck and creates the new
+// one if it is enabled in the configuration
+func (c *Component) reloadSelfCheck(daemonConfig *Configuration) error {
+	for name := range c.Healthcheck.SourceChecksNames(healthcheck.SourceSelf) {
+		err := c.Healthcheck.RemoveCheck(name)
+		if err != nil {
+			return errors.Wrapf(err, "Fail to remove the self healthcheck")
+		}
+	}
+	if daemonConfig.SelfCheck == nil {
+		return nil
+	}
+	check := healthcheck.NewSelfHealthcheck(c.Logger, daemonConfig.SelfCheck.DeepCopy())
+	check.ChanResult = c.ChanResult
+	check.ExportersStatus = c.Exporter.ExportersStatus
+	check.SetSource(healthcheck.SourceSelf)
+	err := c.Healthcheck.AddCheck(check)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to add the self healthcheck")
+	}
+	return nil
 }
 
 // Reload reloads the Cabourotte daemon. This function will remove or keep
 // existing healthchecks depending of the new configuration. New checks will be added.
+// Unchanged healthchecks are not restarted.
 // The HTTP server will also be reloaded if its configuration has changed.
 func (c *Component) Reload(daemonConfig *Configuration) error {
 	c.Logger.Info("Reloading the Cabourotte daemon")
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	runningConfig := *daemonConfig
+	daemonConfig = &runningConfig
+	c.keepRestartSettings(daemonConfig)
+	c.Healthcheck.Resolver.Update(&daemonConfig.Resolver)
+	c.Healthcheck.SetAnnotationsLimits(annotationsLimits(daemonConfig))
+	c.Healthcheck.SetMaxConcurrentChecks(daemonConfig.MaxConcurrentChecks)
+	if c.Config.Maintenance != daemonConfig.Maintenance {
+		c.Healthcheck.SetMaintenance(daemonConfig.Maintenance)
+	}
 	err := c.ReloadHealthchecks(daemonConfig)
 	if err != nil {
 		return errors.Wrapf(err, "Fail to reload healthchecks")
 	}
+	if !reflect.DeepEqual(c.Config.SelfCheck, daemonConfig.SelfCheck) {
+		err := c.reloadSelfCheck(daemonConfig)
+		if err != nil {
+			return err
+		}
+	}
 	// compare the server config to see if we need to recreate it
 	if !reflect.DeepEqual(c.Config.HTTP, daemonConfig.HTTP) {
 		err := c.HTTP.Stop()
@@ -148,13 +322,16 @@ func (c *Component) Reload(daemonConfig *Configuration) error {
 		if err != nil {
 			return errors.Wrapf(err, "Fail to create the HTTP server")
 		}
+		http.RunningConfig = c.RunningConfig
 		err = http.Start()
 		if err != nil {
 			return errors.Wrapf(err, "Fail to start the HTTP server")
 		}
 		c.HTTP = http
 	}
+	c.configLock.Lock()
 	c.Config = daemonConfig
+	c.configLock.Unlock()
 	c.Logger.Info("Reloaded")
 	return nil
 }
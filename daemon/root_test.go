@@ -1,7 +1,12 @@
 package daemon
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	nethttp "net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -201,3 +206,285 @@ func TestReload(t *testing.T) {
 		t.Fatalf("Fail to start the component\n%v", err)
 	}
 }
+
+func TestWaitExecuted(t *testing.T) {
+	component, err := New(zap.NewExample(), &Configuration{
+		HTTP: http.Configuration{
+			Host: "127.0.0.1",
+			Port: 2002,
+		},
+		TCPChecks: []healthcheck.TCPHealthcheckConfiguration{
+			{
+				Base: healthcheck.Base{
+					Name:     "foo",
+					Interval: healthcheck.Duration(time.Second * 10),
+				},
+				Target:  "127.0.0.1",
+				Port:    2002,
+				Timeout: healthcheck.Duration(time.Second * 2),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = component.WaitExecuted(ctx)
+	if err != nil {
+		t.Fatalf("Fail to wait for the healthchecks executions\n%v", err)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+	var metrics bytes.Buffer
+	err = component.Prometheus.Write(&metrics)
+	if err != nil {
+		t.Fatalf("Fail to write the metrics\n%v", err)
+	}
+	if !strings.Contains(metrics.String(), `healthcheck_total{name="foo",raw_status="success",status="success"} 1`) {
+		t.Fatalf("Invalid metrics\n%s", metrics.String())
+	}
+}
+
+func TestWaitExecutedSkipped(t *testing.T) {
+	component, err := New(zap.NewExample(), &Configuration{
+		HTTP: http.Configuration{
+			Host: "127.0.0.1",
+			Port: 2002,
+		},
+		Maintenance:   true,
+		StartupJitter: healthcheck.Duration(time.Millisecond),
+		TCPChecks: []healthcheck.TCPHealthcheckConfiguration{
+			{
+				Base: healthcheck.Base{
+					Name:     "foo",
+					Interval: healthcheck.Duration(time.Second * 10),
+				},
+				Target:  "127.0.0.1",
+				Port:    2002,
+				Timeout: healthcheck.Duration(time.Second * 2),
+			},
+			{
+				Base: healthcheck.Base{
+					Name: "bar",
+					Cron: "0 0 1 1 *",
+				},
+				Target:  "127.0.0.1",
+				Port:    2002,
+				Timeout: healthcheck.Duration(time.Second * 2),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = component.WaitExecuted(ctx)
+	if err != nil {
+		t.Fatalf("The skipped and cron healthcheck executions should not be waited for\n%v", err)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestReloadUnchangedChecks(t *testing.T) {
+	config := func(host string, barPort uint) *Configuration {
+		return &Configuration{
+			HTTP: http.Configuration{
+				Host: host,
+				Port: 2002,
+			},
+			TCPChecks: []healthcheck.TCPHealthcheckConfiguration{
+				{
+					Base: healthcheck.Base{
+						Name:     "foo",
+						Interval: healthcheck.Duration(time.Second * 10),
+						Labels:   map[string]string{"env": "prod"},
+					},
+					Target:  "127.0.0.1",
+					Port:    2002,
+					Timeout: healthcheck.Duration(time.Second * 2),
+				},
+				{
+					Base: healthcheck.Base{
+						Name:     "bar",
+						Interval: healthcheck.Duration(time.Second * 10),
+					},
+					Target:  "127.0.0.1",
+					Port:    barPort,
+					Timeout: healthcheck.Duration(time.Second * 2),
+				},
+			},
+		}
+	}
+	component, err := New(zap.NewExample(), config("127.0.0.1", 2002))
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = component.WaitExecuted(ctx)
+	if err != nil {
+		t.Fatalf("Fail to wait for the healthchecks executions\n%v", err)
+	}
+	foo := component.Healthcheck.Healthchecks["foo"]
+	bar := component.Healthcheck.Healthchecks["bar"]
+	// the HTTP server is recreated and the bar healthcheck is updated
+	err = component.Reload(config("127.0.0.2", 2003))
+	if err != nil {
+		t.Fatalf("Fail to reload the component\n%v", err)
+	}
+	if component.Healthcheck.Healthchecks["foo"] != foo {
+		t.Fatalf("The unchanged healthcheck was recreated")
+	}
+	if component.Healthcheck.Healthchecks["bar"] == bar {
+		t.Fatalf("The updated healthcheck was not recreated")
+	}
+	var metrics bytes.Buffer
+	err = component.Prometheus.Write(&metrics)
+	if err != nil {
+		t.Fatalf("Fail to write the metrics\n%v", err)
+	}
+	if !strings.Contains(metrics.String(), `healthcheck_total{name="foo",raw_status="success",status="success"} 1`) {
+		t.Fatalf("The unchanged healthcheck metrics were reset\n%s", metrics.String())
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestRunningConfig(t *testing.T) {
+	config := func(name string) *Configuration {
+		return &Configuration{
+			HTTP: http.Configuration{
+				Host:      "127.0.0.1",
+				Port:      2002,
+				BasicAuth: http.BasicAuth{Username: "admin", Password: "server-secret"},
+			},
+			HTTPChecks: []healthcheck.HTTPHealthcheckConfiguration{
+				{
+					Base: healthcheck.Base{
+						Name:     name,
+						Interval: healthcheck.Duration(time.Second * 10),
+					},
+					Path:        "/",
+					Target:      "127.0.0.1",
+					Port:        2002,
+					Protocol:    healthcheck.HTTP,
+					Timeout:     healthcheck.Duration(time.Second * 2),
+					ValidStatus: []uint{200},
+					BasicAuth:   &healthcheck.BasicAuth{Username: "user", Password: "check-secret"},
+					Headers:     map[string]string{"Authorization": "Bearer check-token"},
+				},
+			},
+		}
+	}
+	get := func() string {
+		req, err := nethttp.NewRequest(nethttp.MethodGet, "http://127.0.0.1:2002/api/v1/config", nil)
+		if err != nil {
+			t.Fatalf("Fail to build the request\n%v", err)
+		}
+		req.SetBasicAuth("admin", "server-secret")
+		resp, err := nethttp.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("HTTP error\n%v", err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Fail to read the body\n%v", err)
+		}
+		if resp.StatusCode != nethttp.StatusOK {
+			t.Fatalf("Invalid status %d: %s", resp.StatusCode, string(body))
+		}
+		return string(body)
+	}
+	component, err := New(zap.NewExample(), config("foo"))
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	body := get()
+	if !strings.Contains(body, `"foo"`) {
+		t.Fatalf("The running configuration is invalid: %s", body)
+	}
+	for _, secret := range []string{"server-secret", "check-secret", "check-token"} {
+		if strings.Contains(body, secret) {
+			t.Fatalf("The secret %s is not redacted: %s", secret, body)
+		}
+	}
+	err = component.Reload(config("bar"))
+	if err != nil {
+		t.Fatalf("Fail to reload the component\n%v", err)
+	}
+	body = get()
+	if !strings.Contains(body, `"bar"`) || strings.Contains(body, `"foo"`) {
+		t.Fatalf("The running configuration was not reloaded: %s", body)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestReloadSettings(t *testing.T) {
+	config := &Configuration{
+		HTTP: http.Configuration{
+			Host: "127.0.0.1",
+			Port: 2002,
+		},
+	}
+	component, err := New(zap.NewExample(), config)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	newConfig := &Configuration{
+		HTTP: http.Configuration{
+			Host: "127.0.0.1",
+			Port: 2002,
+		},
+		MaxAnnotations:      healthcheck.UnlimitedAnnotations,
+		MaxAnnotationsSize:  100,
+		MaxConcurrentChecks: 2,
+		Maintenance:         true,
+		HistorySize:         10,
+		PersistPath:         "/tmp/cabourotte-results.json",
+		Resolver: healthcheck.ResolverConfiguration{
+			Hosts: map[string]string{"foo.example": "127.0.0.1"},
+		},
+	}
+	err = component.Reload(newConfig)
+	if err != nil {
+		t.Fatalf("Fail to reload the component\n%v", err)
+	}
+	maxCount, maxSize := component.Healthcheck.AnnotationsLimits()
+	if maxCount != healthcheck.UnlimitedAnnotations || maxSize != 100 {
+		t.Fatalf("The annotations limits were not reloaded: %d %d", maxCount, maxSize)
+	}
+	if !component.Healthcheck.Maintenance() {
+		t.Fatalf("The global maintenance was not reloaded")
+	}
+	names, err := component.Healthcheck.Resolver.LookupAddr(context.Background(), "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Fail to lookup the address\n%v", err)
+	}
+	if len(names) != 1 || names[0] != "foo.example" {
+		t.Fatalf("The resolver was not reloaded: %v", names)
+	}
+	running := component.RunningConfig().(*Configuration)
+	if running.HistorySize != 0 || running.PersistPath != "" {
+		t.Fatalf("The running configuration contains settings not applied: %v", running)
+	}
+	if !running.Maintenance || running.MaxConcurrentChecks != 2 {
+		t.Fatalf("The running configuration does not contain the reloaded settings: %v", running)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
@@ -1,10 +1,21 @@
 package discovery
 
 import (
+	"github.com/appclacks/cabourotte/discovery/dnssrv"
+	"github.com/appclacks/cabourotte/discovery/file"
 	"github.com/appclacks/cabourotte/discovery/http"
+	"github.com/appclacks/cabourotte/healthcheck"
 )
 
 // Configuration the service discovery mechanisms configuration
 type Configuration struct {
 	HTTP []http.Configuration
+	File []file.Configuration
+	// DNSSRV the DNS SRV discovery mechanisms
+	DNSSRV []dnssrv.Configuration `yaml:"dns-srv"`
+	// MaxResultChanSize the reloads are deferred when the number of results
+	// waiting to be exported is greater than this value (0 to disable)
+	MaxResultChanSize int `yaml:"max-result-chan-size"`
+	// DeferInterval the interval before retrying a deferred reload
+	DeferInterval healthcheck.Duration `yaml:"defer-interval"`
 }
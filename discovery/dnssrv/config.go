@@ -0,0 +1,80 @@
+package dnssrv
+
+import (
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// Configuration the DNS SRV discovery configuration. An healthcheck is
+// created from the TCP or HTTP healthcheck template for each target of the
+// SRV records.
+type Configuration struct {
+	Name string
+	// Records the SRV records to resolve (for example
+	// _http._tcp.example.com)
+	Records []string
+	// Server the DNS server (host:port) to query instead of the default
+	// resolver
+	Server   string               `json:"server,omitempty" yaml:"server,omitempty"`
+	Interval healthcheck.Duration `json:"interval"`
+	// TCPCheck the template of the TCP healthchecks. The target and the
+	// port are set from the SRV records.
+	TCPCheck *healthcheck.TCPHealthcheckConfiguration `json:"tcp-check,omitempty" yaml:"tcp-check,omitempty"`
+	// HTTPCheck the template of the HTTP healthchecks. The target and the
+	// port are set from the SRV records.
+	HTTPCheck *healthcheck.HTTPHealthcheckConfiguration `json:"http-check,omitempty" yaml:"http-check,omitempty"`
+	// RenderTemplates enables Go templates in the path of the HTTP
+	// healthchecks. Templates can use the healthcheck name, labels and the
+	// target and port annotations (for example
+	// /healthz/{{ .Annotations.port }}).
+	RenderTemplates bool `json:"render-templates" yaml:"render-templates"`
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read DNS SRV discovery configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid DNS SRV discovery data source name configuration")
+	}
+	if len(raw.Records) == 0 {
+		return errors.New("The SRV records of the DNS SRV discovery are missing")
+	}
+	if raw.Server != "" {
+		if _, _, err := net.SplitHostPort(raw.Server); err != nil {
+			return errors.Wrapf(err, "Invalid DNS server %s", raw.Server)
+		}
+	}
+	if raw.Interval < healthcheck.Duration(time.Second) {
+		return errors.New("The interval should be greater or equal than 1 second")
+	}
+	if (raw.TCPCheck == nil) == (raw.HTTPCheck == nil) {
+		return errors.New("The DNS SRV discovery should have exactly one TCP or HTTP healthcheck template")
+	}
+	// the template is validated using an example target
+	config := Configuration(raw)
+	if raw.TCPCheck != nil {
+		check := newTCPCheck(&config, target{host: "127.0.0.1", port: 1})
+		if err := check.Validate(); err != nil {
+			return errors.Wrap(err, "Invalid TCP healthcheck template")
+		}
+	}
+	if raw.HTTPCheck != nil {
+		check, err := newHTTPCheck(&config, target{host: "127.0.0.1", port: 1})
+		if err != nil {
+			return errors.Wrap(err, "Invalid HTTP healthcheck template")
+		}
+		if err := check.Validate(); err != nil {
+			return errors.Wrap(err, "Invalid HTTP healthcheck template")
+		}
+	}
+	*configuration = config
+	return nil
+}
@@ -0,0 +1,72 @@
+package dnssrv
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalConfig(t *testing.T) {
+	valid := `
+name: web
+records: ["_http._tcp.web.test"]
+server: "127.0.0.1:53"
+interval: 30s
+http-check:
+  protocol: http
+  path: /healthz
+  valid-status: [200]
+  timeout: 2s
+  interval: 10s
+`
+	var config Configuration
+	if err := yaml.Unmarshal([]byte(valid), &config); err != nil {
+		t.Fatalf("Unmarshal yaml error:\n%v", err)
+	}
+	if config.HTTPCheck == nil || config.HTTPCheck.Path != "/healthz" || len(config.Records) != 1 {
+		t.Fatalf("Invalid configuration %v", config)
+	}
+	invalid := []string{
+		`
+records: ["_http._tcp.web.test"]
+interval: 30s
+tcp-check: {timeout: 2s, interval: 10s}
+`,
+		`
+name: web
+interval: 30s
+tcp-check: {timeout: 2s, interval: 10s}
+`,
+		`
+name: web
+records: ["_http._tcp.web.test"]
+interval: 30s
+`,
+		`
+name: web
+records: ["_http._tcp.web.test"]
+interval: 30s
+tcp-check: {interval: 10s}
+`,
+		`
+name: web
+records: ["_http._tcp.web.test"]
+interval: 30s
+render-templates: true
+http-check: {path: "/{{ .Labels.missing }}", protocol: http, valid-status: [200], timeout: 2s, interval: 10s}
+`,
+		`
+name: web
+records: ["_http._tcp.web.test"]
+server: "127.0.0.1"
+interval: 30s
+tcp-check: {timeout: 2s, interval: 10s}
+`,
+	}
+	for _, in := range invalid {
+		var config Configuration
+		if err := yaml.Unmarshal([]byte(in), &config); err == nil {
+			t.Fatalf("Was expecting an error for %s", in)
+		}
+	}
+}
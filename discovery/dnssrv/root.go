@@ -0,0 +1,204 @@
+package dnssrv
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	dhttp "github.com/appclacks/cabourotte/discovery/http"
+	"github.com/appclacks/cabourotte/discovery/poller"
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// lookupTimeout the maximum duration of the resolution of a SRV record
+const lookupTimeout = 5 * time.Second
+
+// DNSSRVDiscovery the DNS SRV discovery struct
+type DNSSRVDiscovery struct {
+	Logger            *zap.Logger
+	Healthcheck       *healthcheck.Component
+	Config            *Configuration
+	Resolver          *net.Resolver
+	resolutionCounter *prom.CounterVec
+	Poller            *poller.Poller
+
+	// targets the last targets resolved for each record, used when a
+	// record cannot be resolved anymore
+	targets map[string][]target
+}
+
+// target a target of a SRV record
+type target struct {
+	host string
+	port uint16
+}
+
+// newResolver returns a resolver querying the DNS server, or the default
+// resolver if the server is empty
+func newResolver(server string) *net.Resolver {
+	if server == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialer := net.Dialer{}
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// New creates a new DNS SRV discovery
+func New(logger *zap.Logger, config *Configuration, checkComponent *healthcheck.Component, counter *prom.CounterVec, deferred *prom.CounterVec) (*DNSSRVDiscovery, error) {
+	component := DNSSRVDiscovery{
+		Logger:            logger,
+		Healthcheck:       checkComponent,
+		Config:            config,
+		Resolver:          newResolver(config.Server),
+		resolutionCounter: counter,
+		targets:           make(map[string][]target),
+	}
+	component.Poller = poller.New(logger, "DNS SRV discovery", config.Name, checkComponent, deferred, component.load)
+	component.Poller.Immediate = true
+	return &component, nil
+}
+
+// checkName returns the name of the healthcheck created for a target
+func checkName(discoveryName string, templateName string, t target) string {
+	name := templateName
+	if name == "" {
+		name = discoveryName
+	}
+	return fmt.Sprintf("%s-%s-%d", name, t.host, t.port)
+}
+
+// newTCPCheck creates the TCP healthcheck of a target from the template
+func newTCPCheck(config *Configuration, t target) *healthcheck.TCPHealthcheckConfiguration {
+	check := config.TCPCheck.DeepCopy()
+	check.Base.Name = checkName(config.Name, config.TCPCheck.Base.Name, t)
+	check.Target = t.host
+	check.Port = uint(t.port)
+	return check
+}
+
+// newHTTPCheck creates the HTTP healthcheck of a target from the template.
+// The path template is rendered if the templates are enabled, the target
+// and the port being available in the annotations.
+func newHTTPCheck(config *Configuration, t target) (*healthcheck.HTTPHealthcheckConfiguration, error) {
+	check := config.HTTPCheck.DeepCopy()
+	check.Base.Name = checkName(config.Name, config.HTTPCheck.Base.Name, t)
+	check.Target = t.host
+	check.Port = uint(t.port)
+	if config.RenderTemplates {
+		annotations := map[string]string{
+			"target": t.host,
+			"port":   strconv.Itoa(int(t.port)),
+		}
+		path, err := dhttp.Render(check.Path, check.Base, annotations)
+		if err != nil {
+			return nil, err
+		}
+		check.Path = path
+	}
+	return check, nil
+}
+
+// resolve resolves a SRV record and returns its targets
+func (c *DNSSRVDiscovery) resolve(record string) ([]target, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	_, records, err := c.Resolver.LookupSRV(ctx, "", "", record)
+	if err != nil {
+		return nil, errors.Wrapf(err, "DNS SRV discovery: fail to resolve %s", record)
+	}
+	targets := make([]target, 0, len(records))
+	for _, srv := range records {
+		targets = append(targets, target{
+			host: strings.TrimSuffix(srv.Target, "."),
+			port: srv.Port,
+		})
+	}
+	return targets, nil
+}
+
+// load resolves all SRV records and reloads the healthchecks.
+// If a record cannot be resolved, its last resolved targets are used.
+func (c *DNSSRVDiscovery) load() error {
+	c.Logger.Debug(fmt.Sprintf("DNS SRV discovery: resolving %s", strings.Join(c.Config.Records, ", ")))
+	targets := make(map[string][]target)
+	var all []target
+	for _, record := range c.Config.Records {
+		status := "success"
+		recordTargets, err := c.resolve(record)
+		if err != nil {
+			status = "failure"
+			c.Logger.Error(err.Error())
+			previous, ok := c.targets[record]
+			if !ok {
+				c.resolutionCounter.With(prom.Labels{"status": status, "name": c.Config.Name}).Inc()
+				continue
+			}
+			recordTargets = previous
+		}
+		c.resolutionCounter.With(prom.Labels{"status": status, "name": c.Config.Name}).Inc()
+		targets[record] = recordTargets
+		all = append(all, recordTargets...)
+	}
+	c.targets = targets
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].host != all[j].host {
+			return all[i].host < all[j].host
+		}
+		return all[i].port < all[j].port
+	})
+	var tcpChecks []healthcheck.TCPHealthcheckConfiguration
+	var httpChecks []healthcheck.HTTPHealthcheckConfiguration
+	for i, t := range all {
+		// the same target can be returned by several records
+		if i > 0 && t == all[i-1] {
+			continue
+		}
+		if c.Config.TCPCheck != nil {
+			tcpChecks = append(tcpChecks, *newTCPCheck(c.Config, t))
+		}
+		if c.Config.HTTPCheck != nil {
+			check, err := newHTTPCheck(c.Config, t)
+			if err != nil {
+				return errors.Wrapf(err, "DNS SRV discovery: fail to render the templates of %s", c.Config.Name)
+			}
+			httpChecks = append(httpChecks, *check)
+		}
+	}
+	return c.Healthcheck.ReloadForSource(
+		fmt.Sprintf("%s-%s", healthcheck.SourceDNSSRVDiscovery, c.Config.Name),
+		nil,
+		nil,
+		nil,
+		tcpChecks,
+		httpChecks,
+		nil,
+		nil,
+		nil,
+		nil)
+}
+
+// Start starts the DNS SRV discovery component
+func (c *DNSSRVDiscovery) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the DNS SRV healthcheck discovery %s", c.Config.Name))
+	c.Poller.Start(time.Duration(c.Config.Interval))
+	return nil
+}
+
+// Stop stops the DNS SRV discovery component
+func (c *DNSSRVDiscovery) Stop() error {
+	c.Logger.Info("Stopping the DNS SRV discovery")
+	return c.Poller.Stop()
+}
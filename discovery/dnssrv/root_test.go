@@ -0,0 +1,188 @@
+package dnssrv
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+// startDNSServer starts a DNS server answering to SRV queries with the given
+// records, and returns its address. Unknown records are answered with a
+// server failure.
+func startDNSServer(t *testing.T, records map[string][]dnsmessage.SRVResource) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the DNS server :\n%v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			var request dnsmessage.Message
+			if err := request.Unpack(buffer[:n]); err != nil || len(request.Questions) == 0 {
+				continue
+			}
+			question := request.Questions[0]
+			response := dnsmessage.Message{
+				Header: dnsmessage.Header{
+					ID:            request.Header.ID,
+					Response:      true,
+					Authoritative: true,
+				},
+				Questions: request.Questions,
+			}
+			srvRecords, ok := records[question.Name.String()]
+			if !ok {
+				response.Header.RCode = dnsmessage.RCodeServerFailure
+			}
+			for i := range srvRecords {
+				response.Answers = append(response.Answers, dnsmessage.Resource{
+					Header: dnsmessage.ResourceHeader{
+						Name:  question.Name,
+						Type:  dnsmessage.TypeSRV,
+						Class: dnsmessage.ClassINET,
+						TTL:   60,
+					},
+					Body: &srvRecords[i],
+				})
+			}
+			packed, err := response.Pack()
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(packed, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestLoad(t *testing.T) {
+	counter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "dnssrv_discovery_resolutions_total",
+			Help: "Count the number of SRV records resolutions by the DNS SRV discovery.",
+		},
+		[]string{"status", "name"})
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	err = promComponent.Register(counter)
+	if err != nil {
+		t.Fatalf("Fail to register the counter\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), promComponent, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	records := map[string][]dnsmessage.SRVResource{
+		"_http._tcp.web.test.": {
+			{Priority: 1, Weight: 1, Port: 8080, Target: dnsmessage.MustNewName("web-1.test.")},
+			{Priority: 1, Weight: 1, Port: 8081, Target: dnsmessage.MustNewName("web-2.test.")},
+		},
+		"_http._tcp.api.test.": {
+			{Priority: 1, Weight: 1, Port: 8080, Target: dnsmessage.MustNewName("web-1.test.")},
+		},
+	}
+	discoveryConfig := Configuration{
+		Name:     "web",
+		Records:  []string{"_http._tcp.web.test", "_http._tcp.api.test"},
+		Server:   startDNSServer(t, records),
+		Interval: healthcheck.Duration(10 * time.Second),
+		TCPCheck: &healthcheck.TCPHealthcheckConfiguration{
+			Base: healthcheck.Base{
+				Interval: healthcheck.Duration(10 * time.Second),
+				Labels:   map[string]string{"env": "prod"},
+			},
+			Timeout: healthcheck.Duration(2 * time.Second),
+		},
+	}
+	discovery, err := New(logger, &discoveryConfig, checkComponent, counter, nil)
+	if err != nil {
+		t.Fatalf("Fail to create the DNS SRV discovery component :\n%v", err)
+	}
+	err = discovery.load()
+	if err != nil {
+		t.Fatalf("DNS SRV discovery load failed\n%v", err)
+	}
+	checks := checkComponent.SourceChecksNames("dnssrv-web")
+	if len(checks) != 2 || !checks["web-web-1.test-8080"] || !checks["web-web-2.test-8081"] {
+		t.Fatalf("Invalid healthchecks %v", checks)
+	}
+	check := checkComponent.GetCheck("web-web-2.test-8081")
+	config := check.GetConfig().(*healthcheck.TCPHealthcheckConfiguration)
+	if config.Target != "web-2.test" || config.Port != 8081 || config.Base.Labels["env"] != "prod" {
+		t.Fatalf("Invalid healthcheck configuration %v", config)
+	}
+	// the previous targets are kept if the record can't be resolved
+	discovery.Resolver = newResolver(startDNSServer(t, map[string][]dnsmessage.SRVResource{
+		"_http._tcp.api.test.": records["_http._tcp.api.test."],
+	}))
+	err = discovery.load()
+	if err != nil {
+		t.Fatalf("DNS SRV discovery load failed\n%v", err)
+	}
+	checks = checkComponent.SourceChecksNames("dnssrv-web")
+	if len(checks) != 2 {
+		t.Fatalf("The previous targets should be kept %v", checks)
+	}
+	var metrics bytes.Buffer
+	err = promComponent.Write(&metrics)
+	if err != nil {
+		t.Fatalf("Fail to write the metrics\n%v", err)
+	}
+	for _, expected := range []string{
+		`dnssrv_discovery_resolutions_total{name="web",status="success"} 3`,
+		`dnssrv_discovery_resolutions_total{name="web",status="failure"} 1`,
+	} {
+		if !strings.Contains(metrics.String(), expected) {
+			t.Fatalf("Invalid metrics, %s not found\n%s", expected, metrics.String())
+		}
+	}
+}
+
+func TestNewHTTPCheckRenderTemplates(t *testing.T) {
+	config := Configuration{
+		Name: "web",
+		HTTPCheck: &healthcheck.HTTPHealthcheckConfiguration{
+			Base: healthcheck.Base{
+				Labels: map[string]string{"component": "api"},
+			},
+			Path: "/healthz/{{ .Labels.component }}/{{ .Annotations.port }}",
+		},
+	}
+	check, err := newHTTPCheck(&config, target{host: "web-1.test", port: 8080})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck\n%v", err)
+	}
+	if check.Path != config.HTTPCheck.Path {
+		t.Fatalf("The path should not be rendered: %s", check.Path)
+	}
+	config.RenderTemplates = true
+	check, err = newHTTPCheck(&config, target{host: "web-1.test", port: 8080})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck\n%v", err)
+	}
+	if check.Path != "/healthz/api/8080" || check.Target != "web-1.test" {
+		t.Fatalf("Invalid healthcheck %v", check)
+	}
+	config.HTTPCheck.Path = "/healthz/{{ .Labels.missing }}"
+	_, err = newHTTPCheck(&config, target{host: "web-1.test", port: 8080})
+	if err == nil {
+		t.Fatalf("Was expecting an error for a missing label")
+	}
+}
@@ -0,0 +1,40 @@
+package file
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// Configuration the file discovery configuration
+type Configuration struct {
+	Name string
+	// Glob the pattern of the files containing the healthchecks definitions
+	Glob     string
+	Interval healthcheck.Duration `json:"interval"`
+	// RenderTemplates enables Go templates in the healthchecks targets,
+	// port templates, paths and domains, as in the HTTP discovery
+	RenderTemplates bool `json:"render-templates" yaml:"render-templates"`
+}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read file discovery configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid file discovery data source name configuration")
+	}
+	if raw.Glob == "" {
+		return errors.New("Invalid glob for the file discovery configuration")
+	}
+	if raw.Interval < healthcheck.Duration(time.Second) {
+		return errors.New("The interval should be greater or equal than 1 second")
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
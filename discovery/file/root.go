@@ -0,0 +1,213 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	dhttp "github.com/appclacks/cabourotte/discovery/http"
+	"github.com/appclacks/cabourotte/discovery/poller"
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// FileDiscovery the file discovery struct
+type FileDiscovery struct {
+	Logger      *zap.Logger
+	Healthcheck *healthcheck.Component
+	Config      *Configuration
+	readCounter *prom.CounterVec
+	Poller      *poller.Poller
+
+	// payloads the last valid healthchecks of each file, used when a file
+	// cannot be read anymore or becomes invalid
+	payloads map[string]dhttp.Healthchecks
+}
+
+// New creates a new file discovery
+func New(logger *zap.Logger, config *Configuration, checkComponent *healthcheck.Component, counter *prom.CounterVec, deferred *prom.CounterVec) (*FileDiscovery, error) {
+	if _, err := filepath.Match(config.Glob, ""); err != nil {
+		return nil, errors.Wrapf(err, "Invalid glob %s", config.Glob)
+	}
+	component := FileDiscovery{
+		Logger:      logger,
+		Healthcheck: checkComponent,
+		Config:      config,
+		readCounter: counter,
+		payloads:    make(map[string]dhttp.Healthchecks),
+	}
+	component.Poller = poller.New(logger, "File discovery", config.Name, checkComponent, deferred, component.load)
+	component.Poller.Immediate = true
+	return &component, nil
+}
+
+// readFile reads an healthchecks definition file and renders its templates
+// if enabled
+func readFile(path string, render bool) (dhttp.Healthchecks, error) {
+	var payload dhttp.ResultPayload
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return dhttp.Healthchecks{}, errors.Wrapf(err, "File discovery: fail to read %s", path)
+	}
+	if err := yaml.UnmarshalStrict(content, &payload); err != nil {
+		return dhttp.Healthchecks{}, errors.Wrapf(err, "File discovery: fail to parse %s", path)
+	}
+	checks, err := payload.Healthchecks(render)
+	if err != nil {
+		return checks, errors.Wrapf(err, "File discovery: fail to render the templates of %s", path)
+	}
+	return checks, nil
+}
+
+// payloadNames returns the names of the healthchecks defined in a file
+func payloadNames(payload dhttp.Healthchecks) []string {
+	names := []string{}
+	for _, config := range payload.CommandChecks {
+		names = append(names, config.Base.Name)
+	}
+	for _, config := range payload.DNSChecks {
+		names = append(names, config.Base.Name)
+	}
+	for _, config := range payload.TCPChecks {
+		names = append(names, config.Base.Name)
+	}
+	for _, config := range payload.HTTPChecks {
+		names = append(names, config.Base.Name)
+	}
+	for _, config := range payload.TLSChecks {
+		names = append(names, config.Base.Name)
+	}
+	for _, config := range payload.GRPCChecks {
+		names = append(names, config.Base.Name)
+	}
+	for _, config := range payload.PostgresChecks {
+		names = append(names, config.Base.Name)
+	}
+	for _, config := range payload.UDPChecks {
+		names = append(names, config.Base.Name)
+	}
+	return names
+}
+
+// registerNames verifies that the healthchecks names of a file are unique
+// and not already used by another file, and then registers them
+func registerNames(names map[string]string, path string, payload dhttp.Healthchecks) error {
+	fileNames := payloadNames(payload)
+	seen := make(map[string]bool)
+	for _, name := range fileNames {
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("File discovery: the healthcheck %s of %s is already defined in %s", name, path, other)
+		}
+		if seen[name] {
+			return fmt.Errorf("File discovery: the healthcheck %s is defined several times in %s", name, path)
+		}
+		seen[name] = true
+	}
+	for _, name := range fileNames {
+		names[name] = path
+	}
+	return nil
+}
+
+// validate verifies the healthchecks defined in a file
+func (c *FileDiscovery) validate(source string, path string, payload dhttp.Healthchecks) error {
+	_, err := c.Healthcheck.BuildChecks(
+		source,
+		nil,
+		payload.CommandChecks,
+		payload.DNSChecks,
+		payload.TCPChecks,
+		payload.HTTPChecks,
+		payload.TLSChecks,
+		payload.GRPCChecks,
+		payload.PostgresChecks,
+		payload.UDPChecks)
+	if err != nil {
+		return errors.Wrapf(err, "File discovery: invalid healthcheck in %s", path)
+	}
+	return nil
+}
+
+// load reads all files matching the glob and reloads the healthchecks.
+// If a file cannot be read, contains an invalid healthcheck or a
+// healthcheck already defined in another file, the last valid definitions
+// for this file are used.
+func (c *FileDiscovery) load() error {
+	c.Logger.Debug(fmt.Sprintf("File discovery: loading %s", c.Config.Glob))
+	paths, err := filepath.Glob(c.Config.Glob)
+	if err != nil {
+		return errors.Wrapf(err, "File discovery: invalid glob %s", c.Config.Glob)
+	}
+	sort.Strings(paths)
+	source := fmt.Sprintf("%s-%s", healthcheck.SourceFileDiscovery, c.Config.Name)
+	payloads := make(map[string]dhttp.Healthchecks)
+	names := make(map[string]string)
+	var merged dhttp.Healthchecks
+	for _, path := range paths {
+		status := "success"
+		payload, err := readFile(path, c.Config.RenderTemplates)
+		if err == nil {
+			err = c.validate(source, path, payload)
+		}
+		if err == nil {
+			err = registerNames(names, path, payload)
+		}
+		if err != nil {
+			status = "failure"
+			c.Logger.Error(err.Error())
+			previous, ok := c.payloads[path]
+			if ok {
+				err = registerNames(names, path, previous)
+				if err != nil {
+					c.Logger.Error(err.Error())
+				}
+			}
+			if !ok || err != nil {
+				c.readCounter.With(prom.Labels{"status": status, "name": c.Config.Name}).Inc()
+				continue
+			}
+			payload = previous
+		}
+		c.readCounter.With(prom.Labels{"status": status, "name": c.Config.Name}).Inc()
+		payloads[path] = payload
+		merged.CommandChecks = append(merged.CommandChecks, payload.CommandChecks...)
+		merged.DNSChecks = append(merged.DNSChecks, payload.DNSChecks...)
+		merged.TCPChecks = append(merged.TCPChecks, payload.TCPChecks...)
+		merged.HTTPChecks = append(merged.HTTPChecks, payload.HTTPChecks...)
+		merged.TLSChecks = append(merged.TLSChecks, payload.TLSChecks...)
+		merged.GRPCChecks = append(merged.GRPCChecks, payload.GRPCChecks...)
+		merged.PostgresChecks = append(merged.PostgresChecks, payload.PostgresChecks...)
+		merged.UDPChecks = append(merged.UDPChecks, payload.UDPChecks...)
+	}
+	c.payloads = payloads
+	return c.Healthcheck.ReloadForSource(
+		source,
+		nil,
+		merged.CommandChecks,
+		merged.DNSChecks,
+		merged.TCPChecks,
+		merged.HTTPChecks,
+		merged.TLSChecks,
+		merged.GRPCChecks,
+		merged.PostgresChecks,
+		merged.UDPChecks)
+}
+
+// Start starts the file discovery component
+func (c *FileDiscovery) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the file healthcheck discovery on %s", c.Config.Glob))
+	c.Poller.Start(time.Duration(c.Config.Interval))
+	return nil
+}
+
+// Stop stops the file discovery component
+func (c *FileDiscovery) Stop() error {
+	c.Logger.Info("Stopping the file discovery")
+	return c.Poller.Stop()
+}
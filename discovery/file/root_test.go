@@ -0,0 +1,263 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestLoad(t *testing.T) {
+	counter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "file_discovery_reads_total",
+			Help: "Count the number of files read by the file discovery.",
+		},
+		[]string{"status", "name"})
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), promComponent, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"tcp.yaml": `
+tcp-checks:
+  - name: "tcp"
+    target: "127.0.0.1"
+    port: 9000
+    timeout: 2s
+    interval: 10s
+`,
+		"dns.yaml": `
+dns-checks:
+  - name: "dns"
+    domain: "mcorbin.fr"
+    timeout: 2s
+    interval: 10s
+`,
+		"invalid.yaml": `invalid-checks: []`,
+		"ignored.json": `{}`,
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("Fail to write file\n%v", err)
+		}
+	}
+	discoveryConfig := Configuration{
+		Name:     "foo",
+		Glob:     filepath.Join(dir, "*.yaml"),
+		Interval: healthcheck.Duration(10 * time.Second),
+	}
+	discovery, err := New(logger, &discoveryConfig, checkComponent, counter, nil)
+	if err != nil {
+		t.Fatalf("Fail to create the file discovery component :\n%v", err)
+	}
+	err = discovery.load()
+	if err != nil {
+		t.Fatalf("File discovery load failed\n%v", err)
+	}
+	checks := checkComponent.SourceChecksNames("file-foo")
+	if len(checks) != 2 || !checks["tcp"] || !checks["dns"] {
+		t.Fatalf("Invalid healthchecks %v", checks)
+	}
+	err = os.WriteFile(filepath.Join(dir, "tcp.yaml"), []byte("tcp-checks: ["), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write file\n%v", err)
+	}
+	err = os.Remove(filepath.Join(dir, "dns.yaml"))
+	if err != nil {
+		t.Fatalf("Fail to remove file\n%v", err)
+	}
+	err = discovery.load()
+	if err != nil {
+		t.Fatalf("File discovery load failed\n%v", err)
+	}
+	checks = checkComponent.SourceChecksNames("file-foo")
+	if len(checks) != 1 || !checks["tcp"] {
+		t.Fatalf("The previous definitions should be kept for invalid files %v", checks)
+	}
+	// an invalid healthcheck or a duplicated name only impacts its file
+	files = map[string]string{
+		"tcp.yaml": `
+tcp-checks:
+  - name: "tcp"
+    target: "127.0.0.1"
+    port: 9000
+    timeout: 2s
+`,
+		"dns.yaml": `
+dns-checks:
+  - name: "dns"
+    domain: "mcorbin.fr"
+    timeout: 2s
+    interval: 10s
+`,
+		"other.yaml": `
+dns-checks:
+  - name: "dns"
+    domain: "appclacks.com"
+    timeout: 2s
+    interval: 10s
+`,
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("Fail to write file\n%v", err)
+		}
+	}
+	err = discovery.load()
+	if err != nil {
+		t.Fatalf("File discovery load failed\n%v", err)
+	}
+	checks = checkComponent.SourceChecksNames("file-foo")
+	if len(checks) != 2 || !checks["tcp"] || !checks["dns"] {
+		t.Fatalf("Invalid healthchecks %v", checks)
+	}
+	if _, ok := discovery.payloads[filepath.Join(dir, "other.yaml")]; ok {
+		t.Fatalf("The file with a duplicated healthcheck should be ignored")
+	}
+	for _, check := range checkComponent.ListChecks() {
+		if check.Base().Name == "dns" && check.GetConfig().(*healthcheck.DNSHealthcheckConfiguration).Domain != "mcorbin.fr" {
+			t.Fatalf("The duplicated healthcheck should be ignored")
+		}
+	}
+}
+
+func TestLoadRenderTemplates(t *testing.T) {
+	counter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "file_discovery_reads_total",
+			Help: "Count the number of files read by the file discovery.",
+		},
+		[]string{"status", "name"})
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), promComponent, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	dir := t.TempDir()
+	content := `
+tcp-checks:
+  - name: "tcp"
+    target: "{{ .Name }}.svc"
+    port-template: "{{ .Annotations.port }}"
+    annotations:
+      port: "9000"
+    timeout: 2s
+    interval: 10s
+`
+	err = os.WriteFile(filepath.Join(dir, "tcp.yaml"), []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write file\n%v", err)
+	}
+	discoveryConfig := Configuration{
+		Name:     "foo",
+		Glob:     filepath.Join(dir, "*.yaml"),
+		Interval: healthcheck.Duration(10 * time.Second),
+	}
+	discovery, err := New(logger, &discoveryConfig, checkComponent, counter, nil)
+	if err != nil {
+		t.Fatalf("Fail to create the file discovery component :\n%v", err)
+	}
+	// the port template is rejected if the templates are not rendered
+	err = discovery.load()
+	if err != nil {
+		t.Fatalf("File discovery load failed\n%v", err)
+	}
+	if len(checkComponent.SourceChecksNames("file-foo")) != 0 {
+		t.Fatalf("The port template should be rejected")
+	}
+	discoveryConfig.RenderTemplates = true
+	err = discovery.load()
+	if err != nil {
+		t.Fatalf("File discovery load failed\n%v", err)
+	}
+	config := checkComponent.GetCheck("tcp").GetConfig().(*healthcheck.TCPHealthcheckConfiguration)
+	if config.Target != "tcp.svc" || config.Port != 9000 {
+		t.Fatalf("Invalid rendered healthcheck %v", config)
+	}
+}
+
+func TestPollBackpressure(t *testing.T) {
+	counter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "file_discovery_reads_total",
+			Help: "Count the number of files read by the file discovery.",
+		},
+		[]string{"status", "name"})
+	deferred := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "discovery_deferred_reloads_total",
+			Help: "Count the number of discovery reloads deferred because the daemon is overloaded.",
+		},
+		[]string{"name"})
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	chanResult := make(chan *healthcheck.Result, 10)
+	checkComponent, err := healthcheck.New(logger, chanResult, promComponent, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	dir := t.TempDir()
+	err = os.WriteFile(filepath.Join(dir, "tcp.yaml"), []byte(`
+tcp-checks:
+  - name: "tcp"
+    target: "127.0.0.1"
+    port: 9000
+    timeout: 2s
+    interval: 10s
+`), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write file\n%v", err)
+	}
+	discoveryConfig := Configuration{
+		Name:     "foo",
+		Glob:     filepath.Join(dir, "*.yaml"),
+		Interval: healthcheck.Duration(10 * time.Second),
+	}
+	discovery, err := New(logger, &discoveryConfig, checkComponent, counter, deferred)
+	if err != nil {
+		t.Fatalf("Fail to create the file discovery component :\n%v", err)
+	}
+	discovery.Poller.MaxResultChanSize = 2
+	for i := 0; i < 3; i++ {
+		chanResult <- &healthcheck.Result{}
+	}
+	if discovery.Poller.Poll() {
+		t.Fatalf("The reload should be deferred")
+	}
+	if len(checkComponent.SourceChecksNames("file-foo")) != 0 {
+		t.Fatalf("The healthchecks should not be loaded")
+	}
+	<-chanResult
+	if !discovery.Poller.Poll() {
+		t.Fatalf("The reload should not be deferred")
+	}
+	if len(checkComponent.SourceChecksNames("file-foo")) != 1 {
+		t.Fatalf("The healthchecks should be loaded")
+	}
+	err = checkComponent.RemoveCheck("tcp")
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+}
@@ -19,14 +19,95 @@ type Configuration struct {
 	Cert     string               `json:"cert,omitempty"`
 	Cacert   string               `json:"cacert,omitempty"`
 	Insecure bool
+	// RenderTemplates enables Go templates in the discovered healthchecks
+	// targets, port templates, paths and domains. Templates can use the
+	// healthcheck name, labels and annotations (for example
+	// /healthz/{{ .Labels.component }}).
+	RenderTemplates bool `json:"render-templates" yaml:"render-templates"`
+	// Strict rejects payloads with unknown fields or an unsupported version
+	Strict bool `json:"strict" yaml:"strict"`
 }
 
+// PayloadVersion the version of the discovery payload supported by
+// Cabourotte. Payloads without version are considered as using this version.
+const PayloadVersion = 1
+
 type ResultPayload struct {
-	CommandChecks []healthcheck.CommandHealthcheckConfiguration `json:"command-checks"`
-	DNSChecks     []healthcheck.DNSHealthcheckConfiguration     `json:"dns-checks"`
-	TCPChecks     []healthcheck.TCPHealthcheckConfiguration     `json:"tcp-checks"`
-	HTTPChecks    []healthcheck.HTTPHealthcheckConfiguration    `json:"http-checks"`
-	TLSChecks     []healthcheck.TLSHealthcheckConfiguration     `json:"tls-checks"`
+	Version        int                                           `json:"version,omitempty" yaml:"version,omitempty"`
+	CommandChecks  []healthcheck.CommandHealthcheckConfiguration `json:"command-checks" yaml:"command-checks"`
+	DNSChecks      []DNSCheck                                    `json:"dns-checks" yaml:"dns-checks"`
+	TCPChecks      []TCPCheck                                    `json:"tcp-checks" yaml:"tcp-checks"`
+	HTTPChecks     []HTTPCheck                                   `json:"http-checks" yaml:"http-checks"`
+	TLSChecks      []TLSCheck                                    `json:"tls-checks" yaml:"tls-checks"`
+	GRPCChecks     []GRPCCheck                                   `json:"grpc-checks" yaml:"grpc-checks"`
+	PostgresChecks []PostgresCheck                               `json:"postgres-checks" yaml:"postgres-checks"`
+	UDPChecks      []UDPCheck                                    `json:"udp-checks" yaml:"udp-checks"`
+}
+
+// DNSCheck a discovered DNS healthcheck
+type DNSCheck struct {
+	healthcheck.DNSHealthcheckConfiguration `yaml:",inline"`
+	// Annotations the annotations of the discovered object, available in
+	// the templates
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+}
+
+// Metadata the metadata of a discovered healthcheck, used to render its
+// templates
+type Metadata struct {
+	// Annotations the annotations of the discovered object, available in
+	// the templates
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	// PortTemplate a Go template rendered into the healthcheck port
+	PortTemplate string `json:"port-template,omitempty" yaml:"port-template,omitempty"`
+}
+
+// TCPCheck a discovered TCP healthcheck
+type TCPCheck struct {
+	healthcheck.TCPHealthcheckConfiguration `yaml:",inline"`
+	Metadata                                `yaml:",inline"`
+}
+
+// HTTPCheck a discovered HTTP healthcheck
+type HTTPCheck struct {
+	healthcheck.HTTPHealthcheckConfiguration `yaml:",inline"`
+	Metadata                                 `yaml:",inline"`
+}
+
+// TLSCheck a discovered TLS healthcheck
+type TLSCheck struct {
+	healthcheck.TLSHealthcheckConfiguration `yaml:",inline"`
+	Metadata                                `yaml:",inline"`
+}
+
+// GRPCCheck a discovered GRPC healthcheck
+type GRPCCheck struct {
+	healthcheck.GRPCHealthcheckConfiguration `yaml:",inline"`
+	Metadata                                 `yaml:",inline"`
+}
+
+// PostgresCheck a discovered Postgres healthcheck
+type PostgresCheck struct {
+	healthcheck.PostgresHealthcheckConfiguration `yaml:",inline"`
+	Metadata                                     `yaml:",inline"`
+}
+
+// UDPCheck a discovered UDP healthcheck
+type UDPCheck struct {
+	healthcheck.UDPHealthcheckConfiguration `yaml:",inline"`
+	Metadata                                `yaml:",inline"`
+}
+
+// Healthchecks the healthchecks configurations of a discovery payload
+type Healthchecks struct {
+	CommandChecks  []healthcheck.CommandHealthcheckConfiguration
+	DNSChecks      []healthcheck.DNSHealthcheckConfiguration
+	TCPChecks      []healthcheck.TCPHealthcheckConfiguration
+	HTTPChecks     []healthcheck.HTTPHealthcheckConfiguration
+	TLSChecks      []healthcheck.TLSHealthcheckConfiguration
+	GRPCChecks     []healthcheck.GRPCHealthcheckConfiguration
+	PostgresChecks []healthcheck.PostgresHealthcheckConfiguration
+	UDPChecks      []healthcheck.UDPHealthcheckConfiguration
 }
 
 // UnmarshalYAML Parse a configuration from YAML.
@@ -1,18 +1,22 @@
 package http
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/pkg/errors"
 	prom "github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
-	"gopkg.in/tomb.v2"
 
+	"github.com/appclacks/cabourotte/discovery/poller"
 	"github.com/appclacks/cabourotte/healthcheck"
 	"github.com/appclacks/cabourotte/tls"
 )
@@ -26,12 +30,11 @@ type HTTPDiscovery struct {
 	URL              string
 	Config           *Configuration
 	Client           *http.Client
-	t                tomb.Tomb
-	tick             *time.Ticker
+	Poller           *poller.Poller
 }
 
 // New creates a new HTTP Discovery
-func New(logger *zap.Logger, config *Configuration, checkComponent *healthcheck.Component, counter *prom.CounterVec, histogram *prom.HistogramVec) (*HTTPDiscovery, error) {
+func New(logger *zap.Logger, config *Configuration, checkComponent *healthcheck.Component, counter *prom.CounterVec, histogram *prom.HistogramVec, deferred *prom.CounterVec) (*HTTPDiscovery, error) {
 	protocol := "http"
 	tlsConfig, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, "", config.Insecure)
 	if err != nil {
@@ -64,6 +67,7 @@ func New(logger *zap.Logger, config *Configuration, checkComponent *healthcheck.
 			},
 		},
 	}
+	component.Poller = poller.New(logger, "HTTP discovery", config.Name, checkComponent, deferred, component.load)
 	return &component, nil
 }
 
@@ -98,56 +102,193 @@ func (c *HTTPDiscovery) request() error {
 	if resp.StatusCode != 200 {
 		return fmt.Errorf("HTTP Discovery: request failed, status %d, body %s", resp.StatusCode, string(responseBody))
 	}
-	var payload ResultPayload
-	if err := json.Unmarshal(responseBody, &payload); err != nil {
-		return fmt.Errorf("HTTP Discovery: fail to convert the payload from json: %s", err.Error())
+	payload, err := c.parsePayload(responseBody)
+	if err != nil {
+		return err
+	}
+	checks, err := payload.Healthchecks(c.Config.RenderTemplates)
+	if err != nil {
+		return errors.Wrapf(err, "HTTP Discovery: fail to render templates")
 	}
 	return c.Healthcheck.ReloadForSource(
 		fmt.Sprintf("%s-%s", healthcheck.SourceHTTPDiscovery, c.Config.Name),
 		nil,
-		payload.CommandChecks,
-		payload.DNSChecks,
-		payload.TCPChecks,
-		payload.HTTPChecks,
-		payload.TLSChecks)
+		checks.CommandChecks,
+		checks.DNSChecks,
+		checks.TCPChecks,
+		checks.HTTPChecks,
+		checks.TLSChecks,
+		checks.GRPCChecks,
+		checks.PostgresChecks,
+		checks.UDPChecks)
 }
 
-// Start starts the HTTP discovery component
-func (c *HTTPDiscovery) Start() error {
-	c.tick = time.NewTicker(time.Duration(c.Config.Interval))
-	c.t.Go(func() error {
-		c.Logger.Info(fmt.Sprintf("Starting the HTTP healthcheck discovery on %s:%d", c.Config.Host, c.Config.Port))
-		for {
-			select {
-			case <-c.tick.C:
-				c.Logger.Debug(fmt.Sprintf("HTTP discovery: polling %s", c.URL))
-				start := time.Now()
-				status := "success"
-				err := c.request()
-				duration := time.Since(start)
-				if err != nil {
-					status = "failure"
-					msg := fmt.Sprintf("HTTP discovery error: %s", err.Error())
-					c.Logger.Error(msg)
-				}
-				c.requestHistogram.With(prom.Labels{"name": c.Config.Name}).Observe(duration.Seconds())
-				c.responseCounter.With(prom.Labels{"status": status, "name": c.Config.Name}).Inc()
-			case <-c.t.Dying():
-				return nil
+// parsePayload parses the discovery payload and verifies its version.
+// Unknown fields and unsupported versions are rejected in strict mode.
+func (c *HTTPDiscovery) parsePayload(body []byte) (ResultPayload, error) {
+	var payload ResultPayload
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if c.Config.Strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&payload); err != nil {
+		return payload, fmt.Errorf("HTTP Discovery: fail to convert the payload from json: %s", err.Error())
+	}
+	if payload.Version != 0 && payload.Version != PayloadVersion {
+		msg := fmt.Sprintf("HTTP Discovery: unsupported payload version %d (supported version: %d)", payload.Version, PayloadVersion)
+		if c.Config.Strict {
+			return payload, errors.New(msg)
+		}
+		c.Logger.Warn(msg)
+	}
+	return payload, nil
+}
+
+// templateData the data available in the discovered healthchecks templates
+type templateData struct {
+	Name        string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// Render renders a template using an healthcheck name and labels, and the
+// annotations of the discovered object
+func Render(value string, base healthcheck.Base, annotations map[string]string) (string, error) {
+	tmpl, err := template.New(base.Name).Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", errors.Wrapf(err, "Invalid template %s for healthcheck %s", value, base.Name)
+	}
+	var result bytes.Buffer
+	data := templateData{
+		Name:        base.Name,
+		Labels:      base.Labels,
+		Annotations: annotations,
+	}
+	if err := tmpl.Execute(&result, data); err != nil {
+		return "", errors.Wrapf(err, "Fail to render template %s for healthcheck %s", value, base.Name)
+	}
+	return result.String(), nil
+}
+
+// render renders the given values and the port template of a discovered
+// healthcheck into the port. The port template is rejected if the
+// templates are disabled.
+func (m Metadata) render(enabled bool, base healthcheck.Base, port *uint, values ...*string) error {
+	if !enabled {
+		if m.PortTemplate != "" {
+			return fmt.Errorf("The port template %s of the healthcheck %s requires render-templates", m.PortTemplate, base.Name)
+		}
+		return nil
+	}
+	for _, value := range values {
+		rendered, err := Render(*value, base, m.Annotations)
+		if err != nil {
+			return err
+		}
+		*value = rendered
+	}
+	if m.PortTemplate == "" {
+		return nil
+	}
+	rendered, err := Render(m.PortTemplate, base, m.Annotations)
+	if err != nil {
+		return err
+	}
+	result, err := strconv.ParseUint(strings.TrimSpace(rendered), 10, 16)
+	if err != nil || result == 0 {
+		return fmt.Errorf("Invalid port %s rendered from template %s for healthcheck %s", rendered, m.PortTemplate, base.Name)
+	}
+	*port = uint(result)
+	return nil
+}
+
+// Healthchecks returns the healthchecks configurations of the payload. The
+// templates in the targets, ports, paths and domains are rendered if render
+// is true.
+func (p ResultPayload) Healthchecks(render bool) (Healthchecks, error) {
+	checks := Healthchecks{
+		CommandChecks: p.CommandChecks,
+	}
+	for _, check := range p.DNSChecks {
+		config := check.DNSHealthcheckConfiguration
+		if render {
+			domain, err := Render(config.Domain, config.Base, check.Annotations)
+			if err != nil {
+				return checks, err
 			}
+			config.Domain = domain
+		}
+		checks.DNSChecks = append(checks.DNSChecks, config)
+	}
+	for _, check := range p.TCPChecks {
+		config := check.TCPHealthcheckConfiguration
+		if err := check.render(render, config.Base, &config.Port, &config.Target); err != nil {
+			return checks, err
+		}
+		checks.TCPChecks = append(checks.TCPChecks, config)
+	}
+	for _, check := range p.HTTPChecks {
+		config := check.HTTPHealthcheckConfiguration
+		if err := check.render(render, config.Base, &config.Port, &config.Target, &config.Path); err != nil {
+			return checks, err
+		}
+		checks.HTTPChecks = append(checks.HTTPChecks, config)
+	}
+	for _, check := range p.TLSChecks {
+		config := check.TLSHealthcheckConfiguration
+		if err := check.render(render, config.Base, &config.Port, &config.Target); err != nil {
+			return checks, err
+		}
+		checks.TLSChecks = append(checks.TLSChecks, config)
+	}
+	for _, check := range p.GRPCChecks {
+		config := check.GRPCHealthcheckConfiguration
+		if err := check.render(render, config.Base, &config.Port, &config.Target); err != nil {
+			return checks, err
+		}
+		checks.GRPCChecks = append(checks.GRPCChecks, config)
+	}
+	for _, check := range p.PostgresChecks {
+		config := check.PostgresHealthcheckConfiguration
+		if err := check.render(render, config.Base, &config.Port, &config.Host); err != nil {
+			return checks, err
 		}
-	})
+		checks.PostgresChecks = append(checks.PostgresChecks, config)
+	}
+	for _, check := range p.UDPChecks {
+		config := check.UDPHealthcheckConfiguration
+		if err := check.render(render, config.Base, &config.Port, &config.Target); err != nil {
+			return checks, err
+		}
+		checks.UDPChecks = append(checks.UDPChecks, config)
+	}
+	return checks, nil
+}
+
+// load polls the discovery endpoint and reloads the healthchecks
+func (c *HTTPDiscovery) load() error {
+	c.Logger.Debug(fmt.Sprintf("HTTP discovery: polling %s", c.URL))
+	start := time.Now()
+	status := "success"
+	err := c.request()
+	duration := time.Since(start)
+	if err != nil {
+		status = "failure"
+	}
+	c.requestHistogram.With(prom.Labels{"name": c.Config.Name}).Observe(duration.Seconds())
+	c.responseCounter.With(prom.Labels{"status": status, "name": c.Config.Name}).Inc()
+	return err
+}
+
+// Start starts the HTTP discovery component
+func (c *HTTPDiscovery) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the HTTP healthcheck discovery on %s:%d", c.Config.Host, c.Config.Port))
+	c.Poller.Start(time.Duration(c.Config.Interval))
 	return nil
 }
 
 // Stop stops the HTTP discovery component
 func (c *HTTPDiscovery) Stop() error {
 	c.Logger.Info("Stopping the http discovery")
-	c.tick.Stop()
-	c.t.Kill(nil)
-	err := c.t.Wait()
-	if err != nil {
-		return err
-	}
-	return nil
+	return c.Poller.Stop()
 }
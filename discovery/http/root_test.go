@@ -19,44 +19,50 @@ import (
 
 func TestRequest(t *testing.T) {
 	firstResultPayload := ResultPayload{
-		DNSChecks: []healthcheck.DNSHealthcheckConfiguration{
-			healthcheck.DNSHealthcheckConfiguration{
-				Base: healthcheck.Base{
-					Name:        "foo",
-					Description: "bar",
-					Interval:    healthcheck.Duration(time.Second * 10),
+		DNSChecks: []DNSCheck{
+			{
+				DNSHealthcheckConfiguration: healthcheck.DNSHealthcheckConfiguration{
+					Base: healthcheck.Base{
+						Name:        "foo",
+						Description: "bar",
+						Interval:    healthcheck.Duration(time.Second * 10),
+					},
+					Timeout: healthcheck.Duration(time.Second * 2),
+					Domain:  "mcorbin.fr",
 				},
-				Timeout: healthcheck.Duration(time.Second * 2),
-				Domain:  "mcorbin.fr",
 			},
 		},
 	}
 	secondResultPayload := ResultPayload{
-		DNSChecks: []healthcheck.DNSHealthcheckConfiguration{
-			healthcheck.DNSHealthcheckConfiguration{
-				Base: healthcheck.Base{
-					Name:        "new",
-					Description: "bar",
-					Interval:    healthcheck.Duration(time.Second * 10),
+		DNSChecks: []DNSCheck{
+			{
+				DNSHealthcheckConfiguration: healthcheck.DNSHealthcheckConfiguration{
+					Base: healthcheck.Base{
+						Name:        "new",
+						Description: "bar",
+						Interval:    healthcheck.Duration(time.Second * 10),
+					},
+					Timeout: healthcheck.Duration(time.Second * 2),
+					Domain:  "mcorbin.fr",
 				},
-				Timeout: healthcheck.Duration(time.Second * 2),
-				Domain:  "mcorbin.fr",
 			},
 		},
-		TCPChecks: []healthcheck.TCPHealthcheckConfiguration{
-			healthcheck.TCPHealthcheckConfiguration{
-				Base: healthcheck.Base{
-					Name:        "tcp",
-					Description: "bar",
-					Interval:    healthcheck.Duration(time.Second * 10),
-					Labels: map[string]string{
-						"environment": "prod",
+		TCPChecks: []TCPCheck{
+			{
+				TCPHealthcheckConfiguration: healthcheck.TCPHealthcheckConfiguration{
+					Base: healthcheck.Base{
+						Name:        "tcp",
+						Description: "bar",
+						Interval:    healthcheck.Duration(time.Second * 10),
+						Labels: map[string]string{
+							"environment": "prod",
+						},
 					},
+					Target:   "127.0.0.1",
+					Port:     8080,
+					SourceIP: healthcheck.IP(net.ParseIP("10.0.0.4")),
+					Timeout:  healthcheck.Duration(time.Second * 5),
 				},
-				Target:   "127.0.0.1",
-				Port:     8080,
-				SourceIP: healthcheck.IP(net.ParseIP("10.0.0.4")),
-				Timeout:  healthcheck.Duration(time.Second * 5),
 			},
 		},
 	}
@@ -76,6 +82,12 @@ func TestRequest(t *testing.T) {
 			Help: "Count the number of HTTP responses for discovery requests.",
 		},
 		[]string{"status", "name"})
+	deferred := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "discovery_deferred_reloads_total",
+			Help: "Count the number of discovery reloads deferred because the daemon is overloaded.",
+		},
+		[]string{"name"})
 	prom, err := prometheus.New()
 	if err != nil {
 		t.Fatalf("Error creating prometheus component :\n%v", err)
@@ -118,9 +130,9 @@ func TestRequest(t *testing.T) {
 		Path:     "/",
 		Port:     uint32(port),
 		Protocol: healthcheck.HTTP,
-		Interval: 10,
+		Interval: healthcheck.Duration(10 * time.Second),
 	}
-	discovery, err := New(logger, &discoveryConfig, checkComponent, counter, histo)
+	discovery, err := New(logger, &discoveryConfig, checkComponent, counter, histo, deferred)
 	if err != nil {
 		t.Fatalf("Fail to create the HTTP discovery component :\n%v", err)
 	}
@@ -156,3 +168,181 @@ func TestRequest(t *testing.T) {
 		)
 	}
 }
+
+func TestRenderTemplates(t *testing.T) {
+	payload := ResultPayload{
+		HTTPChecks: []HTTPCheck{
+			{
+				HTTPHealthcheckConfiguration: healthcheck.HTTPHealthcheckConfiguration{
+					Base: healthcheck.Base{
+						Name: "foo",
+						Labels: map[string]string{
+							"component": "api",
+							"service":   "users",
+						},
+					},
+					Target: "{{ .Labels.service }}.svc",
+					Path:   "/healthz/{{ .Labels.component }}",
+				},
+				Metadata: Metadata{
+					Annotations:  map[string]string{"port": "8080"},
+					PortTemplate: "{{ .Annotations.port }}",
+				},
+			},
+		},
+		TCPChecks: []TCPCheck{
+			{
+				TCPHealthcheckConfiguration: healthcheck.TCPHealthcheckConfiguration{
+					Base: healthcheck.Base{
+						Name: "bar",
+					},
+					Target: "{{ .Name }}.svc",
+				},
+			},
+		},
+	}
+	checks, err := payload.Healthchecks(true)
+	if err != nil {
+		t.Fatalf("Fail to render templates\n%v", err)
+	}
+	if checks.HTTPChecks[0].Target != "users.svc" {
+		t.Fatalf("Invalid target %s", checks.HTTPChecks[0].Target)
+	}
+	if checks.HTTPChecks[0].Path != "/healthz/api" {
+		t.Fatalf("Invalid path %s", checks.HTTPChecks[0].Path)
+	}
+	if checks.TCPChecks[0].Target != "bar.svc" {
+		t.Fatalf("Invalid target %s", checks.TCPChecks[0].Target)
+	}
+	if checks.HTTPChecks[0].Port != 8080 {
+		t.Fatalf("Invalid port %d", checks.HTTPChecks[0].Port)
+	}
+	if payload.HTTPChecks[0].Target != "{{ .Labels.service }}.svc" {
+		t.Fatalf("The payload should not be modified")
+	}
+	for _, port := range []string{"foo", "0", "70000"} {
+		payload.HTTPChecks[0].Annotations["port"] = port
+		_, err = payload.Healthchecks(true)
+		if err == nil {
+			t.Fatalf("Was expecting an error for the port %s", port)
+		}
+	}
+	payload.HTTPChecks[0].Annotations["port"] = "8080"
+	// the port templates require the templates to be rendered
+	_, err = payload.Healthchecks(false)
+	if err == nil {
+		t.Fatalf("Was expecting an error for a port template without rendering")
+	}
+	payload.HTTPChecks[0].PortTemplate = ""
+	checks, err = payload.Healthchecks(false)
+	if err != nil {
+		t.Fatalf("Fail to convert the payload\n%v", err)
+	}
+	if checks.TCPChecks[0].Target != "{{ .Name }}.svc" {
+		t.Fatalf("The templates should not be rendered: %s", checks.TCPChecks[0].Target)
+	}
+	payload.TCPChecks[0].Target = "{{ .Labels.missing }}"
+	_, err = payload.Healthchecks(true)
+	if err == nil {
+		t.Fatalf("Was expecting an error for a missing label")
+	}
+}
+
+func TestPollBackpressure(t *testing.T) {
+	histo := prom.NewHistogramVec(prom.HistogramOpts{
+		Name: "http_discovery_duration_seconds",
+		Help: "Time to execute the HTTP request for healthchecks discovery.",
+	},
+		[]string{"name"},
+	)
+	counter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "http_discovery_responses_total",
+			Help: "Count the number of HTTP responses for discovery requests.",
+		},
+		[]string{"status", "name"})
+	deferred := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "discovery_deferred_reloads_total",
+			Help: "Count the number of discovery reloads deferred because the daemon is overloaded.",
+		},
+		[]string{"name"})
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	chanResult := make(chan *healthcheck.Result, 10)
+	checkComponent, err := healthcheck.New(logger, chanResult, promComponent, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	discoveryConfig := Configuration{
+		Name:     "foo",
+		Host:     "127.0.0.1",
+		Path:     "/",
+		Port:     uint32(port),
+		Protocol: healthcheck.HTTP,
+		Interval: healthcheck.Duration(10 * time.Second),
+	}
+	discovery, err := New(logger, &discoveryConfig, checkComponent, counter, histo, deferred)
+	if err != nil {
+		t.Fatalf("Fail to create the HTTP discovery component :\n%v", err)
+	}
+	discovery.Poller.MaxResultChanSize = 2
+	for i := 0; i < 3; i++ {
+		chanResult <- &healthcheck.Result{}
+	}
+	if discovery.Poller.Poll() {
+		t.Fatalf("The reload should be deferred")
+	}
+	if requests != 0 {
+		t.Fatalf("The discovery endpoint should not be called")
+	}
+	<-chanResult
+	if !discovery.Poller.Poll() {
+		t.Fatalf("The reload should not be deferred")
+	}
+	if requests != 1 {
+		t.Fatalf("The discovery endpoint should be called")
+	}
+}
+
+func TestParsePayload(t *testing.T) {
+	cases := []struct {
+		body    string
+		strict  bool
+		success bool
+	}{
+		{body: `{"tcp-checks": []}`, strict: true, success: true},
+		{body: `{"version": 1, "tcp-checks": []}`, strict: true, success: true},
+		{body: `{"version": 2, "tcp-checks": []}`, strict: false, success: true},
+		{body: `{"version": 2, "tcp-checks": []}`, strict: true, success: false},
+		{body: `{"unknown-checks": []}`, strict: false, success: true},
+		{body: `{"unknown-checks": []}`, strict: true, success: false},
+		{body: `{"tcp-checks": [{"name": "foo", "unknown": true}]}`, strict: true, success: false},
+	}
+	for _, c := range cases {
+		discovery := HTTPDiscovery{
+			Logger: zap.NewExample(),
+			Config: &Configuration{Strict: c.strict},
+		}
+		_, err := discovery.parsePayload([]byte(c.body))
+		if c.success && err != nil {
+			t.Fatalf("Fail to parse the payload %s\n%v", c.body, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %s", c.body)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package poller
+
+import (
+	"fmt"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// DefaultDeferInterval the default interval before retrying a deferred reload
+const DefaultDeferInterval = 5 * time.Second
+
+// Poller periodically loads the healthchecks of a discovery. The loads are
+// deferred while the daemon is overloaded.
+type Poller struct {
+	Logger          *zap.Logger
+	Healthcheck     *healthcheck.Component
+	deferredCounter *prom.CounterVec
+	// Name the name of the discovery
+	Name string
+	// Kind the kind of discovery used in the logs (for example "File
+	// discovery")
+	Kind string
+	// Load loads the healthchecks of the discovery
+	Load func() error
+	// Immediate loads the healthchecks when the poller starts instead of
+	// waiting for the first tick
+	Immediate bool
+	t         tomb.Tomb
+	tick      *time.Ticker
+
+	// MaxResultChanSize reloads are deferred when the number of results
+	// waiting to be exported is greater than this value (0 to disable)
+	MaxResultChanSize int
+	// DeferInterval the interval before retrying a deferred reload
+	DeferInterval time.Duration
+}
+
+// New creates a new poller
+func New(logger *zap.Logger, kind string, name string, checkComponent *healthcheck.Component, deferred *prom.CounterVec, load func() error) *Poller {
+	return &Poller{
+		Logger:          logger,
+		Healthcheck:     checkComponent,
+		deferredCounter: deferred,
+		Name:            name,
+		Kind:            kind,
+		Load:            load,
+		DeferInterval:   DefaultDeferInterval,
+	}
+}
+
+// overloaded returns true if too many results are waiting to be exported
+func (p *Poller) overloaded() bool {
+	return p.MaxResultChanSize > 0 && len(p.Healthcheck.ChanResult) > p.MaxResultChanSize
+}
+
+// Poll loads the healthchecks and logs errors. Returns false if the reload
+// was deferred because the daemon is overloaded.
+func (p *Poller) Poll() bool {
+	if p.overloaded() {
+		p.Logger.Warn(fmt.Sprintf("%s: too many results waiting to be exported, deferring the reload of %s", p.Kind, p.Name))
+		p.deferredCounter.With(prom.Labels{"name": p.Name}).Inc()
+		return false
+	}
+	err := p.Load()
+	if err != nil {
+		p.Logger.Error(fmt.Sprintf("%s error: %s", p.Kind, err.Error()))
+	}
+	return true
+}
+
+// Start polls the discovery at the given interval. A deferred reload is
+// retried after DeferInterval.
+func (p *Poller) Start(interval time.Duration) {
+	p.tick = time.NewTicker(interval)
+	p.t.Go(func() error {
+		var retry <-chan time.Time
+		if p.Immediate && !p.Poll() {
+			retry = time.After(p.DeferInterval)
+		}
+		for {
+			select {
+			case <-p.tick.C:
+				retry = nil
+				if !p.Poll() {
+					retry = time.After(p.DeferInterval)
+				}
+			case <-retry:
+				retry = nil
+				if !p.Poll() {
+					retry = time.After(p.DeferInterval)
+				}
+			case <-p.t.Dying():
+				return nil
+			}
+		}
+	})
+}
+
+// Stop stops the poller
+func (p *Poller) Stop() error {
+	p.tick.Stop()
+	p.t.Kill(nil)
+	return p.t.Wait()
+}
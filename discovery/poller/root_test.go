@@ -0,0 +1,57 @@
+package poller
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestStartDeferred(t *testing.T) {
+	deferred := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "discovery_deferred_reloads_total",
+			Help: "Count the number of discovery reloads deferred because the daemon is overloaded.",
+		},
+		[]string{"name"})
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	chanResult := make(chan *healthcheck.Result, 10)
+	checkComponent, err := healthcheck.New(logger, chanResult, promComponent, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	var loads atomic.Int32
+	poller := New(logger, "Test discovery", "foo", checkComponent, deferred, func() error {
+		loads.Add(1)
+		return nil
+	})
+	poller.Immediate = true
+	poller.MaxResultChanSize = 2
+	poller.DeferInterval = 50 * time.Millisecond
+	for i := 0; i < 3; i++ {
+		chanResult <- &healthcheck.Result{}
+	}
+	poller.Start(time.Hour)
+	time.Sleep(200 * time.Millisecond)
+	if loads.Load() != 0 {
+		t.Fatalf("The load should be deferred")
+	}
+	<-chanResult
+	time.Sleep(200 * time.Millisecond)
+	if loads.Load() != 1 {
+		t.Fatalf("The deferred load should be retried once, got %d loads", loads.Load())
+	}
+	err = poller.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the poller\n%v", err)
+	}
+}
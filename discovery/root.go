@@ -2,11 +2,15 @@ package discovery
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
+	"github.com/appclacks/cabourotte/discovery/dnssrv"
+	"github.com/appclacks/cabourotte/discovery/file"
 	dhttp "github.com/appclacks/cabourotte/discovery/http"
+	"github.com/appclacks/cabourotte/discovery/poller"
 	"github.com/appclacks/cabourotte/healthcheck"
 	"github.com/appclacks/cabourotte/prometheus"
 	prom "github.com/prometheus/client_golang/prometheus"
@@ -16,6 +20,8 @@ import (
 type Component struct {
 	Logger           *zap.Logger
 	HTTPDiscovery    []*dhttp.HTTPDiscovery
+	FileDiscovery    []*file.FileDiscovery
+	DNSSRVDiscovery  []*dnssrv.DNSSRVDiscovery
 	requestHistogram *prom.HistogramVec
 	responseCounter  *prom.CounterVec
 	Prometheus       *prometheus.Prometheus
@@ -26,21 +32,41 @@ func New(logger *zap.Logger, config Configuration, promComponent *prometheus.Pro
 	component := &Component{
 		Logger: logger,
 	}
+	deferInterval := poller.DefaultDeferInterval
+	if config.DeferInterval != 0 {
+		deferInterval = time.Duration(config.DeferInterval)
+	}
+	var deferred *prom.CounterVec
+	if len(config.HTTP) != 0 || len(config.File) != 0 || len(config.DNSSRV) != 0 {
+		deferred = prom.NewCounterVec(
+			prom.CounterOpts{
+				Namespace: promComponent.Namespace(),
+				Name:      "discovery_deferred_reloads_total",
+				Help:      "Count the number of discovery reloads deferred because the daemon is overloaded.",
+			},
+			[]string{"name"})
+		err := promComponent.Register(deferred)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to register the discovery deferred reloads counter")
+		}
+	}
 	if len(config.HTTP) != 0 {
 		buckets := []float64{
 			0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 0.75, 1,
 			2.5, 5, 7.5, 10}
 		histo := prom.NewHistogramVec(prom.HistogramOpts{
-			Name:    "http_discovery_duration_seconds",
-			Help:    "Time to execute the HTTP request for healthchecks discovery.",
-			Buckets: buckets,
+			Namespace: promComponent.Namespace(),
+			Name:      "http_discovery_duration_seconds",
+			Help:      "Time to execute the HTTP request for healthchecks discovery.",
+			Buckets:   buckets,
 		},
 			[]string{"name"},
 		)
 		counter := prom.NewCounterVec(
 			prom.CounterOpts{
-				Name: "http_discovery_responses_total",
-				Help: "Count the number of HTTP responses for discovery requests.",
+				Namespace: promComponent.Namespace(),
+				Name:      "http_discovery_responses_total",
+				Help:      "Count the number of HTTP responses for discovery requests.",
 			},
 			[]string{"status", "name"})
 		err := promComponent.Register(histo)
@@ -60,10 +86,12 @@ func New(logger *zap.Logger, config Configuration, promComponent *prometheus.Pro
 				return nil, fmt.Errorf("HTTP discovery sources names should be unique (duplicate found for %s)", configHTTP.Name)
 			}
 			logger.Info(fmt.Sprintf("Enabling HTTP discovery %s", configHTTP.Name))
-			httpDiscovery, err := dhttp.New(logger, &configHTTP, healthcheck, counter, histo)
+			httpDiscovery, err := dhttp.New(logger, &configHTTP, healthcheck, counter, histo, deferred)
 			if err != nil {
 				return nil, errors.Wrapf(err, "Fail to create the HTTP discovery component")
 			}
+			httpDiscovery.Poller.MaxResultChanSize = config.MaxResultChanSize
+			httpDiscovery.Poller.DeferInterval = deferInterval
 			httpNames[configHTTP.Name] = true
 			discovery = append(discovery, httpDiscovery)
 		}
@@ -71,6 +99,66 @@ func New(logger *zap.Logger, config Configuration, promComponent *prometheus.Pro
 		component.responseCounter = counter
 		component.requestHistogram = histo
 	}
+	if len(config.File) != 0 {
+		counter := prom.NewCounterVec(
+			prom.CounterOpts{
+				Namespace: promComponent.Namespace(),
+				Name:      "file_discovery_reads_total",
+				Help:      "Count the number of files read by the file discovery.",
+			},
+			[]string{"status", "name"})
+		err := promComponent.Register(counter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to register the file discovery counter")
+		}
+		fileNames := make(map[string]bool)
+		for i := range config.File {
+			configFile := config.File[i]
+			_, ok := fileNames[configFile.Name]
+			if ok {
+				return nil, fmt.Errorf("File discovery sources names should be unique (duplicate found for %s)", configFile.Name)
+			}
+			logger.Info(fmt.Sprintf("Enabling file discovery %s", configFile.Name))
+			fileDiscovery, err := file.New(logger, &configFile, healthcheck, counter, deferred)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Fail to create the file discovery component")
+			}
+			fileDiscovery.Poller.MaxResultChanSize = config.MaxResultChanSize
+			fileDiscovery.Poller.DeferInterval = deferInterval
+			fileNames[configFile.Name] = true
+			component.FileDiscovery = append(component.FileDiscovery, fileDiscovery)
+		}
+	}
+	if len(config.DNSSRV) != 0 {
+		counter := prom.NewCounterVec(
+			prom.CounterOpts{
+				Namespace: promComponent.Namespace(),
+				Name:      "dnssrv_discovery_resolutions_total",
+				Help:      "Count the number of SRV records resolutions by the DNS SRV discovery.",
+			},
+			[]string{"status", "name"})
+		err := promComponent.Register(counter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to register the DNS SRV discovery counter")
+		}
+		dnssrvNames := make(map[string]bool)
+		for i := range config.DNSSRV {
+			configDNSSRV := config.DNSSRV[i]
+			_, ok := dnssrvNames[configDNSSRV.Name]
+			if ok {
+				return nil, fmt.Errorf("DNS SRV discovery sources names should be unique (duplicate found for %s)", configDNSSRV.Name)
+			}
+			logger.Info(fmt.Sprintf("Enabling DNS SRV discovery %s", configDNSSRV.Name))
+			dnssrvDiscovery, err := dnssrv.New(logger, &configDNSSRV, healthcheck, counter, deferred)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Fail to create the DNS SRV discovery component")
+			}
+			dnssrvDiscovery.Poller.MaxResultChanSize = config.MaxResultChanSize
+			dnssrvDiscovery.Poller.DeferInterval = deferInterval
+			dnssrvNames[configDNSSRV.Name] = true
+			component.DNSSRVDiscovery = append(component.DNSSRVDiscovery, dnssrvDiscovery)
+		}
+	}
 	return component, nil
 }
 
@@ -85,6 +173,18 @@ func (c *Component) Start() error {
 			}
 		}
 	}
+	for i := range c.FileDiscovery {
+		err := c.FileDiscovery[i].Start()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.DNSSRVDiscovery {
+		err := c.DNSSRVDiscovery[i].Start()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -99,5 +199,17 @@ func (c *Component) Stop() error {
 			}
 		}
 	}
+	for i := range c.FileDiscovery {
+		err := c.FileDiscovery[i].Stop()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.DNSSRVDiscovery {
+		err := c.DNSSRVDiscovery[i].Stop()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
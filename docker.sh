@@ -1,6 +1,8 @@
 #!/bin/bash
 
 version=$1
+commit=$(git rev-parse HEAD)
+date=$(date -u +%Y-%m-%dT%H:%M:%SZ)
 
-docker build -t appclacks/cabourotte:${version} .
+docker build --build-arg version=${version} --build-arg commit=${commit} --build-arg date=${date} -t appclacks/cabourotte:${version} .
 docker push appclacks/cabourotte:${version}
@@ -1,7 +1,108 @@
 package exporter
 
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
 // Configuration the main configuration for the exporter component
 type Configuration struct {
 	HTTP    []HTTPConfiguration
 	Riemann []RiemannConfiguration
+	Kafka   []KafkaConfiguration
+	Webhook []WebhookConfiguration
+	Datadog []DatadogConfiguration
+	// PushRetries the number of times a failed push is retried before
+	// stopping the exporter
+	PushRetries uint `yaml:"push-retries"`
+	// PushRetryInterval the interval between the first two push attempts.
+	// The interval is doubled after each attempt, up to
+	// MaxPushRetryInterval.
+	PushRetryInterval healthcheck.Duration `yaml:"push-retry-interval"`
+	// StartTimeout the maximum time to wait for a group of exporters to
+	// start before starting the next one
+	StartTimeout healthcheck.Duration `yaml:"start-timeout"`
+	// FlushTimeout the maximum time to wait for the exporters to push their
+	// buffered results when the component is stopped
+	FlushTimeout healthcheck.Duration `yaml:"flush-timeout"`
+	// QueueSize the maximum number of results waiting to be pushed to each
+	// exporter. The results are dropped when the queue is full.
+	QueueSize uint `yaml:"queue-size"`
+}
+
+// ExporterRouting the start priority of an exporter and the matchers
+// selecting the results pushed to it
+type ExporterRouting struct {
+	// Priority exporters with a lower priority are started first. Exporters
+	// with the same priority are started in parallel.
+	Priority int
+	// MatchLabels only the results having all these labels are pushed to
+	// the exporter
+	MatchLabels map[string]string `json:"match-labels,omitempty" yaml:"match-labels"`
+	// MatchSource only the results of the healthchecks from this source
+	// are pushed to the exporter
+	MatchSource string `json:"match-source,omitempty" yaml:"match-source"`
+}
+
+// DefaultPushRetryInterval the default interval between push attempts
+const DefaultPushRetryInterval = healthcheck.Duration(200 * time.Millisecond)
+
+// MaxPushRetries the maximum number of times a failed push can be retried
+const MaxPushRetries = 10
+
+// MaxPushRetryInterval the maximum interval between two push attempts
+const MaxPushRetryInterval = healthcheck.Duration(30 * time.Second)
+
+// DefaultStartTimeout the default maximum time to wait for a group of
+// exporters to start
+const DefaultStartTimeout = healthcheck.Duration(10 * time.Second)
+
+// DefaultQueueSize the default maximum number of results waiting to be
+// pushed to each exporter
+const DefaultQueueSize = 1000
+
+// DefaultFlushTimeout the default maximum time to wait for the exporters to
+// flush their buffered results
+const DefaultFlushTimeout = healthcheck.Duration(10 * time.Second)
+
+// Names returns the names of the configured exporters
+func (c *Configuration) Names() []string {
+	names := []string{}
+	for i := range c.HTTP {
+		names = append(names, c.HTTP[i].Name)
+	}
+	for i := range c.Riemann {
+		names = append(names, c.Riemann[i].Name)
+	}
+	for i := range c.Kafka {
+		names = append(names, c.Kafka[i].Name)
+	}
+	for i := range c.Webhook {
+		names = append(names, c.Webhook[i].Name)
+	}
+	for i := range c.Datadog {
+		names = append(names, c.Datadog[i].Name)
+	}
+	return names
+}
+
+// UnmarshalYAML parses the configuration of the exporter component from YAML.
+func (c *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the exporters configuration")
+	}
+	if raw.PushRetries > MaxPushRetries {
+		return fmt.Errorf("The number of push retries should be lower or equal than %d", MaxPushRetries)
+	}
+	if raw.PushRetryInterval < 0 || raw.PushRetryInterval > MaxPushRetryInterval {
+		return fmt.Errorf("The push retry interval should be between 0 and %s", time.Duration(MaxPushRetryInterval))
+	}
+	*c = Configuration(raw)
+	return nil
 }
@@ -3,6 +3,7 @@ package exporter
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v2"
 
@@ -80,6 +81,27 @@ insecure: true
 				Insecure: true,
 			},
 		},
+		{
+			in: `
+host: "127.0.0.2"
+port: 2003
+protocol: http
+name: foo
+match-labels:
+  env: prod
+match-source: api
+`,
+			want: HTTPConfiguration{
+				Name:     "foo",
+				Host:     "127.0.0.2",
+				Port:     2003,
+				Protocol: healthcheck.HTTP,
+				ExporterRouting: ExporterRouting{
+					MatchLabels: map[string]string{"env": "prod"},
+					MatchSource: "api",
+				},
+			},
+		},
 	}
 	for _, c := range cases {
 		var result HTTPConfiguration
@@ -137,3 +159,25 @@ cert: /tmp/cert
 		}
 	}
 }
+
+func TestUnmarshalPushRetries(t *testing.T) {
+	var result Configuration
+	err := yaml.Unmarshal([]byte("push-retries: 3\npush-retry-interval: 1s\n"), &result)
+	if err != nil {
+		t.Fatalf("Unmarshal yaml error:\n%v", err)
+	}
+	if result.PushRetries != 3 || result.PushRetryInterval != healthcheck.Duration(time.Second) {
+		t.Fatalf("Invalid configuration: %+v", result)
+	}
+	cases := []string{
+		"push-retries: 1000\n",
+		"push-retry-interval: -1s\n",
+		"push-retry-interval: 1h\n",
+	}
+	for _, c := range cases {
+		var result Configuration
+		if err := yaml.Unmarshal([]byte(c), &result); err == nil {
+			t.Fatalf("Was expecting an error for:\n%s", c)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+const (
+	// DatadogDogStatsD the results are sent to the Datadog agent using the
+	// DogStatsD protocol
+	DatadogDogStatsD string = "dogstatsd"
+	// DatadogAPI the results are sent to the Datadog HTTP API
+	DatadogAPI string = "api"
+)
+
+const (
+	// DatadogServiceCheck the results are sent as service checks
+	DatadogServiceCheck string = "service-check"
+	// DatadogEvent the results are sent as events
+	DatadogEvent string = "event"
+)
+
+// DefaultDatadogAddress the default address of the DogStatsD agent
+const DefaultDatadogAddress = "127.0.0.1:8125"
+
+// DefaultDatadogURL the default URL of the Datadog API
+const DefaultDatadogURL = "https://api.datadoghq.com"
+
+// DefaultDatadogCheckName the default name of the service checks
+const DefaultDatadogCheckName = "cabourotte.healthcheck"
+
+// Datadog service checks statuses
+const (
+	datadogOK       = 0
+	datadogWarning  = 1
+	datadogCritical = 2
+)
+
+// DatadogConfiguration the Datadog exporter configuration
+type DatadogConfiguration struct {
+	Name string
+	// Protocol the protocol used to send the results (dogstatsd or api,
+	// dogstatsd by default)
+	Protocol string
+	// Mode the results are sent as service checks or as events
+	// (service-check or event, service-check by default)
+	Mode string
+	// Address the address (host:port) of the DogStatsD agent
+	Address string
+	// URL the URL of the Datadog API
+	URL string
+	// APIKey the Datadog API key, required by the api protocol
+	APIKey string `json:"api-key,omitempty" yaml:"api-key"`
+	// Hostname the hostname attached to the service checks and events
+	// (the host hostname by default when using the API)
+	Hostname string
+	// CheckName the name of the service checks
+	CheckName string `json:"check-name,omitempty" yaml:"check-name"`
+	// Tags the tags added to all service checks and events
+	Tags []string `json:"tags,omitempty"`
+	// TagLabels the healthchecks labels converted to tags, associated to
+	// the tags names. All labels are converted to tags using their names if
+	// not set.
+	TagLabels       map[string]string `json:"tag-labels,omitempty" yaml:"tag-labels"`
+	ExporterRouting `yaml:",inline"`
+}
+
+// DatadogExporter the Datadog exporter struct
+type DatadogExporter struct {
+	Started atomic.Bool
+	Logger  *zap.Logger
+	Config  *DatadogConfiguration
+	Client  *http.Client
+	conn    net.Conn
+}
+
+// UnmarshalYAML parses the configuration of the Datadog exporter from YAML.
+func (c *DatadogConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration DatadogConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read Datadog exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the Datadog exporter configuration")
+	}
+	if raw.Protocol == "" {
+		raw.Protocol = DatadogDogStatsD
+	}
+	if raw.Mode == "" {
+		raw.Mode = DatadogServiceCheck
+	}
+	if raw.CheckName == "" {
+		raw.CheckName = DefaultDatadogCheckName
+	}
+	switch raw.Protocol {
+	case DatadogDogStatsD:
+		if raw.Address == "" {
+			raw.Address = DefaultDatadogAddress
+		}
+		if _, _, err := net.SplitHostPort(raw.Address); err != nil {
+			return errors.Wrapf(err, "Invalid address for the Datadog exporter configuration")
+		}
+	case DatadogAPI:
+		if raw.URL == "" {
+			raw.URL = DefaultDatadogURL
+		}
+		if raw.APIKey == "" {
+			return errors.New("The API key is missing in the Datadog exporter configuration")
+		}
+	default:
+		return fmt.Errorf("Invalid protocol %s for the Datadog exporter configuration", raw.Protocol)
+	}
+	if raw.Mode != DatadogServiceCheck && raw.Mode != DatadogEvent {
+		return fmt.Errorf("Invalid mode %s for the Datadog exporter configuration", raw.Mode)
+	}
+	*c = DatadogConfiguration(raw)
+	return nil
+}
+
+// NewDatadogExporter creates a new Datadog exporter
+func NewDatadogExporter(logger *zap.Logger, config *DatadogConfiguration) (*DatadogExporter, error) {
+	if config.Protocol == DatadogAPI && config.Hostname == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to get the hostname for the Datadog exporter")
+		}
+		config.Hostname = hostname
+	}
+	exporter := DatadogExporter{
+		Logger: logger,
+		Config: config,
+		Client: &http.Client{
+			Timeout: time.Second * 3,
+		},
+	}
+	return &exporter, nil
+}
+
+// IsStarted returns the exporter status
+func (c *DatadogExporter) IsStarted() bool {
+	return c.Started.Load()
+}
+
+// Start starts the Datadog exporter component
+func (c *DatadogExporter) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the Datadog exporter %s", c.Config.Name))
+	if c.Config.Protocol == DatadogDogStatsD {
+		conn, err := net.Dial("udp", c.Config.Address)
+		if err != nil {
+			return errors.Wrapf(err, "Fail to connect to the DogStatsD agent %s", c.Config.Address)
+		}
+		c.conn = conn
+	}
+	c.Started.Store(true)
+	return nil
+}
+
+// Reconnect reconnects the Datadog exporter component
+func (c *DatadogExporter) Reconnect() error {
+	c.Logger.Info(fmt.Sprintf("Reconnecting the Datadog exporter %s", c.Config.Name))
+	return c.Start()
+}
+
+// Flush flushes the Datadog exporter. It does nothing, the results are pushed synchronously.
+func (c *DatadogExporter) Flush() error {
+	return nil
+}
+
+// Stop stops the Datadog exporter component
+func (c *DatadogExporter) Stop() error {
+	c.Logger.Info(fmt.Sprintf("Stopping the Datadog exporter %s", c.Config.Name))
+	c.Started.Store(false)
+	if c.conn != nil {
+		err := c.conn.Close()
+		c.conn = nil
+		if err != nil {
+			return errors.Wrapf(err, "Fail to close the DogStatsD connection")
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the exporter
+func (c *DatadogExporter) Name() string {
+	return c.Config.Name
+}
+
+// GetConfig returns the config of the exporter
+func (c *DatadogExporter) GetConfig() interface{} {
+	return c.Config
+}
+
+// tags returns the tags of a result: the static tags, the healthcheck name
+// and the labels sorted by name
+func (c *DatadogExporter) tags(result *healthcheck.Result) []string {
+	tags := append([]string{}, c.Config.Tags...)
+	tags = append(tags, fmt.Sprintf("healthcheck:%s", result.Name))
+	labels := make([]string, 0, len(result.Labels))
+	for label := range result.Labels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		tag := label
+		if c.Config.TagLabels != nil {
+			name, ok := c.Config.TagLabels[label]
+			if !ok {
+				continue
+			}
+			tag = name
+		}
+		tags = append(tags, fmt.Sprintf("%s:%s", tag, result.Labels[label]))
+	}
+	return tags
+}
+
+// datadogStatus returns the service check status of a result
+func datadogStatus(result *healthcheck.Result) int {
+	if !result.Success {
+		return datadogCritical
+	}
+	if result.Degraded {
+		return datadogWarning
+	}
+	return datadogOK
+}
+
+// datadogAlertType returns the event alert type of a result
+func datadogAlertType(result *healthcheck.Result) string {
+	if !result.Success {
+		return "error"
+	}
+	if result.Degraded {
+		return "warning"
+	}
+	return "success"
+}
+
+// datadogEventTitle returns the title of the event of a result
+func datadogEventTitle(result *healthcheck.Result) string {
+	if !result.Success {
+		return fmt.Sprintf("Healthcheck %s failed", result.Name)
+	}
+	if result.Degraded {
+		return fmt.Sprintf("Healthcheck %s degraded", result.Name)
+	}
+	return fmt.Sprintf("Healthcheck %s successful", result.Name)
+}
+
+// dogStatsDEscaper escapes the values of the DogStatsD datagrams
+var dogStatsDEscaper = strings.NewReplacer("\n", "\\n", "|", "_")
+
+// dogStatsDTagEscaper escapes the tags of the DogStatsD datagrams
+var dogStatsDTagEscaper = strings.NewReplacer("\n", "_", "|", "_", ",", "_")
+
+// datagram builds the DogStatsD datagram of a result
+func (c *DatadogExporter) datagram(result *healthcheck.Result) string {
+	tags := c.tags(result)
+	for i := range tags {
+		tags[i] = dogStatsDTagEscaper.Replace(tags[i])
+	}
+	var datagram strings.Builder
+	if c.Config.Mode == DatadogEvent {
+		title := dogStatsDEscaper.Replace(datadogEventTitle(result))
+		text := dogStatsDEscaper.Replace(result.Message)
+		fmt.Fprintf(&datagram, "_e{%d,%d}:%s|%s|d:%d", len(title), len(text), title, text, result.HealthcheckTimestamp)
+		if c.Config.Hostname != "" {
+			fmt.Fprintf(&datagram, "|h:%s", c.Config.Hostname)
+		}
+		fmt.Fprintf(&datagram, "|t:%s|#%s", datadogAlertType(result), strings.Join(tags, ","))
+		return datagram.String()
+	}
+	fmt.Fprintf(&datagram, "_sc|%s|%d|d:%d", c.Config.CheckName, datadogStatus(result), result.HealthcheckTimestamp)
+	if c.Config.Hostname != "" {
+		fmt.Fprintf(&datagram, "|h:%s", c.Config.Hostname)
+	}
+	// the message should be the last field
+	fmt.Fprintf(&datagram, "|#%s|m:%s", strings.Join(tags, ","), dogStatsDEscaper.Replace(result.Message))
+	return datagram.String()
+}
+
+// datadogServiceCheck the payload of the service checks API
+type datadogServiceCheck struct {
+	Check     string   `json:"check"`
+	HostName  string   `json:"host_name"`
+	Status    int      `json:"status"`
+	Timestamp int64    `json:"timestamp"`
+	Message   string   `json:"message"`
+	Tags      []string `json:"tags"`
+}
+
+// datadogEvent the payload of the events API
+type datadogEvent struct {
+	Title        string   `json:"title"`
+	Text         string   `json:"text"`
+	AlertType    string   `json:"alert_type"`
+	DateHappened int64    `json:"date_happened"`
+	Host         string   `json:"host"`
+	Tags         []string `json:"tags"`
+}
+
+// pushAPI sends a result to the Datadog API
+func (c *DatadogExporter) pushAPI(result *healthcheck.Result) error {
+	var payload interface{}
+	path := "/api/v1/check_run"
+	if c.Config.Mode == DatadogEvent {
+		path = "/api/v1/events"
+		payload = datadogEvent{
+			Title:        datadogEventTitle(result),
+			Text:         result.Message,
+			AlertType:    datadogAlertType(result),
+			DateHappened: result.HealthcheckTimestamp,
+			Host:         c.Config.Hostname,
+			Tags:         c.tags(result),
+		}
+	} else {
+		payload = datadogServiceCheck{
+			Check:     c.Config.CheckName,
+			HostName:  c.Config.Hostname,
+			Status:    datadogStatus(result),
+			Timestamp: result.HealthcheckTimestamp,
+			Message:   result.Message,
+			Tags:      c.tags(result),
+		}
+	}
+	jsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to convert result to json:\n%v", result)
+	}
+	url := strings.TrimSuffix(c.Config.URL, "/") + path
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return errors.Wrapf(err, "Datadog exporter: fail to create request for %s", url)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", c.Config.APIKey)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "Datadog exporter: fail to send healthchecks to %s", url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("Datadog exporter: request failed, status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Push sends a result to Datadog as a service check or as an event
+func (c *DatadogExporter) Push(result *healthcheck.Result) error {
+	if c.Config.Protocol == DatadogAPI {
+		return c.pushAPI(result)
+	}
+	if c.conn == nil {
+		return errors.New("Datadog exporter: the DogStatsD connection is closed")
+	}
+	_, err := c.conn.Write([]byte(c.datagram(result)))
+	if err != nil {
+		return errors.Wrapf(err, "Datadog exporter: fail to send healthchecks to %s", c.Config.Address)
+	}
+	return nil
+}
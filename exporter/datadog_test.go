@@ -0,0 +1,204 @@
+package exporter
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestDatadogExporterDogStatsD(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the DogStatsD server :\n%v", err)
+	}
+	defer conn.Close()
+	result := &healthcheck.Result{
+		Name:                 "foo",
+		Success:              false,
+		Message:              "error\nstatus 500",
+		HealthcheckTimestamp: 1000,
+		Labels:               map[string]string{"env": "prod", "team": "a|b"},
+	}
+	cases := []struct {
+		config   DatadogConfiguration
+		expected string
+	}{
+		{
+			config: DatadogConfiguration{
+				Mode:      DatadogServiceCheck,
+				CheckName: DefaultDatadogCheckName,
+				Tags:      []string{"service:cabourotte"},
+			},
+			expected: "_sc|cabourotte.healthcheck|2|d:1000|#service:cabourotte,healthcheck:foo,env:prod,team:a_b|m:error\\nstatus 500",
+		},
+		{
+			config: DatadogConfiguration{
+				Mode:      DatadogEvent,
+				Hostname:  "host1",
+				TagLabels: map[string]string{"env": "environment"},
+			},
+			expected: "_e{22,17}:Healthcheck foo failed|error\\nstatus 500|d:1000|h:host1|t:error|#healthcheck:foo,environment:prod",
+		},
+	}
+	for _, c := range cases {
+		c.config.Name = "datadog"
+		c.config.Protocol = DatadogDogStatsD
+		c.config.Address = conn.LocalAddr().String()
+		exporter, err := NewDatadogExporter(zap.NewExample(), &c.config)
+		if err != nil {
+			t.Fatalf("Error creating the Datadog exporter :\n%v", err)
+		}
+		err = exporter.Start()
+		if err != nil {
+			t.Fatalf("Fail to start the Datadog exporter:\n%v", err)
+		}
+		err = exporter.Push(result)
+		if err != nil {
+			t.Fatalf("Fail to push the result:\n%v", err)
+		}
+		buffer := make([]byte, 1024)
+		err = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if err != nil {
+			t.Fatalf("Fail to set the deadline:\n%v", err)
+		}
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			t.Fatalf("Fail to read the datagram:\n%v", err)
+		}
+		if string(buffer[:n]) != c.expected {
+			t.Fatalf("Invalid datagram %s", string(buffer[:n]))
+		}
+		err = exporter.Stop()
+		if err != nil {
+			t.Fatalf("Fail to stop the Datadog exporter:\n%v", err)
+		}
+	}
+}
+
+func TestDatadogExporterAPI(t *testing.T) {
+	var path string
+	var body map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("DD-API-KEY") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		path = r.URL.Path
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		err = json.Unmarshal(content, &body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+	exporter, err := NewDatadogExporter(
+		zap.NewExample(),
+		&DatadogConfiguration{
+			Name:      "datadog",
+			Protocol:  DatadogAPI,
+			Mode:      DatadogServiceCheck,
+			URL:       ts.URL,
+			APIKey:    "secret",
+			Hostname:  "host1",
+			CheckName: "cabourotte.check",
+		})
+	if err != nil {
+		t.Fatalf("Error creating the Datadog exporter :\n%v", err)
+	}
+	err = exporter.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the Datadog exporter:\n%v", err)
+	}
+	err = exporter.Push(&healthcheck.Result{
+		Name:                 "foo",
+		Success:              true,
+		Message:              "success",
+		HealthcheckTimestamp: 1000,
+	})
+	if err != nil {
+		t.Fatalf("Fail to push the result:\n%v", err)
+	}
+	expected := map[string]interface{}{
+		"check":     "cabourotte.check",
+		"host_name": "host1",
+		"status":    float64(0),
+		"timestamp": float64(1000),
+		"message":   "success",
+		"tags":      []interface{}{"healthcheck:foo"},
+	}
+	if path != "/api/v1/check_run" || !reflect.DeepEqual(body, expected) {
+		t.Fatalf("Invalid request %s %v", path, body)
+	}
+	exporter.Config.APIKey = "invalid"
+	err = exporter.Push(&healthcheck.Result{Name: "foo"})
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
+func TestUnmarshalDatadogConfig(t *testing.T) {
+	var config DatadogConfiguration
+	err := yaml.Unmarshal([]byte(`
+name: datadog
+tag-labels:
+  env: environment
+`), &config)
+	if err != nil {
+		t.Fatalf("Unmarshal yaml error:\n%v", err)
+	}
+	expected := DatadogConfiguration{
+		Name:      "datadog",
+		Protocol:  DatadogDogStatsD,
+		Mode:      DatadogServiceCheck,
+		Address:   DefaultDatadogAddress,
+		CheckName: DefaultDatadogCheckName,
+		TagLabels: map[string]string{"env": "environment"},
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Fatalf("Invalid configuration %v", config)
+	}
+	cases := []string{
+		`
+protocol: api
+api-key: foo
+`,
+		`
+name: datadog
+protocol: api
+`,
+		`
+name: datadog
+protocol: foo
+`,
+		`
+name: datadog
+mode: foo
+`,
+		`
+name: datadog
+address: localhost
+`,
+	}
+	for _, c := range cases {
+		var config DatadogConfiguration
+		if err := yaml.Unmarshal([]byte(c), &config); err == nil {
+			t.Fatalf("Was expecting an error for:\n%s", c)
+		}
+	}
+}
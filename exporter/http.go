@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -17,21 +18,22 @@ import (
 
 // HTTPConfiguration The configuration for the HTTP exporter.
 type HTTPConfiguration struct {
-	Name     string
-	Host     string
-	Path     string
-	Port     uint32
-	Protocol healthcheck.Protocol
-	Headers  map[string]string `json:"headers,omitempty"`
-	Key      string            `json:"key,omitempty"`
-	Cert     string            `json:"cert,omitempty"`
-	Cacert   string            `json:"cacert,omitempty"`
-	Insecure bool
+	Name            string
+	Host            string
+	Path            string
+	Port            uint32
+	Protocol        healthcheck.Protocol
+	Headers         map[string]string `json:"headers,omitempty"`
+	Key             string            `json:"key,omitempty"`
+	Cert            string            `json:"cert,omitempty"`
+	Cacert          string            `json:"cacert,omitempty"`
+	Insecure        bool
+	ExporterRouting `yaml:",inline"`
 }
 
 // HTTPExporter the http exporter struct
 type HTTPExporter struct {
-	Started bool
+	Started atomic.Bool
 	Logger  *zap.Logger
 	URL     string
 	Config  *HTTPConfiguration
@@ -98,28 +100,33 @@ func NewHTTPExporter(logger *zap.Logger, config *HTTPConfiguration) (*HTTPExport
 
 // IsStarted returns the exporter status
 func (c *HTTPExporter) IsStarted() bool {
-	return c.Started
+	return c.Started.Load()
 }
 
 // Start starts the HTTP exporter component
 func (c *HTTPExporter) Start() error {
 	// nothing to do
 	c.Logger.Info(fmt.Sprintf("Starting the HTTP healthcheck exporter on %s:%d", c.Config.Host, c.Config.Port))
-	c.Started = true
+	c.Started.Store(true)
 	return nil
 }
 
 // Reconnect reconnects the HTTP exporter component
 func (c *HTTPExporter) Reconnect() error {
 	// nothing to do
-	c.Started = true
+	c.Started.Store(true)
+	return nil
+}
+
+// Flush flushes the HTTP exporter. It does nothing, the results are pushed synchronously.
+func (c *HTTPExporter) Flush() error {
 	return nil
 }
 
 // Stop stops the HTTP exporter component
 func (c *HTTPExporter) Stop() error {
 	c.Logger.Info(fmt.Sprintf("Stopping the http exporter %s", c.Config.Name))
-	c.Started = false
+	c.Started.Store(false)
 	return nil
 }
 
@@ -0,0 +1,242 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/tls"
+)
+
+// DefaultKafkaKey the default template for the key of the Kafka messages
+const DefaultKafkaKey = "{{ .Name }}"
+
+// kafkaBatchTimeout the time the Kafka writer waits for other messages
+// before sending a batch. The messages are written synchronously one by
+// one, so waiting for a batch only delays the exporters loop.
+const kafkaBatchTimeout = 5 * time.Millisecond
+
+// KafkaSASLConfiguration the SASL configuration for the Kafka exporter
+type KafkaSASLConfiguration struct {
+	// Mechanism can be plain, scram-sha-256 or scram-sha-512
+	Mechanism string
+	Username  string
+	Password  string
+}
+
+// KafkaConfiguration the Kafka exporter configuration
+type KafkaConfiguration struct {
+	Name    string
+	Brokers []string
+	Topic   string
+	// Key the template of the messages key, built from the healthcheck result
+	Key             string
+	SASL            KafkaSASLConfiguration `yaml:"sasl"`
+	TLS             bool
+	TLSKey          string `json:"tls-key,omitempty" yaml:"tls-key"`
+	Cert            string `json:"cert,omitempty"`
+	Cacert          string `json:"cacert,omitempty"`
+	Insecure        bool
+	ExporterRouting `yaml:",inline"`
+}
+
+// KafkaExporter the Kafka exporter struct
+type KafkaExporter struct {
+	Started     atomic.Bool
+	Logger      *zap.Logger
+	Config      *KafkaConfiguration
+	Writer      *kafka.Writer
+	keyTemplate *template.Template
+}
+
+// UnmarshalYAML parses the configuration of the Kafka exporter from YAML.
+func (c *KafkaConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration KafkaConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read Kafka exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the Kafka exporter configuration")
+	}
+	if len(raw.Brokers) == 0 {
+		return errors.New("Invalid brokers for the Kafka exporter configuration")
+	}
+	if raw.Topic == "" {
+		return errors.New("Invalid topic for the Kafka exporter configuration")
+	}
+	if !((raw.TLSKey != "" && raw.Cert != "") ||
+		(raw.TLSKey == "" && raw.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	switch raw.SASL.Mechanism {
+	case "", "plain", "scram-sha-256", "scram-sha-512":
+	default:
+		return fmt.Errorf("Invalid SASL mechanism %s for the Kafka exporter configuration", raw.SASL.Mechanism)
+	}
+	if raw.Key == "" {
+		raw.Key = DefaultKafkaKey
+	}
+	if _, err := template.New("key").Parse(raw.Key); err != nil {
+		return errors.Wrap(err, "Invalid key template for the Kafka exporter configuration")
+	}
+	*c = KafkaConfiguration(raw)
+	return nil
+}
+
+// getSASLMechanism returns the SASL mechanism for the configuration
+func getSASLMechanism(config *KafkaSASLConfiguration) (sasl.Mechanism, error) {
+	switch config.Mechanism {
+	case "plain":
+		return plain.Mechanism{
+			Username: config.Username,
+			Password: config.Password,
+		}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, config.Username, config.Password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, config.Username, config.Password)
+	}
+	return nil, nil
+}
+
+// getWriter creates a Kafka writer from the configuration
+func getWriter(config *KafkaConfiguration) (*kafka.Writer, error) {
+	transport := &kafka.Transport{}
+	if config.TLS {
+		tlsConfig, err := tls.GetTLSConfig(config.TLSKey, config.Cert, config.Cacert, "", config.Insecure)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to build the Kafka exporter tls configuration")
+		}
+		transport.TLS = tlsConfig
+	}
+	mechanism, err := getSASLMechanism(&config.SASL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to build the Kafka exporter SASL configuration")
+	}
+	transport.SASL = mechanism
+	return &kafka.Writer{
+		Addr:         kafka.TCP(config.Brokers...),
+		Topic:        config.Topic,
+		Balancer:     &kafka.Hash{},
+		Transport:    transport,
+		RequiredAcks: kafka.RequireOne,
+		WriteTimeout: 5 * time.Second,
+		BatchTimeout: kafkaBatchTimeout,
+	}, nil
+}
+
+// NewKafkaExporter creates a new Kafka exporter from the configuration
+func NewKafkaExporter(logger *zap.Logger, config *KafkaConfiguration) (*KafkaExporter, error) {
+	key := config.Key
+	if key == "" {
+		key = DefaultKafkaKey
+	}
+	keyTemplate, err := template.New("key").Parse(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid key template for the Kafka exporter")
+	}
+	writer, err := getWriter(config)
+	if err != nil {
+		return nil, err
+	}
+	exporter := &KafkaExporter{
+		Logger:      logger,
+		Config:      config,
+		Writer:      writer,
+		keyTemplate: keyTemplate,
+	}
+	return exporter, nil
+}
+
+// Start starts the Kafka exporter component
+func (c *KafkaExporter) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the Kafka healthcheck exporter on %s", strings.Join(c.Config.Brokers, ",")))
+	c.Started.Store(true)
+	return nil
+}
+
+// Flush flushes the Kafka exporter. It does nothing, the messages are written synchronously.
+func (c *KafkaExporter) Flush() error {
+	return nil
+}
+
+// Stop stops the Kafka exporter component
+func (c *KafkaExporter) Stop() error {
+	c.Logger.Info(fmt.Sprintf("Stopping the Kafka exporter %s", c.Config.Name))
+	c.Started.Store(false)
+	return c.Writer.Close()
+}
+
+// Reconnect reconnects the Kafka exporter component
+func (c *KafkaExporter) Reconnect() error {
+	c.Logger.Info("Kafka exporter: reconnecting")
+	writer, err := getWriter(c.Config)
+	if err != nil {
+		return err
+	}
+	c.Writer = writer
+	c.Logger.Info("Kafka exporter: reconnected")
+	c.Started.Store(true)
+	return nil
+}
+
+// Name returns the name of the exporter
+func (c *KafkaExporter) Name() string {
+	return c.Config.Name
+}
+
+// GetConfig returns the config of the exporter
+func (c *KafkaExporter) GetConfig() interface{} {
+	return c.Config
+}
+
+// IsStarted returns the exporter status
+func (c *KafkaExporter) IsStarted() bool {
+	return c.Started.Load()
+}
+
+// messageKey builds the key of the Kafka message for a result
+func (c *KafkaExporter) messageKey(result *healthcheck.Result) ([]byte, error) {
+	var key bytes.Buffer
+	err := c.keyTemplate.Execute(&key, result)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Kafka exporter: fail to build the message key")
+	}
+	return key.Bytes(), nil
+}
+
+// Push pushes events to the Kafka topic
+func (c *KafkaExporter) Push(result *healthcheck.Result) error {
+	value, err := json.Marshal(result)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to convert result to json:\n%v", result)
+	}
+	key, err := c.messageKey(result)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = c.Writer.WriteMessages(ctx, kafka.Message{
+		Key:   key,
+		Value: value,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Kafka exporter: fail to send event")
+	}
+	return nil
+}
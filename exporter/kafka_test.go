@@ -0,0 +1,153 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	metadataAPI "github.com/segmentio/kafka-go/protocol/metadata"
+	produceAPI "github.com/segmentio/kafka-go/protocol/produce"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestUnmarshalKafkaConfig(t *testing.T) {
+	in := `
+name: foo
+brokers:
+  - 127.0.0.1:9092
+  - 127.0.0.2:9092
+topic: healthchecks
+sasl:
+  mechanism: scram-sha-512
+  username: user
+  password: pass
+`
+	want := KafkaConfiguration{
+		Name:    "foo",
+		Brokers: []string{"127.0.0.1:9092", "127.0.0.2:9092"},
+		Topic:   "healthchecks",
+		Key:     DefaultKafkaKey,
+		SASL: KafkaSASLConfiguration{
+			Mechanism: "scram-sha-512",
+			Username:  "user",
+			Password:  "pass",
+		},
+	}
+	var result KafkaConfiguration
+	if err := yaml.Unmarshal([]byte(in), &result); err != nil {
+		t.Fatalf("Unmarshal yaml error:\n%v", err)
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Fatalf("Invalid configuration: \n%v\n%v", result, want)
+	}
+}
+
+func TestUnmarshalKafkaConfigError(t *testing.T) {
+	cases := []string{
+		`
+brokers: [127.0.0.1:9092]
+topic: healthchecks
+`,
+		`
+name: foo
+topic: healthchecks
+`,
+		`
+name: foo
+brokers: [127.0.0.1:9092]
+`,
+		`
+name: foo
+brokers: [127.0.0.1:9092]
+topic: healthchecks
+sasl:
+  mechanism: gssapi
+`,
+		`
+name: foo
+brokers: [127.0.0.1:9092]
+topic: healthchecks
+key: "{{ .Name"
+`,
+	}
+	for _, c := range cases {
+		var result KafkaConfiguration
+		if err := yaml.Unmarshal([]byte(c), &result); err == nil {
+			t.Fatalf("Was expecting an error for:\n%s", c)
+		}
+	}
+}
+
+func TestKafkaMessageKey(t *testing.T) {
+	exporter, err := NewKafkaExporter(zap.NewExample(), &KafkaConfiguration{
+		Name:    "foo",
+		Brokers: []string{"127.0.0.1:9092"},
+		Topic:   "healthchecks",
+		Key:     "{{ .Source }}/{{ .Name }}",
+	})
+	if err != nil {
+		t.Fatalf("Fail to create the exporter\n%v", err)
+	}
+	key, err := exporter.messageKey(&healthcheck.Result{Name: "bar", Source: "api"})
+	if err != nil {
+		t.Fatalf("Fail to build the message key\n%v", err)
+	}
+	if string(key) != "api/bar" {
+		t.Fatalf("Invalid message key %s", string(key))
+	}
+}
+
+// fakeKafkaTransport answers the metadata and produce requests of the
+// Kafka writer without a broker
+type fakeKafkaTransport struct{}
+
+func (t *fakeKafkaTransport) RoundTrip(ctx context.Context, addr net.Addr, req kafka.Request) (kafka.Response, error) {
+	switch r := req.(type) {
+	case *metadataAPI.Request:
+		return &metadataAPI.Response{
+			Topics: []metadataAPI.ResponseTopic{{
+				Name:       r.TopicNames[0],
+				Partitions: []metadataAPI.ResponsePartition{{PartitionIndex: 0}},
+			}},
+		}, nil
+	case *produceAPI.Request:
+		return &produceAPI.Response{
+			Topics: []produceAPI.ResponseTopic{{
+				Topic:      r.Topics[0].Topic,
+				Partitions: []produceAPI.ResponsePartition{{Partition: 0}},
+			}},
+		}, nil
+	}
+	return nil, fmt.Errorf("unexpected request %T", req)
+}
+
+func TestKafkaPushLatency(t *testing.T) {
+	exporter, err := NewKafkaExporter(zap.NewExample(), &KafkaConfiguration{
+		Name:    "foo",
+		Brokers: []string{"127.0.0.1:9092"},
+		Topic:   "healthchecks",
+	})
+	if err != nil {
+		t.Fatalf("Fail to create the exporter\n%v", err)
+	}
+	exporter.Writer.Transport = &fakeKafkaTransport{}
+	defer exporter.Writer.Close()
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		err = exporter.Push(&healthcheck.Result{Name: "bar"})
+		if err != nil {
+			t.Fatalf("Fail to push the result\n%v", err)
+		}
+	}
+	duration := time.Since(start)
+	if duration > 500*time.Millisecond {
+		t.Fatalf("The pushes are too slow: %s", duration)
+	}
+}
@@ -3,6 +3,7 @@ package exporter
 import (
 	"fmt"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -15,19 +16,20 @@ import (
 
 // RiemannConfiguration the Riemann exporter configuration
 type RiemannConfiguration struct {
-	Name     string
-	Host     string
-	Port     uint32
-	TTL      healthcheck.Duration
-	Key      string `json:"key,omitempty"`
-	Cert     string `json:"cert,omitempty"`
-	Cacert   string `json:"cacert,omitempty"`
-	Insecure bool
+	Name            string
+	Host            string
+	Port            uint32
+	TTL             healthcheck.Duration
+	Key             string `json:"key,omitempty"`
+	Cert            string `json:"cert,omitempty"`
+	Cacert          string `json:"cacert,omitempty"`
+	Insecure        bool
+	ExporterRouting `yaml:",inline"`
 }
 
 // RiemannExporter the Riemann exporter struct
 type RiemannExporter struct {
-	Started bool
+	Started atomic.Bool
 	Logger  *zap.Logger
 	Config  *RiemannConfiguration
 	Client  riemanngo.Client
@@ -101,14 +103,19 @@ func (c *RiemannExporter) Start() error {
 	if err != nil {
 		return errors.Wrapf(err, "Fail to start the Riemann exporter")
 	}
-	c.Started = true
+	c.Started.Store(true)
+	return nil
+}
+
+// Flush flushes the Riemann exporter. It does nothing, the events are sent synchronously.
+func (c *RiemannExporter) Flush() error {
 	return nil
 }
 
 // Stop stops the Riemann exporter component
 func (c *RiemannExporter) Stop() error {
 	c.Logger.Info(fmt.Sprintf("Stopping the Riemann exporter %s", c.Config.Name))
-	c.Started = false
+	c.Started.Store(false)
 	return c.Client.Close()
 }
 
@@ -125,7 +132,7 @@ func (c *RiemannExporter) Reconnect() error {
 		return errors.Wrapf(err, "Fail to restart the Riemann exporter")
 	}
 	c.Logger.Info("Riemann exporter: reconnected")
-	c.Started = true
+	c.Started.Store(true)
 	return nil
 }
 
@@ -141,7 +148,7 @@ func (c *RiemannExporter) GetConfig() interface{} {
 
 // IsStarted returns the exporter status
 func (c *RiemannExporter) IsStarted() bool {
-	return c.Started
+	return c.Started.Load()
 }
 
 // Push pushes events to the desination
@@ -149,10 +156,13 @@ func (c *RiemannExporter) Push(result *healthcheck.Result) error {
 	state := "ok"
 	if !result.Success {
 		state = "critical"
+	} else if result.Degraded {
+		state = "warning"
 	}
 	attributes := map[string]string{
 		"healthcheck": result.Name,
 		"source":      result.Source,
+		"type":        result.Type,
 	}
 	for k, v := range result.Labels {
 		attributes[k] = v
@@ -161,7 +171,7 @@ func (c *RiemannExporter) Push(result *healthcheck.Result) error {
 		Service:     "cabourotte-healthcheck",
 		Metric:      result.Duration,
 		Description: fmt.Sprintf("%s: %s", result.Summary, result.Message),
-		Time:        time.Unix(result.HealthcheckTimestamp, 0),
+		Time:        result.Time(),
 		State:       state,
 		Tags:        []string{"cabourotte"},
 		TTL:         time.Duration(c.Config.TTL),
@@ -2,6 +2,8 @@ package exporter
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,14 +26,22 @@ type Exporter interface {
 	Name() string
 	GetConfig() interface{}
 	Push(*healthcheck.Result) error
+	// Flush pushes the results buffered by the exporter. It is called
+	// before stopping the exporter.
+	Flush() error
 }
 
 // Component the exporter component
 type Component struct {
-	Logger            *zap.Logger
-	Config            *Configuration
-	ChanResult        chan *healthcheck.Result
-	Exporters         map[string]Exporter
+	Logger     *zap.Logger
+	Config     *Configuration
+	ChanResult chan *healthcheck.Result
+	Exporters  map[string]Exporter
+	// startOrder the exporters names grouped by priority, in configuration
+	// order
+	startOrder [][]string
+	// routes the results matchers of the exporters
+	routes            map[string]route
 	MemoryStore       *memorystore.MemoryStore
 	exporterHistogram *prom.HistogramVec
 	chanResultGauge   *prom.GaugeVec
@@ -45,14 +55,14 @@ type Component struct {
 
 // New creates a new exporter component
 func New(logger *zap.Logger, store *memorystore.MemoryStore, chanResult chan *healthcheck.Result, promComponent *prometheus.Prometheus, config *Configuration) (*Component, error) {
-	exporters := make(map[string]Exporter)
+	var configured []configuredExporter
 	for i := range config.HTTP {
 		httpConfig := config.HTTP[i]
 		exporter, err := NewHTTPExporter(logger, &httpConfig)
 		if err != nil {
 			return nil, errors.Wrapf(err, "fail to create the http exporter")
 		}
-		exporters[httpConfig.Name] = exporter
+		configured = append(configured, configuredExporter{name: httpConfig.Name, exporter: exporter, routing: httpConfig.ExporterRouting})
 	}
 	for i := range config.Riemann {
 		riemannConfig := config.Riemann[i]
@@ -60,20 +70,54 @@ func New(logger *zap.Logger, store *memorystore.MemoryStore, chanResult chan *he
 		if err != nil {
 			return nil, errors.Wrapf(err, "fail to create the http exporter")
 		}
-		exporters[riemannConfig.Name] = exporter
+		configured = append(configured, configuredExporter{name: riemannConfig.Name, exporter: exporter, routing: riemannConfig.ExporterRouting})
+	}
+	for i := range config.Kafka {
+		kafkaConfig := config.Kafka[i]
+		exporter, err := NewKafkaExporter(logger, &kafkaConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the kafka exporter")
+		}
+		configured = append(configured, configuredExporter{name: kafkaConfig.Name, exporter: exporter, routing: kafkaConfig.ExporterRouting})
+	}
+	for i := range config.Webhook {
+		webhookConfig := config.Webhook[i]
+		exporter, err := NewWebhookExporter(logger, &webhookConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the webhook exporter")
+		}
+		configured = append(configured, configuredExporter{name: webhookConfig.Name, exporter: exporter, routing: webhookConfig.ExporterRouting})
+	}
+	for i := range config.Datadog {
+		datadogConfig := config.Datadog[i]
+		exporter, err := NewDatadogExporter(logger, &datadogConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the datadog exporter")
+		}
+		configured = append(configured, configuredExporter{name: datadogConfig.Name, exporter: exporter, routing: datadogConfig.ExporterRouting})
+	}
+	exporters := make(map[string]Exporter)
+	var priorities []exporterPriority
+	routes := make(map[string]route)
+	for _, c := range configured {
+		exporters[c.name] = c.exporter
+		priorities = append(priorities, exporterPriority{name: c.name, priority: c.routing.Priority})
+		routes[c.name] = route{labels: c.routing.MatchLabels, source: c.routing.MatchSource}
 	}
 	buckets := []float64{
 		0.05, 0.1, 0.2, 0.4, 0.8, 1,
 		1.5, 2, 3, 5}
 	histo := prom.NewHistogramVec(prom.HistogramOpts{
-		Name:    "exporter_duration_seconds",
-		Help:    "Time to push to an exporter.",
-		Buckets: buckets,
+		Namespace: promComponent.Namespace(),
+		Name:      "exporter_duration_seconds",
+		Help:      "Time to push to an exporter.",
+		Buckets:   buckets,
 	},
 		[]string{"name", "status"})
 	gauge := prom.NewGaugeVec(prom.GaugeOpts{
-		Name: "result_chan_size",
-		Help: "Size of the result channel.",
+		Namespace: promComponent.Namespace(),
+		Name:      "result_chan_size",
+		Help:      "Size of the result channel.",
 	}, []string{})
 	err := promComponent.Register(histo)
 	if err != nil {
@@ -91,22 +135,101 @@ func New(logger *zap.Logger, store *memorystore.MemoryStore, chanResult chan *he
 		Config:            config,
 		ChanResult:        chanResult,
 		Exporters:         exporters,
+		startOrder:        startOrder(priorities),
+		routes:            routes,
 		prometheus:        promComponent,
 		gaugeTick:         time.NewTicker(time.Duration(time.Second * 10)),
 	}, nil
 }
 
+// configuredExporter an exporter created from the configuration
+type configuredExporter struct {
+	name     string
+	exporter Exporter
+	routing  ExporterRouting
+}
+
+// exporterPriority associates an exporter name to its start priority
+type exporterPriority struct {
+	name     string
+	priority int
+}
+
+// startOrder groups the exporters names by priority. The groups are sorted
+// by priority, and the names keep the configuration order inside a group.
+func startOrder(priorities []exporterPriority) [][]string {
+	sort.SliceStable(priorities, func(i, j int) bool {
+		return priorities[i].priority < priorities[j].priority
+	})
+	var result [][]string
+	for i, p := range priorities {
+		if i == 0 || p.priority != priorities[i-1].priority {
+			result = append(result, []string{})
+		}
+		result[len(result)-1] = append(result[len(result)-1], p.name)
+	}
+	return result
+}
+
+// route the matchers selecting the results pushed to an exporter. All
+// results are pushed to the exporter if the matchers are empty.
+type route struct {
+	labels map[string]string
+	source string
+}
+
+// match returns true if the result should be pushed to the exporter
+func (r route) match(result *healthcheck.Result) bool {
+	if r.source != "" && !result.FromSource(r.source) {
+		return false
+	}
+	for k, v := range r.labels {
+		if value, ok := result.Labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// startGroup starts a group of exporters in parallel. It returns when all
+// exporters are started or when the start timeout is reached.
+func (c *Component) startGroup(names []string) {
+	timeout := time.Duration(c.Config.StartTimeout)
+	if timeout == 0 {
+		timeout = time.Duration(DefaultStartTimeout)
+	}
+	var wg sync.WaitGroup
+	for _, name := range names {
+		exporter := c.Exporters[name]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := exporter.Start()
+			if err != nil {
+				// do not return error on purpose, clients should be able to reconnect
+				c.Logger.Error(fmt.Sprintf("fail to create the exporter %s: %s", exporter.Name(), err.Error()))
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		c.Logger.Error(fmt.Sprintf("timeout while starting the exporters %s", strings.Join(names, ", ")))
+	}
+}
+
 // Start starts the exporter component
 func (c *Component) Start() error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	c.Logger.Info("Starting the exporters")
-	for _, exporter := range c.Exporters {
-		err := exporter.Start()
-		if err != nil {
-			// do not return error on purpose, clients should be able to reconnect
-			c.Logger.Error(fmt.Sprintf("fail to create the exporter %s: %s", exporter.Name(), err.Error()))
-		}
+	for _, group := range c.startOrder {
+		c.startGroup(group)
 	}
 	c.wg.Add(1)
 	c.t.Go(func() error {
@@ -120,11 +243,37 @@ func (c *Component) Start() error {
 			}
 		}
 	})
+	queueSize := int(c.Config.QueueSize)
+	if queueSize == 0 {
+		queueSize = DefaultQueueSize
+	}
+	// each exporter pushes the results from its own queue, so an exporter
+	// retrying a push does not block the other ones
+	queues := make(map[string]chan *healthcheck.Result, len(c.Exporters))
+	for k := range c.Exporters {
+		exporter := c.Exporters[k]
+		queue := make(chan *healthcheck.Result, queueSize)
+		queues[k] = queue
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			for message := range queue {
+				c.export(exporter, message)
+			}
+		}()
+	}
 	go func() {
 		defer c.wg.Done()
 		for message := range c.ChanResult {
 			c.MemoryStore.Add(message)
-			if message.Success {
+			if message.Degraded {
+				c.Logger.Warn("healthcheck degraded",
+					zap.String("name", message.Name),
+					zap.Reflect("labels", message.Labels),
+					zap.String("cause", message.Message),
+					zap.Int64("healthcheck-timestamp", message.HealthcheckTimestamp),
+				)
+			} else if message.Success {
 				c.Logger.Debug("Healthcheck successful",
 					zap.String("name", message.Name),
 					zap.Reflect("labels", message.Labels),
@@ -140,34 +289,19 @@ func (c *Component) Start() error {
 			}
 			for k := range c.Exporters {
 				exporter := c.Exporters[k]
-				if exporter.IsStarted() {
-					start := time.Now()
-					err := exporter.Push(message)
-					duration := time.Since(start)
-					status := "success"
-					name := exporter.Name()
-					if err != nil {
-						c.Logger.Error(fmt.Sprintf("Failed to push healthchecks result for exporter %s: %s", name, err.Error()))
-						status = "failure"
-						err := exporter.Stop()
-						if err != nil {
-							// do not return error
-							// on purpose
-							c.Logger.Error(fmt.Sprintf("Fail to close the exporter %s: %s", name, err.Error()))
-						}
-					}
-					c.exporterHistogram.With(prom.Labels{"name": name, "status": status}).Observe(duration.Seconds())
+				if !message.ExportedTo(exporter.Name()) || !c.routes[k].match(message) {
+					continue
 				}
-				if !exporter.IsStarted() {
-					err := exporter.Reconnect()
-					if err != nil {
-						// do not return error
-						// on purpose
-						c.Logger.Error(fmt.Sprintf("fail to reconnect the exporter %s: %s", exporter.Name(), err.Error()))
-					}
+				select {
+				case queues[k] <- message:
+				default:
+					c.Logger.Error(fmt.Sprintf("The queue of the exporter %s is full, dropping the result of the healthcheck %s", exporter.Name(), message.Name))
 				}
 			}
 		}
+		for k := range queues {
+			close(queues[k])
+		}
 		c.Logger.Info("Exporter routine stopped")
 
 	}()
@@ -175,19 +309,126 @@ func (c *Component) Start() error {
 	return nil
 }
 
+// export pushes a result to an exporter. The exporter is stopped if the
+// push fails, and reconnected if it is not started.
+func (c *Component) export(exporter Exporter, message *healthcheck.Result) {
+	if exporter.IsStarted() {
+		start := time.Now()
+		err := c.push(exporter, message)
+		duration := time.Since(start)
+		status := "success"
+		name := exporter.Name()
+		if err != nil {
+			c.Logger.Error(fmt.Sprintf("Failed to push healthchecks result for exporter %s: %s", name, err.Error()))
+			status = "failure"
+			err := exporter.Stop()
+			if err != nil {
+				// do not return error
+				// on purpose
+				c.Logger.Error(fmt.Sprintf("Fail to close the exporter %s: %s", name, err.Error()))
+			}
+		}
+		c.exporterHistogram.With(prom.Labels{"name": name, "status": status}).Observe(duration.Seconds())
+	}
+	if !exporter.IsStarted() {
+		err := exporter.Reconnect()
+		if err != nil {
+			// do not return error
+			// on purpose
+			c.Logger.Error(fmt.Sprintf("fail to reconnect the exporter %s: %s", exporter.Name(), err.Error()))
+		}
+	}
+}
+
+// ExportersStatus returns, for each exporter, if it is started
+func (c *Component) ExportersStatus() map[string]bool {
+	result := make(map[string]bool, len(c.Exporters))
+	for name, exporter := range c.Exporters {
+		result[name] = exporter.IsStarted()
+	}
+	return result
+}
+
+// push pushes a result to an exporter, retrying on failure
+func (c *Component) push(exporter Exporter, result *healthcheck.Result) error {
+	interval := time.Duration(c.Config.PushRetryInterval)
+	if interval == 0 {
+		interval = time.Duration(DefaultPushRetryInterval)
+	}
+	var err error
+	for attempt := uint(0); attempt <= c.Config.PushRetries; attempt++ {
+		if attempt != 0 {
+			c.Logger.Debug(fmt.Sprintf("Retrying to push healthchecks result for exporter %s (attempt %d): %s", exporter.Name(), attempt, err.Error()))
+			select {
+			case <-time.After(interval):
+			case <-c.t.Dying():
+				// the component is stopping, the result is not retried
+				return err
+			}
+			interval = interval * 2
+			if interval > time.Duration(MaxPushRetryInterval) {
+				interval = time.Duration(MaxPushRetryInterval)
+			}
+		}
+		err = exporter.Push(result)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// flush flushes the started exporters in parallel. It returns when all
+// exporters are flushed or when the flush timeout is reached.
+func (c *Component) flush() {
+	timeout := time.Duration(c.Config.FlushTimeout)
+	if timeout == 0 {
+		timeout = time.Duration(DefaultFlushTimeout)
+	}
+	var wg sync.WaitGroup
+	for k := range c.Exporters {
+		exporter := c.Exporters[k]
+		if !exporter.IsStarted() {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := exporter.Flush()
+			if err != nil {
+				// do not return error on purpose, the exporter should
+				// still be stopped
+				c.Logger.Error(fmt.Sprintf("fail to flush the exporter %s: %s", exporter.Name(), err.Error()))
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		c.Logger.Error("timeout while flushing the exporters")
+	}
+}
+
 // Stop the exporters
 func (c *Component) Stop() error {
 	c.Logger.Info("Stopping exporters")
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	c.wg.Wait()
+	// the tomb is killed first to interrupt the push retries
 	c.t.Kill(nil)
+	c.wg.Wait()
 	err := c.t.Wait()
 	if err != nil {
 		return err
 	}
 	c.prometheus.Unregister(c.chanResultGauge)
 	c.prometheus.Unregister(c.exporterHistogram)
+	c.flush()
 	for k := range c.Exporters {
 		e := c.Exporters[k]
 		err := e.Stop()
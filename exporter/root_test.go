@@ -3,6 +3,7 @@ package exporter
 import (
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -83,3 +84,397 @@ func TestStartStop(t *testing.T) {
 		t.Fatalf("Error stopping the component :\n%v", err)
 	}
 }
+
+func TestRoutes(t *testing.T) {
+	received := make(chan string, 10)
+	newServer := func(name string) uint32 {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received <- name
+			w.WriteHeader(http.StatusOK)
+		}))
+		t.Cleanup(ts.Close)
+		port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+		if err != nil {
+			t.Fatalf("Error getting HTTP server port :\n%v", err)
+		}
+		return uint32(port)
+	}
+	chanResult := make(chan *healthcheck.Result, 10)
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(
+		logger,
+		memorystore.NewMemoryStore(logger),
+		chanResult,
+		prom,
+		&Configuration{
+			HTTP: []HTTPConfiguration{
+				{
+					Name:     "prod",
+					Host:     "127.0.0.1",
+					Port:     newServer("prod"),
+					Protocol: healthcheck.HTTP,
+					ExporterRouting: ExporterRouting{
+						MatchLabels: map[string]string{"env": "prod"},
+					},
+				},
+				{
+					Name:     "staging",
+					Host:     "127.0.0.1",
+					Port:     newServer("staging"),
+					Protocol: healthcheck.HTTP,
+					ExporterRouting: ExporterRouting{
+						MatchLabels: map[string]string{"env": "staging"},
+					},
+				},
+			}})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Error starting the component :\n%v", err)
+	}
+	chanResult <- &healthcheck.Result{
+		Name:                 "foo",
+		Labels:               map[string]string{"env": "prod"},
+		Success:              true,
+		HealthcheckTimestamp: time.Now().Unix(),
+		Message:              "message",
+	}
+	close(chanResult)
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Error stopping the component :\n%v", err)
+	}
+	close(received)
+	names := []string{}
+	for name := range received {
+		names = append(names, name)
+	}
+	if !reflect.DeepEqual(names, []string{"prod"}) {
+		t.Fatalf("Invalid exporters %v", names)
+	}
+}
+
+func TestRouteMatch(t *testing.T) {
+	cases := []struct {
+		route  route
+		result healthcheck.Result
+		match  bool
+	}{
+		{route: route{}, result: healthcheck.Result{}, match: true},
+		{route: route{labels: map[string]string{"env": "prod"}}, result: healthcheck.Result{Labels: map[string]string{"env": "prod", "team": "a"}}, match: true},
+		{route: route{labels: map[string]string{"env": "prod"}}, result: healthcheck.Result{Labels: map[string]string{"env": "staging"}}, match: false},
+		{route: route{labels: map[string]string{"env": "prod"}}, result: healthcheck.Result{}, match: false},
+		{route: route{source: "configuration"}, result: healthcheck.Result{}, match: true},
+		{route: route{source: "api"}, result: healthcheck.Result{Source: "api"}, match: true},
+		{route: route{source: "api"}, result: healthcheck.Result{}, match: false},
+	}
+	for _, c := range cases {
+		if c.route.match(&c.result) != c.match {
+			t.Fatalf("Invalid match for %+v and %+v", c.route, c.result)
+		}
+	}
+}
+
+func TestPushRetry(t *testing.T) {
+	count := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		if count < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(
+		logger,
+		memorystore.NewMemoryStore(logger),
+		make(chan *healthcheck.Result, 10),
+		prom,
+		&Configuration{
+			PushRetries:       2,
+			PushRetryInterval: healthcheck.Duration(time.Millisecond * 10),
+			HTTP: []HTTPConfiguration{
+				HTTPConfiguration{
+					Name:     "foo",
+					Host:     "127.0.0.1",
+					Port:     uint32(port),
+					Protocol: healthcheck.HTTP,
+				},
+			}})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	result := &healthcheck.Result{
+		Name:                 "foo",
+		Success:              true,
+		HealthcheckTimestamp: time.Now().Unix(),
+		Message:              "message",
+	}
+	err = component.push(component.Exporters["foo"], result)
+	if err != nil {
+		t.Fatalf("The push should succeed after retries :\n%v", err)
+	}
+	if count != 3 {
+		t.Fatalf("Expected 3 push attempts, got %d", count)
+	}
+	component.Config.PushRetries = 0
+	count = 0
+	err = component.push(component.Exporters["foo"], result)
+	if err == nil {
+		t.Fatalf("Was expecting an error without retries")
+	}
+}
+
+func TestStopDuringPushRetry(t *testing.T) {
+	requests := make(chan struct{}, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- struct{}{}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	chanResult := make(chan *healthcheck.Result, 10)
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(
+		logger,
+		memorystore.NewMemoryStore(logger),
+		chanResult,
+		prom,
+		&Configuration{
+			PushRetries:       5,
+			PushRetryInterval: healthcheck.Duration(time.Second * 10),
+			HTTP: []HTTPConfiguration{
+				HTTPConfiguration{
+					Name:     "foo",
+					Host:     "127.0.0.1",
+					Port:     uint32(port),
+					Protocol: healthcheck.HTTP,
+				},
+			}})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Error starting the component :\n%v", err)
+	}
+	chanResult <- &healthcheck.Result{
+		Name:                 "foo",
+		Success:              true,
+		HealthcheckTimestamp: time.Now().Unix(),
+		Message:              "message",
+	}
+	select {
+	case <-requests:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("The result was not pushed")
+	}
+	close(chanResult)
+	start := time.Now()
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Error stopping the component :\n%v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatalf("The component was not stopped during the push retry")
+	}
+}
+
+func TestPushRetryDoesNotBlock(t *testing.T) {
+	received := make(chan struct{}, 10)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer working.Close()
+	port := func(ts *httptest.Server) uint32 {
+		port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+		if err != nil {
+			t.Fatalf("Error getting HTTP server port :\n%v", err)
+		}
+		return uint32(port)
+	}
+	chanResult := make(chan *healthcheck.Result, 10)
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	store := memorystore.NewMemoryStore(logger)
+	component, err := New(
+		logger,
+		store,
+		chanResult,
+		prom,
+		&Configuration{
+			PushRetries:       5,
+			PushRetryInterval: healthcheck.Duration(time.Second * 10),
+			HTTP: []HTTPConfiguration{
+				{
+					Name:     "failing",
+					Host:     "127.0.0.1",
+					Port:     port(failing),
+					Protocol: healthcheck.HTTP,
+				},
+				{
+					Name:     "working",
+					Host:     "127.0.0.1",
+					Port:     port(working),
+					Protocol: healthcheck.HTTP,
+				},
+			}})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Error starting the component :\n%v", err)
+	}
+	for _, name := range []string{"foo", "bar"} {
+		chanResult <- &healthcheck.Result{
+			Name:                 name,
+			Success:              true,
+			HealthcheckTimestamp: time.Now().Unix(),
+			Message:              "message",
+		}
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("The results were not pushed to the working exporter")
+		}
+	}
+	if len(store.List()) != 2 {
+		t.Fatalf("The results were not added to the memory store: %v", store.List())
+	}
+	close(chanResult)
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Error stopping the component :\n%v", err)
+	}
+}
+
+func TestStartOrder(t *testing.T) {
+	order := startOrder([]exporterPriority{
+		{name: "a", priority: 1},
+		{name: "b", priority: 0},
+		{name: "c", priority: 1},
+		{name: "d", priority: 0},
+		{name: "e", priority: 2},
+	})
+	expected := [][]string{{"b", "d"}, {"a", "c"}, {"e"}}
+	if !reflect.DeepEqual(order, expected) {
+		t.Fatalf("Invalid start order %v", order)
+	}
+}
+
+// slowExporter an exporter taking some time to start and to flush
+type slowExporter struct {
+	name    string
+	delay   time.Duration
+	started chan string
+	flushed chan string
+}
+
+func (e *slowExporter) Start() error {
+	time.Sleep(e.delay)
+	e.started <- e.name
+	return nil
+}
+func (e *slowExporter) Stop() error                    { return nil }
+func (e *slowExporter) Reconnect() error               { return nil }
+func (e *slowExporter) IsStarted() bool                { return true }
+func (e *slowExporter) Name() string                   { return e.name }
+func (e *slowExporter) GetConfig() interface{}         { return nil }
+func (e *slowExporter) Push(*healthcheck.Result) error { return nil }
+func (e *slowExporter) Flush() error {
+	time.Sleep(e.delay)
+	e.flushed <- e.name
+	return nil
+}
+
+func TestStartGroups(t *testing.T) {
+	started := make(chan string, 3)
+	component := Component{
+		Logger: zap.NewExample(),
+		Config: &Configuration{
+			StartTimeout: healthcheck.Duration(200 * time.Millisecond),
+		},
+		Exporters: map[string]Exporter{
+			"slow":     &slowExporter{name: "slow", delay: 2 * time.Second, started: started},
+			"primary":  &slowExporter{name: "primary", delay: 50 * time.Millisecond, started: started},
+			"fallback": &slowExporter{name: "fallback", started: started},
+		},
+		startOrder: [][]string{{"slow", "primary"}, {"fallback"}},
+	}
+	start := time.Now()
+	for _, group := range component.startOrder {
+		component.startGroup(group)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("The start timeout was not respected")
+	}
+	if first := <-started; first != "primary" {
+		t.Fatalf("Invalid first exporter %s", first)
+	}
+	if second := <-started; second != "fallback" {
+		t.Fatalf("Invalid second exporter %s", second)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	flushed := make(chan string, 2)
+	component := Component{
+		Logger: zap.NewExample(),
+		Config: &Configuration{
+			FlushTimeout: healthcheck.Duration(200 * time.Millisecond),
+		},
+		Exporters: map[string]Exporter{
+			"slow": &slowExporter{name: "slow", delay: 2 * time.Second, flushed: flushed},
+			"fast": &slowExporter{name: "fast", flushed: flushed},
+		},
+	}
+	start := time.Now()
+	component.flush()
+	if time.Since(start) > time.Second {
+		t.Fatalf("The flush timeout was not respected")
+	}
+	select {
+	case name := <-flushed:
+		if name != "fast" {
+			t.Fatalf("Invalid flushed exporter %s", name)
+		}
+	default:
+		t.Fatalf("The exporter was not flushed")
+	}
+}
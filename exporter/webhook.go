@@ -0,0 +1,189 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/tls"
+)
+
+// WebhookConfiguration the webhook exporter configuration
+type WebhookConfiguration struct {
+	Name string
+	URL  string
+	// Method the HTTP method (POST by default)
+	Method  string
+	Headers map[string]string `json:"headers,omitempty"`
+	// Template the template of the request body, rendered with the
+	// healthcheck result
+	Template string
+	// OnlyFailures only sends the failed healthchecks results
+	OnlyFailures    bool   `yaml:"only-failures"`
+	Key             string `json:"key,omitempty"`
+	Cert            string `json:"cert,omitempty"`
+	Cacert          string `json:"cacert,omitempty"`
+	Insecure        bool
+	ExporterRouting `yaml:",inline"`
+}
+
+// WebhookExporter the webhook exporter struct
+type WebhookExporter struct {
+	Started  atomic.Bool
+	Logger   *zap.Logger
+	Config   *WebhookConfiguration
+	Client   *http.Client
+	template *template.Template
+}
+
+// webhookTemplate parses a webhook template. The json function can be used
+// to encode a value to JSON.
+func webhookTemplate(value string) (*template.Template, error) {
+	return template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			result, err := json.Marshal(v)
+			return string(result), err
+		},
+	}).Parse(value)
+}
+
+// UnmarshalYAML parses the configuration of the webhook exporter from YAML.
+func (c *WebhookConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration WebhookConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read webhook exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the webhook exporter configuration")
+	}
+	if raw.URL == "" {
+		return errors.New("Invalid URL for the webhook exporter configuration")
+	}
+	if raw.Template == "" {
+		return errors.New("Invalid template for the webhook exporter configuration")
+	}
+	if raw.Method == "" {
+		raw.Method = http.MethodPost
+	}
+	if !((raw.Key != "" && raw.Cert != "") ||
+		(raw.Key == "" && raw.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	if _, err := webhookTemplate(raw.Template); err != nil {
+		return errors.Wrap(err, "Invalid template for the webhook exporter configuration")
+	}
+	*c = WebhookConfiguration(raw)
+	return nil
+}
+
+// NewWebhookExporter creates a new webhook exporter
+func NewWebhookExporter(logger *zap.Logger, config *WebhookConfiguration) (*WebhookExporter, error) {
+	tlsConfig, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, "", config.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := webhookTemplate(config.Template)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid template for the webhook exporter")
+	}
+	transport := &http.Transport{
+		TLSClientConfig: tlsConfig,
+	}
+	exporter := WebhookExporter{
+		Logger:   logger,
+		Config:   config,
+		template: tmpl,
+		Client: &http.Client{
+			Transport: transport,
+			Timeout:   time.Second * 3,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	return &exporter, nil
+}
+
+// IsStarted returns the exporter status
+func (c *WebhookExporter) IsStarted() bool {
+	return c.Started.Load()
+}
+
+// Start starts the webhook exporter component
+func (c *WebhookExporter) Start() error {
+	// nothing to do
+	c.Logger.Info(fmt.Sprintf("Starting the webhook healthcheck exporter %s", c.Config.Name))
+	c.Started.Store(true)
+	return nil
+}
+
+// Reconnect reconnects the webhook exporter component
+func (c *WebhookExporter) Reconnect() error {
+	// nothing to do
+	c.Started.Store(true)
+	return nil
+}
+
+// Flush flushes the webhook exporter. It does nothing, the results are pushed synchronously.
+func (c *WebhookExporter) Flush() error {
+	return nil
+}
+
+// Stop stops the webhook exporter component
+func (c *WebhookExporter) Stop() error {
+	c.Logger.Info(fmt.Sprintf("Stopping the webhook exporter %s", c.Config.Name))
+	c.Started.Store(false)
+	return nil
+}
+
+// Name returns the name of the exporter
+func (c *WebhookExporter) Name() string {
+	return c.Config.Name
+}
+
+// GetConfig returns the config of the exporter
+func (c *WebhookExporter) GetConfig() interface{} {
+	return c.Config
+}
+
+// Push renders the template and sends the result to the webhook
+func (c *WebhookExporter) Push(result *healthcheck.Result) error {
+	if c.Config.OnlyFailures && result.Success {
+		return nil
+	}
+	var body bytes.Buffer
+	err := c.template.Execute(&body, result)
+	if err != nil {
+		return errors.Wrapf(err, "Webhook exporter: fail to render the template")
+	}
+	method := c.Config.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(method, c.Config.URL, &body)
+	if err != nil {
+		return errors.Wrapf(err, "Webhook exporter: fail to create request for %s", c.Config.URL)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.Config.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "Webhook exporter: fail to send healthchecks to %s", c.Config.URL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("Webhook exporter: request failed, status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package exporter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestWebhookExporter(t *testing.T) {
+	bodies := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Fail to read the body :\n%v", err)
+		}
+		if r.Method != http.MethodPut || r.Header.Get("X-Foo") != "bar" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	exporter, err := NewWebhookExporter(
+		zap.NewExample(),
+		&WebhookConfiguration{
+			Name:         "foo",
+			URL:          ts.URL,
+			Method:       http.MethodPut,
+			Headers:      map[string]string{"X-Foo": "bar"},
+			Template:     `{"text": {{ printf "%s failed: %s" .Name .Message | json }}}`,
+			OnlyFailures: true,
+		})
+	if err != nil {
+		t.Fatalf("Error creating the webhook exporter :\n%v", err)
+	}
+	err = exporter.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the webhook exporter:\n%v", err)
+	}
+	err = exporter.Push(&healthcheck.Result{
+		Name:    "foo",
+		Success: true,
+		Message: "success",
+	})
+	if err != nil {
+		t.Fatalf("Fail to push the result:\n%v", err)
+	}
+	err = exporter.Push(&healthcheck.Result{
+		Name:    "foo",
+		Success: false,
+		Message: `status "500"`,
+	})
+	if err != nil {
+		t.Fatalf("Fail to push the result:\n%v", err)
+	}
+	expected := `{"text": "foo failed: status \"500\""}`
+	if len(bodies) != 1 || bodies[0] != expected {
+		t.Fatalf("Invalid webhook requests %v", bodies)
+	}
+}
+
+func TestUnmarshalWebhookConfigError(t *testing.T) {
+	cases := []string{
+		`
+url: "http://127.0.0.1"
+template: "{{ .Name }}"
+`,
+		`
+name: "foo"
+template: "{{ .Name }}"
+`,
+		`
+name: "foo"
+url: "http://127.0.0.1"
+`,
+		`
+name: "foo"
+url: "http://127.0.0.1"
+template: "{{ .Name "
+`,
+	}
+	for _, c := range cases {
+		var result WebhookConfiguration
+		if err := yaml.Unmarshal([]byte(c), &result); err == nil {
+			t.Fatalf("Was expecting an error when decoding the configuration: \n%s", c)
+		}
+	}
+}
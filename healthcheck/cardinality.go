@@ -0,0 +1,114 @@
+package healthcheck
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// OverflowLabelValue the value replacing the healthchecks labels values
+// exceeding the maximum number of values of a metric label
+const OverflowLabelValue = "other"
+
+// labelGuard tracks the values of the healthchecks labels exposed in the
+// metrics in order to limit their cardinality. The healthchecks using each
+// value are tracked, a value is released when the last healthcheck using it
+// is removed.
+type labelGuard struct {
+	lock   sync.Mutex
+	values map[string]map[string]map[string]bool
+}
+
+// newLabelGuard creates a new label guard
+func newLabelGuard() *labelGuard {
+	return &labelGuard{
+		values: make(map[string]map[string]map[string]bool),
+	}
+}
+
+// allow returns true if the value is already tracked for the label, or if
+// it can be added without exceeding max values (no limit if 0). The value
+// is then tracked as used by the healthcheck.
+func (g *labelGuard) allow(label string, value string, check string, max int) bool {
+	if max <= 0 {
+		return true
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	values, ok := g.values[label]
+	if !ok {
+		values = make(map[string]map[string]bool)
+		g.values[label] = values
+	}
+	checks, ok := values[value]
+	if !ok {
+		if len(values) >= max {
+			return false
+		}
+		checks = make(map[string]bool)
+		values[value] = checks
+	}
+	checks[check] = true
+	return true
+}
+
+// release stops tracking the values used by an healthcheck. The values not
+// used anymore by other healthchecks are released.
+func (g *labelGuard) release(check string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	for label, values := range g.values {
+		for value, checks := range values {
+			delete(checks, check)
+			if len(checks) == 0 {
+				delete(values, value)
+			}
+		}
+		if len(values) == 0 {
+			delete(g.values, label)
+		}
+	}
+}
+
+// isDiscoverySource returns true if the source is a service discovery
+// mechanism
+func isDiscoverySource(source string) bool {
+	return strings.HasPrefix(source, SourceHTTPDiscovery+"-") ||
+		strings.HasPrefix(source, SourceFileDiscovery+"-") ||
+		strings.HasPrefix(source, SourceDNSSRVDiscovery+"-")
+}
+
+// metricsLabels returns the values of the healthchecks labels exposed in the
+// metrics for a result.
+// Labels of healthchecks created by service discovery are only exposed if
+// they are in DiscoveryMetricsLabels, and values exceeding MaxLabelValues
+// are replaced by OverflowLabelValue.
+func (c *Component) metricsLabels(w *Wrapper, result *Result) map[string]string {
+	labels := make(map[string]string, len(c.healthchecksLabels))
+	discovered := isDiscoverySource(w.healthcheck.Base().Source)
+	for _, k := range c.healthchecksLabels {
+		value := result.Labels[k]
+		if discovered && c.DiscoveryMetricsLabels != nil && !contains(c.DiscoveryMetricsLabels, k) {
+			value = ""
+		}
+		if value != "" && !c.labelGuard.allow(k, value, w.healthcheck.Base().Name, c.MaxLabelValues) {
+			w.healthcheck.LogDebug(fmt.Sprintf("Too many values for the metric label %s, replacing %s by %s", k, value, OverflowLabelValue))
+			c.labelOverflowCounter.With(prom.Labels{"label": k}).Inc()
+			value = OverflowLabelValue
+		}
+		labels[k] = value
+	}
+	return labels
+}
+
+// contains returns true if the value is in the slice
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package healthcheck
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestMetricsLabels(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{"env", "pod"})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	component.MaxLabelValues = 2
+	component.DiscoveryMetricsLabels = []string{"env"}
+	newWrapper := func(source string) *Wrapper {
+		return NewWrapper(NewTCPHealthcheck(zap.NewExample(), &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:   "foo",
+				Source: source,
+			},
+		}))
+	}
+	configured := newWrapper(SourceConfig)
+	discovered := newWrapper(SourceHTTPDiscovery + "-foo")
+	labels := component.metricsLabels(configured, &Result{Labels: map[string]string{"env": "prod", "pod": "a"}})
+	if labels["env"] != "prod" || labels["pod"] != "a" {
+		t.Fatalf("Invalid labels %v", labels)
+	}
+	labels = component.metricsLabels(discovered, &Result{Labels: map[string]string{"env": "dev", "pod": "b"}})
+	if labels["env"] != "dev" || labels["pod"] != "" {
+		t.Fatalf("Invalid labels %v", labels)
+	}
+	labels = component.metricsLabels(configured, &Result{Labels: map[string]string{"env": "staging", "pod": "a"}})
+	if labels["env"] != OverflowLabelValue || labels["pod"] != "a" {
+		t.Fatalf("Invalid labels %v", labels)
+	}
+	labels = component.metricsLabels(configured, &Result{Labels: map[string]string{"env": "prod"}})
+	if labels["env"] != "prod" || labels["pod"] != "" {
+		t.Fatalf("Invalid labels %v", labels)
+	}
+}
+
+func TestLabelGuardRelease(t *testing.T) {
+	guard := newLabelGuard()
+	if !guard.allow("pod", "a", "foo", 2) || !guard.allow("pod", "b", "bar", 2) {
+		t.Fatalf("The values should be allowed")
+	}
+	if !guard.allow("pod", "a", "baz", 2) {
+		t.Fatalf("The tracked value should be allowed")
+	}
+	if guard.allow("pod", "c", "qux", 2) {
+		t.Fatalf("The value should not be allowed")
+	}
+	guard.release("foo")
+	// the value a is still used by baz
+	if guard.allow("pod", "c", "qux", 2) {
+		t.Fatalf("The value should not be allowed")
+	}
+	guard.release("bar")
+	if !guard.allow("pod", "c", "qux", 2) {
+		t.Fatalf("The value should be allowed once released")
+	}
+}
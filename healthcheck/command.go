@@ -5,7 +5,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -18,8 +23,31 @@ type CommandHealthcheckConfiguration struct {
 	Command   string   `json:"command"`
 	Arguments []string `json:"arguments"`
 	Timeout   Duration `json:"timeout"`
+	// Shell executes the command through a shell. The arguments are
+	// available as positional parameters.
+	Shell     bool   `json:"shell,omitempty" yaml:"shell,omitempty"`
+	ShellPath string `json:"shell-path,omitempty" yaml:"shell-path,omitempty"`
+	// ValidExitCodes the exit codes considered successful (0 if not set)
+	ValidExitCodes []int `json:"valid-exit-codes,omitempty" yaml:"valid-exit-codes,omitempty"`
+	// StdoutRegexp the standard output of the command should match all
+	// these regular expressions
+	StdoutRegexp []Regexp `json:"stdout-regexp,omitempty" yaml:"stdout-regexp,omitempty"`
+	// Environment the environment variables added to the command
+	// environment
+	Environment map[string]string `json:"environment,omitempty" yaml:"environment,omitempty"`
 }
 
+// DefaultShellPath the default shell used to execute commands
+const DefaultShellPath = "/bin/sh"
+
+// commandStdoutExcerptSize the maximum size of the command standard output
+// added to the annotations
+const commandStdoutExcerptSize = 200
+
+// commandWaitDelay the time given to the command outputs to be closed after
+// the command is killed. The children of a killed shell can keep them open.
+const commandWaitDelay = 100 * time.Millisecond
+
 // CommandHealthcheck defines an HTTP healthcheck
 type CommandHealthcheck struct {
 	Logger *zap.Logger
@@ -34,13 +62,26 @@ func (config *CommandHealthcheckConfiguration) Validate() error {
 	if config.Base.Name == "" {
 		return errors.New("The healthcheck name is missing")
 	}
+	if err := config.Base.validate(); err != nil {
+		return err
+	}
 	if config.Command == "" {
 		return errors.New("The healthcheck command is missing")
 	}
 	if config.Timeout == 0 {
 		return errors.New("The healthcheck timeout is missing")
 	}
-	if !config.Base.OneOff {
+	for _, code := range config.ValidExitCodes {
+		if code < 0 || code > 255 {
+			return fmt.Errorf("Invalid exit code %d", code)
+		}
+	}
+	for k := range config.Environment {
+		if k == "" || strings.Contains(k, "=") {
+			return fmt.Errorf("Invalid environment variable name '%s'", k)
+		}
+	}
+	if !config.Base.OneOff && config.Base.Cron == "" {
 		if config.Base.Interval < Duration(2*time.Second) {
 			return errors.New("The healthcheck interval should be greater than 2 second")
 		}
@@ -71,6 +112,10 @@ func (h *CommandHealthcheck) SetSource(source string) {
 	h.Config.Base.Source = source
 }
 
+// SetResolver set the healthcheck resolver. Commands do not use it.
+func (h *CommandHealthcheck) SetResolver(resolver *Resolver) {
+}
+
 // Summary returns an healthcheck summary
 func (h *CommandHealthcheck) Summary() string {
 	summary := ""
@@ -106,13 +151,98 @@ func (h *CommandHealthcheck) LogInfo(message string) {
 		zap.String("name", h.Config.Base.Name))
 }
 
+// command builds the command to execute, depending of the healthcheck
+// configuration
+func (h *CommandHealthcheck) command(ctx context.Context) *exec.Cmd {
+	if !h.Config.Shell {
+		return exec.CommandContext(ctx, h.Config.Command, h.Config.Arguments...)
+	}
+	shell := h.Config.ShellPath
+	if shell == "" {
+		shell = DefaultShellPath
+	}
+	arguments := []string{"-c", h.Config.Command, shell}
+	arguments = append(arguments, h.Config.Arguments...)
+	return exec.CommandContext(ctx, shell, arguments...)
+}
+
+// environment returns the environment of the command, or nil to use the
+// current process environment
+func (h *CommandHealthcheck) environment() []string {
+	if len(h.Config.Environment) == 0 {
+		return nil
+	}
+	env := os.Environ()
+	keys := make([]string, 0, len(h.Config.Environment))
+	for k := range h.Config.Environment {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, fmt.Sprintf("%s=%s", k, h.Config.Environment[k]))
+	}
+	return env
+}
+
+// validExitCode returns true if the exit code is one of the valid exit codes
+func (h *CommandHealthcheck) validExitCode(code int) bool {
+	if len(h.Config.ValidExitCodes) == 0 {
+		return code == 0
+	}
+	for _, valid := range h.Config.ValidExitCodes {
+		if code == valid {
+			return true
+		}
+	}
+	return false
+}
+
 // Execute executes an healthcheck on the given domain
-func (h *CommandHealthcheck) Execute() error {
+func (h *CommandHealthcheck) Execute(ctx context.Context) (Annotations, error) {
 	h.LogDebug("start executing healthcheck")
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(h.Config.Timeout)*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
 	defer cancel()
+	annotations := Annotations{}
+	var stdOut bytes.Buffer
+	var stdErr bytes.Buffer
+	cmd := h.command(ctx)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = commandWaitDelay
+	cmd.Env = h.environment()
+	cmd.Stdout = &stdOut
+	cmd.Stderr = &stdErr
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		exitErr, isExitError := err.(*exec.ExitError)
+		if !isExitError || ctx.Err() != nil {
+			return annotations, errors.Wrapf(err, "The command failed, stderr=%s", stdErr.String())
+		}
+		exitCode = exitErr.ExitCode()
+	}
+	annotations["exit-code"] = strconv.Itoa(exitCode)
+	if stdOut.Len() != 0 {
+		annotations["stdout"] = truncateBody(stdOut.Bytes(), commandStdoutExcerptSize)
+	}
+	if !h.validExitCode(exitCode) {
+		return annotations, fmt.Errorf("The command failed with code=%d, stderr=%s", exitCode, stdErr.String())
+	}
+	for _, regex := range h.Config.StdoutRegexp {
+		r := regexp.Regexp(regex)
+		if !r.Match(stdOut.Bytes()) {
+			return annotations, fmt.Errorf("The command output does not match the regexp %s", r.String())
+		}
+	}
+	return annotations, nil
+}
+
+// runCommand runs a command. The returned error contains the exit code and
+// the standard error output of the command if it fails. Like for the
+// command healthchecks, the process group of the command is killed when
+// its context is done.
+func runCommand(cmd *exec.Cmd) error {
+	setProcessGroup(cmd)
+	cmd.WaitDelay = commandWaitDelay
 	var stdErr bytes.Buffer
-	cmd := exec.CommandContext(ctx, h.Config.Command, h.Config.Arguments...)
 	cmd.Stderr = &stdErr
 	if err := cmd.Run(); err != nil {
 		var errorMsg string
@@ -124,7 +254,6 @@ func (h *CommandHealthcheck) Execute() error {
 		}
 		return errors.Wrapf(err, errorMsg)
 	}
-
 	return nil
 }
 
@@ -150,6 +279,25 @@ func (in *CommandHealthcheckConfiguration) DeepCopyInto(out *CommandHealthcheckC
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ValidExitCodes != nil {
+		in, out := &in.ValidExitCodes, &out.ValidExitCodes
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.StdoutRegexp != nil {
+		in, out := &in.StdoutRegexp, &out.StdoutRegexp
+		*out = make([]Regexp, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Environment != nil {
+		in, out := &in.Environment, &out.Environment
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommandHealthcheckConfiguration.
@@ -0,0 +1,82 @@
+//go:build linux
+
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// processRunning returns true if the process exists and is not a zombie
+func processRunning(pid string) bool {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%s/stat", pid))
+	if err != nil {
+		return false
+	}
+	// the state follows the command name, which is between parentheses
+	fields := strings.Fields(string(stat[strings.LastIndex(string(stat), ")")+1:]))
+	return len(fields) != 0 && fields[0] != "Z"
+}
+
+func TestCommandExecuteTimeoutKillChildren(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	h := CommandHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &CommandHealthcheckConfiguration{
+			Command: fmt.Sprintf("sleep 10 & echo $! > %s; wait", pidFile),
+			Shell:   true,
+			Timeout: Duration(time.Millisecond * 200),
+		},
+	}
+	_, err := h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("healthcheck was expected to fail")
+	}
+	content, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("Fail to read the pid file\n%v", err)
+	}
+	pid := strings.TrimSpace(string(content))
+	for i := 0; i < 20; i++ {
+		if !processRunning(pid) {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("The child process %s of the shell is still running", pid)
+}
+
+func TestRunCommandTimeoutKillChildren(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", fmt.Sprintf("sleep 10 & echo $! > %s; wait", pidFile))
+	start := time.Now()
+	err := runCommand(cmd)
+	if err == nil {
+		t.Fatalf("The command was expected to fail")
+	}
+	if duration := time.Since(start); duration > 5*time.Second {
+		t.Fatalf("The command was not stopped on timeout: %s", duration)
+	}
+	content, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("Fail to read the pid file\n%v", err)
+	}
+	pid := strings.TrimSpace(string(content))
+	for i := 0; i < 20; i++ {
+		if !processRunning(pid) {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("The child process %s of the shell is still running", pid)
+}
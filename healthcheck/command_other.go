@@ -0,0 +1,12 @@
+//go:build !unix
+
+package healthcheck
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup does nothing, process groups are only supported on Unix
+// systems. Only the command is killed when the command context is done.
+func setProcessGroup(cmd *exec.Cmd) {
+}
@@ -1,6 +1,8 @@
 package healthcheck
 
 import (
+	"context"
+	"regexp"
 	"testing"
 	"time"
 
@@ -15,7 +17,7 @@ func TestCommandExecuteSuccess(t *testing.T) {
 			Timeout: Duration(time.Second * 2),
 		},
 	}
-	err := h.Execute()
+	_, err := h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -30,8 +32,133 @@ func TestCommandExecuteFailure(t *testing.T) {
 			Timeout:   Duration(time.Second * 2),
 		},
 	}
-	err := h.Execute()
+	_, err := h.Execute(context.Background())
 	if err == nil {
 		t.Fatalf("healthcheck was expected to fail")
 	}
 }
+
+func TestCommandExecuteShell(t *testing.T) {
+	h := CommandHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &CommandHealthcheckConfiguration{
+			Command:   "echo \"$1\" | grep -q foo && test -n \"$HOME\"",
+			Arguments: []string{"foo"},
+			Timeout:   Duration(time.Second * 2),
+			Shell:     true,
+		},
+	}
+	_, err := h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	h.Config.Arguments = []string{"bar"}
+	_, err = h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("healthcheck was expected to fail")
+	}
+	h.Config.Shell = false
+	h.Config.Arguments = nil
+	_, err = h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("healthcheck was expected to fail without a shell")
+	}
+}
+
+func TestCommandExecuteExitCodesAndStdout(t *testing.T) {
+	cases := []struct {
+		command        string
+		validExitCodes []int
+		stdoutRegexp   []string
+		environment    map[string]string
+		exitCode       string
+		success        bool
+	}{
+		{command: "echo status: ok", stdoutRegexp: []string{"^status: ok"}, exitCode: "0", success: true},
+		{command: "echo status: ko", stdoutRegexp: []string{"^status: ok"}, exitCode: "0", success: false},
+		{command: "echo warning; exit 1", validExitCodes: []int{0, 1}, exitCode: "1", success: true},
+		{command: "exit 2", validExitCodes: []int{0, 1}, exitCode: "2", success: false},
+		{command: "exit 0", validExitCodes: []int{1}, exitCode: "0", success: false},
+		{command: "echo $CABOUROTTE_ENV", stdoutRegexp: []string{"prod"}, environment: map[string]string{"CABOUROTTE_ENV": "prod"}, exitCode: "0", success: true},
+	}
+	for _, c := range cases {
+		regexps := []Regexp{}
+		for _, s := range c.stdoutRegexp {
+			regexps = append(regexps, Regexp(*regexp.MustCompile(s)))
+		}
+		h := CommandHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &CommandHealthcheckConfiguration{
+				Command:        c.command,
+				Shell:          true,
+				ValidExitCodes: c.validExitCodes,
+				StdoutRegexp:   regexps,
+				Environment:    c.environment,
+				Timeout:        Duration(time.Second * 2),
+			},
+		}
+		annotations, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %s:\n%v", c.command, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("healthcheck was expected to fail for %s", c.command)
+		}
+		if annotations["exit-code"] != c.exitCode {
+			t.Fatalf("Invalid exit code annotation for %s: %v", c.command, annotations)
+		}
+	}
+}
+
+func TestCommandValidate(t *testing.T) {
+	cases := []struct {
+		config CommandHealthcheckConfiguration
+		valid  bool
+	}{
+		{config: CommandHealthcheckConfiguration{ValidExitCodes: []int{0, 1}}, valid: true},
+		{config: CommandHealthcheckConfiguration{ValidExitCodes: []int{256}}, valid: false},
+		{config: CommandHealthcheckConfiguration{Environment: map[string]string{"FOO": "bar"}}, valid: true},
+		{config: CommandHealthcheckConfiguration{Environment: map[string]string{"FOO=": "bar"}}, valid: false},
+	}
+	for _, c := range cases {
+		c.config.Base = Base{Name: "foo", OneOff: true}
+		c.config.Command = "ls"
+		c.config.Timeout = Duration(time.Second)
+		err := c.config.Validate()
+		if c.valid && err != nil {
+			t.Fatalf("Invalid configuration %v:\n%v", c.config, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Was expecting an error for %v", c.config)
+		}
+	}
+}
+
+func TestCommandExecuteTimeout(t *testing.T) {
+	configs := []*CommandHealthcheckConfiguration{
+		{
+			Command:   "sleep",
+			Arguments: []string{"10"},
+			Timeout:   Duration(time.Millisecond * 200),
+		},
+		{
+			Command: "sleep 10; echo hi",
+			Shell:   true,
+			Timeout: Duration(time.Millisecond * 200),
+		},
+	}
+	for _, config := range configs {
+		h := CommandHealthcheck{
+			Logger: zap.NewExample(),
+			Config: config,
+		}
+		start := time.Now()
+		_, err := h.Execute(context.Background())
+		if err == nil {
+			t.Fatalf("healthcheck was expected to fail for %s", config.Command)
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Fatalf("The command %s was not killed after the timeout", config.Command)
+		}
+	}
+}
@@ -1,5 +1,13 @@
 package healthcheck
 
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
 const (
 	// SourceConfig the check is managed by the configuration file
 	SourceConfig string = ""
@@ -7,16 +15,130 @@ const (
 	SourceAPI string = "api"
 	// SourceHTTPDiscovery the check was created from the http discovery mechanism
 	SourceHTTPDiscovery string = "http-discovery"
+	// SourceFileDiscovery the check was created from the file discovery mechanism
+	SourceFileDiscovery string = "file"
+	// SourceDNSSRVDiscovery the check was created from the DNS SRV discovery
+	// mechanism
+	SourceDNSSRVDiscovery string = "dnssrv"
+	// SourceSelf the check is the self healthcheck
+	SourceSelf string = "self"
+)
+
+const (
+	// TypeDNS the type of DNS healthchecks
+	TypeDNS string = "dns"
+	// TypeTCP the type of TCP healthchecks
+	TypeTCP string = "tcp"
+	// TypeTLS the type of TLS healthchecks
+	TypeTLS string = "tls"
+	// TypeHTTP the type of HTTP healthchecks
+	TypeHTTP string = "http"
+	// TypeCommand the type of Command healthchecks
+	TypeCommand string = "command"
+	// TypeGRPC the type of gRPC healthchecks
+	TypeGRPC string = "grpc"
+	// TypePostgres the type of PostgreSQL healthchecks
+	TypePostgres string = "postgres"
+	// TypeUDP the type of UDP healthchecks
+	TypeUDP string = "udp"
+	// TypeSelf the type of the self healthcheck
+	TypeSelf string = "self"
 )
 
+// checkType returns the type of an healthcheck
+func checkType(healthcheck Healthcheck) string {
+	switch healthcheck.(type) {
+	case *DNSHealthcheck:
+		return TypeDNS
+	case *TCPHealthcheck:
+		return TypeTCP
+	case *TLSHealthcheck:
+		return TypeTLS
+	case *HTTPHealthcheck:
+		return TypeHTTP
+	case *CommandHealthcheck:
+		return TypeCommand
+	case *GRPCHealthcheck:
+		return TypeGRPC
+	case *PostgresHealthcheck:
+		return TypePostgres
+	case *UDPHealthcheck:
+		return TypeUDP
+	case *SelfHealthcheck:
+		return TypeSelf
+	}
+	return ""
+}
+
 // Base shared fields between healthchecks
 type Base struct {
-	Name        string            `json:"name"`
-	Description string            `json:"description"`
-	Interval    Duration          `json:"interval"`
-	OneOff      bool              `json:"one-off"`
-	Source      string            `json:"source"`
-	Labels      map[string]string `json:"labels,omitempty"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Interval    Duration `json:"interval"`
+	// Cron the cron expression used to schedule the healthcheck, as an
+	// alternative to Interval
+	Cron   string `json:"cron,omitempty" yaml:"cron,omitempty"`
+	OneOff bool   `json:"one-off"`
+	// Enabled the healthcheck is executed only if enabled (true if not set)
+	Enabled *bool             `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Source  string            `json:"source"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	// Exporters the exporters receiving the healthcheck results (all
+	// exporters if empty)
+	Exporters []string `json:"exporters,omitempty" yaml:"exporters,omitempty"`
+	// Weight the weight of the healthcheck in the weighted aggregate status
+	// (1 if not set)
+	Weight int `json:"weight,omitempty" yaml:"weight,omitempty"`
+	// FailureThreshold the number of consecutive failures before the
+	// healthcheck is reported as failed (1 if not set)
+	FailureThreshold uint `json:"failure-threshold,omitempty" yaml:"failure-threshold,omitempty"`
+	// SuccessThreshold the number of consecutive successes before a failed
+	// healthcheck is reported as successful again (1 if not set)
+	SuccessThreshold uint `json:"success-threshold,omitempty" yaml:"success-threshold,omitempty"`
+	// PromoteAnnotations the annotations copied into the labels of the
+	// healthcheck results
+	PromoteAnnotations []string `json:"promote-annotations,omitempty" yaml:"promote-annotations,omitempty"`
+	// TargetLabels adds the healthcheck target host, port and scheme to the
+	// results labels
+	TargetLabels bool `json:"target-labels,omitempty" yaml:"target-labels,omitempty"`
+	// MaintenanceWindows the results of the healthcheck are flagged as in
+	// maintenance during these windows
+	MaintenanceWindows []MaintenanceWindow `json:"maintenance-windows,omitempty" yaml:"maintenance-windows,omitempty"`
+	// ActiveWindow the healthcheck is only executed during this window
+	ActiveWindow *ActiveWindow `json:"active-window,omitempty" yaml:"active-window,omitempty"`
+	// StartupJitter overrides the maximum random delay before the first
+	// execution of the healthcheck
+	StartupJitter Duration `json:"startup-jitter,omitempty" yaml:"startup-jitter,omitempty"`
+}
+
+// IsEnabled returns true if the healthcheck is enabled
+func (b *Base) IsEnabled() bool {
+	return b.Enabled == nil || *b.Enabled
+}
+
+// validate validates the base configuration fields shared between
+// healthchecks
+func (b *Base) validate() error {
+	if b.Cron != "" {
+		if b.Interval != 0 {
+			return errors.New("The healthcheck interval and cron expression can't be both set")
+		}
+		if _, err := parseCron(b.Cron); err != nil {
+			return err
+		}
+	}
+	for i := range b.MaintenanceWindows {
+		if err := b.MaintenanceWindows[i].Validate(); err != nil {
+			return errors.Wrap(err, "Invalid maintenance window")
+		}
+	}
+	if err := b.ActiveWindow.Validate(); err != nil {
+		return errors.Wrap(err, "Invalid active window")
+	}
+	if b.StartupJitter < 0 {
+		return errors.New("The healthcheck startup jitter should be positive")
+	}
+	return nil
 }
 
 // SourceChecksNames returns all checks managed by the given source
@@ -33,9 +155,62 @@ func (c *Component) SourceChecksNames(source string) map[string]bool {
 	return checks
 }
 
+// SourceStats contains information about the healthchecks managed by a source
+type SourceStats struct {
+	Source     string    `json:"source"`
+	Checks     int       `json:"checks"`
+	LastChange time.Time `json:"last-change"`
+}
+
+// sourceName returns the name of a source as exposed in results and metrics
+func sourceName(source string) string {
+	if source == SourceConfig {
+		return "configuration"
+	}
+	return source
+}
+
+// sourceChanged updates the statistics for a source after an healthcheck
+// was added to or removed from it.
+// The function is *not* thread-safe.
+func (c *Component) sourceChanged(source string) {
+	count := 0
+	for i := range c.Healthchecks {
+		if c.Healthchecks[i].healthcheck.Base().Source == source {
+			count++
+		}
+	}
+	name := sourceName(source)
+	c.sources[name] = &SourceStats{
+		Source:     name,
+		Checks:     count,
+		LastChange: time.Now(),
+	}
+	c.sourceGauge.With(prom.Labels{"source": name}).Set(float64(count))
+}
+
+// Sources returns the statistics for all sources, sorted by name
+func (c *Component) Sources() []SourceStats {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	result := make([]SourceStats, 0, len(c.sources))
+	for _, stats := range c.sources {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Source < result[j].Source
+	})
+	return result
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Base) DeepCopyInto(out *Base) {
 	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
 	if in.Labels != nil {
 		in, out := &in.Labels, &out.Labels
 		*out = make(map[string]string, len(*in))
@@ -43,6 +218,28 @@ func (in *Base) DeepCopyInto(out *Base) {
 			(*out)[key] = val
 		}
 	}
+	if in.Exporters != nil {
+		in, out := &in.Exporters, &out.Exporters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PromoteAnnotations != nil {
+		in, out := &in.PromoteAnnotations, &out.PromoteAnnotations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ActiveWindow != nil {
+		in, out := &in.ActiveWindow, &out.ActiveWindow
+		*out = new(ActiveWindow)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Base.
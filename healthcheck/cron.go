@@ -0,0 +1,46 @@
+package healthcheck
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser parses standard cron expressions (minute, hour, day of month,
+// month, day of week) and descriptors like @daily
+var cronParser = cron.NewParser(
+	cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// parseCron parses a cron expression. Expressions never matching (like the
+// 30th of February) are rejected.
+func parseCron(expression string) (cron.Schedule, error) {
+	schedule, err := cronParser.Parse(expression)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid cron expression %s", expression)
+	}
+	// Next returns the zero time if nothing matches in the next 5 years
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, errors.Errorf("Invalid cron expression %s: it never matches", expression)
+	}
+	return schedule, nil
+}
+
+// NextRun returns the next execution time after t of an healthcheck
+// scheduled using a cron expression, or nil if the healthcheck is not
+// scheduled using a cron expression.
+func (b *Base) NextRun(t time.Time) *time.Time {
+	if b.Cron == "" {
+		return nil
+	}
+	schedule, err := parseCron(b.Cron)
+	if err != nil {
+		return nil
+	}
+	next := schedule.Next(t)
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
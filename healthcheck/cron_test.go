@@ -0,0 +1,88 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/prometheus"
+)
+
+func TestCronValidate(t *testing.T) {
+	config := TCPHealthcheckConfiguration{
+		Base: Base{
+			Name: "foo",
+			Cron: "0 2 * * *",
+		},
+		Target:  "127.0.0.1",
+		Port:    9000,
+		Timeout: Duration(time.Second * 2),
+	}
+	err := config.Validate()
+	if err != nil {
+		t.Fatalf("Invalid configuration\n%v", err)
+	}
+	config.Base.Interval = Duration(time.Second * 10)
+	err = config.Validate()
+	if err == nil {
+		t.Fatalf("Was expecting an error because the interval and the cron expression are both set")
+	}
+	config.Base.Interval = 0
+	config.Base.Cron = "invalid"
+	err = config.Validate()
+	if err == nil {
+		t.Fatalf("Was expecting an error because the cron expression is invalid")
+	}
+	config.Base.Cron = "0 0 30 2 *"
+	err = config.Validate()
+	if err == nil {
+		t.Fatalf("Was expecting an error because the cron expression never matches")
+	}
+}
+
+func TestBaseNextRun(t *testing.T) {
+	base := Base{}
+	now := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+	if base.NextRun(now) != nil {
+		t.Fatalf("No next run was expected without a cron expression")
+	}
+	base.Cron = "0 2 * * *"
+	next := base.NextRun(now)
+	expected := time.Date(2024, 1, 2, 2, 0, 0, 0, time.UTC)
+	if next == nil || !next.Equal(expected) {
+		t.Fatalf("Invalid next run %v", next)
+	}
+}
+
+func TestCronHealthcheck(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	check := NewTCPHealthcheck(zap.NewExample(), &TCPHealthcheckConfiguration{
+		Base: Base{
+			Name: "foo",
+			Cron: "@daily",
+		},
+		Target:  "127.0.0.1",
+		Port:    9000,
+		Timeout: Duration(time.Second * 2),
+	})
+	err = component.AddCheck(check)
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	err = component.SetInterval("foo", Duration(time.Second*10))
+	if err == nil {
+		t.Fatalf("Was expecting an error because the healthcheck is scheduled using a cron expression")
+	}
+	err = component.RemoveCheck("foo")
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+}
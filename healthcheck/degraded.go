@@ -0,0 +1,67 @@
+package healthcheck
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// StatusSuccess the status of successful results
+	StatusSuccess = "success"
+	// StatusDegraded the status of successful but degraded results
+	StatusDegraded = "degraded"
+	// StatusFailure the status of failed results
+	StatusFailure = "failure"
+)
+
+// DegradedError is returned by the healthchecks when the target works but
+// is degraded, for example when it is slow. The result of the healthcheck
+// is successful but flagged as degraded.
+type DegradedError struct {
+	Message string
+}
+
+func (e *DegradedError) Error() string {
+	return e.Message
+}
+
+// checkWarnResponseTime returns a DegradedError if the response time is
+// greater than the warning threshold
+func checkWarnResponseTime(responseTime time.Duration, warn Duration, annotations Annotations) error {
+	if warn == 0 {
+		return nil
+	}
+	annotations["response-time"] = responseTime.String()
+	annotations["warn-response-time"] = time.Duration(warn).String()
+	if responseTime > time.Duration(warn) {
+		return &DegradedError{
+			Message: fmt.Sprintf("Response time %s is greater than the warning response time %s", responseTime, time.Duration(warn)),
+		}
+	}
+	return nil
+}
+
+// validateWarnResponseTime validates the warning response time against the
+// maximum response time
+func validateWarnResponseTime(warn Duration, max Duration) error {
+	if warn < 0 {
+		return errors.New("The warning response time should be positive")
+	}
+	if warn != 0 && max != 0 && warn >= max {
+		return errors.New("The warning response time should be lower than the maximum response time")
+	}
+	return nil
+}
+
+// Status returns the status of the result (success, degraded or failure)
+func (r *Result) Status() string {
+	if !r.Success {
+		return StatusFailure
+	}
+	if r.Degraded {
+		return StatusDegraded
+	}
+	return StatusSuccess
+}
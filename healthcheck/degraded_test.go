@@ -0,0 +1,95 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+func TestNewResultDegraded(t *testing.T) {
+	check := NewTCPHealthcheck(nil, &TCPHealthcheckConfiguration{})
+	cases := []struct {
+		err    error
+		status string
+	}{
+		{err: nil, status: StatusSuccess},
+		{err: errors.New("error"), status: StatusFailure},
+		{err: &DegradedError{Message: "slow"}, status: StatusDegraded},
+		{err: errors.Wrap(&DegradedError{Message: "slow"}, "wrapped"), status: StatusDegraded},
+	}
+	for _, c := range cases {
+		result := NewResult(check, 0, nil, c.err)
+		if result.Status() != c.status {
+			t.Fatalf("Invalid status\nexpected: %s\nactual: %s", c.status, result.Status())
+		}
+	}
+	result := NewResult(check, 0, nil, &DegradedError{Message: "slow"})
+	if !result.Success || !result.Degraded || result.Message != "slow" {
+		t.Fatalf("Invalid result %+v", result)
+	}
+}
+
+func TestTCPExecuteWarnResponseTime(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := TCPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &TCPHealthcheckConfiguration{
+			Port:             uint(port),
+			Target:           "127.0.0.1",
+			Timeout:          Duration(time.Second * 2),
+			WarnResponseTime: Duration(time.Nanosecond),
+		},
+	}
+	h.buildURL()
+	annotations, err := h.Execute(context.Background())
+	var degradedErr *DegradedError
+	if !errors.As(err, &degradedErr) {
+		t.Fatalf("Was expecting a degraded error, got %v", err)
+	}
+	if annotations["warn-response-time"] != "1ns" || annotations["response-time"] == "" {
+		t.Fatalf("Invalid annotations %v", annotations)
+	}
+	h.Config.WarnResponseTime = Duration(time.Second * 2)
+	_, err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+}
+
+func TestValidateWarnResponseTime(t *testing.T) {
+	cases := []struct {
+		warn  Duration
+		max   Duration
+		valid bool
+	}{
+		{warn: 0, max: 0, valid: true},
+		{warn: Duration(time.Second), max: 0, valid: true},
+		{warn: Duration(time.Second), max: Duration(2 * time.Second), valid: true},
+		{warn: Duration(2 * time.Second), max: Duration(time.Second), valid: false},
+		{warn: Duration(-time.Second), max: 0, valid: false},
+	}
+	for _, c := range cases {
+		err := validateWarnResponseTime(c.warn, c.max)
+		if c.valid && err != nil {
+			t.Fatalf("Unexpected error for %+v\n%v", c, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Was expecting an error for %+v", c)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
@@ -18,14 +20,61 @@ type DNSHealthcheckConfiguration struct {
 	Base        `json:",inline" yaml:",inline"`
 	Timeout     Duration `json:"timeout"`
 	ExpectedIPs []IP     `json:"expected-ips,omitempty" yaml:"expected-ips,omitempty"`
-	Domain      string   `json:"domain"`
+	// Domain the domain to lookup. For PTR records, the IP address to
+	// reverse lookup.
+	Domain string `json:"domain"`
+	// RecordType the type of the DNS record to lookup (A and AAAA if empty)
+	RecordType     string   `json:"record-type,omitempty" yaml:"record-type,omitempty"`
+	ExpectedValues []string `json:"expected-values,omitempty" yaml:"expected-values,omitempty"`
+	// ExpectedHostname the hostname which should be returned by the reverse
+	// lookup of PTR records
+	ExpectedHostname string `json:"expected-hostname,omitempty" yaml:"expected-hostname,omitempty"`
+	// ExpectedHostnameRegexp a regular expression which should match one of
+	// the hostnames returned by the reverse lookup of PTR records
+	ExpectedHostnameRegexp *Regexp `json:"expected-hostname-regexp,omitempty" yaml:"expected-hostname-regexp,omitempty"`
+	// Server the DNS server (host:port) to query instead of the default
+	// resolver
+	Server string `json:"server,omitempty" yaml:"server,omitempty"`
+	// Protocol the protocol used to query the server (udp or tcp)
+	Protocol string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	// DoH resolves the domain using the DNS-over-HTTPS endpoint DoHURL
+	// instead of the DNS resolver
+	DoH    bool   `json:"doh,omitempty" yaml:"doh,omitempty"`
+	DoHURL string `json:"doh-url,omitempty" yaml:"doh-url,omitempty"`
+	// ShouldFail the healthcheck is successful only if the domain does not
+	// exist (NXDOMAIN)
+	ShouldFail bool `json:"should-fail,omitempty" yaml:"should-fail,omitempty"`
 }
 
+const (
+	// RecordTypeA DNS A records
+	RecordTypeA string = "A"
+	// RecordTypeAAAA DNS AAAA records
+	RecordTypeAAAA string = "AAAA"
+	// RecordTypeCNAME DNS CNAME records
+	RecordTypeCNAME string = "CNAME"
+	// RecordTypeMX DNS MX records
+	RecordTypeMX string = "MX"
+	// RecordTypeTXT DNS TXT records
+	RecordTypeTXT string = "TXT"
+	// RecordTypeNS DNS NS records
+	RecordTypeNS string = "NS"
+	// RecordTypeSRV DNS SRV records
+	RecordTypeSRV string = "SRV"
+	// RecordTypePTR DNS PTR records (reverse lookup)
+	RecordTypePTR string = "PTR"
+)
+
 // DNSHealthcheck defines an HTTP healthcheck
 type DNSHealthcheck struct {
-	Logger *zap.Logger
-	Config *DNSHealthcheckConfiguration
-	URL    string
+	Logger   *zap.Logger
+	Resolver *Resolver
+	Config   *DNSHealthcheckConfiguration
+	URL      string
+	// DNSResolver the resolver querying the configured server
+	DNSResolver *net.Resolver
+	// DoHClient the HTTP client querying the DNS-over-HTTPS endpoint
+	DoHClient *http.Client
 
 	Tick *time.Ticker
 }
@@ -35,13 +84,58 @@ func (config *DNSHealthcheckConfiguration) Validate() error {
 	if config.Base.Name == "" {
 		return errors.New("The healthcheck name is missing")
 	}
+	if err := config.Base.validate(); err != nil {
+		return err
+	}
 	if config.Domain == "" {
 		return errors.New("The healthcheck domain is missing")
 	}
 	if config.Timeout == 0 {
 		return errors.New("The healthcheck timeout is missing")
 	}
-	if !config.Base.OneOff {
+	if config.Server != "" {
+		_, _, err := net.SplitHostPort(config.Server)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid DNS server %s", config.Server)
+		}
+	}
+	if config.Protocol != "" && config.Protocol != "udp" && config.Protocol != "tcp" {
+		return fmt.Errorf("Invalid DNS protocol %s", config.Protocol)
+	}
+	if config.DoH {
+		if config.Server != "" {
+			return errors.New("The DNS server and DoH can't be both set")
+		}
+		if err := validateDoHURL(config.DoHURL); err != nil {
+			return err
+		}
+	}
+	if config.ShouldFail && (len(config.ExpectedIPs) != 0 || len(config.ExpectedValues) != 0) {
+		return errors.New("Expected IPs and values are not supported when should-fail is set")
+	}
+	switch config.RecordType {
+	case "", RecordTypeA, RecordTypeAAAA:
+	case RecordTypeCNAME, RecordTypeMX, RecordTypeTXT, RecordTypeNS, RecordTypeSRV, RecordTypePTR:
+		if len(config.ExpectedIPs) != 0 {
+			return fmt.Errorf("Expected IPs are not supported for %s records", config.RecordType)
+		}
+	default:
+		return fmt.Errorf("Invalid DNS record type %s", config.RecordType)
+	}
+	if config.RecordType == RecordTypePTR {
+		if net.ParseIP(config.Domain) == nil {
+			return fmt.Errorf("The domain should be an IP address for PTR records, got %s", config.Domain)
+		}
+		if config.DoH {
+			return errors.New("PTR records are not supported using DoH")
+		}
+		if config.ShouldFail && (config.ExpectedHostname != "" || config.ExpectedHostnameRegexp != nil) {
+			return errors.New("The expected hostname is not supported when should-fail is set")
+		}
+	} else if config.ExpectedHostname != "" || config.ExpectedHostnameRegexp != nil {
+		return errors.New("The expected hostname is only supported for PTR records")
+	}
+	if !config.Base.OneOff && config.Base.Cron == "" {
 		if config.Base.Interval < Duration(2*time.Second) {
 			return errors.New("The healthcheck interval should be greater than 2 second")
 		}
@@ -54,9 +148,36 @@ func (config *DNSHealthcheckConfiguration) Validate() error {
 
 // Initialize the healthcheck.
 func (h *DNSHealthcheck) Initialize() error {
+	if h.Config.DoH {
+		h.DoHClient = &http.Client{
+			Timeout: time.Duration(h.Config.Timeout),
+		}
+	}
+	if h.Config.Server != "" {
+		protocol := h.Config.Protocol
+		if protocol == "" {
+			protocol = "udp"
+		}
+		server := h.Config.Server
+		h.DNSResolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				dialer := net.Dialer{}
+				return dialer.DialContext(ctx, protocol, server)
+			},
+		}
+	}
 	return nil
 }
 
+// netResolver returns the resolver used for the DNS lookups
+func (h *DNSHealthcheck) netResolver() *net.Resolver {
+	if h.DNSResolver != nil {
+		return h.DNSResolver
+	}
+	return h.Resolver.netResolver()
+}
+
 // GetConfig get the config
 func (h *DNSHealthcheck) GetConfig() interface{} {
 	return h.Config
@@ -72,6 +193,11 @@ func (h *DNSHealthcheck) SetSource(source string) {
 	h.Config.Base.Source = source
 }
 
+// SetResolver set the healthcheck resolver
+func (h *DNSHealthcheck) SetResolver(resolver *Resolver) {
+	h.Resolver = resolver
+}
+
 // Summary returns an healthcheck summary
 func (h *DNSHealthcheck) Summary() string {
 	summary := ""
@@ -82,6 +208,10 @@ func (h *DNSHealthcheck) Summary() string {
 		summary = fmt.Sprintf("DNS healthcheck on %s", h.Config.Domain)
 	}
 
+	if h.Config.ShouldFail {
+		summary = summary + shouldFailSummary
+	}
+
 	return summary
 }
 
@@ -135,10 +265,47 @@ func verifyIPs(expectedIPs []IP, lookupIPs []net.IP) error {
 	return nil
 }
 
-func (h *DNSHealthcheck) lookupIP() ([]net.IP, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(h.Config.Timeout))
+// verifyValues verifies that all expected values are in the lookup result
+func verifyValues(expectedValues []string, lookupValues []string) error {
+	notFound := []string{}
+	for _, expected := range expectedValues {
+		found := false
+		for _, value := range lookupValues {
+			if normalizeDNSValue(expected) == normalizeDNSValue(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			notFound = append(notFound, expected)
+		}
+	}
+	if len(notFound) != 0 {
+		return fmt.Errorf("The values %s were not found. The DNS result was %s ", strings.Join(notFound, ", "), strings.Join(lookupValues, ", "))
+	}
+	return nil
+}
+
+// normalizeDNSValue normalizes a value for comparison, ignoring the case and
+// the trailing dot of domain names
+func normalizeDNSValue(value string) string {
+	return strings.ToLower(strings.TrimSuffix(value, "."))
+}
+
+func (h *DNSHealthcheck) lookupIP(ctx context.Context) ([]net.IP, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
 	defer cancel()
-	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, h.Config.Domain)
+	network := ""
+	switch h.Config.RecordType {
+	case RecordTypeA:
+		network = "ip4"
+	case RecordTypeAAAA:
+		network = "ip6"
+	}
+	if network != "" {
+		return h.netResolver().LookupIP(ctx, network, h.Config.Domain)
+	}
+	addrs, err := h.netResolver().LookupIPAddr(ctx, h.Config.Domain)
 	if err != nil {
 		return nil, err
 	}
@@ -149,18 +316,187 @@ func (h *DNSHealthcheck) lookupIP() ([]net.IP, error) {
 	return ips, nil
 }
 
+// lookupRecords looks up the DNS records of the configured type, and returns
+// their values
+func (h *DNSHealthcheck) lookupRecords(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
+	defer cancel()
+	resolver := h.netResolver()
+	values := []string{}
+	switch h.Config.RecordType {
+	case RecordTypeCNAME:
+		cname, err := resolver.LookupCNAME(ctx, h.Config.Domain)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, cname)
+	case RecordTypeMX:
+		records, err := resolver.LookupMX(ctx, h.Config.Domain)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			values = append(values, record.Host)
+		}
+	case RecordTypeTXT:
+		records, err := resolver.LookupTXT(ctx, h.Config.Domain)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, records...)
+	case RecordTypeNS:
+		records, err := resolver.LookupNS(ctx, h.Config.Domain)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			values = append(values, record.Host)
+		}
+	case RecordTypeSRV:
+		_, records, err := resolver.LookupSRV(ctx, "", "", h.Config.Domain)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			values = append(values, record.Target)
+		}
+	case RecordTypePTR:
+		names, err := resolver.LookupAddr(ctx, h.Config.Domain)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, names...)
+	}
+	return values, nil
+}
+
+// verifyHostname verifies that the reverse lookup returned the expected
+// hostname. The hostnames and the expected value are added to the
+// annotations on mismatch.
+func (h *DNSHealthcheck) verifyHostname(hostnames []string, annotations Annotations) error {
+	if h.Config.ExpectedHostname != "" {
+		found := false
+		for _, hostname := range hostnames {
+			if normalizeDNSValue(hostname) == normalizeDNSValue(h.Config.ExpectedHostname) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			annotations["reverse-dns"] = strings.Join(hostnames, ", ")
+			annotations["expected-hostname"] = h.Config.ExpectedHostname
+			return fmt.Errorf("The reverse lookup of %s returned %s, expected %s", h.Config.Domain, strings.Join(hostnames, ", "), h.Config.ExpectedHostname)
+		}
+	}
+	if h.Config.ExpectedHostnameRegexp != nil {
+		r := regexp.Regexp(*h.Config.ExpectedHostnameRegexp)
+		found := false
+		for _, hostname := range hostnames {
+			if r.MatchString(hostname) || r.MatchString(strings.TrimSuffix(hostname, ".")) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			annotations["reverse-dns"] = strings.Join(hostnames, ", ")
+			annotations["expected-hostname-regexp"] = r.String()
+			return fmt.Errorf("The reverse lookup of %s returned %s, which does not match the regexp %s", h.Config.Domain, strings.Join(hostnames, ", "), r.String())
+		}
+	}
+	return nil
+}
+
+// reverseDNS performs a reverse lookup of the IP address of addr using the
+// resolver, and adds the IP address and the lookup result to the
+// annotations. The lookup is bounded by the healthcheck timeout.
+func reverseDNS(ctx context.Context, resolver *Resolver, addr net.Addr, annotations Annotations) {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return
+	}
+	annotations["remote-ip"] = host
+	names, err := resolver.LookupAddr(ctx, host)
+	if err != nil {
+		annotations["reverse-dns-error"] = err.Error()
+		return
+	}
+	annotations["reverse-dns"] = strings.Join(names, ", ")
+}
+
 // Execute executes an healthcheck on the given domain
-func (h *DNSHealthcheck) Execute() error {
+func (h *DNSHealthcheck) Execute(ctx context.Context) (Annotations, error) {
 	h.LogDebug("start executing healthcheck")
-	ips, err := h.lookupIP()
-	if err != nil {
-		return errors.Wrapf(err, "Fail to lookup IP for domain")
+	if h.Config.ShouldFail {
+		return nil, h.executeShouldFail(ctx)
+	}
+	switch h.Config.RecordType {
+	case "", RecordTypeA, RecordTypeAAAA:
+		var ips []net.IP
+		var err error
+		if h.Config.DoH {
+			ips, err = h.dohLookupIP(ctx)
+		} else {
+			ips, err = h.lookupIP(ctx)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to lookup IP for domain")
+		}
+		err = verifyIPs(h.Config.ExpectedIPs, ips)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]string, len(ips))
+		for i, ip := range ips {
+			values[i] = ip.String()
+		}
+		return nil, verifyValues(h.Config.ExpectedValues, values)
+	}
+	var values []string
+	var err error
+	if h.Config.DoH {
+		values, err = h.dohLookup(ctx)
+	} else {
+		values, err = h.lookupRecords(ctx)
 	}
-	err = verifyIPs(h.Config.ExpectedIPs, ips)
 	if err != nil {
-		return err
+		return nil, errors.Wrapf(err, "Fail to lookup %s records for domain", h.Config.RecordType)
 	}
-	return nil
+	if h.Config.RecordType == RecordTypePTR {
+		annotations := Annotations{}
+		err = h.verifyHostname(values, annotations)
+		if err != nil {
+			return annotations, err
+		}
+	}
+	return nil, verifyValues(h.Config.ExpectedValues, values)
+}
+
+// executeShouldFail verifies that the domain does not exist. Other lookup
+// errors (timeouts, server failures...) are reported.
+func (h *DNSHealthcheck) executeShouldFail(ctx context.Context) error {
+	var err error
+	switch h.Config.RecordType {
+	case "", RecordTypeA, RecordTypeAAAA:
+		if h.Config.DoH {
+			_, err = h.dohLookupIP(ctx)
+		} else {
+			_, err = h.lookupIP(ctx)
+		}
+	default:
+		if h.Config.DoH {
+			_, err = h.dohLookup(ctx)
+		} else {
+			_, err = h.lookupRecords(ctx)
+		}
+	}
+	if err == nil {
+		return fmt.Errorf("DNS check is successful on %s but NXDOMAIN was expected", h.Config.Domain)
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil
+	}
+	return errors.Wrapf(err, "Fail to lookup domain %s, NXDOMAIN was expected", h.Config.Domain)
 }
 
 // NewDNSHealthcheck creates a DNS healthcheck from a logger and a configuration
@@ -191,6 +527,15 @@ func (h *DNSHealthcheckConfiguration) DeepCopyInto(out *DNSHealthcheckConfigurat
 			}
 		}
 	}
+	if h.ExpectedValues != nil {
+		h, out := &h.ExpectedValues, &out.ExpectedValues
+		*out = make([]string, len(*h))
+		copy(*out, *h)
+	}
+	if h.ExpectedHostnameRegexp != nil {
+		h, out := &h.ExpectedHostnameRegexp, &out.ExpectedHostnameRegexp
+		*out = (*h).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSHealthcheckConfiguration.
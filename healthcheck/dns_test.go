@@ -1,11 +1,18 @@
 package healthcheck
 
 import (
+	"context"
+	"errors"
+	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
 	"testing"
 	"time"
 
 	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
 
 	"github.com/appclacks/cabourotte/prometheus"
 )
@@ -20,7 +27,7 @@ func TestDNSExecuteSuccess(t *testing.T) {
 		},
 	}
 
-	err := h.Execute()
+	_, err := h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -35,7 +42,7 @@ func TestDNSExecuteFailure(t *testing.T) {
 		},
 	}
 
-	err := h.Execute()
+	_, err := h.Execute(context.Background())
 	if err == nil {
 		t.Fatalf("Was expecting an error: the domain does not exist")
 	}
@@ -115,3 +122,443 @@ func TestVerifyIPs(t *testing.T) {
 		t.Fatalf("Was expecting an error")
 	}
 }
+
+// dnsRecord a record served by the test DNS server
+type dnsRecord struct {
+	rtype dnsmessage.Type
+	body  dnsmessage.ResourceBody
+}
+
+// startDNSServer starts a DNS server answering with the given records, and
+// returns its address
+func startDNSServer(t *testing.T, records map[string][]dnsRecord) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the DNS server :\n%v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			packed, err := dnsAnswer(buffer[:n], records)
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(packed, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// dnsAnswer builds the packed response of a packed DNS query using the
+// given records
+func dnsAnswer(query []byte, records map[string][]dnsRecord) ([]byte, error) {
+	var request dnsmessage.Message
+	if err := request.Unpack(query); err != nil {
+		return nil, err
+	}
+	if len(request.Questions) == 0 {
+		return nil, errors.New("no question in the DNS query")
+	}
+	question := request.Questions[0]
+	response := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:            request.Header.ID,
+			Response:      true,
+			Authoritative: true,
+		},
+		Questions: request.Questions,
+	}
+	if _, ok := records[question.Name.String()]; !ok {
+		response.Header.RCode = dnsmessage.RCodeNameError
+	}
+	for _, record := range records[question.Name.String()] {
+		if record.rtype != question.Type && record.rtype != dnsmessage.TypeCNAME {
+			continue
+		}
+		response.Answers = append(response.Answers, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{
+				Name:  question.Name,
+				Type:  record.rtype,
+				Class: dnsmessage.ClassINET,
+				TTL:   60,
+			},
+			Body: record.body,
+		})
+	}
+	return response.Pack()
+}
+
+// startDoHServer starts a DNS-over-HTTPS server answering with the given
+// records, and returns its URL
+func startDoHServer(t *testing.T, records map[string][]dnsRecord) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dohContentType {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		query, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		packed, err := dnsAnswer(query, records)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", dohContentType)
+		_, _ = w.Write(packed)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/dns-query"
+}
+
+func TestDNSExecuteRecordType(t *testing.T) {
+	server := startDNSServer(t, map[string][]dnsRecord{
+		"cabourotte.test.": {
+			{rtype: dnsmessage.TypeA, body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}}},
+			{rtype: dnsmessage.TypeAAAA, body: &dnsmessage.AAAAResource{AAAA: [16]byte{15: 1}}},
+			{rtype: dnsmessage.TypeMX, body: &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mail.cabourotte.test.")}},
+			{rtype: dnsmessage.TypeTXT, body: &dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}}},
+			{rtype: dnsmessage.TypeNS, body: &dnsmessage.NSResource{NS: dnsmessage.MustNewName("ns1.cabourotte.test.")}},
+		},
+		"www.cabourotte.test.": {
+			{rtype: dnsmessage.TypeCNAME, body: &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("cabourotte.test.")}},
+		},
+		"_http._tcp.cabourotte.test.": {
+			{rtype: dnsmessage.TypeSRV, body: &dnsmessage.SRVResource{Priority: 1, Weight: 1, Port: 80, Target: dnsmessage.MustNewName("web.cabourotte.test.")}},
+		},
+	})
+	resolver := NewResolver(&ResolverConfiguration{Server: server})
+	cases := []struct {
+		domain      string
+		recordType  string
+		expectedIPs []IP
+		expected    []string
+		success     bool
+	}{
+		{domain: "cabourotte.test", recordType: RecordTypeA, expectedIPs: []IP{IP(net.ParseIP("10.0.0.1"))}, success: true},
+		{domain: "cabourotte.test", recordType: RecordTypeA, expected: []string{"10.0.0.2"}, success: false},
+		{domain: "cabourotte.test", recordType: RecordTypeAAAA, expected: []string{"::1"}, success: true},
+		{domain: "cabourotte.test", recordType: RecordTypeMX, expected: []string{"mail.cabourotte.test"}, success: true},
+		{domain: "cabourotte.test", recordType: RecordTypeMX, expected: []string{"other.cabourotte.test"}, success: false},
+		{domain: "cabourotte.test", recordType: RecordTypeTXT, expected: []string{"v=spf1 -all"}, success: true},
+		{domain: "cabourotte.test", recordType: RecordTypeTXT, expected: []string{"v=spf1 ~all"}, success: false},
+		{domain: "cabourotte.test", recordType: RecordTypeNS, expected: []string{"NS1.cabourotte.test."}, success: true},
+		{domain: "www.cabourotte.test", recordType: RecordTypeCNAME, expected: []string{"cabourotte.test"}, success: true},
+		{domain: "_http._tcp.cabourotte.test", recordType: RecordTypeSRV, expected: []string{"web.cabourotte.test"}, success: true},
+	}
+	for _, c := range cases {
+		h := DNSHealthcheck{
+			Logger:   zap.NewExample(),
+			Resolver: resolver,
+			Config: &DNSHealthcheckConfiguration{
+				Domain:         c.domain,
+				Timeout:        Duration(time.Second * 2),
+				RecordType:     c.recordType,
+				ExpectedIPs:    c.expectedIPs,
+				ExpectedValues: c.expected,
+			},
+		}
+		_, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %s %s:\n%v", c.recordType, c.domain, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %s %s", c.recordType, c.domain)
+		}
+	}
+}
+
+func TestDNSExecuteShouldFail(t *testing.T) {
+	records := map[string][]dnsRecord{
+		"cabourotte.test.": {
+			{rtype: dnsmessage.TypeA, body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}}},
+			{rtype: dnsmessage.TypeTXT, body: &dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}}},
+		},
+	}
+	resolver := NewResolver(&ResolverConfiguration{Server: startDNSServer(t, records)})
+	dohURL := startDoHServer(t, records)
+	cases := []struct {
+		domain     string
+		recordType string
+		doh        bool
+		success    bool
+	}{
+		{domain: "unknown.test", recordType: RecordTypeA, success: true},
+		{domain: "unknown.test", recordType: RecordTypeTXT, success: true},
+		{domain: "cabourotte.test", recordType: RecordTypeA, success: false},
+		{domain: "cabourotte.test", recordType: RecordTypeTXT, success: false},
+		{domain: "unknown.test", recordType: RecordTypeA, doh: true, success: true},
+		{domain: "cabourotte.test", recordType: RecordTypeA, doh: true, success: false},
+	}
+	for _, c := range cases {
+		h := DNSHealthcheck{
+			Logger:   zap.NewExample(),
+			Resolver: resolver,
+			Config: &DNSHealthcheckConfiguration{
+				Domain:     c.domain,
+				Timeout:    Duration(time.Second * 2),
+				RecordType: c.recordType,
+				ShouldFail: true,
+			},
+		}
+		if c.doh {
+			h.Config.DoH = true
+			h.Config.DoHURL = dohURL
+			h.DoHClient = &http.Client{}
+		}
+		_, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %s %s:\n%v", c.recordType, c.domain, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %s %s", c.recordType, c.domain)
+		}
+	}
+}
+
+func TestDNSExecutePTR(t *testing.T) {
+	server := startDNSServer(t, map[string][]dnsRecord{
+		"1.0.0.10.in-addr.arpa.": {
+			{rtype: dnsmessage.TypePTR, body: &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("mail.cabourotte.test.")}},
+		},
+	})
+	resolver := NewResolver(&ResolverConfiguration{Server: server})
+	mailRegexp := Regexp(*regexp.MustCompile(`^mail\.cabourotte\.test$`))
+	webRegexp := Regexp(*regexp.MustCompile(`^web\.`))
+	cases := []struct {
+		ip         string
+		hostname   string
+		regexp     *Regexp
+		success    bool
+		annotation string
+	}{
+		{ip: "10.0.0.1", hostname: "mail.cabourotte.test", success: true},
+		{ip: "10.0.0.1", hostname: "MAIL.cabourotte.test.", success: true},
+		{ip: "10.0.0.1", regexp: &mailRegexp, success: true},
+		{ip: "10.0.0.1", hostname: "web.cabourotte.test", success: false, annotation: "mail.cabourotte.test."},
+		{ip: "10.0.0.1", regexp: &webRegexp, success: false, annotation: "mail.cabourotte.test."},
+		{ip: "10.0.0.2", hostname: "mail.cabourotte.test", success: false},
+	}
+	for _, c := range cases {
+		h := DNSHealthcheck{
+			Logger:   zap.NewExample(),
+			Resolver: resolver,
+			Config: &DNSHealthcheckConfiguration{
+				Base:                   Base{Name: "foo", OneOff: true},
+				Domain:                 c.ip,
+				Timeout:                Duration(time.Second * 2),
+				RecordType:             RecordTypePTR,
+				ExpectedHostname:       c.hostname,
+				ExpectedHostnameRegexp: c.regexp,
+			},
+		}
+		err := h.Config.Validate()
+		if err != nil {
+			t.Fatalf("Invalid configuration :\n%v", err)
+		}
+		annotations, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %s:\n%v", c.ip, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %s %s", c.ip, c.hostname)
+		}
+		if annotations["reverse-dns"] != c.annotation {
+			t.Fatalf("Invalid reverse-dns annotation for %s: %s", c.ip, annotations["reverse-dns"])
+		}
+	}
+}
+
+func TestDNSValidateRecordType(t *testing.T) {
+	cases := []struct {
+		config DNSHealthcheckConfiguration
+		valid  bool
+	}{
+		{config: DNSHealthcheckConfiguration{RecordType: RecordTypeTXT}, valid: true},
+		{config: DNSHealthcheckConfiguration{RecordType: "PTR"}, valid: false},
+		{config: DNSHealthcheckConfiguration{RecordType: RecordTypeMX, ExpectedIPs: []IP{IP(net.ParseIP("10.0.0.1"))}}, valid: false},
+		{config: DNSHealthcheckConfiguration{RecordType: RecordTypePTR, Domain: "10.0.0.1", ExpectedHostname: "mail.cabourotte.test"}, valid: true},
+		{config: DNSHealthcheckConfiguration{RecordType: RecordTypePTR, Domain: "10.0.0.1", DoH: true, DoHURL: "https://127.0.0.1/dns-query"}, valid: false},
+		{config: DNSHealthcheckConfiguration{RecordType: RecordTypeA, ExpectedHostname: "mail.cabourotte.test"}, valid: false},
+	}
+	for _, c := range cases {
+		c.config.Base = Base{Name: "foo", OneOff: true}
+		if c.config.Domain == "" {
+			c.config.Domain = "cabourotte.test"
+		}
+		c.config.Timeout = Duration(time.Second)
+		err := c.config.Validate()
+		if c.valid && err != nil {
+			t.Fatalf("Invalid configuration %v:\n%v", c.config, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Was expecting an error for %v", c.config)
+		}
+	}
+}
+
+func TestDNSExecuteServer(t *testing.T) {
+	server := startDNSServer(t, map[string][]dnsRecord{
+		"cabourotte.test.": {
+			{rtype: dnsmessage.TypeA, body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}}},
+		},
+	})
+	cases := []struct {
+		protocol string
+		success  bool
+	}{
+		{protocol: "", success: true},
+		{protocol: "udp", success: true},
+		{protocol: "tcp", success: false},
+	}
+	for _, c := range cases {
+		h := NewDNSHealthcheck(zap.NewExample(), &DNSHealthcheckConfiguration{
+			Base:        Base{Name: "foo", OneOff: true},
+			Domain:      "cabourotte.test",
+			Timeout:     Duration(time.Second * 2),
+			ExpectedIPs: []IP{IP(net.ParseIP("10.0.0.1"))},
+			Server:      server,
+			Protocol:    c.protocol,
+		})
+		err := h.Config.Validate()
+		if err != nil {
+			t.Fatalf("Invalid configuration :\n%v", err)
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		_, err = h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for protocol %s:\n%v", c.protocol, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for protocol %s", c.protocol)
+		}
+	}
+	for _, config := range []DNSHealthcheckConfiguration{
+		{Server: "127.0.0.1"},
+		{Server: "127.0.0.1:53", Protocol: "http"},
+	} {
+		config.Base = Base{Name: "foo", OneOff: true}
+		config.Domain = "cabourotte.test"
+		config.Timeout = Duration(time.Second)
+		if err := config.Validate(); err == nil {
+			t.Fatalf("Was expecting an error for %v", config)
+		}
+	}
+}
+
+func TestDNSExecuteTimeout(t *testing.T) {
+	// the server never answers to the queries
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the DNS server :\n%v", err)
+	}
+	defer conn.Close()
+	h := NewDNSHealthcheck(zap.NewExample(), &DNSHealthcheckConfiguration{
+		Base:     Base{Name: "foo", OneOff: true},
+		Domain:   "cabourotte.test",
+		Timeout:  Duration(300 * time.Millisecond),
+		Server:   conn.LocalAddr().String(),
+		Protocol: "udp",
+	})
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	start := time.Now()
+	_, err = h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatalf("The lookup did not time out: %s", time.Since(start))
+	}
+	for _, config := range []DNSHealthcheckConfiguration{
+		{Base: Base{Name: "foo", Interval: Duration(10 * time.Second)}},
+		{Base: Base{Name: "foo", Interval: Duration(10 * time.Second)}, Timeout: Duration(20 * time.Second)},
+	} {
+		config.Domain = "cabourotte.test"
+		if err := config.Validate(); err == nil {
+			t.Fatalf("Was expecting an error for %v", config)
+		}
+	}
+}
+
+func TestDNSExecuteDoH(t *testing.T) {
+	dohURL := startDoHServer(t, map[string][]dnsRecord{
+		"cabourotte.test.": {
+			{rtype: dnsmessage.TypeA, body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}}},
+			{rtype: dnsmessage.TypeAAAA, body: &dnsmessage.AAAAResource{AAAA: [16]byte{15: 1}}},
+			{rtype: dnsmessage.TypeTXT, body: &dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}}},
+		},
+	})
+	cases := []struct {
+		domain      string
+		recordType  string
+		expectedIPs []IP
+		expected    []string
+		success     bool
+	}{
+		{domain: "cabourotte.test", expectedIPs: []IP{IP(net.ParseIP("10.0.0.1")), IP(net.ParseIP("::1"))}, success: true},
+		{domain: "cabourotte.test", recordType: RecordTypeA, expected: []string{"10.0.0.1"}, success: true},
+		{domain: "cabourotte.test", recordType: RecordTypeA, expected: []string{"10.0.0.2"}, success: false},
+		{domain: "cabourotte.test", recordType: RecordTypeTXT, expected: []string{"v=spf1 -all"}, success: true},
+		{domain: "unknown.test", recordType: RecordTypeA, success: false},
+	}
+	for _, c := range cases {
+		h := NewDNSHealthcheck(zap.NewExample(), &DNSHealthcheckConfiguration{
+			Domain:         c.domain,
+			Timeout:        Duration(time.Second * 2),
+			RecordType:     c.recordType,
+			ExpectedIPs:    c.expectedIPs,
+			ExpectedValues: c.expected,
+			DoH:            true,
+			DoHURL:         dohURL,
+		})
+		err := h.Initialize()
+		if err != nil {
+			t.Fatalf("Fail to initialize the healthcheck\n%v", err)
+		}
+		_, err = h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %s %s:\n%v", c.recordType, c.domain, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %s %s", c.recordType, c.domain)
+		}
+	}
+}
+
+func TestDNSValidateDoH(t *testing.T) {
+	cases := []struct {
+		config DNSHealthcheckConfiguration
+		valid  bool
+	}{
+		{config: DNSHealthcheckConfiguration{DoH: true, DoHURL: "https://cloudflare-dns.com/dns-query"}, valid: true},
+		{config: DNSHealthcheckConfiguration{DoH: true}, valid: false},
+		{config: DNSHealthcheckConfiguration{DoH: true, DoHURL: "ftp://cloudflare-dns.com/dns-query"}, valid: false},
+		{config: DNSHealthcheckConfiguration{DoH: true, DoHURL: "https://cloudflare-dns.com/dns-query", Server: "1.1.1.1:53"}, valid: false},
+		{config: DNSHealthcheckConfiguration{DoHURL: "invalid"}, valid: true},
+	}
+	for _, c := range cases {
+		c.config.Base = Base{Name: "foo", OneOff: true}
+		c.config.Domain = "cabourotte.test"
+		c.config.Timeout = Duration(time.Second)
+		err := c.config.Validate()
+		if c.valid && err != nil {
+			t.Fatalf("Invalid configuration %v:\n%v", c.config, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Was expecting an error for %v", c.config)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dohContentType the content type of the DNS-over-HTTPS requests and
+// responses (RFC 8484)
+const dohContentType = "application/dns-message"
+
+// dohMaxResponseSize the maximum size of a DNS-over-HTTPS response
+const dohMaxResponseSize = 65535
+
+// validateDoHURL validates the URL of a DNS-over-HTTPS endpoint
+func validateDoHURL(dohURL string) error {
+	if dohURL == "" {
+		return errors.New("The DoH URL is missing")
+	}
+	u, err := url.Parse(dohURL)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid DoH URL %s", dohURL)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("Invalid DoH URL scheme %s (https or http expected)", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("The DoH URL %s has no host", dohURL)
+	}
+	return nil
+}
+
+// dohRecordTypes returns the DNS types to query for a record type
+func dohRecordTypes(recordType string) []dnsmessage.Type {
+	switch recordType {
+	case RecordTypeA:
+		return []dnsmessage.Type{dnsmessage.TypeA}
+	case RecordTypeAAAA:
+		return []dnsmessage.Type{dnsmessage.TypeAAAA}
+	case RecordTypeCNAME:
+		return []dnsmessage.Type{dnsmessage.TypeCNAME}
+	case RecordTypeMX:
+		return []dnsmessage.Type{dnsmessage.TypeMX}
+	case RecordTypeTXT:
+		return []dnsmessage.Type{dnsmessage.TypeTXT}
+	case RecordTypeNS:
+		return []dnsmessage.Type{dnsmessage.TypeNS}
+	case RecordTypeSRV:
+		return []dnsmessage.Type{dnsmessage.TypeSRV}
+	}
+	return []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA}
+}
+
+// dohQuery sends a DNS query for the configured domain to the DoH endpoint
+// and returns the answers of the given type
+func (h *DNSHealthcheck) dohQuery(ctx context.Context, rtype dnsmessage.Type) ([]dnsmessage.Resource, error) {
+	name, err := dnsmessage.NewName(dnsFQDN(h.Config.Domain))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid domain %s", h.Config.Domain)
+	}
+	// the ID should be 0 in order to be cache-friendly (RFC 8484)
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: name, Type: rtype, Class: dnsmessage.ClassINET},
+		},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to build the DNS query")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.Config.DoHURL, bytes.NewReader(packed))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to build the DoH request")
+	}
+	req.Header.Set("Content-Type", dohContentType)
+	req.Header.Set("Accept", dohContentType)
+	response, err := h.DoHClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "DoH request failed")
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(response.Body, dohMaxResponseSize))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the DoH response")
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH request failed with status %d", response.StatusCode)
+	}
+	var answer dnsmessage.Message
+	err = answer.Unpack(body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to parse the DoH response")
+	}
+	if answer.Header.RCode == dnsmessage.RCodeNameError {
+		return nil, &net.DNSError{
+			Err:        "no such host",
+			Name:       h.Config.Domain,
+			Server:     h.Config.DoHURL,
+			IsNotFound: true,
+		}
+	}
+	if answer.Header.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("DoH query failed with response code %s", answer.Header.RCode)
+	}
+	result := []dnsmessage.Resource{}
+	for _, resource := range answer.Answers {
+		if resource.Header.Type == rtype {
+			result = append(result, resource)
+		}
+	}
+	return result, nil
+}
+
+// dohLookup looks up the DNS records of the configured type using the DoH
+// endpoint, and returns their values
+func (h *DNSHealthcheck) dohLookup(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
+	defer cancel()
+	values := []string{}
+	for _, rtype := range dohRecordTypes(h.Config.RecordType) {
+		resources, err := h.dohQuery(ctx, rtype)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range resources {
+			switch body := resource.Body.(type) {
+			case *dnsmessage.AResource:
+				values = append(values, net.IP(body.A[:]).String())
+			case *dnsmessage.AAAAResource:
+				values = append(values, net.IP(body.AAAA[:]).String())
+			case *dnsmessage.CNAMEResource:
+				values = append(values, body.CNAME.String())
+			case *dnsmessage.MXResource:
+				values = append(values, body.MX.String())
+			case *dnsmessage.TXTResource:
+				values = append(values, strings.Join(body.TXT, ""))
+			case *dnsmessage.NSResource:
+				values = append(values, body.NS.String())
+			case *dnsmessage.SRVResource:
+				values = append(values, body.Target.String())
+			}
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("No record found for domain %s", h.Config.Domain)
+	}
+	return values, nil
+}
+
+// dohLookupIP looks up the IP addresses of the configured domain using the
+// DoH endpoint
+func (h *DNSHealthcheck) dohLookupIP(ctx context.Context) ([]net.IP, error) {
+	values, err := h.dohLookup(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(values))
+	for i, value := range values {
+		ips[i] = net.ParseIP(value)
+	}
+	return ips, nil
+}
+
+// dnsFQDN returns the fully qualified version of a domain
+func dnsFQDN(domain string) string {
+	if strings.HasSuffix(domain, ".") {
+		return domain
+	}
+	return domain + "."
+}
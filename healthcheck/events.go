@@ -0,0 +1,62 @@
+package healthcheck
+
+import (
+	"sync"
+)
+
+// DefaultMaxExecutionEvents the default number of execution events kept for
+// each healthcheck
+const DefaultMaxExecutionEvents = 20
+
+// ExecutionEvent an healthcheck execution
+type ExecutionEvent struct {
+	Timestamp int64  `json:"timestamp"`
+	Success   bool   `json:"success"`
+	Message   string `json:"message"`
+	Duration  int64  `json:"duration"`
+}
+
+// eventsBuffer a ring buffer of the last execution events of an healthcheck
+type eventsBuffer struct {
+	lock   sync.Mutex
+	events []ExecutionEvent
+	next   int
+	full   bool
+}
+
+// newEventsBuffer creates a new buffer keeping the last size events
+func newEventsBuffer(size int) *eventsBuffer {
+	return &eventsBuffer{
+		events: make([]ExecutionEvent, size),
+	}
+}
+
+// add adds an event to the buffer, replacing the oldest one if the buffer is
+// full
+func (b *eventsBuffer) add(event ExecutionEvent) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if len(b.events) == 0 {
+		return
+	}
+	b.events[b.next] = event
+	b.next = (b.next + 1) % len(b.events)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// list returns the events, from the oldest to the most recent
+func (b *eventsBuffer) list() []ExecutionEvent {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if !b.full {
+		result := make([]ExecutionEvent, b.next)
+		copy(result, b.events[:b.next])
+		return result
+	}
+	result := make([]ExecutionEvent, 0, len(b.events))
+	result = append(result, b.events[b.next:]...)
+	result = append(result, b.events[:b.next]...)
+	return result
+}
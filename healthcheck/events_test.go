@@ -0,0 +1,30 @@
+package healthcheck
+
+import (
+	"testing"
+)
+
+func TestEventsBuffer(t *testing.T) {
+	buffer := newEventsBuffer(3)
+	if len(buffer.list()) != 0 {
+		t.Fatalf("The buffer should be empty")
+	}
+	buffer.add(ExecutionEvent{Timestamp: 1})
+	buffer.add(ExecutionEvent{Timestamp: 2})
+	events := buffer.list()
+	if len(events) != 2 || events[0].Timestamp != 1 || events[1].Timestamp != 2 {
+		t.Fatalf("Invalid events %v", events)
+	}
+	buffer.add(ExecutionEvent{Timestamp: 3})
+	buffer.add(ExecutionEvent{Timestamp: 4})
+	buffer.add(ExecutionEvent{Timestamp: 5})
+	events = buffer.list()
+	if len(events) != 3 || events[0].Timestamp != 3 || events[2].Timestamp != 5 {
+		t.Fatalf("Invalid events %v", events)
+	}
+	empty := newEventsBuffer(0)
+	empty.add(ExecutionEvent{Timestamp: 1})
+	if len(empty.list()) != 0 {
+		t.Fatalf("The buffer should be empty")
+	}
+}
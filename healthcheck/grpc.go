@@ -0,0 +1,217 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"gopkg.in/tomb.v2"
+
+	"github.com/appclacks/cabourotte/tls"
+)
+
+// GRPCHealthcheckConfiguration defines a gRPC healthcheck configuration
+type GRPCHealthcheckConfiguration struct {
+	Base `json:",inline" yaml:",inline"`
+	// can be an IP or a domain
+	Target string `json:"target"`
+	Port   uint   `json:"port"`
+	// Service the name of the service to check (the server health if empty)
+	Service    string   `json:"service,omitempty"`
+	Timeout    Duration `json:"timeout"`
+	TLS        bool     `json:"tls"`
+	Key        string   `json:"key,omitempty"`
+	Cert       string   `json:"cert,omitempty"`
+	Cacert     string   `json:"cacert,omitempty"`
+	ServerName string   `json:"server-name,omitempty" yaml:"server-name"`
+	Insecure   bool     `json:"insecure"`
+}
+
+// GRPCHealthcheck defines a gRPC healthcheck
+type GRPCHealthcheck struct {
+	Logger      *zap.Logger
+	Resolver    *Resolver
+	Config      *GRPCHealthcheckConfiguration
+	URL         string
+	Credentials credentials.TransportCredentials
+
+	Tick *time.Ticker
+	t    tomb.Tomb
+}
+
+// Validate validates the healthcheck configuration
+func (config *GRPCHealthcheckConfiguration) Validate() error {
+	if config.Base.Name == "" {
+		return errors.New("The healthcheck name is missing")
+	}
+	if err := config.Base.validate(); err != nil {
+		return err
+	}
+	if config.Target == "" {
+		return errors.New("The healthcheck target is missing")
+	}
+	if config.Port == 0 {
+		return errors.New("The healthcheck port is missing")
+	}
+	if config.Timeout == 0 {
+		return errors.New("The healthcheck timeout is missing")
+	}
+	if !config.Base.OneOff && config.Base.Cron == "" {
+		if config.Base.Interval < Duration(2*time.Second) {
+			return errors.New("The healthcheck interval should be greater than 2 second")
+		}
+		if config.Base.Interval < config.Timeout {
+			return errors.New("The healthcheck interval should be greater than the timeout")
+		}
+	}
+	if !((config.Key != "" && config.Cert != "") ||
+		(config.Key == "" && config.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	return nil
+}
+
+// Base get the base configuration
+func (h *GRPCHealthcheck) Base() Base {
+	return h.Config.Base
+}
+
+// SetSource set the healthcheck source
+func (h *GRPCHealthcheck) SetSource(source string) {
+	h.Config.Base.Source = source
+}
+
+// SetResolver set the healthcheck resolver
+func (h *GRPCHealthcheck) SetResolver(resolver *Resolver) {
+	h.Resolver = resolver
+}
+
+// Summary returns an healthcheck summary
+func (h *GRPCHealthcheck) Summary() string {
+	summary := ""
+	if h.Config.Base.Description != "" {
+		summary = fmt.Sprintf("gRPC healthcheck %s on %s:%d", h.Config.Base.Description, h.Config.Target, h.Config.Port)
+
+	} else {
+		summary = fmt.Sprintf("gRPC healthcheck on %s:%d", h.Config.Target, h.Config.Port)
+	}
+
+	return summary
+}
+
+// buildURL build the target URL for the gRPC healthcheck, depending of its
+// configuration
+func (h *GRPCHealthcheck) buildURL() {
+	h.URL = net.JoinHostPort(h.Config.Target, fmt.Sprintf("%d", h.Config.Port))
+}
+
+// Initialize the healthcheck.
+func (h *GRPCHealthcheck) Initialize() error {
+	h.buildURL()
+	h.Credentials = insecure.NewCredentials()
+	if h.Config.TLS {
+		tlsConfig, err := tls.GetTLSConfig(h.Config.Key, h.Config.Cert, h.Config.Cacert, h.Config.ServerName, h.Config.Insecure)
+		if err != nil {
+			return err
+		}
+		h.Credentials = credentials.NewTLS(tlsConfig)
+	}
+	return nil
+}
+
+// GetConfig get the config
+func (h *GRPCHealthcheck) GetConfig() interface{} {
+	return h.Config
+}
+
+// LogError logs an error with context
+func (h *GRPCHealthcheck) LogError(err error, message string) {
+	h.Logger.Error(err.Error(),
+		zap.String("extra", message),
+		zap.String("target", h.Config.Target),
+		zap.Uint("port", h.Config.Port),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogDebug logs a message with context
+func (h *GRPCHealthcheck) LogDebug(message string) {
+	h.Logger.Debug(message,
+		zap.String("target", h.Config.Target),
+		zap.Uint("port", h.Config.Port),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogInfo logs a message with context
+func (h *GRPCHealthcheck) LogInfo(message string) {
+	h.Logger.Info(message,
+		zap.String("target", h.Config.Target),
+		zap.Uint("port", h.Config.Port),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// Execute executes an healthcheck on the given target
+func (h *GRPCHealthcheck) Execute(ctx context.Context) (Annotations, error) {
+	h.LogDebug("start executing healthcheck")
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
+	defer cancel()
+	dial := h.Resolver.DialContext(&net.Dialer{})
+	conn, err := grpc.NewClient(
+		fmt.Sprintf("passthrough:///%s", h.URL),
+		grpc.WithTransportCredentials(h.Credentials),
+		grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			return dial(ctx, "tcp", address)
+		}),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to create the gRPC client for %s", h.URL)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+	response, err := client.Check(timeoutCtx, &healthpb.HealthCheckRequest{
+		Service: h.Config.Service,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "gRPC health request failed on %s", h.URL)
+	}
+	if response.Status != healthpb.HealthCheckResponse_SERVING {
+		return nil, fmt.Errorf("gRPC service status is %s on %s", response.Status.String(), h.URL)
+	}
+	return nil, nil
+}
+
+// NewGRPCHealthcheck creates a gRPC healthcheck from a logger and a configuration
+func NewGRPCHealthcheck(logger *zap.Logger, config *GRPCHealthcheckConfiguration) *GRPCHealthcheck {
+	return &GRPCHealthcheck{
+		Logger: logger,
+		Config: config,
+	}
+}
+
+// MarshalJSON marshal to json a gRPC healthcheck
+func (h *GRPCHealthcheck) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Config)
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GRPCHealthcheckConfiguration) DeepCopyInto(out *GRPCHealthcheckConfiguration) {
+	*out = *in
+	in.Base.DeepCopyInto(&out.Base)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPCHealthcheckConfiguration.
+func (in *GRPCHealthcheckConfiguration) DeepCopy() *GRPCHealthcheckConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(GRPCHealthcheckConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
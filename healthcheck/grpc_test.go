@@ -0,0 +1,68 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestGRPCExecute(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen :\n%v", err)
+	}
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("foo", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("bar", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	_, portStr, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Fail to get the server port :\n%v", err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		t.Fatalf("Fail to get the server port :\n%v", err)
+	}
+	cases := []struct {
+		service string
+		success bool
+	}{
+		{service: "", success: true},
+		{service: "foo", success: true},
+		{service: "bar", success: false},
+		{service: "unknown", success: false},
+	}
+	for _, c := range cases {
+		h := NewGRPCHealthcheck(zap.NewExample(), &GRPCHealthcheckConfiguration{
+			Base: Base{
+				Name: "grpc",
+			},
+			Target:  "127.0.0.1",
+			Port:    uint(port),
+			Service: c.service,
+			Timeout: Duration(time.Second * 2),
+		})
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Fail to initialize the healthcheck :\n%v", err)
+		}
+		_, err = h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for service '%s' :\n%v", c.service, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for service '%s'", c.service)
+		}
+	}
+}
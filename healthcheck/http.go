@@ -3,45 +3,171 @@ package healthcheck
 import (
 	"bytes"
 	"context"
+	cryptotls "crypto/tls"
 	"encoding/json"
 	"fmt"
 	"html"
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/appclacks/cabourotte/tls"
+	"github.com/ohler55/ojg/jp"
 	"github.com/pkg/errors"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 	"go.uber.org/zap"
 
 	"gopkg.in/tomb.v2"
 )
 
+// DefaultMaxBodySize the maximum number of bytes of the HTTP responses
+// bodies added to the error messages and annotations if not configured
+const DefaultMaxBodySize = 1000
+
+// DefaultMaxBodyReadSize the maximum number of bytes read from the HTTP
+// responses bodies if not configured
+const DefaultMaxBodyReadSize = 10 * 1024 * 1024
+
+// DefaultHappyEyeballsDelay the head start given to the first address family
+// when happy eyeballs is enabled, as recommended by RFC 8305
+const DefaultHappyEyeballsDelay = Duration(250 * time.Millisecond)
+
+// BasicAuth the credentials for HTTP basic authentication
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
 // HTTPHealthcheckConfiguration defines an HTTP healthcheck configuration
 type HTTPHealthcheckConfiguration struct {
 	Base        `json:",inline" yaml:",inline"`
 	ValidStatus []uint `json:"valid-status" yaml:"valid-status"`
+	// Accept2xx all 2xx status codes are considered successful
+	Accept2xx bool `json:"accept-2xx,omitempty" yaml:"accept-2xx,omitempty"`
 	// can be an IP or a domain
-	Target     string            `json:"target"`
-	Host       string            `json:"host,omitempty"`
-	Method     string            `json:"method"`
-	Port       uint              `json:"port"`
-	Redirect   bool              `json:"redirect"`
-	Body       string            `json:"body,omitempty"`
-	Query      map[string]string `json:"query,omitempty"`
-	Headers    map[string]string `json:"headers,omitempty"`
-	Protocol   Protocol          `json:"protocol"`
-	Path       string            `json:"path,omitempty"`
-	SourceIP   IP                `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
-	BodyRegexp []Regexp          `json:"body-regexp,omitempty" yaml:"body-regexp,omitempty"`
-	Insecure   bool              `json:"insecure"`
-	ServerName string            `json:"server-name"`
-	Timeout    Duration          `json:"timeout"`
-	Key        string            `json:"key,omitempty"`
-	Cert       string            `json:"cert,omitempty"`
-	Cacert     string            `json:"cacert,omitempty"`
+	Target   string `json:"target"`
+	Host     string `json:"host,omitempty"`
+	Method   string `json:"method"`
+	Port     uint   `json:"port"`
+	Redirect bool   `json:"redirect"`
+	// MaxRedirects the maximum number of redirects to follow (10 by default)
+	MaxRedirects uint `json:"max-redirects,omitempty" yaml:"max-redirects,omitempty"`
+	// AllowCrossHostRedirect allows redirects to a different host
+	AllowCrossHostRedirect bool              `json:"allow-cross-host-redirect,omitempty" yaml:"allow-cross-host-redirect,omitempty"`
+	Body                   string            `json:"body,omitempty"`
+	Query                  map[string]string `json:"query,omitempty"`
+	Headers                map[string]string `json:"headers,omitempty"`
+	ResponseHeaders        map[string]string `json:"response-headers,omitempty" yaml:"response-headers,omitempty"`
+	Protocol               Protocol          `json:"protocol"`
+	Path                   string            `json:"path,omitempty"`
+	SourceIP               IP                `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
+	BodyRegexp             []Regexp          `json:"body-regexp,omitempty" yaml:"body-regexp,omitempty"`
+	// ForbiddenBodyRegexp the healthcheck fails if the body matches one of
+	// these regexps
+	ForbiddenBodyRegexp []Regexp `json:"forbidden-body-regexp,omitempty" yaml:"forbidden-body-regexp,omitempty"`
+	// BodyRegexpNegative deprecated alias of ForbiddenBodyRegexp
+	BodyRegexpNegative []Regexp `json:"body-regexp-negative,omitempty" yaml:"body-regexp-negative,omitempty"`
+	Insecure           bool     `json:"insecure"`
+	ServerName         string   `json:"server-name"`
+	Timeout            Duration `json:"timeout"`
+	MaxResponseTime    Duration `json:"max-response-time,omitempty" yaml:"max-response-time,omitempty"`
+	// WarnResponseTime the result is degraded if the response time is
+	// greater than this threshold
+	WarnResponseTime Duration `json:"warn-response-time,omitempty" yaml:"warn-response-time,omitempty"`
+	Retries          int      `json:"retries,omitempty" yaml:"retries,omitempty"`
+	RetryInterval    Duration `json:"retry-interval,omitempty" yaml:"retry-interval,omitempty"`
+	// BasicAuth the credentials sent using HTTP basic authentication
+	BasicAuth *BasicAuth `json:"basic-auth,omitempty" yaml:"basic-auth,omitempty"`
+	// UserAgent the User-Agent header of the requests (Cabourotte if not
+	// set). It takes precedence over a User-Agent set in Headers.
+	UserAgent string `json:"user-agent,omitempty" yaml:"user-agent,omitempty"`
+	// BearerToken the token sent in the Authorization header
+	BearerToken string `json:"bearer-token,omitempty" yaml:"bearer-token,omitempty"`
+	Key         string `json:"key,omitempty"`
+	Cert        string `json:"cert,omitempty"`
+	Cacert      string `json:"cacert,omitempty"`
+	// KeyPEM the PEM content of the key, as an alternative to Key
+	KeyPEM string `json:"key-pem,omitempty" yaml:"key-pem,omitempty"`
+	// CertPEM the PEM content of the certificate, as an alternative to Cert
+	CertPEM string `json:"cert-pem,omitempty" yaml:"cert-pem,omitempty"`
+	// CacertPEM the PEM content of the ca certificate, as an alternative to
+	// Cacert
+	CacertPEM       string          `json:"cacert-pem,omitempty" yaml:"cacert-pem,omitempty"`
+	ReverseDNS      bool            `json:"reverse-dns" yaml:"reverse-dns"`
+	ExpectCacheable bool            `json:"expect-cacheable,omitempty" yaml:"expect-cacheable,omitempty"`
+	ExpectCacheHit  bool            `json:"expect-cache-hit,omitempty" yaml:"expect-cache-hit,omitempty"`
+	ExpectETag      bool            `json:"expect-etag,omitempty" yaml:"expect-etag,omitempty"`
+	JSONAssertions  []JSONAssertion `json:"json-assertions,omitempty" yaml:"json-assertions,omitempty"`
+	// JSONSchema the JSON schema validating the response body, either
+	// inline or the path of a file containing the schema
+	JSONSchema string `json:"json-schema,omitempty" yaml:"json-schema,omitempty"`
+	// MaxBodySize the maximum number of bytes of the response body added to
+	// the error messages and annotations (DefaultMaxBodySize if not set)
+	MaxBodySize int `json:"max-body-size,omitempty" yaml:"max-body-size,omitempty"`
+	// MaxBodyReadSize the maximum number of bytes read from the response
+	// body, the healthcheck fails if the body is larger
+	// (DefaultMaxBodyReadSize if not set)
+	MaxBodyReadSize int64 `json:"max-body-read-size,omitempty" yaml:"max-body-read-size,omitempty"`
+	// CaptureBody adds the response status line and body (limited to
+	// MaxBodySize) to the annotations of the failed results
+	CaptureBody bool `json:"capture-body,omitempty" yaml:"capture-body,omitempty"`
+	// CaptureBodyAlways captures the response also for the successful
+	// results
+	CaptureBodyAlways bool `json:"capture-body-always,omitempty" yaml:"capture-body-always,omitempty"`
+	// HappyEyeballs connects to dual-stack targets by attempting both
+	// address families in parallel, the first one getting a head start of
+	// HappyEyeballsDelay. The family of the connection is added to the
+	// result annotations. The addresses are tried one after the other if
+	// not set.
+	HappyEyeballs bool `json:"happy-eyeballs,omitempty" yaml:"happy-eyeballs,omitempty"`
+	// HappyEyeballsDelay the head start of the first address family
+	// (DefaultHappyEyeballsDelay if not set)
+	HappyEyeballsDelay Duration `json:"happy-eyeballs-delay,omitempty" yaml:"happy-eyeballs-delay,omitempty"`
+	// MinTLSVersion the minimum TLS version (1.0, 1.1, 1.2 or 1.3)
+	MinTLSVersion string `json:"min-tls-version,omitempty" yaml:"min-tls-version,omitempty"`
+	// MaxTLSVersion the maximum TLS version (1.0, 1.1, 1.2 or 1.3)
+	MaxTLSVersion string `json:"max-tls-version,omitempty" yaml:"max-tls-version,omitempty"`
+	// ExpectHandshakeFailure deprecated alias of ShouldFail
+	ExpectHandshakeFailure bool `json:"expect-handshake-failure,omitempty" yaml:"expect-handshake-failure,omitempty"`
+	// ShouldFail the healthcheck is successful only if the request fails
+	// or if the response is not valid, for example to verify that the
+	// server refuses the versions between MinTLSVersion and MaxTLSVersion.
+	// The TLS handshake error is added to the annotations.
+	ShouldFail bool `json:"should-fail,omitempty" yaml:"should-fail,omitempty"`
+}
+
+// JSONAssertion an assertion on a value of a JSON response body
+type JSONAssertion struct {
+	// Path the JSONPath expression selecting the value
+	Path string `json:"path"`
+	// Expected the expected value. Non-string values are compared using
+	// their JSON representation.
+	Expected string `json:"expected"`
+}
+
+// certificates returns the certificates of the healthcheck
+func (config *HTTPHealthcheckConfiguration) certificates() *tls.Certificates {
+	return &tls.Certificates{
+		Key:       config.Key,
+		Cert:      config.Cert,
+		Cacert:    config.Cacert,
+		KeyPEM:    config.KeyPEM,
+		CertPEM:   config.CertPEM,
+		CacertPEM: config.CacertPEM,
+	}
+}
+
+// shouldFail returns true if the healthcheck should fail, using the
+// deprecated ExpectHandshakeFailure alias
+func (config *HTTPHealthcheckConfiguration) shouldFail() bool {
+	return config.ShouldFail || config.ExpectHandshakeFailure
 }
 
 // Validate validates the healthcheck configuration
@@ -49,8 +175,11 @@ func (config *HTTPHealthcheckConfiguration) Validate() error {
 	if config.Base.Name == "" {
 		return errors.New("The healthcheck name is missing")
 	}
-	if len(config.ValidStatus) == 0 {
-		return errors.New("At least one valid status code should be provided")
+	if err := config.Base.validate(); err != nil {
+		return err
+	}
+	if len(config.ValidStatus) == 0 && !config.Accept2xx {
+		return errors.New("At least one valid status code should be provided, or accept-2xx should be enabled")
 	}
 	if config.Target == "" {
 		return errors.New("The healthcheck target is missing")
@@ -68,7 +197,7 @@ func (config *HTTPHealthcheckConfiguration) Validate() error {
 	} else {
 		config.Method = "GET"
 	}
-	if !config.Base.OneOff {
+	if !config.Base.OneOff && config.Base.Cron == "" {
 		if config.Base.Interval < Duration(2*time.Second) {
 			return errors.New("The healthcheck interval should be greater than 2 second")
 		}
@@ -76,22 +205,110 @@ func (config *HTTPHealthcheckConfiguration) Validate() error {
 			return errors.New("The healthcheck interval should be greater than the timeout")
 		}
 	}
-	if !((config.Key != "" && config.Cert != "") ||
-		(config.Key == "" && config.Cert == "")) {
-		return errors.New("Invalid certificates")
+	if err := config.certificates().Validate(); err != nil {
+		return err
+	}
+	if config.BasicAuth != nil && config.BearerToken != "" {
+		return errors.New("Basic authentication and bearer token can't be both set")
+	}
+	if config.BasicAuth != nil && config.BasicAuth.Username == "" {
+		return errors.New("The basic authentication username is missing")
+	}
+	if config.Retries < 0 {
+		return errors.New("The number of retries should be positive")
+	}
+	if config.MaxBodySize < 0 {
+		return errors.New("The maximum body size should be positive")
+	}
+	if config.MaxBodyReadSize < 0 {
+		return errors.New("The maximum body read size should be positive")
+	}
+	if config.CaptureBodyAlways && !config.CaptureBody {
+		return errors.New("The body should be captured to be always captured")
+	}
+	if err := tls.ValidateVersions(config.MinTLSVersion, config.MaxTLSVersion); err != nil {
+		return err
+	}
+	if err := validateWarnResponseTime(config.WarnResponseTime, config.MaxResponseTime); err != nil {
+		return err
+	}
+	if config.HappyEyeballsDelay < 0 {
+		return errors.New("The happy eyeballs delay should be positive")
+	}
+	for _, assertion := range config.JSONAssertions {
+		if _, err := jp.ParseString(assertion.Path); err != nil {
+			return errors.Wrapf(err, "Invalid JSON path %s", assertion.Path)
+		}
+	}
+	if config.JSONSchema != "" {
+		if _, err := compileJSONSchema(config.JSONSchema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DefaultMaxRedirects the default maximum number of redirects followed by
+// HTTP healthchecks
+const DefaultMaxRedirects = 10
+
+// redirectBlockedError is returned when a redirect to another host is blocked
+type redirectBlockedError struct {
+	target string
+}
+
+func (e *redirectBlockedError) Error() string {
+	return fmt.Sprintf("Redirect to another host blocked: %s", e.target)
+}
+
+// checkRedirect verifies if a redirect should be followed depending of the
+// healthcheck configuration
+func (h *HTTPHealthcheck) checkRedirect(req *http.Request, via []*http.Request) error {
+	if !h.Config.Redirect {
+		return http.ErrUseLastResponse
+	}
+	maxRedirects := h.Config.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+	if uint(len(via)) >= maxRedirects {
+		return fmt.Errorf("Stopped after %d redirects", maxRedirects)
+	}
+	if !h.Config.AllowCrossHostRedirect && len(via) != 0 &&
+		!h.sameHost(req.URL.Hostname(), via[0]) {
+		return &redirectBlockedError{target: req.URL.String()}
 	}
 	return nil
 }
 
+// sameHost returns true if the host is the host of the first request, or
+// the configured Host header when the request target is an IP address
+func (h *HTTPHealthcheck) sameHost(host string, first *http.Request) bool {
+	if strings.EqualFold(host, first.URL.Hostname()) {
+		return true
+	}
+	if h.Config.Host == "" {
+		return false
+	}
+	configured, _, err := net.SplitHostPort(h.Config.Host)
+	if err != nil {
+		configured = h.Config.Host
+	}
+	return strings.EqualFold(host, configured)
+}
+
 // HTTPHealthcheck defines an HTTP healthcheck
 type HTTPHealthcheck struct {
-	Logger *zap.Logger
-	Config *HTTPHealthcheckConfiguration
-	URL    string
+	Logger   *zap.Logger
+	Resolver *Resolver
+	Config   *HTTPHealthcheckConfiguration
+	URL      string
 
 	Tick   *time.Ticker
 	t      tomb.Tomb
 	Client *http.Client
+
+	jsonSchema *jsonschema.Schema
 }
 
 // buildURL build the target URL for the HTTP healthcheck, depending of its
@@ -118,6 +335,10 @@ func (h *HTTPHealthcheck) Summary() string {
 		summary = fmt.Sprintf("HTTP healthcheck on %s:%d", h.Config.Target, h.Config.Port)
 	}
 
+	if h.Config.shouldFail() {
+		summary = summary + shouldFailSummary
+	}
+
 	return summary
 }
 
@@ -136,23 +357,34 @@ func (h *HTTPHealthcheck) Initialize() error {
 			LocalAddr: addr,
 		}
 	}
-	tlsConfig, err := tls.GetTLSConfig(h.Config.Key, h.Config.Cert, h.Config.Cacert, h.Config.ServerName, h.Config.Insecure)
+	if h.Config.HappyEyeballs {
+		delay := h.Config.HappyEyeballsDelay
+		if delay == 0 {
+			delay = DefaultHappyEyeballsDelay
+		}
+		dialer.FallbackDelay = time.Duration(delay)
+	}
+	tlsConfig, err := tls.GetTLSConfigWithCertificates(h.Config.certificates(), h.Config.ServerName, h.Config.Insecure)
+	if err != nil {
+		return err
+	}
+	err = tls.SetVersions(tlsConfig, h.Config.MinTLSVersion, h.Config.MaxTLSVersion)
 	if err != nil {
 		return err
 	}
 	transport := &http.Transport{
-		DialContext:     dialer.DialContext,
+		DialContext:     h.Resolver.DialContext(&dialer),
 		TLSClientConfig: tlsConfig,
 	}
-	redirect := http.ErrUseLastResponse
-	if h.Config.Redirect {
-		redirect = nil
-	}
 	h.Client = &http.Client{
-		Transport: transport,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return redirect
-		},
+		Transport:     transport,
+		CheckRedirect: h.checkRedirect,
+	}
+	if h.Config.JSONSchema != "" {
+		h.jsonSchema, err = compileJSONSchema(h.Config.JSONSchema)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -172,9 +404,17 @@ func (h *HTTPHealthcheck) SetSource(source string) {
 	h.Config.Base.Source = source
 }
 
+// SetResolver set the healthcheck resolver
+func (h *HTTPHealthcheck) SetResolver(resolver *Resolver) {
+	h.Resolver = resolver
+}
+
 // isSuccessful verifies if a healthcheck result is considered valid
 // depending of the healthcheck configuration
 func (h *HTTPHealthcheck) isSuccessful(response *http.Response) bool {
+	if h.Config.Accept2xx && response.StatusCode >= 200 && response.StatusCode < 300 {
+		return true
+	}
 	for _, s := range h.Config.ValidStatus {
 		if uint(response.StatusCode) == s {
 			return true
@@ -208,14 +448,13 @@ func (h *HTTPHealthcheck) LogInfo(message string) {
 		zap.String("name", h.Config.Base.Name))
 }
 
-// Execute executes an healthcheck on the given target
-func (h *HTTPHealthcheck) Execute() error {
-	h.LogDebug("start executing healthcheck")
-	ctx := h.t.Context(context.TODO())
+// request executes the HTTP request and reads the response body. An error is
+// returned if the request fails or if the response status is not valid.
+func (h *HTTPHealthcheck) request(ctx context.Context, annotations Annotations) (*http.Response, []byte, time.Duration, error) {
 	body := bytes.NewBuffer([]byte(h.Config.Body))
 	req, err := http.NewRequest(h.Config.Method, h.URL, body)
 	if err != nil {
-		return errors.Wrapf(err, "fail to initialize HTTP request")
+		return nil, nil, 0, errors.Wrapf(err, "fail to initialize HTTP request")
 	}
 	if h.Config.Host != "" {
 		req.Host = h.Config.Host
@@ -224,13 +463,22 @@ func (h *HTTPHealthcheck) Execute() error {
 	for k, v := range h.Config.Headers {
 		req.Header.Set(k, v)
 	}
+	if h.Config.UserAgent != "" {
+		req.Header.Set("User-Agent", h.Config.UserAgent)
+	}
+	if h.Config.BasicAuth != nil {
+		req.SetBasicAuth(h.Config.BasicAuth.Username, h.Config.BasicAuth.Password)
+	}
+	if h.Config.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.Config.BearerToken)
+	}
 	if h.Config.Host != "" {
 		req.Host = h.Config.Host
 	}
 	client := h.Client
-	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
-	defer cancel()
-	req = req.WithContext(timeoutCtx)
+	trace := newRequestTrace()
+	ctx = httptrace.WithClientTrace(ctx, trace.clientTrace())
+	req = req.WithContext(ctx)
 	if len(h.Config.Query) != 0 {
 		q := req.URL.Query()
 		for k, v := range h.Config.Query {
@@ -238,35 +486,486 @@ func (h *HTTPHealthcheck) Execute() error {
 		}
 		req.URL.RawQuery = q.Encode()
 	}
+	start := time.Now()
 	response, err := client.Do(req)
+	result := trace.result()
+	setTimings(ctx, result.timings, annotations)
+	if result.tlsVersion != "" {
+		annotations["tls-version"] = result.tlsVersion
+	}
+	remoteAddr := result.remoteAddr
+	handshakeErr := result.handshakeErr
+	if remoteAddr != nil && h.Config.HappyEyeballs {
+		annotations["ip-family"] = ipFamily(remoteAddr)
+	}
+	if remoteAddr != nil && h.Config.ReverseDNS {
+		reverseDNS(ctx, h.Resolver, remoteAddr, annotations)
+	}
+	if handshakeErr != nil {
+		return nil, nil, 0, &handshakeError{err: handshakeErr}
+	}
 	if err != nil {
-		return errors.Wrapf(err, "HTTP request failed")
+		var blockedErr *redirectBlockedError
+		if errors.As(err, &blockedErr) {
+			annotations["blocked-redirect"] = blockedErr.target
+		}
+		return nil, nil, 0, errors.Wrapf(err, "HTTP request failed")
 	}
 	defer response.Body.Close()
-	responseBody, err := io.ReadAll(response.Body)
+	maxBodySize := h.maxBodySize()
+	maxBodyReadSize := h.maxBodyReadSize()
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, maxBodyReadSize+1))
 	if err != nil {
-		return errors.Wrapf(err, "Fail to read request body")
+		return nil, nil, 0, errors.Wrapf(err, "Fail to read request body")
 	}
-	responseBodyStr := string(responseBody)
-	maxMessageSize := 1000
-	message := responseBodyStr
-	if len(responseBodyStr) > maxMessageSize {
-		message = responseBodyStr[0:maxMessageSize]
+	if int64(len(responseBody)) > maxBodyReadSize {
+		return nil, nil, 0, fmt.Errorf("The response body is larger than %d bytes", maxBodyReadSize)
+	}
+	responseTime := time.Since(start)
+	if h.Config.CaptureBody {
+		annotations["response-status"] = fmt.Sprintf("%s %s", response.Proto, response.Status)
+		annotations["response-body"] = truncateBody(responseBody, maxBodySize)
 	}
 	if !h.isSuccessful(response) {
-		errorMsg := fmt.Sprintf("HTTP request failed: status %d. Body: '%s'", response.StatusCode, html.EscapeString(message))
-		err = errors.New(errorMsg)
-		return err
+		errorMsg := fmt.Sprintf("HTTP request failed: status %d. Body: '%s'", response.StatusCode, html.EscapeString(truncateBody(responseBody, maxBodySize)))
+		return nil, nil, 0, errors.New(errorMsg)
+	}
+	return response, responseBody, responseTime, nil
+}
+
+// ipFamily returns the address family (ipv4 or ipv6) of a connection
+// address
+func ipFamily(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "unknown"
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "unknown"
+	}
+	if ip.To4() != nil {
+		return "ipv4"
+	}
+	return "ipv6"
+}
+
+// handshakeError the error returned when the TLS handshake fails
+type handshakeError struct {
+	err error
+}
+
+func (e *handshakeError) Error() string {
+	return fmt.Sprintf("TLS handshake failed: %s", e.err.Error())
+}
+
+func (e *handshakeError) Unwrap() error {
+	return e.err
+}
+
+// maxBodySize returns the maximum number of bytes of the response body
+// added to the error messages and annotations
+func (h *HTTPHealthcheck) maxBodySize() int {
+	if h.Config.MaxBodySize == 0 {
+		return DefaultMaxBodySize
+	}
+	return h.Config.MaxBodySize
+}
+
+// maxBodyReadSize returns the maximum number of bytes read from the
+// response body
+func (h *HTTPHealthcheck) maxBodyReadSize() int64 {
+	if h.Config.MaxBodyReadSize == 0 {
+		return DefaultMaxBodyReadSize
+	}
+	return h.Config.MaxBodyReadSize
+}
+
+// traceResult the information reported by the httptrace callbacks
+type traceResult struct {
+	timings      map[string]time.Duration
+	tlsVersion   string
+	handshakeErr error
+	remoteAddr   net.Addr
+}
+
+// requestTrace collects the information reported by the httptrace
+// callbacks. The connections can be established in parallel (happy
+// eyeballs), and the callbacks can still be called from the dial goroutines
+// after the request returned.
+type requestTrace struct {
+	lock         sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	traceResult
+}
+
+// newRequestTrace creates a new request trace
+func newRequestTrace() *requestTrace {
+	return &requestTrace{
+		traceResult: traceResult{
+			timings: make(map[string]time.Duration),
+		},
+	}
+}
+
+// clientTrace returns the httptrace callbacks updating the trace
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.timings[MetricDNSSeconds] = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(network, addr string) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			if err == nil {
+				t.timings[MetricConnectSeconds] = time.Since(t.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.tlsStart = time.Now()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.remoteAddr = info.Conn.RemoteAddr()
+		},
+		TLSHandshakeDone: func(state cryptotls.ConnectionState, err error) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			if err != nil {
+				t.handshakeErr = err
+				return
+			}
+			t.timings[MetricTLSSeconds] = time.Since(t.tlsStart)
+			t.tlsVersion = tls.FormatVersion(state.Version)
+		},
+	}
+}
+
+// result returns a copy of the information collected by the trace
+func (t *requestTrace) result() traceResult {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	result := t.traceResult
+	result.timings = make(map[string]time.Duration, len(t.timings))
+	for metric, duration := range t.timings {
+		result.timings[metric] = duration
+	}
+	return result
+}
+
+// timingsAnnotations the annotations of the request timings
+var timingsAnnotations = map[string]string{
+	MetricDNSSeconds:     "dns-time",
+	MetricConnectSeconds: "connect-time",
+	MetricTLSSeconds:     "tls-time",
+}
+
+// setTimings reports the request timings as metrics and adds them to the
+// annotations. The timings are missing when a connection is reused.
+func setTimings(ctx context.Context, timings map[string]time.Duration, annotations Annotations) {
+	metrics := make(map[string]float64, len(timings))
+	for metric, duration := range timings {
+		metrics[metric] = duration.Seconds()
+		annotations[timingsAnnotations[metric]] = duration.String()
+	}
+	reportMetrics(ctx, metrics)
+}
+
+// truncateBody truncates a response body to be used in error messages
+func truncateBody(body []byte, maxSize int) string {
+	message := string(body)
+	if len(message) > maxSize {
+		message = message[0:maxSize]
+	}
+	return message
+}
+
+// Execute executes an healthcheck on the given target. The result is
+// inverted if the healthcheck should fail.
+func (h *HTTPHealthcheck) Execute(ctx context.Context) (Annotations, error) {
+	h.LogDebug("start executing healthcheck")
+	annotations, err := h.execute(ctx)
+	if h.Config.shouldFail() {
+		annotations, err = invertResult("HTTP", h.URL, annotations, err)
+	}
+	var degradedErr *DegradedError
+	if (err == nil || errors.As(err, &degradedErr)) && !h.Config.CaptureBodyAlways {
+		delete(annotations, "response-status")
+		delete(annotations, "response-body")
+	}
+	return annotations, err
+}
+
+// execute sends the request and verifies the response
+func (h *HTTPHealthcheck) execute(ctx context.Context) (Annotations, error) {
+	annotations := Annotations{}
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
+	defer cancel()
+	var response *http.Response
+	var responseBody []byte
+	var responseTime time.Duration
+	var err error
+	attempts := 0
+	for {
+		attempts++
+		response, responseBody, responseTime, err = h.request(timeoutCtx, annotations)
+		if err == nil || attempts > h.Config.Retries {
+			break
+		}
+		h.LogDebug(fmt.Sprintf("attempt %d failed, retrying: %s", attempts, err.Error()))
+		retry := true
+		select {
+		case <-time.After(time.Duration(h.Config.RetryInterval)):
+		case <-timeoutCtx.Done():
+			retry = false
+		}
+		if !retry {
+			break
+		}
+	}
+	if h.Config.Retries != 0 {
+		annotations["attempts"] = strconv.Itoa(attempts)
+	}
+	var tlsErr *handshakeError
+	if h.Config.shouldFail() && errors.As(err, &tlsErr) {
+		annotations["handshake-error"] = tlsErr.err.Error()
+	}
+	if err != nil {
+		return annotations, err
+	}
+	responseBodyStr := string(responseBody)
+	message := truncateBody(responseBody, h.maxBodySize())
+	err = checkResponseTime(responseTime, h.Config.MaxResponseTime, annotations)
+	if err != nil {
+		return annotations, err
+	}
+	err = h.verifyResponseHeaders(response, annotations)
+	if err != nil {
+		return annotations, err
 	}
 	for _, regex := range h.Config.BodyRegexp {
 		r := regexp.Regexp(regex)
 		if !r.MatchString(responseBodyStr) {
-			return fmt.Errorf("healthcheck body does not match regex %s: %s", r.String(), message)
+			return annotations, fmt.Errorf("healthcheck body does not match regex %s: %s", r.String(), message)
+		}
+	}
+	forbidden := append(append([]Regexp{}, h.Config.ForbiddenBodyRegexp...), h.Config.BodyRegexpNegative...)
+	for _, regex := range forbidden {
+		r := regexp.Regexp(regex)
+		if r.MatchString(responseBodyStr) {
+			annotations["forbidden-body-regexp"] = r.String()
+			return annotations, fmt.Errorf("healthcheck body matches the forbidden regex %s: %s", r.String(), message)
+		}
+	}
+	err = h.verifyCacheHeaders(response, annotations)
+	if err != nil {
+		return annotations, err
+	}
+	err = h.verifyJSONAssertions(responseBody, annotations)
+	if err != nil {
+		return annotations, err
+	}
+	err = h.verifyJSONSchema(responseBody, annotations)
+	if err != nil {
+		return annotations, err
+	}
+	return annotations, checkWarnResponseTime(responseTime, h.Config.WarnResponseTime, annotations)
+}
+
+// verifyResponseHeaders verifies that the response headers match the expected
+// headers. An empty expected value means that the header should be present
+// with any value. The mismatching header is added to the annotations.
+func (h *HTTPHealthcheck) verifyResponseHeaders(response *http.Response, annotations Annotations) error {
+	headers := make([]string, 0, len(h.Config.ResponseHeaders))
+	for header := range h.Config.ResponseHeaders {
+		headers = append(headers, header)
+	}
+	sort.Strings(headers)
+	for _, header := range headers {
+		expected := h.Config.ResponseHeaders[header]
+		values, ok := response.Header[http.CanonicalHeaderKey(header)]
+		if !ok {
+			annotations["mismatching-header"] = header
+			return fmt.Errorf("The response header %s is missing", header)
+		}
+		if expected == "" {
+			continue
+		}
+		actual := strings.Join(values, ", ")
+		if actual != expected {
+			annotations["mismatching-header"] = header
+			return fmt.Errorf("The response header %s is '%s', expected '%s'", header, actual, expected)
 		}
 	}
 	return nil
 }
 
+// jsonValue returns the representation of a JSON value compared to the
+// expected value of a JSON assertion
+func jsonValue(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	result, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(result)
+}
+
+// verifyJSONAssertions verifies the JSON assertions of the healthcheck on the
+// response body. The failed assertion is added to the annotations.
+func (h *HTTPHealthcheck) verifyJSONAssertions(body []byte, annotations Annotations) error {
+	if len(h.Config.JSONAssertions) == 0 {
+		return nil
+	}
+	var document interface{}
+	err := json.Unmarshal(body, &document)
+	if err != nil {
+		return errors.Wrap(err, "Fail to parse the response body as JSON")
+	}
+	for _, assertion := range h.Config.JSONAssertions {
+		path, err := jp.ParseString(assertion.Path)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid JSON path %s", assertion.Path)
+		}
+		values := path.Get(document)
+		if len(values) == 0 {
+			msg := fmt.Sprintf("json path %s not found, expected '%s'", assertion.Path, assertion.Expected)
+			annotations["json-assertion"] = msg
+			return errors.New(msg)
+		}
+		for _, value := range values {
+			actual := jsonValue(value)
+			if actual != assertion.Expected {
+				msg := fmt.Sprintf("json path %s = '%s', expected '%s'", assertion.Path, actual, assertion.Expected)
+				annotations["json-assertion"] = msg
+				return errors.New(msg)
+			}
+		}
+	}
+	return nil
+}
+
+// verifyJSONSchema validates the response body using the JSON schema of the
+// healthcheck. The validation errors are added to the annotations.
+func (h *HTTPHealthcheck) verifyJSONSchema(body []byte, annotations Annotations) error {
+	if h.jsonSchema == nil {
+		return nil
+	}
+	var document interface{}
+	err := json.Unmarshal(body, &document)
+	if err != nil {
+		return errors.Wrap(err, "Fail to parse the response body as JSON")
+	}
+	validationErrors := validateJSONSchema(h.jsonSchema, document)
+	if len(validationErrors) != 0 {
+		annotations["json-schema-errors"] = strings.Join(validationErrors, "; ")
+		return fmt.Errorf("The response body does not match the JSON schema: %s", validationErrors[0])
+	}
+	return nil
+}
+
+// cacheHeaders the response headers related to caching
+var cacheHeaders = []string{"Cache-Control", "ETag", "Age", "X-Cache", "Expires"}
+
+// cacheDirectiveSeconds parses the value of a max-age or s-maxage
+// directive. Invalid values are considered as 0 (the response is stale).
+func cacheDirectiveSeconds(directive string) int {
+	seconds, err := strconv.Atoi(directive[strings.Index(directive, "=")+1:])
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return seconds
+}
+
+// isCacheable returns true if the response can be stored and served fresh
+// by a shared cache. s-maxage overrides max-age, which overrides Expires.
+// no-cache responses can be stored (and revalidated) so they are cacheable
+// if they are fresh.
+func isCacheable(header http.Header) bool {
+	maxAge := -1
+	sharedMaxAge := -1
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store" || directive == "private":
+			return false
+		case strings.HasPrefix(directive, "s-maxage="):
+			sharedMaxAge = cacheDirectiveSeconds(directive)
+		case strings.HasPrefix(directive, "max-age="):
+			maxAge = cacheDirectiveSeconds(directive)
+		}
+	}
+	if sharedMaxAge >= 0 {
+		return sharedMaxAge > 0
+	}
+	if maxAge >= 0 {
+		return maxAge > 0
+	}
+	// invalid dates, like 0, are in the past
+	expires, err := http.ParseTime(header.Get("Expires"))
+	if err != nil {
+		return false
+	}
+	now := time.Now()
+	if date, err := http.ParseTime(header.Get("Date")); err == nil {
+		now = date
+	}
+	return expires.After(now)
+}
+
+// isCacheHit returns true if the response was served from a cache
+func isCacheHit(header http.Header) bool {
+	if strings.Contains(strings.ToUpper(header.Get("X-Cache")), "HIT") {
+		return true
+	}
+	age, err := strconv.Atoi(header.Get("Age"))
+	return err == nil && age > 0
+}
+
+// verifyCacheHeaders verifies the caching headers of the response, depending
+// of the healthcheck configuration. The headers are added to the annotations.
+func (h *HTTPHealthcheck) verifyCacheHeaders(response *http.Response, annotations Annotations) error {
+	if !h.Config.ExpectCacheable && !h.Config.ExpectCacheHit && !h.Config.ExpectETag {
+		return nil
+	}
+	for _, header := range cacheHeaders {
+		if value := response.Header.Get(header); value != "" {
+			annotations[strings.ToLower(header)] = value
+		}
+	}
+	if h.Config.ExpectCacheable && !isCacheable(response.Header) {
+		return fmt.Errorf("The response is not cacheable (Cache-Control: '%s')", response.Header.Get("Cache-Control"))
+	}
+	if h.Config.ExpectCacheHit && !isCacheHit(response.Header) {
+		return fmt.Errorf("The response is not a cache hit (X-Cache: '%s', Age: '%s')", response.Header.Get("X-Cache"), response.Header.Get("Age"))
+	}
+	if h.Config.ExpectETag && response.Header.Get("ETag") == "" {
+		return errors.New("The response has no ETag header")
+	}
+	return nil
+}
+
 // NewHTTPHealthcheck creates a HTTP healthcheck from a logger and a configuration
 func NewHTTPHealthcheck(logger *zap.Logger, config *HTTPHealthcheckConfiguration) *HTTPHealthcheck {
 	return &HTTPHealthcheck{
@@ -275,15 +974,29 @@ func NewHTTPHealthcheck(logger *zap.Logger, config *HTTPHealthcheckConfiguration
 	}
 }
 
-// MarshalJSON marshal to json a dns healthcheck
+// MarshalJSON marshal to json an HTTP healthcheck. The credentials are
+// redacted and the inline private key is omitted.
 func (h *HTTPHealthcheck) MarshalJSON() ([]byte, error) {
-	return json.Marshal(h.Config)
+	config := h.Config.DeepCopy()
+	if config.BasicAuth != nil {
+		config.BasicAuth.Password = redactString(config.BasicAuth.Password)
+	}
+	config.BearerToken = redactString(config.BearerToken)
+	config.Headers = redactHeaders(config.Headers)
+	// the private key is never returned
+	config.KeyPEM = ""
+	return json.Marshal(config)
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HTTPHealthcheckConfiguration) DeepCopyInto(out *HTTPHealthcheckConfiguration) {
 	*out = *in
 	in.Base.DeepCopyInto(&out.Base)
+	if in.BasicAuth != nil {
+		in, out := &in.BasicAuth, &out.BasicAuth
+		*out = new(BasicAuth)
+		**out = **in
+	}
 	if in.ValidStatus != nil {
 		in, out := &in.ValidStatus, &out.ValidStatus
 		*out = make([]uint, len(*in))
@@ -296,6 +1009,13 @@ func (in *HTTPHealthcheckConfiguration) DeepCopyInto(out *HTTPHealthcheckConfigu
 			(*out)[key] = val
 		}
 	}
+	if in.Query != nil {
+		in, out := &in.Query, &out.Query
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.SourceIP != nil {
 		in, out := &in.SourceIP, &out.SourceIP
 		*out = make(IP, len(*in))
@@ -308,6 +1028,32 @@ func (in *HTTPHealthcheckConfiguration) DeepCopyInto(out *HTTPHealthcheckConfigu
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ForbiddenBodyRegexp != nil {
+		in, out := &in.ForbiddenBodyRegexp, &out.ForbiddenBodyRegexp
+		*out = make([]Regexp, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BodyRegexpNegative != nil {
+		in, out := &in.BodyRegexpNegative, &out.BodyRegexpNegative
+		*out = make([]Regexp, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ResponseHeaders != nil {
+		in, out := &in.ResponseHeaders, &out.ResponseHeaders
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.JSONAssertions != nil {
+		in, out := &in.JSONAssertions, &out.JSONAssertions
+		*out = make([]JSONAssertion, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPHealthcheckConfiguration.
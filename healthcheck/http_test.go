@@ -1,6 +1,9 @@
 package healthcheck
 
 import (
+	"context"
+	cryptotls "crypto/tls"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
@@ -12,6 +15,7 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
 
 	"github.com/appclacks/cabourotte/prometheus"
 )
@@ -60,6 +64,47 @@ func TestIssuccessfulFailure(t *testing.T) {
 	}
 }
 
+func TestIsSuccessfulAccept2xx(t *testing.T) {
+	h := HTTPHealthcheck{
+		Config: &HTTPHealthcheckConfiguration{
+			Accept2xx: true,
+		},
+	}
+	for _, status := range []int{200, 201, 204, 299} {
+		response := http.Response{StatusCode: status}
+		if !h.isSuccessful(&response) {
+			t.Fatalf("Invalid status check for %d", status)
+		}
+	}
+	for _, status := range []int{199, 301, 404, 500} {
+		response := http.Response{StatusCode: status}
+		if h.isSuccessful(&response) {
+			t.Fatalf("Invalid status check for %d", status)
+		}
+	}
+	h.Config.ValidStatus = []uint{404}
+	response := http.Response{StatusCode: 404}
+	if !h.isSuccessful(&response) {
+		t.Fatalf("Invalid status check")
+	}
+	config := HTTPHealthcheckConfiguration{
+		Base: Base{
+			Name:   "foo",
+			OneOff: true,
+		},
+		Port:    80,
+		Target:  "127.0.0.1",
+		Timeout: Duration(time.Second * 2),
+	}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("Was expecting an error without valid status codes")
+	}
+	config.Accept2xx = true
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Invalid configuration :\n%v", err)
+	}
+}
+
 func TestHTTPExecuteGetSuccess(t *testing.T) {
 	count := 0
 	headersOK := false
@@ -104,7 +149,7 @@ func TestHTTPExecuteGetSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	_, err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -119,6 +164,109 @@ func TestHTTPExecuteGetSuccess(t *testing.T) {
 	}
 }
 
+func TestHTTPExecuteShouldFail(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		port        uint
+		validStatus []uint
+		success     bool
+	}{
+		{port: uint(port), validStatus: []uint{200}, success: true},
+		{port: uint(port), validStatus: []uint{403}, success: false},
+		{port: 1, validStatus: []uint{200}, success: true},
+	}
+	for _, c := range cases {
+		h := HTTPHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &HTTPHealthcheckConfiguration{
+				ValidStatus: c.validStatus,
+				Port:        c.port,
+				Target:      "127.0.0.1",
+				Protocol:    HTTP,
+				Path:        "/",
+				ShouldFail:  true,
+				Timeout:     Duration(time.Second * 2),
+			},
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		annotations, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %+v:\n%v", c, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %+v", c)
+		}
+		if c.success && annotations["expected-error"] == "" {
+			t.Fatalf("Invalid annotations %v", annotations)
+		}
+	}
+}
+
+func TestHTTPExecuteCaptureBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("database unavailable"))
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		validStatus []uint
+		always      bool
+		success     bool
+		captured    bool
+	}{
+		{validStatus: []uint{200}, success: false, captured: true},
+		{validStatus: []uint{500}, success: true, captured: false},
+		{validStatus: []uint{500}, always: true, success: true, captured: true},
+	}
+	for _, c := range cases {
+		h := HTTPHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &HTTPHealthcheckConfiguration{
+				ValidStatus:       c.validStatus,
+				Port:              uint(port),
+				Target:            "127.0.0.1",
+				Protocol:          HTTP,
+				Path:              "/",
+				MaxBodySize:       8,
+				CaptureBody:       true,
+				CaptureBodyAlways: c.always,
+				Timeout:           Duration(time.Second * 2),
+			},
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		annotations, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %+v:\n%v", c, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %+v", c)
+		}
+		if c.captured && (annotations["response-body"] != "database" || annotations["response-status"] != "HTTP/1.1 500 Internal Server Error") {
+			t.Fatalf("Invalid annotations for %+v: %v", c, annotations)
+		}
+		if !c.captured && (annotations["response-body"] != "" || annotations["response-status"] != "") {
+			t.Fatalf("The response should not be captured for %+v: %v", c, annotations)
+		}
+	}
+}
+
 func TestHTTPExecuteRegexpSuccess(t *testing.T) {
 	count := 0
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -154,7 +302,7 @@ func TestHTTPExecuteRegexpSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	_, err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -198,7 +346,7 @@ func TestHTTPExecuteRegexpFailure(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	_, err = h.Execute(context.Background())
 	if err == nil {
 		t.Fatalf("Was expecting an error")
 	}
@@ -240,7 +388,7 @@ func TestHTTPv6ExecuteSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	_, err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -279,7 +427,7 @@ func TestHTTPExecuteFailure(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	_, err = h.Execute(context.Background())
 	if err == nil {
 		t.Fatalf("Was expecting an error")
 	}
@@ -407,7 +555,7 @@ func TestHTTPExecuteSourceIP(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	_, err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -470,7 +618,7 @@ func TestHTTPExecutePostSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	_, err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -546,7 +694,7 @@ func TestHTTPExecuteQueryParam(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	_, err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -560,3 +708,1024 @@ func TestHTTPExecuteQueryParam(t *testing.T) {
 		t.Fatal("Invalid body")
 	}
 }
+
+func TestHTTPExecuteRedirect(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same-host":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/cross-host":
+			// the Host header can be overridden by the healthcheck
+			addr := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+			_, port, _ := net.SplitHostPort(addr.String())
+			http.Redirect(w, r, fmt.Sprintf("http://localhost:%s/ok", port), http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		path       string
+		host       string
+		allowCross bool
+		success    bool
+	}{
+		{path: "/same-host", allowCross: false, success: true},
+		{path: "/same-host", host: "localhost", allowCross: false, success: true},
+		{path: "/cross-host", host: "localhost", allowCross: false, success: true},
+		{path: "/cross-host", allowCross: false, success: false},
+		{path: "/cross-host", allowCross: true, success: true},
+		{path: "/loop", allowCross: false, success: false},
+	}
+	for _, c := range cases {
+		h := HTTPHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &HTTPHealthcheckConfiguration{
+				Base: Base{
+					Name: "foo",
+				},
+				ValidStatus:            []uint{200},
+				Port:                   uint(port),
+				Target:                 "127.0.0.1",
+				Host:                   c.host,
+				Protocol:               HTTP,
+				Path:                   c.path,
+				Timeout:                Duration(time.Second * 2),
+				Redirect:               true,
+				MaxRedirects:           3,
+				AllowCrossHostRedirect: c.allowCross,
+			},
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		annotations, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %s:\n%v", c.path, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %s", c.path)
+		}
+		if !c.success && c.path == "/cross-host" && !strings.Contains(annotations["blocked-redirect"], "localhost") {
+			t.Fatalf("The blocked redirect was not recorded: %v", annotations)
+		}
+	}
+}
+
+func TestHTTPExecuteCacheHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cached":
+			w.Header().Set("Cache-Control", "public, max-age=60")
+			w.Header().Set("ETag", "\"abc\"")
+			w.Header().Set("X-Cache", "HIT")
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+			w.Header().Set("X-Cache", "MISS")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		path      string
+		cacheable bool
+		hit       bool
+		etag      bool
+		success   bool
+	}{
+		{path: "/cached", cacheable: true, hit: true, etag: true, success: true},
+		{path: "/private", cacheable: true, success: false},
+		{path: "/private", hit: true, success: false},
+		{path: "/private", etag: true, success: false},
+		{path: "/none", success: true},
+	}
+	for _, c := range cases {
+		h := HTTPHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &HTTPHealthcheckConfiguration{
+				Base: Base{
+					Name: "foo",
+				},
+				ValidStatus:     []uint{200},
+				Port:            uint(port),
+				Target:          "127.0.0.1",
+				Protocol:        HTTP,
+				Path:            c.path,
+				Timeout:         Duration(time.Second * 2),
+				ExpectCacheable: c.cacheable,
+				ExpectCacheHit:  c.hit,
+				ExpectETag:      c.etag,
+			},
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		annotations, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %s:\n%v", c.path, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %s", c.path)
+		}
+		if c.path == "/cached" && annotations["x-cache"] != "HIT" {
+			t.Fatalf("The cache headers were not added to the annotations: %v", annotations)
+		}
+	}
+}
+
+func TestIsCacheable(t *testing.T) {
+	date := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	future := date.Add(time.Hour).Format(http.TimeFormat)
+	past := date.Add(-time.Hour).Format(http.TimeFormat)
+	cases := []struct {
+		headers   map[string]string
+		cacheable bool
+	}{
+		{headers: map[string]string{}, cacheable: false},
+		{headers: map[string]string{"Cache-Control": "public, max-age=60"}, cacheable: true},
+		{headers: map[string]string{"Cache-Control": "s-maxage=60"}, cacheable: true},
+		{headers: map[string]string{"Cache-Control": "max-age=60, s-maxage=0"}, cacheable: false},
+		{headers: map[string]string{"Cache-Control": "private, max-age=60"}, cacheable: false},
+		{headers: map[string]string{"Cache-Control": "no-store, max-age=60"}, cacheable: false},
+		{headers: map[string]string{"Cache-Control": "no-cache, max-age=60"}, cacheable: true},
+		{headers: map[string]string{"Cache-Control": "no-cache"}, cacheable: false},
+		{headers: map[string]string{"Cache-Control": "max-age=foo"}, cacheable: false},
+		{headers: map[string]string{"Cache-Control": "max-age=0", "Date": date.Format(http.TimeFormat), "Expires": future}, cacheable: false},
+		{headers: map[string]string{"Cache-Control": "max-age=-1", "Date": date.Format(http.TimeFormat), "Expires": future}, cacheable: false},
+		{headers: map[string]string{"Date": date.Format(http.TimeFormat), "Expires": future}, cacheable: true},
+		{headers: map[string]string{"Date": date.Format(http.TimeFormat), "Expires": past}, cacheable: false},
+		{headers: map[string]string{"Expires": time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)}, cacheable: true},
+		{headers: map[string]string{"Expires": past}, cacheable: false},
+		{headers: map[string]string{"Expires": "0"}, cacheable: false},
+	}
+	for _, c := range cases {
+		header := http.Header{}
+		for k, v := range c.headers {
+			header.Set(k, v)
+		}
+		if isCacheable(header) != c.cacheable {
+			t.Fatalf("Invalid cacheable result for %v, expected %t", c.headers, c.cacheable)
+		}
+	}
+}
+
+func TestHTTPExecuteJSONAssertions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"status": "degraded", "replicas": 3, "components": [{"name": "db", "up": true}]}`))
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		assertion JSONAssertion
+		success   bool
+		message   string
+	}{
+		{assertion: JSONAssertion{Path: "$.status", Expected: "degraded"}, success: true},
+		{assertion: JSONAssertion{Path: "$.replicas", Expected: "3"}, success: true},
+		{assertion: JSONAssertion{Path: "$.components[0].up", Expected: "true"}, success: true},
+		{assertion: JSONAssertion{Path: "$.status", Expected: "ok"}, success: false, message: "json path $.status = 'degraded', expected 'ok'"},
+		{assertion: JSONAssertion{Path: "$.missing", Expected: "ok"}, success: false, message: "json path $.missing not found, expected 'ok'"},
+	}
+	for _, c := range cases {
+		h := HTTPHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &HTTPHealthcheckConfiguration{
+				Base: Base{
+					Name:   "foo",
+					OneOff: true,
+				},
+				ValidStatus:    []uint{200},
+				Port:           uint(port),
+				Target:         "127.0.0.1",
+				Protocol:       HTTP,
+				Timeout:        Duration(time.Second * 2),
+				JSONAssertions: []JSONAssertion{c.assertion},
+			},
+		}
+		err = h.Config.Validate()
+		if err != nil {
+			t.Fatalf("Invalid configuration :\n%v", err)
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		annotations, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %s:\n%v", c.assertion.Path, err)
+		}
+		if !c.success {
+			if err == nil {
+				t.Fatalf("Was expecting an error for %s", c.assertion.Path)
+			}
+			if annotations["json-assertion"] != c.message {
+				t.Fatalf("Invalid annotation for %s: %s", c.assertion.Path, annotations["json-assertion"])
+			}
+		}
+	}
+}
+
+func TestHTTPValidateJSONAssertions(t *testing.T) {
+	config := HTTPHealthcheckConfiguration{
+		Base: Base{
+			Name:   "foo",
+			OneOff: true,
+		},
+		ValidStatus:    []uint{200},
+		Port:           80,
+		Target:         "127.0.0.1",
+		Timeout:        Duration(time.Second * 2),
+		JSONAssertions: []JSONAssertion{{Path: "$.status[", Expected: "ok"}},
+	}
+	err := config.Validate()
+	if err == nil {
+		t.Fatalf("Was expecting an error for a malformed JSON path")
+	}
+}
+
+func TestHTTPExecuteMaxResponseTime(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := HTTPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &HTTPHealthcheckConfiguration{
+			Base: Base{
+				Name: "foo",
+			},
+			ValidStatus:     []uint{200},
+			Port:            uint(port),
+			Target:          "127.0.0.1",
+			Protocol:        HTTP,
+			Timeout:         Duration(time.Second * 2),
+			MaxResponseTime: Duration(time.Millisecond * 50),
+		},
+	}
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	annotations, err := h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("Was expecting an error because the response time is too high")
+	}
+	if annotations["max-response-time"] != "50ms" || annotations["response-time"] == "" {
+		t.Fatalf("Invalid annotations %v", annotations)
+	}
+	h.Config.MaxResponseTime = Duration(time.Second)
+	_, err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+}
+
+func TestHTTPExecuteResponseHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		headers  map[string]string
+		success  bool
+		mismatch string
+	}{
+		{headers: map[string]string{"Content-Type": "application/json", "cache-control": ""}, success: true},
+		{headers: map[string]string{"Content-Type": "text/html"}, success: false, mismatch: "Content-Type"},
+		{headers: map[string]string{"X-Request-Id": ""}, success: false, mismatch: "X-Request-Id"},
+	}
+	for _, c := range cases {
+		h := HTTPHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &HTTPHealthcheckConfiguration{
+				Base: Base{
+					Name: "foo",
+				},
+				ValidStatus:     []uint{200},
+				Port:            uint(port),
+				Target:          "127.0.0.1",
+				Protocol:        HTTP,
+				Timeout:         Duration(time.Second * 2),
+				ResponseHeaders: c.headers,
+			},
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		annotations, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %v:\n%v", c.headers, err)
+		}
+		if !c.success {
+			if err == nil {
+				t.Fatalf("Was expecting an error for %v", c.headers)
+			}
+			if annotations["mismatching-header"] != c.mismatch {
+				t.Fatalf("Invalid annotations for %v: %v", c.headers, annotations)
+			}
+		}
+	}
+}
+
+func TestHTTPExecuteRetries(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := HTTPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &HTTPHealthcheckConfiguration{
+			Base: Base{
+				Name: "foo",
+			},
+			ValidStatus:   []uint{200},
+			Port:          uint(port),
+			Target:        "127.0.0.1",
+			Protocol:      HTTP,
+			Timeout:       Duration(time.Second * 2),
+			Retries:       1,
+			RetryInterval: Duration(time.Millisecond * 10),
+		},
+	}
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	annotations, err := h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("Was expecting an error because all attempts failed")
+	}
+	if annotations["attempts"] != "2" {
+		t.Fatalf("Invalid annotations %v", annotations)
+	}
+	requests = 0
+	h.Config.Retries = 2
+	annotations, err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	if annotations["attempts"] != "3" || requests != 3 {
+		t.Fatalf("Invalid annotations %v", annotations)
+	}
+}
+
+func TestHTTPExecuteForbiddenBodyRegexp(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte("<h1>Internal Server Error</h1>"))
+		if err != nil {
+			t.Fatalf("Error writing :\n%v", err)
+		}
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := HTTPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &HTTPHealthcheckConfiguration{
+			ValidStatus: []uint{200},
+			Port:        uint(port),
+			Target:      "127.0.0.1",
+			ForbiddenBodyRegexp: []Regexp{
+				Regexp(*regexp.MustCompile("Exception")),
+			},
+			Protocol: HTTP,
+			Path:     "/",
+			Timeout:  Duration(time.Second * 2),
+		},
+	}
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	_, err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	h.Config.ForbiddenBodyRegexp = append(h.Config.ForbiddenBodyRegexp, Regexp(*regexp.MustCompile("Internal Server Error")))
+	annotations, err := h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("Was expecting an error because the body matches a forbidden regexp")
+	}
+	if annotations["forbidden-body-regexp"] != "Internal Server Error" {
+		t.Fatalf("Invalid annotations %v", annotations)
+	}
+	// body-regexp-negative is an alias of forbidden-body-regexp
+	h.Config.ForbiddenBodyRegexp = nil
+	h.Config.BodyRegexpNegative = []Regexp{Regexp(*regexp.MustCompile("Server Error"))}
+	annotations, err = h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("Was expecting an error because the body matches a negative regexp")
+	}
+	if annotations["forbidden-body-regexp"] != "Server Error" {
+		t.Fatalf("Invalid annotations %v", annotations)
+	}
+}
+
+func TestHTTPExecuteAuthentication(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if ok && username == "foo" && password == "bar" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Header.Get("Authorization") == "Bearer my-token" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := HTTPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &HTTPHealthcheckConfiguration{
+			ValidStatus: []uint{200},
+			Port:        uint(port),
+			Target:      "127.0.0.1",
+			Protocol:    HTTP,
+			Path:        "/",
+			Timeout:     Duration(time.Second * 2),
+		},
+	}
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	_, err = h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("Was expecting an error because the request is not authenticated")
+	}
+	h.Config.BasicAuth = &BasicAuth{Username: "foo", Password: "bar"}
+	_, err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	h.Config.BasicAuth = nil
+	h.Config.BearerToken = "my-token"
+	_, err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+}
+
+func TestHTTPValidateAuthentication(t *testing.T) {
+	config := HTTPHealthcheckConfiguration{
+		Base: Base{
+			Name:   "foo",
+			OneOff: true,
+		},
+		ValidStatus: []uint{200},
+		Port:        80,
+		Target:      "127.0.0.1",
+		Timeout:     Duration(time.Second * 2),
+		BasicAuth:   &BasicAuth{Username: "foo", Password: "bar"},
+		BearerToken: "my-token",
+	}
+	err := config.Validate()
+	if err == nil {
+		t.Fatalf("Was expecting an error because both authentication modes are set")
+	}
+	config.BearerToken = ""
+	err = config.Validate()
+	if err != nil {
+		t.Fatalf("Invalid configuration\n%v", err)
+	}
+}
+
+func TestHTTPExecuteMaxBodySize(t *testing.T) {
+	// the write errors are ignored because the client can stop reading the
+	// body early
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(strings.Repeat("a", 5000) + "end"))
+		case "/large":
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(strings.Repeat("a", DefaultMaxBodyReadSize+1)))
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(strings.Repeat("a", 5000) + "end"))
+		}
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := HTTPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &HTTPHealthcheckConfiguration{
+			ValidStatus: []uint{200},
+			Port:        uint(port),
+			Target:      "127.0.0.1",
+			BodyRegexp: []Regexp{
+				Regexp(*regexp.MustCompile("end")),
+			},
+			Protocol: HTTP,
+			Path:     "/",
+			Timeout:  Duration(time.Second * 2),
+		},
+	}
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	// the body is not truncated for the verifications
+	_, err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	h.Config.Path = "/error"
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	_, err = h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("Was expecting an error because of the status")
+	}
+	if len(err.Error()) > DefaultMaxBodySize+100 || strings.Contains(err.Error(), "end") {
+		t.Fatalf("The error message should be truncated: %s", err.Error())
+	}
+	h.Config.MaxBodySize = 10000
+	_, err = h.Execute(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "end") {
+		t.Fatalf("The error message should contain the whole body: %v", err)
+	}
+	// the body is read up to the configured limit
+	h.Config.Path = "/"
+	h.Config.MaxBodyReadSize = 5003
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	_, err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	h.Config.MaxBodyReadSize = 1024
+	_, err = h.Execute(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "The response body is larger than 1024 bytes") {
+		t.Fatalf("Was expecting an error because the body is too large: %v", err)
+	}
+	h.Config.MaxBodyReadSize = 0
+	h.Config.Path = "/large"
+	// reading the large body can be slow with the race detector
+	h.Config.Timeout = Duration(time.Second * 30)
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	_, err = h.Execute(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "The response body is larger") {
+		t.Fatalf("Was expecting an error because the body is larger than the default limit: %v", err)
+	}
+	h.Config.Base = Base{Name: "foo", OneOff: true}
+	err = h.Config.Validate()
+	if err != nil {
+		t.Fatalf("Invalid configuration\n%v", err)
+	}
+	h.Config.MaxBodySize = -1
+	err = h.Config.Validate()
+	if err == nil {
+		t.Fatalf("Was expecting an error for a negative maximum body size")
+	}
+	h.Config.MaxBodySize = 0
+	h.Config.MaxBodyReadSize = -1
+	err = h.Config.Validate()
+	if err == nil {
+		t.Fatalf("Was expecting an error for a negative maximum body read size")
+	}
+}
+
+func TestHTTPExecuteBodyRegexps(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte("status: ok, errors: 0"))
+		if err != nil {
+			t.Fatalf("Error writing :\n%v", err)
+		}
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	config := &HTTPHealthcheckConfiguration{
+		ValidStatus: []uint{200},
+		Port:        uint(port),
+		Target:      "127.0.0.1",
+		BodyRegexp: []Regexp{
+			Regexp(*regexp.MustCompile("status: ok")),
+		},
+		ForbiddenBodyRegexp: []Regexp{
+			Regexp(*regexp.MustCompile("errors: [1-9]")),
+		},
+		Protocol: HTTP,
+		Path:     "/",
+		Timeout:  Duration(time.Second * 2),
+	}
+	copied := config.DeepCopy()
+	h := HTTPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: config,
+	}
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	_, err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	config.ForbiddenBodyRegexp[0] = Regexp(*regexp.MustCompile("errors: [0-9]"))
+	annotations, err := h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("Was expecting an error because the body matches a forbidden regexp")
+	}
+	if annotations["forbidden-body-regexp"] != "errors: [0-9]" {
+		t.Fatalf("Invalid annotations %v", annotations)
+	}
+	forbidden := regexp.Regexp(copied.ForbiddenBodyRegexp[0])
+	if forbidden.String() != "errors: [1-9]" {
+		t.Fatalf("The copied configuration was modified: %s", forbidden.String())
+	}
+	config.BodyRegexp[0] = Regexp(*regexp.MustCompile("status: ko"))
+	_, err = h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("Was expecting an error because the body does not match the regexp")
+	}
+}
+
+func TestHTTPExecuteUserAgent(t *testing.T) {
+	userAgent := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := HTTPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &HTTPHealthcheckConfiguration{
+			ValidStatus: []uint{200},
+			Port:        uint(port),
+			Target:      "127.0.0.1",
+			Protocol:    HTTP,
+			Path:        "/",
+			Timeout:     Duration(time.Second * 2),
+		},
+	}
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	cases := []struct {
+		headers   map[string]string
+		userAgent string
+		expected  string
+	}{
+		{expected: "Cabourotte"},
+		{headers: map[string]string{"User-Agent": "from-headers"}, expected: "from-headers"},
+		{userAgent: "custom", expected: "custom"},
+		{headers: map[string]string{"User-Agent": "from-headers"}, userAgent: "custom", expected: "custom"},
+	}
+	for _, c := range cases {
+		h.Config.Headers = c.headers
+		h.Config.UserAgent = c.userAgent
+		_, err = h.Execute(context.Background())
+		if err != nil {
+			t.Fatalf("healthcheck error :\n%v", err)
+		}
+		if userAgent != c.expected {
+			t.Fatalf("Invalid User-Agent\nexpected: %s\nactual: %s", c.expected, userAgent)
+		}
+	}
+}
+
+func TestHTTPExecuteHappyEyeballs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := HTTPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &HTTPHealthcheckConfiguration{
+			ValidStatus:   []uint{200},
+			Port:          uint(port),
+			Target:        "127.0.0.1",
+			Protocol:      HTTP,
+			Path:          "/",
+			Timeout:       Duration(time.Second * 2),
+			HappyEyeballs: true,
+		},
+	}
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	annotations, err := h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	if annotations["ip-family"] != "ipv4" {
+		t.Fatalf("Invalid annotations %v", annotations)
+	}
+	addr := &net.TCPAddr{IP: net.ParseIP("::1"), Port: 80}
+	if ipFamily(addr) != "ipv6" {
+		t.Fatalf("Invalid address family %s", ipFamily(addr))
+	}
+	h.Config.Base = Base{Name: "foo", OneOff: true}
+	h.Config.HappyEyeballsDelay = Duration(-time.Second)
+	if err := h.Config.Validate(); err == nil {
+		t.Fatalf("Was expecting an error for a negative happy eyeballs delay")
+	}
+}
+
+func TestHTTPExecuteHappyEyeballsFallback(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	// the IPv6 address is in the discard prefix, the connections to it
+	// never succeed
+	server := startDNSServer(t, map[string][]dnsRecord{
+		"dual.cabourotte.test.": {
+			{rtype: dnsmessage.TypeAAAA, body: &dnsmessage.AAAAResource{AAAA: [16]byte{0: 1, 15: 1}}},
+			{rtype: dnsmessage.TypeA, body: &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}}},
+		},
+	})
+	h := HTTPHealthcheck{
+		Logger:   zap.NewExample(),
+		Resolver: NewResolver(&ResolverConfiguration{Server: server}),
+		Config: &HTTPHealthcheckConfiguration{
+			ValidStatus:   []uint{200},
+			Port:          uint(port),
+			Target:        "dual.cabourotte.test",
+			Protocol:      HTTP,
+			Path:          "/",
+			Timeout:       Duration(time.Second * 4),
+			HappyEyeballs: true,
+		},
+	}
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	start := time.Now()
+	annotations, err := h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	if annotations["ip-family"] != "ipv4" {
+		t.Fatalf("Invalid annotations %v", annotations)
+	}
+	// the IPv4 connection starts after the head start, without waiting
+	// for the IPv6 connection to fail
+	if duration := time.Since(start); duration > time.Second {
+		t.Fatalf("The fallback to IPv4 is too slow: %s", duration)
+	}
+}
+
+func TestHTTPExecuteTLSVersions(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.TLS = &cryptotls.Config{MaxVersion: cryptotls.VersionTLS12}
+	ts.StartTLS()
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		minVersion             string
+		shouldFail             bool
+		expectHandshakeFailure bool
+		success                bool
+	}{
+		{success: true},
+		{minVersion: "1.3", success: false},
+		{minVersion: "1.3", shouldFail: true, success: true},
+		{minVersion: "1.2", shouldFail: true, success: false},
+		{minVersion: "1.3", expectHandshakeFailure: true, success: true},
+	}
+	for _, c := range cases {
+		h := HTTPHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &HTTPHealthcheckConfiguration{
+				ValidStatus:            []uint{200},
+				Port:                   uint(port),
+				Target:                 "127.0.0.1",
+				Protocol:               HTTPS,
+				Path:                   "/",
+				Insecure:               true,
+				MinTLSVersion:          c.minVersion,
+				ShouldFail:             c.shouldFail,
+				ExpectHandshakeFailure: c.expectHandshakeFailure,
+				Timeout:                Duration(time.Second * 2),
+			},
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		annotations, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %+v:\n%v", c, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %+v", c)
+		}
+		shouldFail := c.shouldFail || c.expectHandshakeFailure
+		if c.success && !shouldFail && annotations["tls-version"] != "1.2" {
+			t.Fatalf("Invalid annotations %v", annotations)
+		}
+		if c.success && shouldFail && annotations["handshake-error"] == "" {
+			t.Fatalf("Invalid annotations %v", annotations)
+		}
+	}
+}
+
+func TestHTTPExecuteTimings(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+		ValidStatus: []uint{200},
+		Port:        uint(port),
+		Target:      "localhost",
+		Protocol:    HTTPS,
+		Path:        "/",
+		Insecure:    true,
+		Timeout:     Duration(time.Second * 2),
+	})
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	ctx, executionMetrics := WithExecutionMetrics(context.Background())
+	annotations, err := h.Execute(ctx)
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	metrics := executionMetrics()
+	for metric, annotation := range timingsAnnotations {
+		if _, ok := metrics[metric]; !ok {
+			t.Fatalf("The metric %s is missing: %v", metric, metrics)
+		}
+		if annotations[annotation] == "" {
+			t.Fatalf("The annotation %s is missing: %v", annotation, annotations)
+		}
+	}
+}
+
+func TestHTTPExecuteJSONSchema(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"status": "down", "replicas": 3}`))
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		schema  string
+		success bool
+		message string
+	}{
+		{schema: `{"type": "object", "required": ["status"]}`, success: true},
+		{
+			schema:  `{"properties": {"status": {"const": "ok"}, "replicas": {"maximum": 2}}}`,
+			success: false,
+			message: `#/replicas: must be <= 2 but found 3; #/status: value must be "ok"`,
+		},
+	}
+	for _, c := range cases {
+		h := HTTPHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &HTTPHealthcheckConfiguration{
+				Base: Base{
+					Name:   "foo",
+					OneOff: true,
+				},
+				ValidStatus: []uint{200},
+				Port:        uint(port),
+				Target:      "127.0.0.1",
+				Protocol:    HTTP,
+				Timeout:     Duration(time.Second * 2),
+				JSONSchema:  c.schema,
+			},
+		}
+		err = h.Config.Validate()
+		if err != nil {
+			t.Fatalf("Invalid configuration :\n%v", err)
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		annotations, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %s:\n%v", c.schema, err)
+		}
+		if !c.success {
+			if err == nil {
+				t.Fatalf("Was expecting an error for %s", c.schema)
+			}
+			if annotations["json-schema-errors"] != c.message {
+				t.Fatalf("Invalid annotation for %s: %s", c.schema, annotations["json-schema-errors"])
+			}
+		}
+	}
+	config := HTTPHealthcheckConfiguration{
+		Base: Base{
+			Name:   "foo",
+			OneOff: true,
+		},
+		ValidStatus: []uint{200},
+		Port:        80,
+		Target:      "127.0.0.1",
+		Timeout:     Duration(time.Second * 2),
+		JSONSchema:  `{"type": 1}`,
+	}
+	err = config.Validate()
+	if err == nil {
+		t.Fatalf("Was expecting an error for an invalid JSON schema")
+	}
+}
@@ -0,0 +1,84 @@
+package healthcheck
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// jsonSchemaURL the URL of the JSON schemas of the healthchecks, used to
+// resolve their references
+const jsonSchemaURL = "cabourotte://healthcheck.json"
+
+// loadJSONSchema returns the content of a JSON schema, which is either the
+// schema itself or the path of a file containing it
+func loadJSONSchema(schema string) ([]byte, error) {
+	trimmed := strings.TrimSpace(schema)
+	if strings.HasPrefix(trimmed, "{") {
+		return []byte(trimmed), nil
+	}
+	content, err := os.ReadFile(trimmed)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the JSON schema file %s", trimmed)
+	}
+	return content, nil
+}
+
+// compileJSONSchema loads and compiles a JSON schema. The schema is
+// interpreted using the draft 7 if it does not set $schema. References
+// should target the schema itself (#/definitions/name for example).
+func compileJSONSchema(schema string) (*jsonschema.Schema, error) {
+	content, err := loadJSONSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	compiler.LoadURL = func(url string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("The reference %s is not allowed", url)
+	}
+	err = compiler.AddResource(jsonSchemaURL, bytes.NewReader(content))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to parse the JSON schema")
+	}
+	result, err := compiler.Compile(jsonSchemaURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid JSON schema")
+	}
+	return result, nil
+}
+
+// validationErrors returns the messages of the errors which caused a JSON
+// schema validation failure
+func validationErrors(err *jsonschema.ValidationError) []string {
+	if len(err.Causes) == 0 {
+		return []string{fmt.Sprintf("#%s: %s", err.InstanceLocation, err.Message)}
+	}
+	result := []string{}
+	for _, cause := range err.Causes {
+		result = append(result, validationErrors(cause)...)
+	}
+	return result
+}
+
+// validateJSONSchema validates a JSON document and returns the validation
+// errors, sorted
+func validateJSONSchema(schema *jsonschema.Schema, document interface{}) []string {
+	err := schema.Validate(document)
+	if err == nil {
+		return []string{}
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []string{err.Error()}
+	}
+	result := validationErrors(validationErr)
+	sort.Strings(result)
+	return result
+}
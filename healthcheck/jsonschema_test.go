@@ -0,0 +1,172 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testJSONSchema = `{
+  "type": "object",
+  "required": ["status", "replicas"],
+  "properties": {
+    "status": {"enum": ["ok", "degraded"]},
+    "replicas": {"type": "integer", "minimum": 1},
+    "version": {"type": "string", "pattern": "^v[0-9]+$"},
+    "components": {
+      "type": "array",
+      "minItems": 1,
+      "items": {"$ref": "#/definitions/component"}
+    }
+  },
+  "additionalProperties": false,
+  "definitions": {
+    "component": {
+      "type": "object",
+      "required": ["name"],
+      "properties": {
+        "name": {"type": "string", "minLength": 1},
+        "up": {"type": "boolean"}
+      }
+    }
+  }
+}`
+
+func TestJSONSchemaValidate(t *testing.T) {
+	schema, err := compileJSONSchema(testJSONSchema)
+	if err != nil {
+		t.Fatalf("Fail to compile the schema\n%v", err)
+	}
+	cases := []struct {
+		document string
+		errors   []string
+	}{
+		{
+			document: `{"status": "ok", "replicas": 3, "version": "v2", "components": [{"name": "db", "up": true}]}`,
+			errors:   []string{},
+		},
+		{
+			document: `{"status": "down", "replicas": 0}`,
+			errors: []string{
+				"#/replicas: must be >= 1 but found 0",
+				`#/status: value must be one of "ok", "degraded"`,
+			},
+		},
+		{
+			document: `{"status": "ok", "replicas": 1.5, "version": "2", "extra": true}`,
+			errors: []string{
+				"#/replicas: expected integer, but got number",
+				"#/version: does not match pattern '^v[0-9]+$'",
+				"#: additionalProperties 'extra' not allowed",
+			},
+		},
+		{
+			document: `{"status": "ok", "replicas": 1, "components": [{"name": ""}, {"up": "yes"}]}`,
+			errors: []string{
+				"#/components/0/name: length must be >= 1, but got 0",
+				"#/components/1/up: expected boolean, but got string",
+				"#/components/1: missing properties: 'name'",
+			},
+		},
+		{
+			document: `[]`,
+			errors: []string{
+				"#: expected object, but got array",
+			},
+		},
+	}
+	for _, c := range cases {
+		var document interface{}
+		err := json.Unmarshal([]byte(c.document), &document)
+		if err != nil {
+			t.Fatalf("Invalid document %s\n%v", c.document, err)
+		}
+		result := validateJSONSchema(schema, document)
+		if strings.Join(result, "\n") != strings.Join(c.errors, "\n") {
+			t.Fatalf("Invalid errors for %s\n%s", c.document, strings.Join(result, "\n"))
+		}
+	}
+}
+
+func TestJSONSchemaCombinators(t *testing.T) {
+	schema, err := compileJSONSchema(`{"oneOf": [{"type": "string"}, {"type": "number", "multipleOf": 2}], "not": {"const": "forbidden"}}`)
+	if err != nil {
+		t.Fatalf("Fail to compile the schema\n%v", err)
+	}
+	cases := map[string]bool{
+		`"foo"`:       true,
+		`4`:           true,
+		`3`:           false,
+		`null`:        false,
+		`"forbidden"`: false,
+	}
+	for document, valid := range cases {
+		var value interface{}
+		err := json.Unmarshal([]byte(document), &value)
+		if err != nil {
+			t.Fatalf("Invalid document %s\n%v", document, err)
+		}
+		result := validateJSONSchema(schema, value)
+		if valid != (len(result) == 0) {
+			t.Fatalf("Invalid validation result for %s: %v", document, result)
+		}
+	}
+}
+
+func TestJSONSchemaConditionals(t *testing.T) {
+	schema, err := compileJSONSchema(`{"if": {"properties": {"status": {"const": "degraded"}}}, "then": {"required": ["reason"]}, "else": {"not": {"required": ["reason"]}}, "dependencies": {"replicas": ["status"]}}`)
+	if err != nil {
+		t.Fatalf("Fail to compile the schema\n%v", err)
+	}
+	cases := map[string]bool{
+		`{"status": "ok"}`:                        true,
+		`{"status": "ok", "reason": "foo"}`:       false,
+		`{"status": "degraded", "reason": "foo"}`: true,
+		`{"status": "degraded"}`:                  false,
+		`{"status": "ok", "replicas": 1}`:         true,
+		`{"replicas": 1}`:                         false,
+	}
+	for document, valid := range cases {
+		var value interface{}
+		err := json.Unmarshal([]byte(document), &value)
+		if err != nil {
+			t.Fatalf("Invalid document %s\n%v", document, err)
+		}
+		result := validateJSONSchema(schema, value)
+		if valid != (len(result) == 0) {
+			t.Fatalf("Invalid validation result for %s: %v", document, result)
+		}
+	}
+}
+
+func TestCompileJSONSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	err := os.WriteFile(path, []byte(testJSONSchema), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the schema\n%v", err)
+	}
+	_, err = compileJSONSchema(path)
+	if err != nil {
+		t.Fatalf("Fail to compile the schema file\n%v", err)
+	}
+	invalid := []string{
+		`{"type": "foo"}`,
+		`{"minLength": -1}`,
+		`{"pattern": "("}`,
+		`{"properties": {"foo": 1}}`,
+		`{"items": {"$ref": "#/definitions/missing"}}`,
+		`{"$ref": "http://example.com/schema.json"}`,
+		`{"$ref": "/etc/passwd"}`,
+		`{"anyOf": []}`,
+		`{"type": "string"`,
+		filepath.Join(t.TempDir(), "missing.json"),
+	}
+	for _, schema := range invalid {
+		_, err := compileJSONSchema(schema)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", schema)
+		}
+	}
+}
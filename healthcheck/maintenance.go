@@ -0,0 +1,95 @@
+package healthcheck
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// StatusMaintenance the status label of the healthchecks executions during
+// a maintenance window in the metrics
+const StatusMaintenance = "maintenance"
+
+// MaintenanceWindow a time window during which the healthcheck results are
+// flagged as in maintenance. The window is either a fixed period (start and
+// end) or a recurring period (cron and duration).
+type MaintenanceWindow struct {
+	// Start the start of the window, in the RFC3339 format
+	Start *time.Time `json:"start,omitempty" yaml:"start,omitempty"`
+	// End the end of the window, in the RFC3339 format
+	End *time.Time `json:"end,omitempty" yaml:"end,omitempty"`
+	// Cron the cron expression starting the recurring window
+	Cron string `json:"cron,omitempty" yaml:"cron,omitempty"`
+	// Duration the duration of the recurring window
+	Duration Duration `json:"duration,omitempty" yaml:"duration,omitempty"`
+}
+
+// Validate validates the maintenance window
+func (w *MaintenanceWindow) Validate() error {
+	if w.Cron != "" {
+		if w.Start != nil || w.End != nil {
+			return errors.New("The maintenance window start and end can't be set with a cron expression")
+		}
+		if w.Duration <= 0 {
+			return errors.New("The maintenance window duration should be greater than 0")
+		}
+		if _, err := parseCron(w.Cron); err != nil {
+			return err
+		}
+		return nil
+	}
+	if w.Start == nil || w.End == nil {
+		return errors.New("The maintenance window start and end, or cron expression and duration, should be set")
+	}
+	if w.Duration != 0 {
+		return errors.New("The maintenance window duration can only be set with a cron expression")
+	}
+	if !w.End.After(*w.Start) {
+		return errors.New("The maintenance window end should be after its start")
+	}
+	return nil
+}
+
+// Active returns true if the given time is in the maintenance window
+func (w *MaintenanceWindow) Active(t time.Time) bool {
+	if w.Cron != "" {
+		schedule, err := parseCron(w.Cron)
+		if err != nil {
+			return false
+		}
+		// the window is active if it started during the last Duration.
+		// Next returns the zero time if the expression never matches.
+		next := schedule.Next(t.Add(-time.Duration(w.Duration)))
+		return !next.IsZero() && !next.After(t)
+	}
+	if w.Start == nil || w.End == nil {
+		return false
+	}
+	return !t.Before(*w.Start) && t.Before(*w.End)
+}
+
+// InMaintenance returns true if the given time is in one of the
+// healthcheck maintenance windows
+func (b *Base) InMaintenance(t time.Time) bool {
+	for i := range b.MaintenanceWindows {
+		if b.MaintenanceWindows[i].Active(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	if in.Start != nil {
+		in, out := &in.Start, &out.Start
+		t := **in
+		*out = &t
+	}
+	if in.End != nil {
+		in, out := &in.End, &out.End
+		t := **in
+		*out = &t
+	}
+}
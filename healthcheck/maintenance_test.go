@@ -0,0 +1,90 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindowValidate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	cases := []struct {
+		window MaintenanceWindow
+		valid  bool
+	}{
+		{window: MaintenanceWindow{Start: &start, End: &end}, valid: true},
+		{window: MaintenanceWindow{Start: &end, End: &start}, valid: false},
+		{window: MaintenanceWindow{Start: &start}, valid: false},
+		{window: MaintenanceWindow{Start: &start, End: &end, Duration: Duration(time.Hour)}, valid: false},
+		{window: MaintenanceWindow{Cron: "0 2 * * *", Duration: Duration(time.Hour)}, valid: true},
+		{window: MaintenanceWindow{Cron: "0 2 * * *"}, valid: false},
+		{window: MaintenanceWindow{Cron: "invalid", Duration: Duration(time.Hour)}, valid: false},
+		{window: MaintenanceWindow{Cron: "0 0 30 2 *", Duration: Duration(time.Hour)}, valid: false},
+		{window: MaintenanceWindow{Cron: "0 2 * * *", Duration: Duration(time.Hour), Start: &start}, valid: false},
+		{window: MaintenanceWindow{}, valid: false},
+	}
+	for _, c := range cases {
+		err := c.window.Validate()
+		if c.valid && err != nil {
+			t.Fatalf("The window should be valid %+v\n%v", c.window, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("The window should be invalid %+v", c.window)
+		}
+	}
+}
+
+func TestMaintenanceWindowActive(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	base := Base{
+		MaintenanceWindows: []MaintenanceWindow{
+			{Start: &start, End: &end},
+			{Cron: "0 2 * * *", Duration: Duration(30 * time.Minute)},
+		},
+	}
+	cases := []struct {
+		t      time.Time
+		active bool
+	}{
+		{t: start, active: true},
+		{t: start.Add(30 * time.Minute), active: true},
+		{t: end, active: false},
+		{t: start.Add(-time.Minute), active: false},
+		{t: time.Date(2024, 1, 2, 2, 0, 0, 0, time.Local), active: true},
+		{t: time.Date(2024, 1, 2, 2, 29, 0, 0, time.Local), active: true},
+		{t: time.Date(2024, 1, 2, 2, 30, 0, 0, time.Local), active: false},
+		{t: time.Date(2024, 1, 2, 1, 59, 0, 0, time.Local), active: false},
+	}
+	for _, c := range cases {
+		if base.InMaintenance(c.t) != c.active {
+			t.Fatalf("Invalid maintenance state for %s, expected %t", c.t, c.active)
+		}
+	}
+	never := MaintenanceWindow{Cron: "0 0 30 2 *", Duration: Duration(time.Hour)}
+	if never.Active(time.Now()) {
+		t.Fatalf("A window which never starts should not be active")
+	}
+}
+
+func TestNewResultInMaintenance(t *testing.T) {
+	start := time.Now().Add(-time.Minute)
+	end := time.Now().Add(time.Hour)
+	check := NewTCPHealthcheck(nil, &TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:               "foo",
+			MaintenanceWindows: []MaintenanceWindow{{Start: &start, End: &end}},
+		},
+	})
+	result := NewResult(check, 0, nil, nil)
+	if !result.InMaintenance {
+		t.Fatalf("The result should be in maintenance")
+	}
+	expected := start
+	copied := Base{}
+	check.Config.Base.DeepCopyInto(&copied)
+	*check.Config.Base.MaintenanceWindows[0].Start = end
+	if !copied.MaintenanceWindows[0].Start.Equal(expected) {
+		t.Fatalf("The maintenance windows were not copied")
+	}
+}
@@ -0,0 +1,298 @@
+package healthcheck
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+)
+
+// DefaultPostgresQuery the query executed by the PostgreSQL healthchecks if
+// not set
+const DefaultPostgresQuery = "SELECT 1"
+
+// DefaultPostgresPort the PostgreSQL port used if not set
+const DefaultPostgresPort = 5432
+
+// PostgresHealthcheckConfiguration defines a PostgreSQL healthcheck configuration
+type PostgresHealthcheckConfiguration struct {
+	Base `json:",inline" yaml:",inline"`
+	// DSN the connection string, as an alternative to the host, port, user,
+	// password, dbname and sslmode options
+	DSN      string `json:"dsn,omitempty"`
+	Host     string `json:"host,omitempty"`
+	Port     uint   `json:"port,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+	DBName   string `json:"dbname,omitempty"`
+	SSLMode  string `json:"sslmode,omitempty"`
+	// Query the query executed by the healthcheck (SELECT 1 if not set)
+	Query string `json:"query,omitempty"`
+	// Expected the expected value of the first column of the first row
+	// returned by the query
+	Expected string   `json:"expected,omitempty"`
+	Timeout  Duration `json:"timeout"`
+}
+
+// PostgresHealthcheck defines a PostgreSQL healthcheck
+type PostgresHealthcheck struct {
+	Logger    *zap.Logger
+	Resolver  *Resolver
+	Config    *PostgresHealthcheckConfiguration
+	URL       string
+	Connector *pq.Connector
+
+	Tick *time.Ticker
+	t    tomb.Tomb
+}
+
+// Validate validates the healthcheck configuration
+func (config *PostgresHealthcheckConfiguration) Validate() error {
+	if config.Base.Name == "" {
+		return errors.New("The healthcheck name is missing")
+	}
+	if err := config.Base.validate(); err != nil {
+		return err
+	}
+	if config.DSN == "" && config.Host == "" {
+		return errors.New("The healthcheck DSN or host is missing")
+	}
+	if config.DSN != "" && (config.Host != "" || config.Port != 0 || config.User != "" || config.Password != "" || config.DBName != "" || config.SSLMode != "") {
+		return errors.New("The healthcheck DSN can't be set alongside the connection options")
+	}
+	switch config.SSLMode {
+	case "", "disable", "require", "verify-ca", "verify-full":
+	default:
+		return fmt.Errorf("Invalid sslmode %s", config.SSLMode)
+	}
+	if config.Timeout == 0 {
+		return errors.New("The healthcheck timeout is missing")
+	}
+	if !config.Base.OneOff && config.Base.Cron == "" {
+		if config.Base.Interval < Duration(2*time.Second) {
+			return errors.New("The healthcheck interval should be greater than 2 second")
+		}
+		if config.Base.Interval < config.Timeout {
+			return errors.New("The healthcheck interval should be greater than the timeout")
+		}
+	}
+	return nil
+}
+
+// Base get the base configuration
+func (h *PostgresHealthcheck) Base() Base {
+	return h.Config.Base
+}
+
+// SetSource set the healthcheck source
+func (h *PostgresHealthcheck) SetSource(source string) {
+	h.Config.Base.Source = source
+}
+
+// SetResolver set the healthcheck resolver
+func (h *PostgresHealthcheck) SetResolver(resolver *Resolver) {
+	h.Resolver = resolver
+}
+
+// Summary returns an healthcheck summary
+func (h *PostgresHealthcheck) Summary() string {
+	summary := ""
+	if h.Config.Base.Description != "" {
+		summary = fmt.Sprintf("PostgreSQL healthcheck %s on %s", h.Config.Base.Description, h.URL)
+
+	} else {
+		summary = fmt.Sprintf("PostgreSQL healthcheck on %s", h.URL)
+	}
+
+	return summary
+}
+
+// port returns the configured port, or the default PostgreSQL port
+func (h *PostgresHealthcheck) port() uint {
+	if h.Config.Port == 0 {
+		return DefaultPostgresPort
+	}
+	return h.Config.Port
+}
+
+// dsn returns the connection string of the healthcheck
+func (h *PostgresHealthcheck) dsn() string {
+	if h.Config.DSN != "" {
+		return h.Config.DSN
+	}
+	dsn := url.URL{
+		Scheme: "postgres",
+		Host:   net.JoinHostPort(h.Config.Host, fmt.Sprintf("%d", h.port())),
+		Path:   "/" + h.Config.DBName,
+	}
+	if h.Config.User != "" {
+		dsn.User = url.UserPassword(h.Config.User, h.Config.Password)
+	}
+	if h.Config.SSLMode != "" {
+		dsn.RawQuery = url.Values{"sslmode": []string{h.Config.SSLMode}}.Encode()
+	}
+	return dsn.String()
+}
+
+// buildURL build the target URL for the PostgreSQL healthcheck. The
+// credentials are not part of the URL.
+func (h *PostgresHealthcheck) buildURL() {
+	if h.Config.DSN != "" {
+		h.URL = "dsn"
+		return
+	}
+	h.URL = net.JoinHostPort(h.Config.Host, fmt.Sprintf("%d", h.port()))
+}
+
+// postgresDialer a pq dialer using the healthcheck resolver
+type postgresDialer struct {
+	dial func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Dial connects to the address on the named network
+func (d postgresDialer) Dial(network, address string) (net.Conn, error) {
+	return d.dial(context.Background(), network, address)
+}
+
+// DialTimeout connects to the address on the named network with a timeout
+func (d postgresDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d.dial(ctx, network, address)
+}
+
+// DialContext connects to the address on the named network using the
+// provided context
+func (d postgresDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return d.dial(ctx, network, address)
+}
+
+// Initialize the healthcheck.
+func (h *PostgresHealthcheck) Initialize() error {
+	h.buildURL()
+	connector, err := pq.NewConnector(h.dsn())
+	if err != nil {
+		return errors.Wrapf(err, "Invalid PostgreSQL connection options")
+	}
+	connector.Dialer(postgresDialer{dial: h.Resolver.DialContext(&net.Dialer{})})
+	h.Connector = connector
+	return nil
+}
+
+// GetConfig get the config
+func (h *PostgresHealthcheck) GetConfig() interface{} {
+	return h.Config
+}
+
+// LogError logs an error with context
+func (h *PostgresHealthcheck) LogError(err error, message string) {
+	h.Logger.Error(err.Error(),
+		zap.String("extra", message),
+		zap.String("target", h.URL),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogDebug logs a message with context
+func (h *PostgresHealthcheck) LogDebug(message string) {
+	h.Logger.Debug(message,
+		zap.String("target", h.URL),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogInfo logs a message with context
+func (h *PostgresHealthcheck) LogInfo(message string) {
+	h.Logger.Info(message,
+		zap.String("target", h.URL),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// Execute executes an healthcheck on the given target
+func (h *PostgresHealthcheck) Execute(ctx context.Context) (Annotations, error) {
+	h.LogDebug("start executing healthcheck")
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
+	defer cancel()
+	db := sql.OpenDB(h.Connector)
+	// the connection is closed after each execution
+	defer db.Close()
+	db.SetMaxIdleConns(0)
+	query := h.Config.Query
+	if query == "" {
+		query = DefaultPostgresQuery
+	}
+	rows, err := db.QueryContext(timeoutCtx, query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "PostgreSQL query failed on %s", h.URL)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, errors.Wrapf(err, "PostgreSQL query failed on %s", h.URL)
+		}
+		if h.Config.Expected != "" {
+			return nil, fmt.Errorf("PostgreSQL query returned no row on %s", h.URL)
+		}
+		return nil, nil
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the PostgreSQL query columns on %s", h.URL)
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	err = rows.Scan(dest...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the PostgreSQL query result on %s", h.URL)
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	annotations := Annotations{"value": values[0].String}
+	if h.Config.Expected != "" && values[0].String != h.Config.Expected {
+		return annotations, fmt.Errorf("PostgreSQL query returned %s instead of %s on %s", values[0].String, h.Config.Expected, h.URL)
+	}
+	return annotations, nil
+}
+
+// NewPostgresHealthcheck creates a PostgreSQL healthcheck from a logger and a configuration
+func NewPostgresHealthcheck(logger *zap.Logger, config *PostgresHealthcheckConfiguration) *PostgresHealthcheck {
+	return &PostgresHealthcheck{
+		Logger: logger,
+		Config: config,
+	}
+}
+
+// MarshalJSON marshal to json a PostgreSQL healthcheck. The password is
+// redacted, including from the DSN.
+func (h *PostgresHealthcheck) MarshalJSON() ([]byte, error) {
+	config := h.Config.DeepCopy()
+	config.Password = redactString(config.Password)
+	config.DSN = redactDSN(config.DSN)
+	return json.Marshal(config)
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresHealthcheckConfiguration) DeepCopyInto(out *PostgresHealthcheckConfiguration) {
+	*out = *in
+	in.Base.DeepCopyInto(&out.Base)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresHealthcheckConfiguration.
+func (in *PostgresHealthcheckConfiguration) DeepCopy() *PostgresHealthcheckConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresHealthcheckConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,207 @@
+package healthcheck
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// postgresMessage builds a PostgreSQL backend message
+func postgresMessage(kind byte, payload []byte) []byte {
+	message := []byte{kind}
+	message = binary.BigEndian.AppendUint32(message, uint32(len(payload)+4))
+	return append(message, payload...)
+}
+
+// servePostgres answers the startup message and the simple queries of a
+// PostgreSQL client with a single text column containing value
+func servePostgres(conn net.Conn, value string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return
+	}
+	startup := make([]byte, binary.BigEndian.Uint32(header)-4)
+	if _, err := io.ReadFull(reader, startup); err != nil {
+		return
+	}
+	_, _ = conn.Write(postgresMessage('R', []byte{0, 0, 0, 0}))
+	_, _ = conn.Write(postgresMessage('Z', []byte{'I'}))
+	for {
+		kind, err := reader.ReadByte()
+		if err != nil {
+			return
+		}
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return
+		}
+		body := make([]byte, binary.BigEndian.Uint32(header)-4)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return
+		}
+		if kind != 'Q' {
+			return
+		}
+		// one text column named value
+		description := binary.BigEndian.AppendUint16(nil, 1)
+		description = append(description, []byte("value\x00")...)
+		description = binary.BigEndian.AppendUint32(description, 0)
+		description = binary.BigEndian.AppendUint16(description, 0)
+		description = binary.BigEndian.AppendUint32(description, 25)
+		description = binary.BigEndian.AppendUint16(description, 0xffff)
+		description = binary.BigEndian.AppendUint32(description, 0xffffffff)
+		description = binary.BigEndian.AppendUint16(description, 0)
+		_, _ = conn.Write(postgresMessage('T', description))
+		row := binary.BigEndian.AppendUint16(nil, 1)
+		row = binary.BigEndian.AppendUint32(row, uint32(len(value)))
+		row = append(row, []byte(value)...)
+		_, _ = conn.Write(postgresMessage('D', row))
+		_, _ = conn.Write(postgresMessage('C', []byte("SELECT 1\x00")))
+		_, _ = conn.Write(postgresMessage('Z', []byte{'I'}))
+	}
+}
+
+func TestPostgresExecute(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen :\n%v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go servePostgres(conn, "1")
+		}
+	}()
+	_, portStr, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Fail to get the server port :\n%v", err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		t.Fatalf("Fail to get the server port :\n%v", err)
+	}
+	cases := []struct {
+		expected string
+		success  bool
+	}{
+		{expected: "", success: true},
+		{expected: "1", success: true},
+		{expected: "2", success: false},
+	}
+	for _, c := range cases {
+		h := NewPostgresHealthcheck(zap.NewExample(), &PostgresHealthcheckConfiguration{
+			Base: Base{
+				Name: "postgres",
+			},
+			Host:     "127.0.0.1",
+			Port:     uint(port),
+			User:     "foo",
+			DBName:   "bar",
+			SSLMode:  "disable",
+			Expected: c.expected,
+			Timeout:  Duration(time.Second * 2),
+		})
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Fail to initialize the healthcheck :\n%v", err)
+		}
+		annotations, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error :\n%v", err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for the expected value %s", c.expected)
+		}
+		if annotations["value"] != "1" {
+			t.Fatalf("Invalid annotations %v", annotations)
+		}
+	}
+}
+
+func TestPostgresExecuteFailure(t *testing.T) {
+	h := NewPostgresHealthcheck(zap.NewExample(), &PostgresHealthcheckConfiguration{
+		Base: Base{
+			Name: "postgres",
+		},
+		DSN:     "postgres://127.0.0.1:1/foo?sslmode=disable",
+		Timeout: Duration(time.Second * 2),
+	})
+	err := h.Initialize()
+	if err != nil {
+		t.Fatalf("Fail to initialize the healthcheck :\n%v", err)
+	}
+	_, err = h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
+func TestPostgresValidate(t *testing.T) {
+	cases := []struct {
+		config  PostgresHealthcheckConfiguration
+		success bool
+	}{
+		{
+			config: PostgresHealthcheckConfiguration{
+				Base:    Base{Name: "foo", Interval: Duration(time.Second * 10)},
+				Host:    "127.0.0.1",
+				Timeout: Duration(time.Second * 2),
+			},
+			success: true,
+		},
+		{
+			config: PostgresHealthcheckConfiguration{
+				Base:    Base{Name: "foo", Interval: Duration(time.Second * 10)},
+				DSN:     "postgres://127.0.0.1/foo",
+				Timeout: Duration(time.Second * 2),
+			},
+			success: true,
+		},
+		{
+			config: PostgresHealthcheckConfiguration{
+				Base:    Base{Name: "foo", Interval: Duration(time.Second * 10)},
+				Timeout: Duration(time.Second * 2),
+			},
+			success: false,
+		},
+		{
+			config: PostgresHealthcheckConfiguration{
+				Base:    Base{Name: "foo", Interval: Duration(time.Second * 10)},
+				DSN:     "postgres://127.0.0.1/foo",
+				Host:    "127.0.0.1",
+				Timeout: Duration(time.Second * 2),
+			},
+			success: false,
+		},
+		{
+			config: PostgresHealthcheckConfiguration{
+				Base:    Base{Name: "foo", Interval: Duration(time.Second * 10)},
+				Host:    "127.0.0.1",
+				SSLMode: "invalid",
+				Timeout: Duration(time.Second * 2),
+			},
+			success: false,
+		},
+	}
+	for _, c := range cases {
+		err := c.config.Validate()
+		if c.success && err != nil {
+			t.Fatalf("Invalid configuration\n%v", err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %v", c.config)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package healthcheck
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redacted the value replacing the secrets in the healthchecks outputs
+const Redacted = "<redacted>"
+
+// dsnPasswordRegexp matches the password of a key/value connection string
+var dsnPasswordRegexp = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// redactString returns Redacted if the value is set
+func redactString(value string) string {
+	if value == "" {
+		return value
+	}
+	return Redacted
+}
+
+// redactHeaders returns a copy of the headers with the Authorization header
+// redacted
+func redactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	result := make(map[string]string, len(headers))
+	for key, value := range headers {
+		if strings.EqualFold(key, "Authorization") {
+			value = redactString(value)
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// redactDSN redacts the password of a connection string, in the URL or in
+// the key/value format
+func redactDSN(dsn string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsnPasswordRegexp.ReplaceAllString(dsn, "${1}"+Redacted)
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return Redacted
+	}
+	query := u.Query()
+	if query.Has("password") {
+		query.Set("password", "xxxxx")
+		u.RawQuery = query.Encode()
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,140 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ResolverConfiguration the configuration of the resolver shared by all
+// healthchecks
+type ResolverConfiguration struct {
+	// Hosts static hostname to IP address mappings
+	Hosts map[string]string `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	// Server the DNS server (host:port) used to resolve names not present in Hosts
+	Server string `json:"server,omitempty" yaml:"server,omitempty"`
+}
+
+// Validate validates the resolver configuration
+func (config *ResolverConfiguration) Validate() error {
+	for host, ip := range config.Hosts {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("Invalid IP address %s for host %s in the resolver configuration", ip, host)
+		}
+	}
+	if config.Server != "" {
+		_, _, err := net.SplitHostPort(config.Server)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid resolver server %s", config.Server)
+		}
+	}
+	return nil
+}
+
+// Resolver resolves the healthchecks targets using static hosts and an
+// optional DNS server
+type Resolver struct {
+	lock     sync.RWMutex
+	config   *ResolverConfiguration
+	resolver *net.Resolver
+}
+
+// NewResolver creates a new resolver from its configuration
+func NewResolver(config *ResolverConfiguration) *Resolver {
+	resolver := &Resolver{}
+	resolver.Update(config)
+	return resolver
+}
+
+// Update replaces the configuration of the resolver. The healthchecks
+// using the resolver use the new configuration for their next executions.
+func (r *Resolver) Update(config *ResolverConfiguration) {
+	var resolver *net.Resolver
+	if config.Server != "" {
+		server := config.Server
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				dialer := net.Dialer{}
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.config = config
+	r.resolver = resolver
+}
+
+// hosts returns the static hostname to IP address mappings
+func (r *Resolver) hosts() map[string]string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.config.Hosts
+}
+
+// DialContext returns a dial function using the resolver for the given dialer.
+// The dialer is used as is if the resolver is nil.
+func (r *Resolver) DialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	if r == nil {
+		return dialer.DialContext
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid address %s", address)
+		}
+		if ip, ok := r.hosts()[host]; ok {
+			address = net.JoinHostPort(ip, port)
+		}
+		// the dialer is copied because the resolver can be updated
+		// between two executions
+		resolverDialer := *dialer
+		if resolver := r.netResolver(); resolver != net.DefaultResolver {
+			resolverDialer.Resolver = resolver
+		}
+		return resolverDialer.DialContext(ctx, network, address)
+	}
+}
+
+// LookupIPAddr looks up the IP addresses of a host, using the resolver DNS
+// server if configured
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return r.netResolver().LookupIPAddr(ctx, host)
+}
+
+// LookupAddr performs a reverse lookup of an IP address. The static hosts
+// mapped to the address are returned if any, the resolver DNS server is
+// used otherwise.
+func (r *Resolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	if r != nil {
+		names := []string{}
+		for host, ip := range r.hosts() {
+			if net.ParseIP(ip).Equal(net.ParseIP(addr)) {
+				names = append(names, host)
+			}
+		}
+		if len(names) != 0 {
+			sort.Strings(names)
+			return names, nil
+		}
+	}
+	return r.netResolver().LookupAddr(ctx, addr)
+}
+
+// netResolver returns the resolver used for DNS lookups
+func (r *Resolver) netResolver() *net.Resolver {
+	if r == nil {
+		return net.DefaultResolver
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if r.resolver == nil {
+		return net.DefaultResolver
+	}
+	return r.resolver
+}
@@ -0,0 +1,63 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestResolverHosts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	config := &ResolverConfiguration{
+		Hosts: map[string]string{
+			"cabourotte.invalid": "127.0.0.1",
+		},
+	}
+	err = config.Validate()
+	if err != nil {
+		t.Fatalf("Invalid resolver configuration :\n%v", err)
+	}
+	h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+		Port:        uint(port),
+		Target:      "cabourotte.invalid",
+		Timeout:     Duration(time.Second * 2),
+		Protocol:    HTTP,
+		ValidStatus: []uint{200},
+		Method:      "GET",
+	})
+	h.SetResolver(NewResolver(config))
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Fail to initialize the healthcheck :\n%v", err)
+	}
+	_, err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+}
+
+func TestResolverConfigurationValidate(t *testing.T) {
+	configs := []ResolverConfiguration{
+		{Hosts: map[string]string{"foo": "invalid"}},
+		{Server: "127.0.0.1"},
+	}
+	for _, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Fatalf("Was expecting an error for %v", config)
+		}
+	}
+}
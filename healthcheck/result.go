@@ -1,19 +1,119 @@
 package healthcheck
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultMaxAnnotations the default maximum number of annotations in a result
+	DefaultMaxAnnotations = 30
+	// DefaultMaxAnnotationsSize the default maximum size of the annotations
+	// (keys and values) in a result
+	DefaultMaxAnnotationsSize = 10000
+	// UnlimitedAnnotations disables the limit on the number or the size of
+	// the annotations in a result
+	UnlimitedAnnotations = -1
+	// truncatedAnnotation the annotation added to a result when annotations
+	// were dropped
+	truncatedAnnotation = "annotations-truncated"
 )
 
+// Annotations contains extra information about an healthcheck execution
+type Annotations map[string]string
+
 // Result represents the result of an healthcheck
 type Result struct {
 	Name                 string            `json:"name"`
+	Type                 string            `json:"type"`
 	Summary              interface{}       `json:"summary"`
 	Labels               map[string]string `json:"labels,omitempty"`
 	Success              bool              `json:"success"`
 	HealthcheckTimestamp int64             `json:"healthcheck-timestamp"`
-	Message              string            `json:"message"`
-	Duration             int64             `json:"duration"`
-	Source               string            `json:"source"`
+	// HealthcheckTime the healthcheck timestamp in RFC3339 format, with
+	// nanosecond precision
+	HealthcheckTime string      `json:"healthcheck-time,omitempty"`
+	Message         string      `json:"message"`
+	Duration        int64       `json:"duration"`
+	Source          string      `json:"source"`
+	Annotations     Annotations `json:"annotations,omitempty"`
+	// Exporters the exporters receiving the result (all exporters if empty)
+	Exporters []string `json:"-"`
+	// Weight the weight of the healthcheck in the weighted aggregate status
+	Weight int `json:"weight,omitempty"`
+	// Metrics the metrics reported by the healthcheck execution
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	// InMaintenance the healthcheck was executed during a maintenance window
+	InMaintenance bool `json:"in-maintenance,omitempty"`
+	// Degraded the healthcheck is successful but the target is degraded
+	Degraded bool `json:"degraded,omitempty"`
+}
+
+// MetricCertificateExpiry the number of seconds before the expiration of
+// the earliest-expiring peer certificate
+const MetricCertificateExpiry = "certificate-expiry-seconds"
+
+const (
+	// MetricDNSSeconds the duration of the DNS resolution
+	MetricDNSSeconds = "dns-seconds"
+	// MetricConnectSeconds the duration of the connection establishment
+	MetricConnectSeconds = "connect-seconds"
+	// MetricTLSSeconds the duration of the TLS handshake
+	MetricTLSSeconds = "tls-seconds"
+)
+
+// executionMetricsKey the context key of the metrics of an healthcheck
+// execution
+type executionMetricsKey struct{}
+
+// executionMetrics collects the metrics reported by an healthcheck
+// execution
+type executionMetrics struct {
+	lock   sync.Mutex
+	values map[string]float64
+}
+
+// WithExecutionMetrics returns a context collecting the metrics reported by
+// an healthcheck execution, and a function returning these metrics. The
+// metrics are scoped to the execution because an healthcheck can be
+// executed concurrently.
+func WithExecutionMetrics(ctx context.Context) (context.Context, func() map[string]float64) {
+	metrics := &executionMetrics{}
+	values := func() map[string]float64 {
+		metrics.lock.Lock()
+		defer metrics.lock.Unlock()
+		if len(metrics.values) == 0 {
+			return nil
+		}
+		result := make(map[string]float64, len(metrics.values))
+		for k, v := range metrics.values {
+			result[k] = v
+		}
+		return result
+	}
+	return context.WithValue(ctx, executionMetricsKey{}, metrics), values
+}
+
+// reportMetrics adds metrics to the metrics of the execution, if they are
+// collected by the context
+func reportMetrics(ctx context.Context, metrics map[string]float64) {
+	collector, ok := ctx.Value(executionMetricsKey{}).(*executionMetrics)
+	if !ok {
+		return
+	}
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	if collector.values == nil {
+		collector.values = make(map[string]float64, len(metrics))
+	}
+	for k, v := range metrics {
+		collector.values[k] = v
+	}
 }
 
 // Equals implements Equals for Result
@@ -21,6 +121,9 @@ func (r Result) Equals(v Result) bool {
 	if r.Name != v.Name {
 		return false
 	}
+	if r.Type != v.Type {
+		return false
+	}
 	if r.Summary != v.Summary {
 		return false
 	}
@@ -30,6 +133,9 @@ func (r Result) Equals(v Result) bool {
 	if r.HealthcheckTimestamp != v.HealthcheckTimestamp {
 		return false
 	}
+	if r.HealthcheckTime != v.HealthcheckTime {
+		return false
+	}
 	if r.Message != v.Message {
 		return false
 	}
@@ -39,6 +145,23 @@ func (r Result) Equals(v Result) bool {
 	if r.Source != v.Source {
 		return false
 	}
+	if r.Weight != v.Weight {
+		return false
+	}
+	if r.InMaintenance != v.InMaintenance {
+		return false
+	}
+	if r.Degraded != v.Degraded {
+		return false
+	}
+	if len(r.Metrics) != len(v.Metrics) {
+		return false
+	}
+	for k, value := range r.Metrics {
+		if value != v.Metrics[k] {
+			return false
+		}
+	}
 	if len(r.Labels) != len(v.Labels) {
 		return false
 	}
@@ -47,25 +170,162 @@ func (r Result) Equals(v Result) bool {
 			return false
 		}
 	}
+	if len(r.Annotations) != len(v.Annotations) {
+		return false
+	}
+	for k, value := range r.Annotations {
+		if value != v.Annotations[k] {
+			return false
+		}
+	}
 	return true
 }
 
+// Time returns the healthcheck timestamp, using the RFC3339 timestamp if
+// available for a better precision
+func (r *Result) Time() time.Time {
+	if r.HealthcheckTime != "" {
+		t, err := time.Parse(time.RFC3339Nano, r.HealthcheckTime)
+		if err == nil {
+			return t
+		}
+	}
+	return time.Unix(r.HealthcheckTimestamp, 0)
+}
+
+// ExportedTo returns true if the result should be pushed to the exporter
+func (r *Result) ExportedTo(exporter string) bool {
+	if len(r.Exporters) == 0 {
+		return true
+	}
+	for _, name := range r.Exporters {
+		if name == exporter {
+			return true
+		}
+	}
+	return false
+}
+
+// FromSource returns true if the result is from an healthcheck of the
+// source. The healthchecks from the configuration file are matched by the
+// "configuration" source.
+func (r *Result) FromSource(source string) bool {
+	return r.Source == source || sourceName(r.Source) == source
+}
+
+// LimitAnnotations drops the result annotations exceeding the maximum number
+// of annotations or the maximum annotations size (a zero or negative value
+// means no limit).
+// Annotations are kept in alphabetical order, and the number of dropped
+// annotations is added as a marker, which counts in the limits.
+func (r *Result) LimitAnnotations(maxCount int, maxSize int) {
+	keys := make([]string, 0, len(r.Annotations))
+	for k := range r.Annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	countLimit, sizeLimit := UnlimitedAnnotations, UnlimitedAnnotations
+	if maxCount > 0 {
+		countLimit = maxCount
+	}
+	if maxSize > 0 {
+		sizeLimit = maxSize
+	}
+	_, dropped := r.keepAnnotations(keys, countLimit, sizeLimit)
+	if dropped == 0 {
+		return
+	}
+	// room is reserved for the marker, its value being the longest when
+	// all annotations are dropped
+	if countLimit > 0 {
+		countLimit--
+	}
+	if sizeLimit >= 0 {
+		marker := fmt.Sprintf("%d annotations dropped", len(keys))
+		sizeLimit -= len(truncatedAnnotation) + len(marker)
+		if sizeLimit < 0 {
+			sizeLimit = 0
+		}
+	}
+	annotations, dropped := r.keepAnnotations(keys, countLimit, sizeLimit)
+	annotations[truncatedAnnotation] = fmt.Sprintf("%d annotations dropped", dropped)
+	r.Annotations = annotations
+}
+
+// keepAnnotations returns the annotations, in the order of the given keys,
+// fitting in the maximum number of annotations and the maximum size (a
+// negative value means no limit), and the number of dropped annotations
+func (r *Result) keepAnnotations(keys []string, maxCount int, maxSize int) (Annotations, int) {
+	size := 0
+	dropped := 0
+	annotations := Annotations{}
+	for _, k := range keys {
+		v := r.Annotations[k]
+		if (maxCount >= 0 && len(annotations) >= maxCount) ||
+			(maxSize >= 0 && size+len(k)+len(v) > maxSize) {
+			dropped++
+			continue
+		}
+		size += len(k) + len(v)
+		annotations[k] = v
+	}
+	return annotations, dropped
+}
+
+// promoteAnnotations copies the given annotations into the result labels.
+// The labels are copied first because they are shared with the healthcheck
+// configuration.
+func (r *Result) promoteAnnotations(names []string) {
+	labels := make(map[string]string, len(r.Labels)+len(names))
+	promoted := false
+	for _, name := range names {
+		if value, ok := r.Annotations[name]; ok {
+			labels[name] = value
+			promoted = true
+		}
+	}
+	if !promoted {
+		return
+	}
+	for k, v := range r.Labels {
+		if _, ok := labels[k]; !ok {
+			labels[k] = v
+		}
+	}
+	r.Labels = labels
+}
+
 // NewResult build a a new result for an healthcheck
-func NewResult(healthcheck Healthcheck, duration int64, err error) *Result {
+func NewResult(healthcheck Healthcheck, duration int64, annotations Annotations, err error) *Result {
 	now := time.Now()
-	source := "configuration"
-	if healthcheck.Base().Source != "" {
-		source = healthcheck.Base().Source
-	}
+	source := sourceName(healthcheck.Base().Source)
+	base := healthcheck.Base()
 	result := Result{
 		Name:                 healthcheck.Base().Name,
+		Type:                 checkType(healthcheck),
 		Summary:              healthcheck.Summary(),
 		Labels:               healthcheck.Base().Labels,
 		HealthcheckTimestamp: now.Unix(),
+		HealthcheckTime:      now.Format(time.RFC3339Nano),
 		Duration:             duration,
 		Source:               source,
+		Exporters:            healthcheck.Base().Exporters,
+		Weight:               healthcheck.Base().Weight,
+		InMaintenance:        base.InMaintenance(now),
 	}
-	if err != nil {
+	if len(annotations) != 0 {
+		result.Annotations = annotations
+	}
+	if healthcheck.Base().TargetLabels {
+		result.addTargetLabels(healthcheck)
+	}
+	result.promoteAnnotations(healthcheck.Base().PromoteAnnotations)
+	var degradedErr *DegradedError
+	if errors.As(err, &degradedErr) {
+		result.Success = true
+		result.Degraded = true
+		result.Message = err.Error()
+	} else if err != nil {
 		result.Success = false
 		result.Message = err.Error()
 	} else {
@@ -0,0 +1,189 @@
+package healthcheck
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimitAnnotations(t *testing.T) {
+	result := Result{
+		Annotations: Annotations{
+			"a": "1",
+			"b": "2",
+			"c": "3",
+		},
+	}
+	result.LimitAnnotations(0, 0)
+	if len(result.Annotations) != 3 {
+		t.Fatalf("Annotations should not be dropped without limits: %v", result.Annotations)
+	}
+	result.LimitAnnotations(UnlimitedAnnotations, UnlimitedAnnotations)
+	if len(result.Annotations) != 3 {
+		t.Fatalf("Annotations should not be dropped without limits: %v", result.Annotations)
+	}
+	result.LimitAnnotations(3, 0)
+	if len(result.Annotations) != 3 {
+		t.Fatalf("Annotations should not be dropped below the limits: %v", result.Annotations)
+	}
+	result.LimitAnnotations(2, 0)
+	if len(result.Annotations) != 2 {
+		t.Fatalf("Invalid annotations %v", result.Annotations)
+	}
+	if result.Annotations["a"] != "1" {
+		t.Fatalf("Invalid annotations %v", result.Annotations)
+	}
+	if result.Annotations[truncatedAnnotation] != "2 annotations dropped" {
+		t.Fatalf("The truncated marker is missing %v", result.Annotations)
+	}
+	for maxCount := 1; maxCount <= 4; maxCount++ {
+		result = Result{
+			Annotations: Annotations{
+				"a": "1",
+				"b": "2",
+				"c": "3",
+				"d": "4",
+				"e": "5",
+			},
+		}
+		result.LimitAnnotations(maxCount, 0)
+		if len(result.Annotations) > maxCount {
+			t.Fatalf("Too many annotations for the limit %d: %v", maxCount, result.Annotations)
+		}
+	}
+	result = Result{
+		Annotations: Annotations{
+			"a": "1",
+			"b": strings.Repeat("a long value", 10),
+		},
+	}
+	maxSize := len(truncatedAnnotation) + len("2 annotations dropped") + 5
+	result.LimitAnnotations(0, maxSize)
+	if len(result.Annotations) != 2 || result.Annotations["a"] != "1" {
+		t.Fatalf("Invalid annotations %v", result.Annotations)
+	}
+	if _, ok := result.Annotations["b"]; ok {
+		t.Fatalf("The annotation should be dropped %v", result.Annotations)
+	}
+	size := 0
+	for k, v := range result.Annotations {
+		size += len(k) + len(v)
+	}
+	if size > maxSize {
+		t.Fatalf("The annotations size %d is greater than the limit %d", size, maxSize)
+	}
+}
+
+func TestNewResultType(t *testing.T) {
+	cases := []struct {
+		healthcheck Healthcheck
+		expected    string
+	}{
+		{healthcheck: NewDNSHealthcheck(nil, &DNSHealthcheckConfiguration{}), expected: TypeDNS},
+		{healthcheck: NewTCPHealthcheck(nil, &TCPHealthcheckConfiguration{}), expected: TypeTCP},
+		{healthcheck: NewTLSHealthcheck(nil, &TLSHealthcheckConfiguration{}), expected: TypeTLS},
+		{healthcheck: NewHTTPHealthcheck(nil, &HTTPHealthcheckConfiguration{}), expected: TypeHTTP},
+		{healthcheck: NewCommandHealthcheck(nil, &CommandHealthcheckConfiguration{}), expected: TypeCommand},
+		{healthcheck: NewPostgresHealthcheck(nil, &PostgresHealthcheckConfiguration{}), expected: TypePostgres},
+		{healthcheck: NewUDPHealthcheck(nil, &UDPHealthcheckConfiguration{}), expected: TypeUDP},
+	}
+	for _, c := range cases {
+		result := NewResult(c.healthcheck, 0, nil, nil)
+		if result.Type != c.expected {
+			t.Fatalf("Invalid result type\nexpected: %s\nactual: %s", c.expected, result.Type)
+		}
+	}
+}
+
+func TestResultExportedTo(t *testing.T) {
+	result := Result{}
+	if !result.ExportedTo("foo") {
+		t.Fatalf("A result without exporters should be exported everywhere")
+	}
+	result.Exporters = []string{"foo", "bar"}
+	if !result.ExportedTo("foo") || !result.ExportedTo("bar") {
+		t.Fatalf("The result should be exported to the listed exporters")
+	}
+	if result.ExportedTo("baz") {
+		t.Fatalf("The result should not be exported to baz")
+	}
+}
+
+func TestNewResultPromoteAnnotations(t *testing.T) {
+	labels := map[string]string{"env": "prod", "status": "configured"}
+	check := NewHTTPHealthcheck(nil, &HTTPHealthcheckConfiguration{
+		Base: Base{
+			Name:               "foo",
+			Labels:             labels,
+			PromoteAnnotations: []string{"status", "missing"},
+		},
+	})
+	result := NewResult(check, 0, Annotations{"status": "503", "other": "a"}, nil)
+	if len(result.Labels) != 2 || result.Labels["status"] != "503" || result.Labels["env"] != "prod" {
+		t.Fatalf("Invalid result labels %v", result.Labels)
+	}
+	if labels["status"] != "configured" {
+		t.Fatalf("The healthcheck labels were modified")
+	}
+	result = NewResult(check, 0, nil, nil)
+	if result.Labels["status"] != "configured" {
+		t.Fatalf("Invalid result labels %v", result.Labels)
+	}
+}
+
+func TestNewResultTime(t *testing.T) {
+	result := NewResult(NewTCPHealthcheck(nil, &TCPHealthcheckConfiguration{}), 0, nil, nil)
+	parsed, err := time.Parse(time.RFC3339Nano, result.HealthcheckTime)
+	if err != nil {
+		t.Fatalf("Invalid RFC3339 timestamp %s\n%v", result.HealthcheckTime, err)
+	}
+	if parsed.Unix() != result.HealthcheckTimestamp {
+		t.Fatalf("The timestamps are not consistent: %s, %d", result.HealthcheckTime, result.HealthcheckTimestamp)
+	}
+	if !result.Time().Equal(parsed) {
+		t.Fatalf("Invalid result time %s", result.Time())
+	}
+	result.HealthcheckTime = ""
+	if result.Time().Unix() != result.HealthcheckTimestamp {
+		t.Fatalf("Invalid result time %s", result.Time())
+	}
+}
+
+func TestNewResultTargetLabels(t *testing.T) {
+	check := NewHTTPHealthcheck(nil, &HTTPHealthcheckConfiguration{
+		Base: Base{
+			Name:         "foo",
+			Labels:       map[string]string{"env": "prod", "host": "api"},
+			TargetLabels: true,
+		},
+		Target:   "127.0.0.1",
+		Port:     8443,
+		Protocol: HTTPS,
+	})
+	result := NewResult(check, 0, nil, nil)
+	expected := map[string]string{"env": "prod", "host": "api", "port": "8443", "scheme": "https"}
+	if len(result.Labels) != len(expected) {
+		t.Fatalf("Invalid result labels %v", result.Labels)
+	}
+	for k, v := range expected {
+		if result.Labels[k] != v {
+			t.Fatalf("Invalid result labels %v", result.Labels)
+		}
+	}
+	if len(check.Base().Labels) != 2 {
+		t.Fatalf("The healthcheck labels were modified")
+	}
+	dnsCheck := NewDNSHealthcheck(nil, &DNSHealthcheckConfiguration{
+		Base:   Base{Name: "bar", TargetLabels: true},
+		Domain: "mcorbin.fr",
+	})
+	result = NewResult(dnsCheck, 0, nil, nil)
+	if len(result.Labels) != 2 || result.Labels["host"] != "mcorbin.fr" || result.Labels["scheme"] != "dns" {
+		t.Fatalf("Invalid result labels %v", result.Labels)
+	}
+	check.Config.Base.TargetLabels = false
+	result = NewResult(check, 0, nil, nil)
+	if len(result.Labels) != 2 {
+		t.Fatalf("The target labels should not be added %v", result.Labels)
+	}
+}
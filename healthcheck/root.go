@@ -1,11 +1,13 @@
 package healthcheck
 
 import (
+	"context"
 	"fmt"
-	"math/rand"
+	"math/rand/v2"
 	"reflect"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -25,22 +27,71 @@ type Healthcheck interface {
 	Initialize() error
 	GetConfig() interface{}
 	Summary() string
-	Execute() error
+	Execute(ctx context.Context) (Annotations, error)
 	LogDebug(message string)
 	LogInfo(message string)
 	Base() Base
 	SetSource(source string)
+	SetResolver(resolver *Resolver)
 	LogError(err error, message string)
 }
 
+// DefaultStartupJitter the default maximum random delay before the first
+// execution of an healthcheck
+const DefaultStartupJitter = Duration(4 * time.Second)
+
 // Component is the component which will manage healthchecks
 type Component struct {
-	Logger             *zap.Logger
-	Healthchecks       map[string]*Wrapper
-	resultHistogram    *prom.HistogramVec
-	resultCounter      *prom.CounterVec
-	lock               sync.RWMutex
-	healthchecksLabels []string
+	Logger               *zap.Logger
+	Healthchecks         map[string]*Wrapper
+	resultHistogram      *prom.HistogramVec
+	resultCounter        *prom.CounterVec
+	statusGauge          *prom.GaugeVec
+	expiryGauge          *prom.GaugeVec
+	driftGauge           *prom.GaugeVec
+	timingHistograms     map[string]*prom.HistogramVec
+	sourceGauge          *prom.GaugeVec
+	infoGauge            *prom.GaugeVec
+	inFlightGauge        *prom.GaugeVec
+	labelOverflowCounter *prom.CounterVec
+	labelGuard           *labelGuard
+	sources              map[string]*SourceStats
+	lock                 sync.RWMutex
+	healthchecksLabels   []string
+	// settingsLock protects the settings which can be changed while the
+	// healthchecks are executed
+	settingsLock sync.RWMutex
+	// semaphore limits the number of concurrent executions (no limit if
+	// nil)
+	semaphore chan struct{}
+	// maxAnnotations the maximum number of annotations in a result (no
+	// limit if negative)
+	maxAnnotations int
+	// maxAnnotationsSize the maximum size of the annotations in a result
+	// (no limit if negative)
+	maxAnnotationsSize int
+	// maintenance the healthchecks are not executed during the global
+	// maintenance
+	maintenance atomic.Bool
+
+	// MaxExecutionEvents the number of execution events kept for each
+	// healthcheck
+	MaxExecutionEvents int
+	// Resolver the resolver used by the healthchecks
+	Resolver *Resolver
+	// MaxLabelValues the maximum number of distinct values of each
+	// healthcheck label exposed in the metrics (no limit if 0)
+	MaxLabelValues int
+	// ExporterNames the names of the configured exporters, the healthchecks
+	// exporters are validated against them (not validated if nil)
+	ExporterNames []string
+	// DiscoveryMetricsLabels the healthchecks labels exposed in the metrics
+	// for the healthchecks created by service discovery (all labels if nil)
+	DiscoveryMetricsLabels []string
+	// StartupJitter the maximum random delay before the first execution of
+	// an healthcheck, spreading the executions when many healthchecks are
+	// added at once
+	StartupJitter Duration
 
 	ChanResult chan *Result
 }
@@ -48,38 +99,38 @@ type Component struct {
 // Start an healthcheck wrapper
 func (c *Component) startWrapper(w *Wrapper) {
 	w.healthcheck.LogInfo("Starting healthcheck")
+	if w.healthcheck.Base().Cron != "" {
+		c.startCronWrapper(w)
+		return
+	}
 	w.Tick = time.NewTicker(time.Duration(w.healthcheck.Base().Interval))
+	w.interval.Store(int64(w.healthcheck.Base().Interval))
 	w.t.Go(func() error {
-		wait := rand.Intn(4000)
-		time.Sleep(time.Duration(wait) * time.Millisecond)
-		for {
-			start := time.Now()
-			err := w.healthcheck.Execute()
-			duration := time.Since(start)
-			result := NewResult(
-				w.healthcheck,
-				duration.Milliseconds(),
-				err)
-			status := "failure"
-			if result.Success {
-				status = "success"
-			}
-			histoLabels := map[string]string{
-				"name": w.healthcheck.Base().Name,
-			}
-			for _, k := range c.healthchecksLabels {
-				histoLabels[k] = result.Labels[k]
+		if jitter := c.startupJitter(w); jitter > 0 {
+			timer := time.NewTimer(rand.N(jitter))
+			select {
+			case <-timer.C:
+			case <-w.t.Dying():
+				timer.Stop()
+				return nil
 			}
-			c.resultHistogram.With(prom.Labels(histoLabels)).Observe(duration.Seconds())
-			counterLabels := map[string]string{
-				"name":   w.healthcheck.Base().Name,
-				"status": status,
+			// the ticker is restarted after the delay, otherwise the
+			// first drift sample would be shifted by the delay
+			w.Tick.Reset(time.Duration(w.interval.Load()))
+			select {
+			case <-w.Tick.C:
+			default:
 			}
-			for _, k := range c.healthchecksLabels {
-				counterLabels[k] = result.Labels[k]
+		}
+		var last time.Time
+		for {
+			now := time.Now()
+			if !last.IsZero() {
+				drift := now.Sub(last) - time.Duration(w.interval.Load())
+				c.driftGauge.With(prom.Labels{"name": w.healthcheck.Base().Name}).Set(drift.Seconds())
 			}
-			c.resultCounter.With(prom.Labels(counterLabels)).Inc()
-			c.ChanResult <- result
+			last = now
+			c.runScheduled(w)
 			select {
 			case <-w.Tick.C:
 				continue
@@ -90,6 +141,212 @@ func (c *Component) startWrapper(w *Wrapper) {
 	})
 }
 
+// runScheduled executes a scheduled healthcheck, unless it is skipped
+// because of the global maintenance, because it is disabled or because it
+// is outside of its active window
+func (c *Component) runScheduled(w *Wrapper) {
+	if c.maintenance.Load() {
+		w.healthcheck.LogDebug("global maintenance, skipping execution")
+	} else if !w.enabled.Load() {
+		w.healthcheck.LogDebug("healthcheck disabled, skipping execution")
+	} else if w.healthcheck.Base().ActiveWindow.Active(time.Now()) {
+		c.run(w)
+		return
+	} else {
+		w.healthcheck.LogDebug("outside of the active window, skipping execution")
+	}
+	// a skipped execution counts as an execution, the healthcheck will not
+	// be executed before its next scheduled execution
+	w.executed.Store(true)
+}
+
+// startupJitter returns the maximum delay before the first execution of an
+// healthcheck
+func (c *Component) startupJitter(w *Wrapper) time.Duration {
+	if jitter := w.healthcheck.Base().StartupJitter; jitter != 0 {
+		return time.Duration(jitter)
+	}
+	return time.Duration(c.StartupJitter)
+}
+
+// startCronWrapper starts an healthcheck wrapper scheduled using a cron
+// expression
+func (c *Component) startCronWrapper(w *Wrapper) {
+	w.t.Go(func() error {
+		// the expression was already validated
+		schedule, err := parseCron(w.healthcheck.Base().Cron)
+		if err != nil {
+			w.healthcheck.LogError(err, "Fail to parse the cron expression")
+			return nil
+		}
+		for {
+			next := schedule.Next(time.Now())
+			if next.IsZero() {
+				w.healthcheck.LogError(errors.New("no next execution"), "Fail to schedule the healthcheck")
+				return nil
+			}
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-timer.C:
+				c.runScheduled(w)
+			case <-w.t.Dying():
+				timer.Stop()
+				return nil
+			}
+		}
+	})
+}
+
+// SetMaxConcurrentChecks limits the number of healthchecks executed at the
+// same time (no limit if 0). The executions in progress are not counted in
+// the new limit.
+func (c *Component) SetMaxConcurrentChecks(max int) {
+	c.settingsLock.Lock()
+	defer c.settingsLock.Unlock()
+	if max <= 0 {
+		c.semaphore = nil
+		return
+	}
+	if c.semaphore != nil && cap(c.semaphore) == max {
+		return
+	}
+	c.semaphore = make(chan struct{}, max)
+}
+
+// SetAnnotationsLimits sets the maximum number and the maximum size of the
+// annotations in a result (no limit if negative)
+func (c *Component) SetAnnotationsLimits(maxCount int, maxSize int) {
+	c.settingsLock.Lock()
+	defer c.settingsLock.Unlock()
+	c.maxAnnotations = maxCount
+	c.maxAnnotationsSize = maxSize
+}
+
+// AnnotationsLimits returns the maximum number and the maximum size of the
+// annotations in a result
+func (c *Component) AnnotationsLimits() (int, int) {
+	c.settingsLock.RLock()
+	defer c.settingsLock.RUnlock()
+	return c.maxAnnotations, c.maxAnnotationsSize
+}
+
+// acquire waits until the number of concurrent executions is below the
+// limit. It returns a function releasing the execution slot, or false if
+// done is closed first.
+func (c *Component) acquire(done <-chan struct{}) (func(), bool) {
+	c.settingsLock.RLock()
+	semaphore := c.semaphore
+	c.settingsLock.RUnlock()
+	if semaphore == nil {
+		return func() {}, true
+	}
+	select {
+	case semaphore <- struct{}{}:
+		return func() { <-semaphore }, true
+	case <-done:
+		return nil, false
+	}
+}
+
+// Acquire waits until the number of concurrent executions is below the
+// limit, in order to execute an healthcheck outside of its schedule. It
+// returns a function releasing the execution slot, or the context error if
+// the context is done first.
+func (c *Component) Acquire(ctx context.Context) (func(), error) {
+	release, ok := c.acquire(ctx.Done())
+	if !ok {
+		return nil, ctx.Err()
+	}
+	return release, nil
+}
+
+// run executes an healthcheck once the number of concurrent executions is
+// below the limit
+func (c *Component) run(w *Wrapper) {
+	release, ok := c.acquire(w.t.Dying())
+	if !ok {
+		return
+	}
+	defer release()
+	inFlight := c.inFlightGauge.WithLabelValues()
+	inFlight.Inc()
+	defer inFlight.Dec()
+	c.execute(w)
+}
+
+// execute executes an healthcheck, updates its metrics and sends the
+// result to the result channel
+func (c *Component) execute(w *Wrapper) {
+	ctx, metrics := WithExecutionMetrics(w.t.Context(context.TODO()))
+	start := time.Now()
+	annotations, err := w.healthcheck.Execute(ctx)
+	duration := time.Since(start)
+	result := NewResult(
+		w.healthcheck,
+		duration.Milliseconds(),
+		annotations,
+		err)
+	result.Metrics = metrics()
+	result.LimitAnnotations(c.AnnotationsLimits())
+	w.events.add(ExecutionEvent{
+		Timestamp: result.HealthcheckTimestamp,
+		Success:   result.Success,
+		Message:   result.Message,
+		Duration:  result.Duration,
+	})
+	rawStatus := result.Status()
+	// failures during a maintenance window are not taken into account
+	// to compute the healthcheck state
+	if !result.InMaintenance {
+		result.Success = w.debounce(result.Success)
+		// a degraded result can be reported as failed if the healthcheck
+		// did not reach its success threshold yet
+		result.Degraded = result.Degraded && result.Success
+	}
+	status := result.Status()
+	metricsLabels := c.metricsLabels(w, result)
+	histoLabels := map[string]string{
+		"name": w.healthcheck.Base().Name,
+	}
+	for k, v := range metricsLabels {
+		histoLabels[k] = v
+	}
+	c.resultHistogram.With(prom.Labels(histoLabels)).Observe(duration.Seconds())
+	if result.InMaintenance {
+		// executions during a maintenance window are counted separately
+		// and do not update the status gauge
+		status = StatusMaintenance
+	} else {
+		statusValue := 0.0
+		switch status {
+		case StatusSuccess:
+			statusValue = 1
+		case StatusDegraded:
+			statusValue = 0.5
+		}
+		c.statusGauge.With(prom.Labels(histoLabels)).Set(statusValue)
+	}
+	if expiry, ok := result.Metrics[MetricCertificateExpiry]; ok {
+		c.expiryGauge.With(prom.Labels{"name": w.healthcheck.Base().Name}).Set(expiry)
+	}
+	for metric, histogram := range c.timingHistograms {
+		if value, ok := result.Metrics[metric]; ok {
+			histogram.With(prom.Labels{"name": w.healthcheck.Base().Name}).Observe(value)
+		}
+	}
+	counterLabels := map[string]string{
+		"name":       w.healthcheck.Base().Name,
+		"status":     status,
+		"raw_status": rawStatus,
+	}
+	for k, v := range metricsLabels {
+		counterLabels[k] = v
+	}
+	c.resultCounter.With(prom.Labels(counterLabels)).Inc()
+	w.executed.Store(true)
+	c.ChanResult <- result
+}
+
 // New creates a new Healthcheck component
 func New(logger *zap.Logger, chanResult chan *Result, promComponent *prometheus.Prometheus, healthchecksLabels []string) (*Component, error) {
 	buckets := []float64{
@@ -98,21 +355,105 @@ func New(logger *zap.Logger, chanResult chan *Result, promComponent *prometheus.
 	histoLabels := []string{"name"}
 	histoLabels = append(histoLabels, healthchecksLabels...)
 	histo := prom.NewHistogramVec(prom.HistogramOpts{
-		Name:    "healthcheck_duration_seconds",
-		Help:    "Time to execute a healthcheck.",
-		Buckets: buckets,
+		Namespace: promComponent.Namespace(),
+		Name:      "healthcheck_duration_seconds",
+		Help:      "Time to execute a healthcheck.",
+		Buckets:   buckets,
 	},
 		histoLabels,
 	)
-	counterLabels := []string{"name", "status"}
+	counterLabels := []string{"name", "status", "raw_status"}
 	counterLabels = append(counterLabels, healthchecksLabels...)
 	counter := prom.NewCounterVec(
 		prom.CounterOpts{
-			Name: "healthcheck_total",
-			Help: "Count the number of healthchecks executions.",
+			Namespace: promComponent.Namespace(),
+			Name:      "healthcheck_total",
+			Help:      "Count the number of healthchecks executions.",
 		},
 		counterLabels)
 
+	statusGauge := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Namespace: promComponent.Namespace(),
+			Name:      "healthcheck_status",
+			Help:      "Status of the last healthcheck execution (1 for success, 0.5 for degraded, 0 for failure).",
+		},
+		histoLabels)
+
+	expiryGauge := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Namespace: promComponent.Namespace(),
+			Name:      "healthcheck_certificate_expiry_seconds",
+			Help:      "Number of seconds before the expiration of the earliest-expiring peer certificate.",
+		},
+		[]string{"name"})
+
+	driftGauge := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Namespace: promComponent.Namespace(),
+			Name:      "healthcheck_schedule_drift_seconds",
+			Help:      "Difference between the time elapsed since the previous scheduled execution and the configured interval.",
+		},
+		[]string{"name"})
+
+	timingHistograms := map[string]*prom.HistogramVec{
+		MetricDNSSeconds: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: promComponent.Namespace(),
+			Name:      "healthcheck_dns_seconds",
+			Help:      "Time to resolve the healthcheck target.",
+			Buckets:   buckets,
+		},
+			[]string{"name"}),
+		MetricConnectSeconds: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: promComponent.Namespace(),
+			Name:      "healthcheck_connect_seconds",
+			Help:      "Time to connect to the healthcheck target.",
+			Buckets:   buckets,
+		},
+			[]string{"name"}),
+		MetricTLSSeconds: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: promComponent.Namespace(),
+			Name:      "healthcheck_tls_seconds",
+			Help:      "Time to do the TLS handshake with the healthcheck target.",
+			Buckets:   buckets,
+		},
+			[]string{"name"}),
+	}
+
+	sourceGauge := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Namespace: promComponent.Namespace(),
+			Name:      "healthcheck_checks",
+			Help:      "Number of healthchecks configured for each source.",
+		},
+		[]string{"source"})
+
+	infoLabels := []string{"name", "source"}
+	infoLabels = append(infoLabels, healthchecksLabels...)
+	infoGauge := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Namespace: promComponent.Namespace(),
+			Name:      "healthcheck_info",
+			Help:      "Information about the configured healthchecks, always 1.",
+		},
+		infoLabels)
+
+	inFlightGauge := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Namespace: promComponent.Namespace(),
+			Name:      "healthcheck_executions_in_flight",
+			Help:      "Number of healthchecks being executed.",
+		},
+		[]string{})
+
+	labelOverflowCounter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Namespace: promComponent.Namespace(),
+			Name:      "healthcheck_label_overflow_total",
+			Help:      "Count the number of healthchecks label values replaced because the metric label has too many values.",
+		},
+		[]string{"label"})
+
 	err := promComponent.Register(histo)
 	if err != nil {
 		return nil, errors.Wrapf(err, "fail to register the healthcheck results Prometheus histogram")
@@ -121,13 +462,61 @@ func New(logger *zap.Logger, chanResult chan *Result, promComponent *prometheus.
 	if err != nil {
 		return nil, errors.Wrapf(err, "fail to register the healthcheck results Prometheus counter")
 	}
+	err = promComponent.Register(statusGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck status Prometheus gauge")
+	}
+	err = promComponent.Register(expiryGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the certificate expiry Prometheus gauge")
+	}
+	err = promComponent.Register(driftGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the schedule drift Prometheus gauge")
+	}
+	for metric, histogram := range timingHistograms {
+		err = promComponent.Register(histogram)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to register the healthcheck %s Prometheus histogram", metric)
+		}
+	}
+	err = promComponent.Register(sourceGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck sources Prometheus gauge")
+	}
+	err = promComponent.Register(infoGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck info Prometheus gauge")
+	}
+	err = promComponent.Register(inFlightGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck executions in flight Prometheus gauge")
+	}
+	err = promComponent.Register(labelOverflowCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the label overflow Prometheus counter")
+	}
 	component := Component{
-		resultCounter:      counter,
-		resultHistogram:    histo,
-		Logger:             logger,
-		Healthchecks:       make(map[string]*Wrapper),
-		ChanResult:         chanResult,
-		healthchecksLabels: healthchecksLabels,
+		resultCounter:        counter,
+		resultHistogram:      histo,
+		statusGauge:          statusGauge,
+		expiryGauge:          expiryGauge,
+		driftGauge:           driftGauge,
+		timingHistograms:     timingHistograms,
+		sourceGauge:          sourceGauge,
+		infoGauge:            infoGauge,
+		inFlightGauge:        inFlightGauge,
+		labelOverflowCounter: labelOverflowCounter,
+		labelGuard:           newLabelGuard(),
+		sources:              make(map[string]*SourceStats),
+		Logger:               logger,
+		Healthchecks:         make(map[string]*Wrapper),
+		ChanResult:           chanResult,
+		healthchecksLabels:   healthchecksLabels,
+		maxAnnotations:       DefaultMaxAnnotations,
+		maxAnnotationsSize:   DefaultMaxAnnotationsSize,
+		MaxExecutionEvents:   DefaultMaxExecutionEvents,
+		StartupJitter:        DefaultStartupJitter,
 	}
 
 	return &component, nil
@@ -165,59 +554,222 @@ func (c *Component) removeCheck(identifier string) error {
 		existingWrapper.healthcheck.LogInfo("Stopping healthcheck")
 		c.resultHistogram.DeletePartialMatch(prom.Labels{"name": identifier})
 		c.resultCounter.DeletePartialMatch(prom.Labels{"name": identifier})
+		c.statusGauge.DeletePartialMatch(prom.Labels{"name": identifier})
+		c.expiryGauge.DeletePartialMatch(prom.Labels{"name": identifier})
+		c.driftGauge.DeletePartialMatch(prom.Labels{"name": identifier})
+		c.infoGauge.DeletePartialMatch(prom.Labels{"name": identifier})
+		for _, histogram := range c.timingHistograms {
+			histogram.DeletePartialMatch(prom.Labels{"name": identifier})
+		}
 		err := existingWrapper.Stop()
 		if err != nil {
 			return errors.Wrapf(err, "Fail to stop healthcheck %s", existingWrapper.healthcheck.Base().Name)
 		}
 		delete(c.Healthchecks, identifier)
+		c.labelGuard.release(identifier)
 		existingWrapper.healthcheck.LogInfo("Healthcheck stopped")
 	}
 	return nil
 }
 
-// AddCheck add an healthcheck to the component and starts it.
-func (c *Component) AddCheck(check Healthcheck) error {
+// sameConfig returns true if an healthcheck with the same name and the same
+// configuration already exists
+func (c *Component) sameConfig(check Healthcheck) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if currentCheck, ok := c.Healthchecks[check.Base().Name]; ok {
+		// an healthcheck with an overridden interval is always replaced
+		// in order to revert the override. The enabled state override is
+		// kept (see AddCheck).
+		return currentCheck.intervalOverride == 0 &&
+			reflect.DeepEqual(currentCheck.healthcheck.GetConfig(), check.GetConfig())
+	}
+	return false
+}
+
+// SetInterval temporarily overrides the interval of a running healthcheck.
+// The override is reverted when the healthcheck is reloaded.
+func (c *Component) SetInterval(name string, interval Duration) error {
+	if interval < Duration(2*time.Second) {
+		return errors.New("The healthcheck interval should be greater than 2 second")
+	}
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	if currentCheck, ok := c.Healthchecks[check.Base().Name]; ok {
-		if reflect.DeepEqual(currentCheck.healthcheck.GetConfig(), check.GetConfig()) {
-			currentCheck.healthcheck.LogDebug("trying to replace existing healthcheck with the same config: do nothing")
-			return nil
+	wrapper, ok := c.Healthchecks[name]
+	if !ok {
+		return fmt.Errorf("Healthcheck %s not found", name)
+	}
+	if wrapper.Tick == nil {
+		return fmt.Errorf("Healthcheck %s is scheduled using a cron expression", name)
+	}
+	wrapper.healthcheck.LogInfo(fmt.Sprintf("Overriding the healthcheck interval to %s", time.Duration(interval)))
+	wrapper.intervalOverride = time.Duration(interval)
+	wrapper.interval.Store(int64(interval))
+	wrapper.Tick.Reset(time.Duration(interval))
+	return nil
+}
+
+// SetEnabled enables or disables a running healthcheck. A disabled
+// healthcheck is kept but not executed.
+// The override is kept when the healthcheck is reloaded, unless its
+// configured enabled state changes.
+func (c *Component) SetEnabled(name string, enabled bool) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	wrapper, ok := c.Healthchecks[name]
+	if !ok {
+		return fmt.Errorf("Healthcheck %s not found", name)
+	}
+	if enabled {
+		wrapper.healthcheck.LogInfo("Enabling healthcheck")
+	} else {
+		wrapper.healthcheck.LogInfo("Disabling healthcheck")
+	}
+	wrapper.enabledOverride = true
+	wrapper.enabled.Store(enabled)
+	return nil
+}
+
+// IsEnabled returns true if the healthcheck is enabled, using the state of
+// the running healthcheck if it was added to the component
+func (c *Component) IsEnabled(check Healthcheck) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if wrapper, ok := c.Healthchecks[check.Base().Name]; ok && wrapper.healthcheck == check {
+		return wrapper.enabled.Load()
+	}
+	base := check.Base()
+	return base.IsEnabled()
+}
+
+// SetMaintenance enables or disables the global maintenance. The
+// healthchecks are not executed during the global maintenance.
+func (c *Component) SetMaintenance(maintenance bool) {
+	if maintenance {
+		c.Logger.Info("Enabling the global maintenance")
+	} else {
+		c.Logger.Info("Disabling the global maintenance")
+	}
+	c.maintenance.Store(maintenance)
+}
+
+// Maintenance returns true if the global maintenance is enabled
+func (c *Component) Maintenance() bool {
+	return c.maintenance.Load()
+}
+
+// ValidateExporters verifies that the exporters of an healthcheck are
+// configured
+func (c *Component) ValidateExporters(base Base) error {
+	if c.ExporterNames == nil {
+		return nil
+	}
+	for _, name := range base.Exporters {
+		if !contains(c.ExporterNames, name) {
+			return errors.Errorf("The exporter %s of the healthcheck %s is not configured", name, base.Name)
 		}
 	}
+	return nil
+}
+
+// AddCheck add an healthcheck to the component and starts it.
+// The healthcheck is initialized outside of the component lock, so several
+// healthchecks can be added in parallel.
+func (c *Component) AddCheck(check Healthcheck) error {
+	err := c.ValidateExporters(check.Base())
+	if err != nil {
+		return err
+	}
+	if c.sameConfig(check) {
+		check.LogDebug("trying to replace existing healthcheck with the same config: do nothing")
+		return nil
+	}
 	wrapper := NewWrapper(check)
+	wrapper.events = newEventsBuffer(c.MaxExecutionEvents)
 	wrapper.healthcheck.LogInfo("Adding healthcheck")
-	err := wrapper.healthcheck.Initialize()
+	wrapper.healthcheck.SetResolver(c.Resolver)
+	err = wrapper.healthcheck.Initialize()
 	if err != nil {
 		return errors.Wrapf(err, "Fail to initialize healthcheck %s", wrapper.healthcheck.Base().Name)
 	}
-
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	// verifies if the healthcheck already exists, and removes it if needed.
 	// Updating an healthcheck is removing the old one and adding the new one.
+	existingWrapper, exists := c.Healthchecks[wrapper.healthcheck.Base().Name]
+	if exists && existingWrapper.enabledOverride {
+		existingBase := existingWrapper.healthcheck.Base()
+		newBase := check.Base()
+		if existingBase.IsEnabled() == newBase.IsEnabled() {
+			wrapper.enabledOverride = true
+			wrapper.enabled.Store(existingWrapper.enabled.Load())
+		}
+	}
 	err = c.removeCheck(wrapper.healthcheck.Base().Name)
 	if err != nil {
 		return errors.Wrapf(err, "Fail to stop existing healthcheck %s", wrapper.healthcheck.Base().Name)
 	}
 	c.startWrapper(wrapper)
 	c.Healthchecks[wrapper.healthcheck.Base().Name] = wrapper
+	c.setInfo(wrapper)
+	if !exists || existingWrapper.healthcheck.Base().Source != check.Base().Source {
+		if exists {
+			c.sourceChanged(existingWrapper.healthcheck.Base().Source)
+		}
+		c.sourceChanged(check.Base().Source)
+	}
 	return nil
 }
 
+// setInfo sets the info metric of an healthcheck, which can be joined on
+// the name label with the other healthchecks metrics.
+// The function is *not* thread-safe.
+func (c *Component) setInfo(w *Wrapper) {
+	base := w.healthcheck.Base()
+	infoLabels := c.metricsLabels(w, &Result{Labels: base.Labels})
+	infoLabels["name"] = base.Name
+	infoLabels["source"] = sourceName(base.Source)
+	c.infoGauge.With(prom.Labels(infoLabels)).Set(1)
+}
+
 // RemoveCheck Removes an healthcheck
 func (c *Component) RemoveCheck(name string) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	c.Logger.Info(fmt.Sprintf("Removing healthcheck %s", name))
-	return c.removeCheck(name)
+	existingWrapper, exists := c.Healthchecks[name]
+	err := c.removeCheck(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		c.sourceChanged(existingWrapper.healthcheck.Base().Source)
+	}
+	return nil
 }
 
 // ListChecks returns the healthchecks currently configured, sorted by name
 func (c *Component) ListChecks() []Healthcheck {
+	return c.ListChecksFiltered("", nil)
+}
+
+// ListChecksFiltered returns the healthchecks of a source (all sources if
+// empty) having all the given labels, sorted by name.
+// The source can be the source as stored in the healthchecks or as exposed
+// in the results and the metrics (for example "configuration").
+func (c *Component) ListChecksFiltered(source string, labels map[string]string) []Healthcheck {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 	result := make([]Healthcheck, 0, len(c.Healthchecks))
 	for i := range c.Healthchecks {
 		wrapper := c.Healthchecks[i]
+		base := wrapper.healthcheck.Base()
+		if source != "" && base.Source != source && sourceName(base.Source) != source {
+			continue
+		}
+		if !hasLabels(base.Labels, labels) {
+			continue
+		}
 		result = append(result, wrapper.healthcheck)
 	}
 	sort.Slice(result, func(i, j int) bool {
@@ -226,6 +778,16 @@ func (c *Component) ListChecks() []Healthcheck {
 	return result
 }
 
+// hasLabels returns true if all the expected labels are in labels
+func hasLabels(labels map[string]string, expected map[string]string) bool {
+	for k, v := range expected {
+		if value, ok := labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
 // GetCheck returns a check if it exists, otherwise an error.
 func (c *Component) GetCheck(name string) Healthcheck {
 	c.lock.RLock()
@@ -236,6 +798,34 @@ func (c *Component) GetCheck(name string) Healthcheck {
 	return nil
 }
 
+// Executed returns true if all enabled healthchecks were executed, or
+// skipped, at least once. The healthchecks scheduled using a cron
+// expression are ignored, they are not executed when they are added.
+func (c *Component) Executed() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for _, wrapper := range c.Healthchecks {
+		if wrapper.healthcheck.Base().Cron != "" {
+			continue
+		}
+		if wrapper.enabled.Load() && !wrapper.executed.Load() {
+			return false
+		}
+	}
+	return true
+}
+
+// GetExecutionEvents returns the last execution events of an healthcheck,
+// from the oldest to the most recent
+func (c *Component) GetExecutionEvents(name string) ([]ExecutionEvent, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if existingWrapper, ok := c.Healthchecks[name]; ok {
+		return existingWrapper.events.list(), nil
+	}
+	return nil, fmt.Errorf("Healthcheck %s not found", name)
+}
+
 // RemoveNonConfiguredHealthchecks takes two list of healthchecks. Delete from the
 // healthcheck component the checks which exist in the first list but not in the
 // second one
@@ -271,90 +861,131 @@ func MergeLabels(base *Base, new map[string]string) {
 
 }
 
-func (c *Component) ReloadForSource(
+// BuildChecks builds the healthchecks for a source from their
+// configurations, merging the common labels and validating the
+// configurations. The healthchecks are not added to the component.
+func (c *Component) BuildChecks(
 	source string,
 	commonLabels map[string]string,
 	command []CommandHealthcheckConfiguration,
 	dns []DNSHealthcheckConfiguration,
 	tcp []TCPHealthcheckConfiguration,
 	http []HTTPHealthcheckConfiguration,
-	tls []TLSHealthcheckConfiguration) error {
+	tls []TLSHealthcheckConfiguration,
+	grpc []GRPCHealthcheckConfiguration,
+	postgres []PostgresHealthcheckConfiguration,
+	udp []UDPHealthcheckConfiguration) ([]Healthcheck, error) {
 
-	oldChecks := c.SourceChecksNames(source)
-	newChecks := make(map[string]bool)
+	checks := []Healthcheck{}
 	for i := range command {
 		config := &command[i]
 		MergeLabels(&config.Base, commonLabels)
 		config.Base.Source = source
-		newChecks[config.Base.Name] = true
 		err := config.Validate()
 		if err != nil {
-			return err
-		}
-		newCheck := NewCommandHealthcheck(c.Logger, config)
-		err = c.AddCheck(newCheck)
-		if err != nil {
-			return errors.Wrapf(err, "Fail to add healthcheck %s", newCheck.Base().Name)
+			return nil, err
 		}
+		checks = append(checks, NewCommandHealthcheck(c.Logger, config))
 	}
 	for i := range dns {
 		config := &dns[i]
 		MergeLabels(&config.Base, commonLabels)
 		config.Base.Source = source
-		newChecks[config.Base.Name] = true
 		err := config.Validate()
 		if err != nil {
-			return err
-		}
-		newCheck := NewDNSHealthcheck(c.Logger, config)
-		err = c.AddCheck(newCheck)
-		if err != nil {
-			return errors.Wrapf(err, "Fail to add healthcheck %s", newCheck.Base().Name)
+			return nil, err
 		}
+		checks = append(checks, NewDNSHealthcheck(c.Logger, config))
 	}
 	for i := range http {
 		config := &http[i]
 		MergeLabels(&config.Base, commonLabels)
 		config.Base.Source = source
-		newChecks[config.Base.Name] = true
 		err := config.Validate()
 		if err != nil {
-			return err
-		}
-		newCheck := NewHTTPHealthcheck(c.Logger, config)
-		err = c.AddCheck(newCheck)
-		if err != nil {
-			return errors.Wrapf(err, "Fail to add healthcheck %s", newCheck.Base().Name)
+			return nil, err
 		}
+		checks = append(checks, NewHTTPHealthcheck(c.Logger, config))
 	}
 	for i := range tcp {
 		config := &tcp[i]
 		MergeLabels(&config.Base, commonLabels)
 		config.Base.Source = source
-		newChecks[config.Base.Name] = true
 		err := config.Validate()
 		if err != nil {
-			return err
-		}
-		newCheck := NewTCPHealthcheck(c.Logger, config)
-		err = c.AddCheck(newCheck)
-		if err != nil {
-			return errors.Wrapf(err, "Fail to add healthcheck %s", newCheck.Base().Name)
+			return nil, err
 		}
+		checks = append(checks, NewTCPHealthcheck(c.Logger, config))
 	}
 	for i := range tls {
 		config := &tls[i]
 		MergeLabels(&config.Base, commonLabels)
 		config.Base.Source = source
-		newChecks[config.Base.Name] = true
 		err := config.Validate()
 		if err != nil {
-			return err
+			return nil, err
+		}
+		checks = append(checks, NewTLSHealthcheck(c.Logger, config))
+	}
+	for i := range grpc {
+		config := &grpc[i]
+		MergeLabels(&config.Base, commonLabels)
+		config.Base.Source = source
+		err := config.Validate()
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, NewGRPCHealthcheck(c.Logger, config))
+	}
+	for i := range postgres {
+		config := &postgres[i]
+		MergeLabels(&config.Base, commonLabels)
+		config.Base.Source = source
+		err := config.Validate()
+		if err != nil {
+			return nil, err
 		}
-		newCheck := NewTLSHealthcheck(c.Logger, config)
-		err = c.AddCheck(newCheck)
+		checks = append(checks, NewPostgresHealthcheck(c.Logger, config))
+	}
+	for i := range udp {
+		config := &udp[i]
+		MergeLabels(&config.Base, commonLabels)
+		config.Base.Source = source
+		err := config.Validate()
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, NewUDPHealthcheck(c.Logger, config))
+	}
+	return checks, nil
+}
+
+// ReloadForSource replaces the healthchecks of a source by the given ones.
+// Healthchecks whose configuration is unchanged keep running: their
+// schedule and their metrics are not reset.
+func (c *Component) ReloadForSource(
+	source string,
+	commonLabels map[string]string,
+	command []CommandHealthcheckConfiguration,
+	dns []DNSHealthcheckConfiguration,
+	tcp []TCPHealthcheckConfiguration,
+	http []HTTPHealthcheckConfiguration,
+	tls []TLSHealthcheckConfiguration,
+	grpc []GRPCHealthcheckConfiguration,
+	postgres []PostgresHealthcheckConfiguration,
+	udp []UDPHealthcheckConfiguration) error {
+
+	oldChecks := c.SourceChecksNames(source)
+	newChecks := make(map[string]bool)
+	checks, err := c.BuildChecks(source, commonLabels, command, dns, tcp, http, tls, grpc, postgres, udp)
+	if err != nil {
+		return err
+	}
+	for _, check := range checks {
+		newChecks[check.Base().Name] = true
+		err := c.AddCheck(check)
 		if err != nil {
-			return errors.Wrapf(err, "Fail to add healthcheck %s", newCheck.Base().Name)
+			return errors.Wrapf(err, "Fail to add healthcheck %s", check.Base().Name)
 		}
 	}
 	return c.RemoveNonConfiguredHealthchecks(oldChecks, newChecks)
@@ -1,6 +1,14 @@
 package healthcheck
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -194,3 +202,832 @@ func TestMergeLabels(t *testing.T) {
 	}
 
 }
+
+func TestSources(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	for _, name := range []string{"foo", "bar"} {
+		healthcheck := NewTCPHealthcheck(
+			logger,
+			&TCPHealthcheckConfiguration{
+				Base: Base{
+					Name:     name,
+					Interval: Duration(time.Second * 5),
+					Source:   SourceAPI,
+				},
+				Target:  "127.0.0.1",
+				Port:    9000,
+				Timeout: Duration(time.Second * 3),
+			},
+		)
+		err = component.AddCheck(healthcheck)
+		if err != nil {
+			t.Fatalf("Fail to add the healthcheck\n%v", err)
+		}
+	}
+	sources := component.Sources()
+	if len(sources) != 1 {
+		t.Fatalf("Expected 1 source, got %d", len(sources))
+	}
+	if sources[0].Source != SourceAPI || sources[0].Checks != 2 {
+		t.Fatalf("Invalid source statistics %v", sources[0])
+	}
+	lastChange := sources[0].LastChange
+	err = component.RemoveCheck("foo")
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+	sources = component.Sources()
+	if sources[0].Checks != 1 {
+		t.Fatalf("Invalid source statistics %v", sources[0])
+	}
+	if sources[0].LastChange.Before(lastChange) {
+		t.Fatalf("The source last change time was not updated")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestMetricsNamespace(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	prom.Config = &prometheus.Configuration{Namespace: "foo"}
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.AddCheck(NewTCPHealthcheck(
+		zap.NewExample(),
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Second * 5),
+			},
+			Target:  "127.0.0.1",
+			Port:    9000,
+			Timeout: Duration(time.Second * 3),
+		},
+	))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	defer component.Stop()
+	families, err := prom.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Fail to gather the metrics\n%v", err)
+	}
+	found := false
+	for _, family := range families {
+		if family.GetName() == "foo_healthcheck_checks" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("The namespaced metric was not found")
+	}
+}
+
+func TestSetInterval(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	newCheck := func() *TCPHealthcheck {
+		return NewTCPHealthcheck(
+			logger,
+			&TCPHealthcheckConfiguration{
+				Base: Base{
+					Name:     "foo",
+					Interval: Duration(time.Minute * 10),
+				},
+				Target:  "127.0.0.1",
+				Port:    9000,
+				Timeout: Duration(time.Second * 3),
+			},
+		)
+	}
+	err = component.AddCheck(newCheck())
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	err = component.SetInterval("foo", Duration(time.Second))
+	if err == nil {
+		t.Fatalf("Was expecting an error for a too small interval")
+	}
+	err = component.SetInterval("bar", Duration(time.Second*5))
+	if err == nil {
+		t.Fatalf("Was expecting an error for an unknown healthcheck")
+	}
+	err = component.SetInterval("foo", Duration(time.Second*5))
+	if err != nil {
+		t.Fatalf("Fail to override the interval\n%v", err)
+	}
+	wrapper := component.Healthchecks["foo"]
+	if wrapper.intervalOverride != time.Second*5 {
+		t.Fatalf("The interval was not overridden")
+	}
+	// reloading the same configuration reverts the override
+	err = component.AddCheck(newCheck())
+	if err != nil {
+		t.Fatalf("Fail to reload the healthcheck\n%v", err)
+	}
+	if component.Healthchecks["foo"] == wrapper || component.Healthchecks["foo"].intervalOverride != 0 {
+		t.Fatalf("The interval override was not reverted")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestSetEnabled(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	enabled := false
+	newCheck := func() *TCPHealthcheck {
+		return NewTCPHealthcheck(
+			logger,
+			&TCPHealthcheckConfiguration{
+				Base: Base{
+					Name:     "foo",
+					Interval: Duration(time.Minute * 10),
+					Enabled:  &enabled,
+				},
+				Target:  "127.0.0.1",
+				Port:    9000,
+				Timeout: Duration(time.Second * 3),
+			},
+		)
+	}
+	check := newCheck()
+	err = component.AddCheck(check)
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	if component.IsEnabled(check) {
+		t.Fatalf("The healthcheck should be disabled")
+	}
+	if !component.Executed() {
+		t.Fatalf("Disabled healthchecks should not be waited for")
+	}
+	err = component.SetEnabled("bar", true)
+	if err == nil {
+		t.Fatalf("Was expecting an error for an unknown healthcheck")
+	}
+	err = component.SetEnabled("foo", true)
+	if err != nil {
+		t.Fatalf("Fail to enable the healthcheck\n%v", err)
+	}
+	if !component.IsEnabled(check) {
+		t.Fatalf("The healthcheck should be enabled")
+	}
+	// reloading the same configuration keeps the override
+	err = component.AddCheck(newCheck())
+	if err != nil {
+		t.Fatalf("Fail to reload the healthcheck\n%v", err)
+	}
+	if !component.IsEnabled(component.GetCheck("foo")) {
+		t.Fatalf("The enabled state override was reverted")
+	}
+	// the override is also kept when another field changes
+	check = newCheck()
+	check.Config.Port = 9001
+	err = component.AddCheck(check)
+	if err != nil {
+		t.Fatalf("Fail to reload the healthcheck\n%v", err)
+	}
+	if !component.IsEnabled(check) {
+		t.Fatalf("The enabled state override was reverted")
+	}
+	// changing the configured enabled state reverts the override
+	err = component.SetEnabled("foo", false)
+	if err != nil {
+		t.Fatalf("Fail to disable the healthcheck\n%v", err)
+	}
+	check = newCheck()
+	configuredEnabled := true
+	check.Config.Base.Enabled = &configuredEnabled
+	err = component.AddCheck(check)
+	if err != nil {
+		t.Fatalf("Fail to reload the healthcheck\n%v", err)
+	}
+	if !component.IsEnabled(check) {
+		t.Fatalf("The enabled state override was not reverted")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestStatusGauge(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *Result, 10)
+	component, err := New(logger, chanResult, prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.AddCheck(NewTCPHealthcheck(
+		logger,
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Minute * 10),
+			},
+			Target:  "127.0.0.1",
+			Port:    9000,
+			Timeout: Duration(time.Second * 1),
+		},
+	))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	select {
+	case <-chanResult:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("The healthcheck was not executed")
+	}
+	statusSeries := func() []float64 {
+		families, err := prom.Registry.Gather()
+		if err != nil {
+			t.Fatalf("Fail to gather the metrics\n%v", err)
+		}
+		result := []float64{}
+		for _, family := range families {
+			if family.GetName() == "healthcheck_status" {
+				for _, metric := range family.GetMetric() {
+					result = append(result, metric.GetGauge().GetValue())
+				}
+			}
+		}
+		return result
+	}
+	series := statusSeries()
+	if len(series) != 1 || series[0] != 0 {
+		t.Fatalf("Invalid status gauge %v", series)
+	}
+	err = component.RemoveCheck("foo")
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+	if len(statusSeries()) != 0 {
+		t.Fatalf("The status gauge was not removed")
+	}
+}
+
+func TestMaintenanceMetrics(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *Result, 10)
+	component, err := New(logger, chanResult, prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	start := time.Now().Add(-time.Minute)
+	end := time.Now().Add(time.Hour)
+	err = component.AddCheck(NewTCPHealthcheck(
+		logger,
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:               "foo",
+				Interval:           Duration(time.Minute * 10),
+				MaintenanceWindows: []MaintenanceWindow{{Start: &start, End: &end}},
+			},
+			Target:  "127.0.0.1",
+			Port:    9000,
+			Timeout: Duration(time.Second * 1),
+		},
+	))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	select {
+	case result := <-chanResult:
+		if result.Success || !result.InMaintenance {
+			t.Fatalf("Invalid result %+v", result)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("The healthcheck was not executed")
+	}
+	families, err := prom.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Fail to gather the metrics\n%v", err)
+	}
+	counters := map[string]float64{}
+	for _, family := range families {
+		switch family.GetName() {
+		case "healthcheck_status":
+			if len(family.GetMetric()) != 0 {
+				t.Fatalf("The status gauge should not be set during a maintenance")
+			}
+		case "healthcheck_total":
+			for _, metric := range family.GetMetric() {
+				for _, label := range metric.GetLabel() {
+					if label.GetName() == "status" {
+						counters[label.GetValue()] += metric.GetCounter().GetValue()
+					}
+				}
+			}
+		}
+	}
+	if counters[StatusFailure] != 0 {
+		t.Fatalf("The failure counter should not be incremented during a maintenance: %v", counters)
+	}
+	if counters[StatusMaintenance] != 1 {
+		t.Fatalf("The execution should be counted as maintenance: %v", counters)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestDriftGauge(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *Result, 10)
+	component, err := New(logger, chanResult, prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.AddCheck(NewTCPHealthcheck(
+		logger,
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Millisecond * 200),
+			},
+			Target:  "127.0.0.1",
+			Port:    9000,
+			Timeout: Duration(time.Millisecond * 100),
+		},
+	))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-chanResult:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("The healthcheck was not executed")
+		}
+	}
+	families, err := prom.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Fail to gather the metrics\n%v", err)
+	}
+	found := false
+	for _, family := range families {
+		if family.GetName() == "healthcheck_schedule_drift_seconds" {
+			for _, metric := range family.GetMetric() {
+				found = true
+				drift := metric.GetGauge().GetValue()
+				if drift < -0.1 || drift > 0.1 {
+					t.Fatalf("Invalid drift %f", drift)
+				}
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("The drift gauge is missing")
+	}
+	err = component.RemoveCheck("foo")
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+}
+
+func TestInfoGauge(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{"env"})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	check := NewTCPHealthcheck(
+		logger,
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Minute * 10),
+				Labels:   map[string]string{"env": "prod"},
+			},
+			Target:  "127.0.0.1",
+			Port:    9000,
+			Timeout: Duration(time.Second * 1),
+		},
+	)
+	check.SetSource(SourceAPI)
+	err = component.AddCheck(check)
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	infoSeries := func() []map[string]string {
+		families, err := prom.Registry.Gather()
+		if err != nil {
+			t.Fatalf("Fail to gather the metrics\n%v", err)
+		}
+		result := []map[string]string{}
+		for _, family := range families {
+			if family.GetName() == "healthcheck_info" {
+				for _, metric := range family.GetMetric() {
+					if metric.GetGauge().GetValue() != 1 {
+						t.Fatalf("Invalid info value %f", metric.GetGauge().GetValue())
+					}
+					labels := make(map[string]string)
+					for _, label := range metric.GetLabel() {
+						labels[label.GetName()] = label.GetValue()
+					}
+					result = append(result, labels)
+				}
+			}
+		}
+		return result
+	}
+	series := infoSeries()
+	if len(series) != 1 {
+		t.Fatalf("Expected one info series, got %d", len(series))
+	}
+	if series[0]["name"] != "foo" || series[0]["source"] != SourceAPI || series[0]["env"] != "prod" {
+		t.Fatalf("Invalid info labels %v", series[0])
+	}
+	err = component.RemoveCheck("foo")
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+	if len(infoSeries()) != 0 {
+		t.Fatalf("The info gauge was not removed")
+	}
+}
+
+func TestStartupJitter(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *Result, 10)
+	component, err := New(logger, chanResult, prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	component.StartupJitter = Duration(time.Hour)
+	for _, base := range []Base{
+		{Name: "foo", Interval: Duration(time.Minute * 10), StartupJitter: Duration(time.Millisecond)},
+		{Name: "bar", Interval: Duration(time.Minute * 10)},
+	} {
+		err = component.AddCheck(NewTCPHealthcheck(
+			logger,
+			&TCPHealthcheckConfiguration{
+				Base:    base,
+				Target:  "127.0.0.1",
+				Port:    9000,
+				Timeout: Duration(time.Second * 1),
+			},
+		))
+		if err != nil {
+			t.Fatalf("Fail to add the healthcheck\n%v", err)
+		}
+	}
+	select {
+	case result := <-chanResult:
+		if result.Name != "foo" {
+			t.Fatalf("Invalid healthcheck executed %s", result.Name)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("The healthcheck was not executed")
+	}
+	// stopping an healthcheck waiting for its first execution should not
+	// block
+	start := time.Now()
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("The component was not stopped during the startup jitter")
+	}
+}
+
+func TestListChecksFiltered(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	checks := []struct {
+		name   string
+		source string
+		labels map[string]string
+	}{
+		{name: "a", source: SourceConfig, labels: map[string]string{"env": "prod", "team": "web"}},
+		{name: "b", source: SourceAPI, labels: map[string]string{"env": "prod"}},
+		{name: "c", source: SourceAPI, labels: map[string]string{"env": "dev"}},
+	}
+	for _, c := range checks {
+		check := NewTCPHealthcheck(
+			logger,
+			&TCPHealthcheckConfiguration{
+				Base: Base{
+					Name:     c.name,
+					Interval: Duration(time.Minute * 10),
+					Labels:   c.labels,
+				},
+				Target:  "127.0.0.1",
+				Port:    9000,
+				Timeout: Duration(time.Second * 1),
+			},
+		)
+		check.SetSource(c.source)
+		err = component.AddCheck(check)
+		if err != nil {
+			t.Fatalf("Fail to add the healthcheck\n%v", err)
+		}
+	}
+	cases := []struct {
+		source   string
+		labels   map[string]string
+		expected []string
+	}{
+		{expected: []string{"a", "b", "c"}},
+		{source: SourceAPI, expected: []string{"b", "c"}},
+		{source: "configuration", expected: []string{"a"}},
+		{labels: map[string]string{"env": "prod"}, expected: []string{"a", "b"}},
+		{source: SourceAPI, labels: map[string]string{"env": "prod"}, expected: []string{"b"}},
+		{labels: map[string]string{"env": "prod", "team": "db"}, expected: []string{}},
+	}
+	for _, c := range cases {
+		names := []string{}
+		for _, check := range component.ListChecksFiltered(c.source, c.labels) {
+			names = append(names, check.Base().Name)
+		}
+		if !reflect.DeepEqual(names, c.expected) {
+			t.Fatalf("Invalid healthchecks for %s %v: %v", c.source, c.labels, names)
+		}
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestTimingHistograms(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *Result, 10)
+	component, err := New(logger, chanResult, prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.AddCheck(NewHTTPHealthcheck(
+		logger,
+		&HTTPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Minute * 10),
+			},
+			ValidStatus: []uint{200},
+			Port:        uint(port),
+			Target:      "127.0.0.1",
+			Protocol:    HTTP,
+			Path:        "/",
+			Timeout:     Duration(time.Second * 1),
+		},
+	))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	select {
+	case <-chanResult:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("The healthcheck was not executed")
+	}
+	connectSamples := func() uint64 {
+		families, err := prom.Registry.Gather()
+		if err != nil {
+			t.Fatalf("Fail to gather the metrics\n%v", err)
+		}
+		count := uint64(0)
+		for _, family := range families {
+			if family.GetName() == "healthcheck_connect_seconds" {
+				for _, metric := range family.GetMetric() {
+					count += metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+		return count
+	}
+	if connectSamples() != 1 {
+		t.Fatalf("The connection duration was not observed")
+	}
+	err = component.RemoveCheck("foo")
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+	if connectSamples() != 0 {
+		t.Fatalf("The connection duration histogram was not removed")
+	}
+}
+
+func TestMaxConcurrentChecks(t *testing.T) {
+	var lock sync.Mutex
+	inFlight := 0
+	maxInFlight := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		lock.Unlock()
+		time.Sleep(200 * time.Millisecond)
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *Result, 10)
+	component, err := New(logger, chanResult, prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	component.StartupJitter = 0
+	component.SetMaxConcurrentChecks(1)
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Fail to parse the test server port\n%v", err)
+	}
+	for _, name := range []string{"foo", "bar", "baz"} {
+		err = component.AddCheck(NewHTTPHealthcheck(
+			logger,
+			&HTTPHealthcheckConfiguration{
+				Base: Base{
+					Name:     name,
+					Interval: Duration(time.Minute * 10),
+				},
+				ValidStatus: []uint{200},
+				Target:      "127.0.0.1",
+				Port:        uint(port),
+				Protocol:    HTTP,
+				Path:        "/",
+				Timeout:     Duration(time.Second * 2),
+			},
+		))
+		if err != nil {
+			t.Fatalf("Fail to add the healthcheck\n%v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case result := <-chanResult:
+			if !result.Success {
+				t.Fatalf("The healthcheck %s failed: %s", result.Name, result.Message)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("The healthchecks were not executed")
+		}
+	}
+	lock.Lock()
+	if maxInFlight != 1 {
+		t.Fatalf("Expected 1 concurrent execution, got %d", maxInFlight)
+	}
+	lock.Unlock()
+	var buf strings.Builder
+	err = prom.Write(&buf)
+	if err != nil {
+		t.Fatalf("Fail to write the metrics\n%v", err)
+	}
+	if !strings.Contains(buf.String(), "healthcheck_executions_in_flight") {
+		t.Fatalf("The in flight gauge is missing\n%s", buf.String())
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	component.SetMaxConcurrentChecks(1)
+	release, err := component.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Fail to acquire an execution slot\n%v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = component.Acquire(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Was expecting a deadline error, got %v", err)
+	}
+	release()
+	release, err = component.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Fail to acquire the released execution slot\n%v", err)
+	}
+	release()
+}
+
+func TestValidateExporters(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	base := Base{Name: "foo", Exporters: []string{"kafka"}}
+	err = component.ValidateExporters(base)
+	if err != nil {
+		t.Fatalf("The exporters should not be validated without configured exporters\n%v", err)
+	}
+	component.ExporterNames = []string{"kafka", "riemann"}
+	err = component.ValidateExporters(base)
+	if err != nil {
+		t.Fatalf("The exporters should be valid\n%v", err)
+	}
+	component.ExporterNames = []string{"riemann"}
+	err = component.ValidateExporters(base)
+	if err == nil {
+		t.Fatalf("Was expecting an error for an exporter not configured")
+	}
+	err = component.AddCheck(NewTCPHealthcheck(logger, &TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:      "foo",
+			Interval:  Duration(time.Minute * 10),
+			Exporters: []string{"kafka"},
+		},
+		Target:  "127.0.0.1",
+		Port:    3000,
+		Timeout: Duration(time.Second * 3),
+	}))
+	if err == nil {
+		t.Fatalf("Was expecting an error for an exporter not configured")
+	}
+}
@@ -0,0 +1,197 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// SelfHealthcheckConfiguration defines the configuration of the healthcheck
+// monitoring Cabourotte itself
+type SelfHealthcheckConfiguration struct {
+	Base `json:",inline" yaml:",inline"`
+	// MaxGoroutines the maximum number of goroutines (0 to disable)
+	MaxGoroutines int `json:"max-goroutines,omitempty" yaml:"max-goroutines,omitempty"`
+	// MaxMemory the maximum allocated heap memory, in bytes (0 to disable)
+	MaxMemory uint64 `json:"max-memory,omitempty" yaml:"max-memory,omitempty"`
+	// MaxResultChanUsage the maximum usage of the result channel, between 0
+	// and 1 (DefaultMaxResultChanUsage if not set)
+	MaxResultChanUsage float64 `json:"max-result-chan-usage,omitempty" yaml:"max-result-chan-usage,omitempty"`
+}
+
+// DefaultMaxResultChanUsage the default maximum usage of the result channel
+const DefaultMaxResultChanUsage = 0.8
+
+// DefaultSelfHealthcheckName the default name of the self healthcheck
+const DefaultSelfHealthcheckName = "self"
+
+// SelfHealthcheck defines an healthcheck monitoring Cabourotte internals
+type SelfHealthcheck struct {
+	Logger *zap.Logger
+	Config *SelfHealthcheckConfiguration
+	// ChanResult the channel containing the results waiting to be exported
+	ChanResult chan *Result
+	// ExportersStatus returns, for each exporter, if it is started
+	ExportersStatus func() map[string]bool
+}
+
+// Validate validates the healthcheck configuration
+func (config *SelfHealthcheckConfiguration) Validate() error {
+	if config.Base.Name == "" {
+		return errors.New("The healthcheck name is missing")
+	}
+	if err := config.Base.validate(); err != nil {
+		return err
+	}
+	if config.Base.Cron == "" && config.Base.Interval < Duration(2*time.Second) {
+		return errors.New("The healthcheck interval should be greater than 2 second")
+	}
+	if config.MaxGoroutines < 0 {
+		return errors.New("The maximum number of goroutines should be positive")
+	}
+	if config.MaxResultChanUsage < 0 || config.MaxResultChanUsage > 1 {
+		return errors.New("The maximum result channel usage should be between 0 and 1")
+	}
+	return nil
+}
+
+// Initialize the healthcheck.
+func (h *SelfHealthcheck) Initialize() error {
+	return nil
+}
+
+// GetConfig get the config
+func (h *SelfHealthcheck) GetConfig() interface{} {
+	return h.Config
+}
+
+// Base get the base configuration
+func (h *SelfHealthcheck) Base() Base {
+	return h.Config.Base
+}
+
+// SetSource set the healthcheck source
+func (h *SelfHealthcheck) SetSource(source string) {
+	h.Config.Base.Source = source
+}
+
+// SetResolver set the healthcheck resolver. The self healthcheck does not
+// use it.
+func (h *SelfHealthcheck) SetResolver(resolver *Resolver) {
+}
+
+// Summary returns an healthcheck summary
+func (h *SelfHealthcheck) Summary() string {
+	if h.Config.Base.Description != "" {
+		return fmt.Sprintf("%s, Cabourotte internals", h.Config.Base.Description)
+	}
+	return "Cabourotte internals"
+}
+
+// LogError logs an error with context
+func (h *SelfHealthcheck) LogError(err error, message string) {
+	h.Logger.Error(err.Error(),
+		zap.String("extra", message),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogDebug logs a message with context
+func (h *SelfHealthcheck) LogDebug(message string) {
+	h.Logger.Debug(message,
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogInfo logs a message with context
+func (h *SelfHealthcheck) LogInfo(message string) {
+	h.Logger.Info(message,
+		zap.String("name", h.Config.Base.Name))
+}
+
+// Execute evaluates Cabourotte internal signals
+func (h *SelfHealthcheck) Execute(ctx context.Context) (Annotations, error) {
+	h.LogDebug("start executing healthcheck")
+	annotations := Annotations{}
+	failures := []string{}
+
+	goroutines := runtime.NumGoroutine()
+	annotations["goroutines"] = strconv.Itoa(goroutines)
+	if h.Config.MaxGoroutines != 0 && goroutines > h.Config.MaxGoroutines {
+		failures = append(failures, fmt.Sprintf("%d goroutines running (max %d)", goroutines, h.Config.MaxGoroutines))
+	}
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	annotations["memory"] = strconv.FormatUint(memStats.HeapAlloc, 10)
+	if h.Config.MaxMemory != 0 && memStats.HeapAlloc > h.Config.MaxMemory {
+		failures = append(failures, fmt.Sprintf("%d bytes of memory allocated (max %d)", memStats.HeapAlloc, h.Config.MaxMemory))
+	}
+
+	if h.ChanResult != nil && cap(h.ChanResult) != 0 {
+		maxUsage := h.Config.MaxResultChanUsage
+		if maxUsage == 0 {
+			maxUsage = DefaultMaxResultChanUsage
+		}
+		size := len(h.ChanResult)
+		annotations["result-chan-size"] = strconv.Itoa(size)
+		usage := float64(size) / float64(cap(h.ChanResult))
+		if usage > maxUsage {
+			failures = append(failures, fmt.Sprintf("%d results waiting to be exported (capacity %d)", size, cap(h.ChanResult)))
+		}
+	}
+
+	if h.ExportersStatus != nil {
+		stopped := []string{}
+		for name, started := range h.ExportersStatus() {
+			if !started {
+				stopped = append(stopped, name)
+			}
+		}
+		if len(stopped) != 0 {
+			sort.Strings(stopped)
+			annotations["stopped-exporters"] = strings.Join(stopped, ",")
+			failures = append(failures, fmt.Sprintf("exporters %s are stopped", strings.Join(stopped, ", ")))
+		}
+	}
+
+	if len(failures) != 0 {
+		return annotations, fmt.Errorf("Cabourotte is unhealthy: %s", strings.Join(failures, ", "))
+	}
+	return annotations, nil
+}
+
+// NewSelfHealthcheck creates a self healthcheck from a logger and a configuration
+func NewSelfHealthcheck(logger *zap.Logger, config *SelfHealthcheckConfiguration) *SelfHealthcheck {
+	return &SelfHealthcheck{
+		Logger: logger,
+		Config: config,
+	}
+}
+
+// MarshalJSON marshal to json a self healthcheck
+func (h *SelfHealthcheck) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Config)
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SelfHealthcheckConfiguration) DeepCopyInto(out *SelfHealthcheckConfiguration) {
+	*out = *in
+	in.Base.DeepCopyInto(&out.Base)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SelfHealthcheckConfiguration.
+func (in *SelfHealthcheckConfiguration) DeepCopy() *SelfHealthcheckConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(SelfHealthcheckConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
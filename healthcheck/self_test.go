@@ -0,0 +1,56 @@
+package healthcheck
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestSelfExecute(t *testing.T) {
+	chanResult := make(chan *Result, 10)
+	exporters := map[string]bool{"foo": true, "bar": true}
+	h := SelfHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &SelfHealthcheckConfiguration{
+			Base: Base{
+				Name: "self",
+			},
+		},
+		ChanResult: chanResult,
+		ExportersStatus: func() map[string]bool {
+			return exporters
+		},
+	}
+	annotations, err := h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	if annotations["goroutines"] == "" || annotations["memory"] == "" || annotations["result-chan-size"] != "0" {
+		t.Fatalf("Invalid annotations %v", annotations)
+	}
+	for i := 0; i < 9; i++ {
+		chanResult <- &Result{}
+	}
+	_, err = h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("Was expecting an error because the result channel is saturated")
+	}
+	for i := 0; i < 9; i++ {
+		<-chanResult
+	}
+	exporters["bar"] = false
+	annotations, err = h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("Was expecting an error because an exporter is stopped")
+	}
+	if annotations["stopped-exporters"] != "bar" {
+		t.Fatalf("Invalid annotations %v", annotations)
+	}
+	exporters["bar"] = true
+	h.Config.MaxGoroutines = 1
+	_, err = h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("Was expecting an error because of the number of goroutines")
+	}
+}
@@ -0,0 +1,27 @@
+package healthcheck
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// shouldFailSummary the suffix of the summary of the healthchecks
+// configured with should-fail
+const shouldFailSummary = ". This healthcheck has should-fail=true."
+
+// invertResult inverts the result of an healthcheck configured with
+// should-fail: the healthcheck is successful only if its execution failed.
+// A degraded target is reachable, and is so considered as a success of the
+// execution.
+func invertResult(checkType string, target string, annotations Annotations, err error) (Annotations, error) {
+	if annotations == nil {
+		annotations = Annotations{}
+	}
+	var degradedErr *DegradedError
+	if err == nil || errors.As(err, &degradedErr) {
+		return annotations, fmt.Errorf("%s check is successful on %s but an error was expected", checkType, target)
+	}
+	annotations["expected-error"] = err.Error()
+	return annotations, nil
+}
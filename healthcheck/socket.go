@@ -0,0 +1,51 @@
+package healthcheck
+
+import (
+	"github.com/pkg/errors"
+)
+
+// SocketOptions the options applied on the healthchecks sockets
+type SocketOptions struct {
+	// ReadBufferSize the size of the socket receive buffer (SO_RCVBUF), in
+	// bytes (system default if not set)
+	ReadBufferSize int `json:"read-buffer-size,omitempty" yaml:"read-buffer-size,omitempty"`
+	// ReuseAddr enables SO_REUSEADDR on the socket
+	ReuseAddr bool `json:"reuse-addr,omitempty" yaml:"reuse-addr,omitempty"`
+	// Linger the SO_LINGER timeout, in seconds. A value of 0 resets the
+	// connection on close, which avoids sockets in the TIME_WAIT state.
+	Linger *int `json:"linger,omitempty" yaml:"linger,omitempty"`
+}
+
+// Validate validates the socket options
+func (o *SocketOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.ReadBufferSize < 0 {
+		return errors.New("The socket read buffer size should be positive")
+	}
+	if o.Linger != nil && *o.Linger < 0 {
+		return errors.New("The socket linger timeout should be positive")
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SocketOptions) DeepCopyInto(out *SocketOptions) {
+	*out = *in
+	if in.Linger != nil {
+		in, out := &in.Linger, &out.Linger
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SocketOptions.
+func (in *SocketOptions) DeepCopy() *SocketOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SocketOptions)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,21 @@
+//go:build !unix
+
+package healthcheck
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// control returns the function applying the socket options, to be used as a
+// dialer Control function. Socket options are only supported on Unix
+// systems.
+func (o *SocketOptions) control() func(network, address string, c syscall.RawConn) error {
+	if o == nil {
+		return nil
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		return errors.New("Socket options are not supported on this system")
+	}
+}
@@ -0,0 +1,48 @@
+//go:build unix
+
+package healthcheck
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// control returns the function applying the socket options, to be used as a
+// dialer Control function
+func (o *SocketOptions) control() func(network, address string, c syscall.RawConn) error {
+	if o == nil {
+		return nil
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		var optErr error
+		err := c.Control(func(fd uintptr) {
+			if o.ReuseAddr {
+				optErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+				if optErr != nil {
+					optErr = errors.Wrap(optErr, "Fail to set SO_REUSEADDR")
+					return
+				}
+			}
+			if o.ReadBufferSize != 0 {
+				optErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF, o.ReadBufferSize)
+				if optErr != nil {
+					optErr = errors.Wrap(optErr, "Fail to set SO_RCVBUF")
+					return
+				}
+			}
+			if o.Linger != nil {
+				linger := syscall.Linger{Onoff: 1, Linger: int32(*o.Linger)}
+				optErr = syscall.SetsockoptLinger(int(fd), syscall.SOL_SOCKET, syscall.SO_LINGER, &linger)
+				if optErr != nil {
+					optErr = errors.Wrap(optErr, "Fail to set SO_LINGER")
+					return
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+		return optErr
+	}
+}
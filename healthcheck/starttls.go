@@ -0,0 +1,114 @@
+package healthcheck
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// StartTLSSMTP upgrades the connection using the SMTP STARTTLS command
+	StartTLSSMTP = "smtp"
+	// StartTLSIMAP upgrades the connection using the IMAP STARTTLS command
+	StartTLSIMAP = "imap"
+	// StartTLSPostgres upgrades the connection using a PostgreSQL SSLRequest
+	StartTLSPostgres = "postgres"
+)
+
+// postgresSSLRequestCode the code of the PostgreSQL SSLRequest message
+const postgresSSLRequestCode = 80877103
+
+// validStartTLS returns true if the STARTTLS protocol is supported
+func validStartTLS(protocol string) bool {
+	return protocol == StartTLSSMTP || protocol == StartTLSIMAP || protocol == StartTLSPostgres
+}
+
+// startTLS performs the plaintext negotiation of the given protocol in
+// order to upgrade the connection to TLS
+func startTLS(conn net.Conn, protocol string) error {
+	switch protocol {
+	case StartTLSSMTP:
+		return startTLSSMTP(conn)
+	case StartTLSIMAP:
+		return startTLSIMAP(conn)
+	case StartTLSPostgres:
+		return startTLSPostgres(conn)
+	}
+	return fmt.Errorf("Unsupported STARTTLS protocol %s", protocol)
+}
+
+// startTLSSMTP upgrades a SMTP connection
+func startTLSSMTP(conn net.Conn) error {
+	text := textproto.NewConn(conn)
+	if _, _, err := text.ReadResponse(220); err != nil {
+		return errors.Wrapf(err, "Invalid SMTP greeting")
+	}
+	if err := text.PrintfLine("EHLO cabourotte"); err != nil {
+		return errors.Wrapf(err, "Fail to send the SMTP EHLO command")
+	}
+	_, message, err := text.ReadResponse(250)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid SMTP EHLO response")
+	}
+	if !strings.Contains(strings.ToUpper(message), "STARTTLS") {
+		return errors.New("The SMTP server does not support STARTTLS")
+	}
+	if err := text.PrintfLine("STARTTLS"); err != nil {
+		return errors.Wrapf(err, "Fail to send the SMTP STARTTLS command")
+	}
+	if _, _, err := text.ReadResponse(220); err != nil {
+		return errors.Wrapf(err, "Invalid SMTP STARTTLS response")
+	}
+	return nil
+}
+
+// startTLSIMAP upgrades an IMAP connection
+func startTLSIMAP(conn net.Conn) error {
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	greeting, err := reader.ReadLine()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to read the IMAP greeting")
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return fmt.Errorf("Invalid IMAP greeting: %s", greeting)
+	}
+	if _, err := io.WriteString(conn, "a001 STARTTLS\r\n"); err != nil {
+		return errors.Wrapf(err, "Fail to send the IMAP STARTTLS command")
+	}
+	for {
+		line, err := reader.ReadLine()
+		if err != nil {
+			return errors.Wrapf(err, "Fail to read the IMAP STARTTLS response")
+		}
+		if strings.HasPrefix(line, "a001 OK") {
+			return nil
+		}
+		if strings.HasPrefix(line, "a001 ") {
+			return fmt.Errorf("Invalid IMAP STARTTLS response: %s", line)
+		}
+	}
+}
+
+// startTLSPostgres upgrades a PostgreSQL connection
+func startTLSPostgres(conn net.Conn) error {
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], postgresSSLRequestCode)
+	if _, err := conn.Write(request); err != nil {
+		return errors.Wrapf(err, "Fail to send the PostgreSQL SSLRequest")
+	}
+	response := make([]byte, 1)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return errors.Wrapf(err, "Fail to read the PostgreSQL SSLRequest response")
+	}
+	if response[0] != 'S' {
+		return errors.New("The PostgreSQL server does not support TLS")
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package healthcheck
+
+import (
+	"strconv"
+)
+
+const (
+	// LabelHost the label containing the healthcheck target host
+	LabelHost = "host"
+	// LabelPort the label containing the healthcheck target port
+	LabelPort = "port"
+	// LabelScheme the label containing the healthcheck target scheme
+	LabelScheme = "scheme"
+)
+
+// TargetReporter is implemented by healthchecks able to describe their
+// target (host, port and scheme)
+type TargetReporter interface {
+	Target() map[string]string
+}
+
+// targetLabels builds the target labels, ignoring empty values
+func targetLabels(host string, port uint, scheme string) map[string]string {
+	labels := make(map[string]string)
+	if host != "" {
+		labels[LabelHost] = host
+	}
+	if port != 0 {
+		labels[LabelPort] = strconv.FormatUint(uint64(port), 10)
+	}
+	if scheme != "" {
+		labels[LabelScheme] = scheme
+	}
+	return labels
+}
+
+// Target returns the target of the healthcheck
+func (h *HTTPHealthcheck) Target() map[string]string {
+	scheme := "http"
+	if h.Config.Protocol == HTTPS {
+		scheme = "https"
+	}
+	return targetLabels(h.Config.Target, h.Config.Port, scheme)
+}
+
+// Target returns the target of the healthcheck
+func (h *TCPHealthcheck) Target() map[string]string {
+	return targetLabels(h.Config.Target, h.Config.Port, "tcp")
+}
+
+// Target returns the target of the healthcheck
+func (h *UDPHealthcheck) Target() map[string]string {
+	return targetLabels(h.Config.Target, h.Config.Port, "udp")
+}
+
+// Target returns the target of the healthcheck
+func (h *TLSHealthcheck) Target() map[string]string {
+	return targetLabels(h.Config.Target, h.Config.Port, "tls")
+}
+
+// Target returns the target of the healthcheck
+func (h *GRPCHealthcheck) Target() map[string]string {
+	return targetLabels(h.Config.Target, h.Config.Port, "grpc")
+}
+
+// Target returns the target of the healthcheck
+func (h *DNSHealthcheck) Target() map[string]string {
+	return targetLabels(h.Config.Domain, 0, "dns")
+}
+
+// Target returns the target of the healthcheck. The host and port are not
+// known if the healthcheck is configured using a DSN.
+func (h *PostgresHealthcheck) Target() map[string]string {
+	return targetLabels(h.Config.Host, h.Config.Port, "postgres")
+}
+
+// addTargetLabels adds the target labels of the healthcheck to the result
+// labels. The labels explicitly configured on the healthcheck take
+// precedence.
+func (r *Result) addTargetLabels(healthcheck Healthcheck) {
+	reporter, ok := healthcheck.(TargetReporter)
+	if !ok {
+		return
+	}
+	labels := reporter.Target()
+	if len(labels) == 0 {
+		return
+	}
+	for k, v := range r.Labels {
+		labels[k] = v
+	}
+	r.Labels = labels
+}
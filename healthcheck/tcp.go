@@ -17,11 +17,18 @@ import (
 type TCPHealthcheckConfiguration struct {
 	Base `json:",inline" yaml:",inline"`
 	// can be an IP or a domain
-	Target     string   `json:"target"`
-	Port       uint     `json:"port"`
-	SourceIP   IP       `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
-	Timeout    Duration `json:"timeout"`
-	ShouldFail bool     `json:"should-fail" yaml:"should-fail"`
+	Target          string   `json:"target"`
+	Port            uint     `json:"port"`
+	SourceIP        IP       `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
+	Timeout         Duration `json:"timeout"`
+	MaxResponseTime Duration `json:"max-response-time,omitempty" yaml:"max-response-time,omitempty"`
+	// WarnResponseTime the result is degraded if the response time is
+	// greater than this threshold
+	WarnResponseTime Duration `json:"warn-response-time,omitempty" yaml:"warn-response-time,omitempty"`
+	ShouldFail       bool     `json:"should-fail" yaml:"should-fail"`
+	ReverseDNS       bool     `json:"reverse-dns" yaml:"reverse-dns"`
+	// SocketOptions the options applied on the healthcheck socket
+	SocketOptions *SocketOptions `json:"socket-options,omitempty" yaml:"socket-options,omitempty"`
 }
 
 // Validate validates the healthcheck configuration
@@ -29,6 +36,12 @@ func (config *TCPHealthcheckConfiguration) Validate() error {
 	if config.Base.Name == "" {
 		return errors.New("The healthcheck name is missing")
 	}
+	if err := config.Base.validate(); err != nil {
+		return err
+	}
+	if err := config.SocketOptions.Validate(); err != nil {
+		return err
+	}
 	if config.Target == "" {
 		return errors.New("The healthcheck target is missing")
 	}
@@ -38,7 +51,7 @@ func (config *TCPHealthcheckConfiguration) Validate() error {
 	if config.Timeout == 0 {
 		return errors.New("The healthcheck timeout is missing")
 	}
-	if !config.Base.OneOff {
+	if !config.Base.OneOff && config.Base.Cron == "" {
 		if config.Base.Interval < Duration(2*time.Second) {
 			return errors.New("The healthcheck interval should be greater than 2 second")
 		}
@@ -46,14 +59,18 @@ func (config *TCPHealthcheckConfiguration) Validate() error {
 			return errors.New("The healthcheck interval should be greater than the timeout")
 		}
 	}
+	if err := validateWarnResponseTime(config.WarnResponseTime, config.MaxResponseTime); err != nil {
+		return err
+	}
 	return nil
 }
 
 // TCPHealthcheck defines a TCP healthcheck
 type TCPHealthcheck struct {
-	Logger *zap.Logger
-	Config *TCPHealthcheckConfiguration
-	URL    string
+	Logger   *zap.Logger
+	Resolver *Resolver
+	Config   *TCPHealthcheckConfiguration
+	URL      string
 
 	Tick *time.Ticker
 	t    tomb.Tomb
@@ -76,7 +93,7 @@ func (h *TCPHealthcheck) Summary() string {
 	}
 
 	if h.Config.ShouldFail {
-		summary = summary + ". This healthcheck has should-fail=true."
+		summary = summary + shouldFailSummary
 	}
 
 	return summary
@@ -103,6 +120,11 @@ func (h *TCPHealthcheck) SetSource(source string) {
 	h.Config.Base.Source = source
 }
 
+// SetResolver set the healthcheck resolver
+func (h *TCPHealthcheck) SetResolver(resolver *Resolver) {
+	h.Resolver = resolver
+}
+
 // LogError logs an error with context
 func (h *TCPHealthcheck) LogError(err error, message string) {
 	h.Logger.Error(err.Error(),
@@ -129,35 +151,46 @@ func (h *TCPHealthcheck) LogInfo(message string) {
 }
 
 // Execute executes an healthcheck on the given target
-func (h *TCPHealthcheck) Execute() error {
+func (h *TCPHealthcheck) Execute(ctx context.Context) (Annotations, error) {
 	h.LogDebug("start executing healthcheck")
-	ctx := h.t.Context(context.TODO())
+	annotations := Annotations{}
 	dialer := net.Dialer{}
 	if h.Config.SourceIP != nil {
 		srcIP := net.IP(h.Config.SourceIP).String()
 		addr, err := net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:0", srcIP))
 		if err != nil {
-			return errors.Wrapf(err, "Fail to set the source IP %s", srcIP)
+			return annotations, errors.Wrapf(err, "Fail to set the source IP %s", srcIP)
 		}
 		dialer = net.Dialer{
 			LocalAddr: addr,
 		}
 	}
+	dialer.Control = h.Config.SocketOptions.control()
 	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
 	defer cancel()
-	conn, err := dialer.DialContext(timeoutCtx, "tcp", h.URL)
+	start := time.Now()
+	conn, err := h.Resolver.DialContext(&dialer)(timeoutCtx, "tcp", h.URL)
+	responseTime := time.Since(start)
+	if h.Config.ReverseDNS && err == nil {
+		reverseDNS(timeoutCtx, h.Resolver, conn.RemoteAddr(), annotations)
+	}
 	if h.Config.ShouldFail {
 		if err == nil {
 			defer conn.Close()
-			return fmt.Errorf("TCP check is successful on %s but an error was expected", h.URL)
+			return annotations, fmt.Errorf("TCP check is successful on %s but an error was expected", h.URL)
 		}
 	} else {
 		if err != nil {
-			return errors.Wrapf(err, "TCP connection failed on %s", h.URL)
+			return annotations, errors.Wrapf(err, "TCP connection failed on %s", h.URL)
 		}
 		defer conn.Close()
+		err = checkResponseTime(responseTime, h.Config.MaxResponseTime, annotations)
+		if err != nil {
+			return annotations, errors.Wrapf(err, "TCP connection too slow on %s", h.URL)
+		}
+		return annotations, checkWarnResponseTime(responseTime, h.Config.WarnResponseTime, annotations)
 	}
-	return nil
+	return annotations, nil
 }
 
 // NewTCPHealthcheck creates a TCP healthcheck from a logger and a configuration
@@ -182,6 +215,11 @@ func (in *TCPHealthcheckConfiguration) DeepCopyInto(out *TCPHealthcheckConfigura
 		*out = make(IP, len(*in))
 		copy(*out, *in)
 	}
+	if in.SocketOptions != nil {
+		in, out := &in.SocketOptions, &out.SocketOptions
+		*out = new(SocketOptions)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPHealthcheckConfiguration.
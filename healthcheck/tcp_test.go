@@ -1,6 +1,7 @@
 package healthcheck
 
 import (
+	"context"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -47,7 +48,7 @@ func TestTCPExecuteSuccess(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err = h.Execute()
+	_, err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -73,7 +74,7 @@ func TestTCPExecuteSuccessSourceIP(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err = h.Execute()
+	_, err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -106,7 +107,7 @@ func TestTCPv6ExecuteSuccess(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err = h.Execute()
+	_, err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -155,8 +156,124 @@ func TestTCPExecuteSuccessShoulddFail(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err := h.Execute()
+	_, err := h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
 }
+
+func TestTCPExecuteReverseDNS(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := TCPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &TCPHealthcheckConfiguration{
+			Port:       uint(port),
+			Target:     "127.0.0.1",
+			Timeout:    Duration(time.Second * 2),
+			ReverseDNS: true,
+		},
+	}
+	h.buildURL()
+	annotations, err := h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	if annotations["remote-ip"] != "127.0.0.1" {
+		t.Fatalf("Invalid remote IP annotation %v", annotations)
+	}
+	if annotations["reverse-dns"] == "" && annotations["reverse-dns-error"] == "" {
+		t.Fatalf("The reverse DNS annotation is missing %v", annotations)
+	}
+	h.SetResolver(NewResolver(&ResolverConfiguration{
+		Hosts: map[string]string{
+			"foo.cabourotte": "127.0.0.1",
+			"bar.cabourotte": "127.0.0.1",
+			"baz.cabourotte": "127.0.0.2",
+		},
+	}))
+	annotations, err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	if annotations["reverse-dns"] != "bar.cabourotte, foo.cabourotte" {
+		t.Fatalf("The reverse lookup should use the resolver %v", annotations)
+	}
+}
+
+func TestTCPExecuteMaxResponseTime(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := TCPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &TCPHealthcheckConfiguration{
+			Port:            uint(port),
+			Target:          "127.0.0.1",
+			Timeout:         Duration(time.Second * 2),
+			MaxResponseTime: Duration(time.Nanosecond),
+		},
+	}
+	h.buildURL()
+	annotations, err := h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("Was expecting an error because the response time is too high")
+	}
+	if annotations["max-response-time"] != "1ns" || annotations["response-time"] == "" {
+		t.Fatalf("Invalid annotations %v", annotations)
+	}
+	h.Config.MaxResponseTime = Duration(time.Second * 2)
+	_, err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+}
+
+func TestTCPExecuteSocketOptions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	linger := 0
+	h := TCPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &TCPHealthcheckConfiguration{
+			Port:    uint(port),
+			Target:  "127.0.0.1",
+			Timeout: Duration(time.Second * 2),
+			SocketOptions: &SocketOptions{
+				ReadBufferSize: 4096,
+				ReuseAddr:      true,
+				Linger:         &linger,
+			},
+		},
+	}
+	h.buildURL()
+	_, err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	linger = -1
+	err = h.Config.SocketOptions.Validate()
+	if err == nil {
+		t.Fatalf("Was expecting an error for a negative linger timeout")
+	}
+}
@@ -1,11 +1,17 @@
 package healthcheck
 
 import (
+	"bytes"
 	"context"
 	cryptotls "crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"net"
+	"os/exec"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/appclacks/cabourotte/tls"
@@ -18,21 +24,62 @@ import (
 type TLSHealthcheckConfiguration struct {
 	Base `json:",inline" yaml:",inline"`
 	// can be an IP or a domain
-	Target          string   `json:"target"`
-	Port            uint     `json:"port"`
-	SourceIP        IP       `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
-	Timeout         Duration `json:"timeout"`
-	Key             string   `json:"key,omitempty"`
-	Cert            string   `json:"cert,omitempty"`
-	Cacert          string   `json:"cacert,omitempty"`
+	Target   string   `json:"target"`
+	Port     uint     `json:"port"`
+	SourceIP IP       `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
+	Timeout  Duration `json:"timeout"`
+	Key      string   `json:"key,omitempty"`
+	Cert     string   `json:"cert,omitempty"`
+	Cacert   string   `json:"cacert,omitempty"`
+	// KeyPEM the PEM content of the key, as an alternative to Key
+	KeyPEM string `json:"key-pem,omitempty" yaml:"key-pem,omitempty"`
+	// CertPEM the PEM content of the certificate, as an alternative to Cert
+	CertPEM string `json:"cert-pem,omitempty" yaml:"cert-pem,omitempty"`
+	// CacertPEM the PEM content of the ca certificate, as an alternative to
+	// Cacert
+	CacertPEM       string   `json:"cacert-pem,omitempty" yaml:"cacert-pem,omitempty"`
 	ServerName      string   `json:"server-name,omitempty" yaml:"server-name"`
 	Insecure        bool     `json:"insecure"`
 	ExpirationDelay Duration `json:"expiration-delay" yaml:"expiration-delay"`
+	// ExpectedSANs the DNS names and IP addresses which should be present
+	// in the certificate subject alternative names
+	ExpectedSANs []string `json:"expected-sans,omitempty" yaml:"expected-sans,omitempty"`
+	// ExactSANs fails the healthcheck if the certificate contains
+	// subject alternative names which are not in ExpectedSANs
+	ExactSANs  bool `json:"exact-sans" yaml:"exact-sans"`
+	ReverseDNS bool `json:"reverse-dns" yaml:"reverse-dns"`
+	// StartTLS the protocol used to upgrade the connection to TLS (smtp,
+	// imap or postgres). The TLS handshake is done directly if empty.
+	StartTLS string `json:"starttls,omitempty" yaml:"starttls,omitempty"`
+	// VerifyHostname verifies that the certificate is valid for the server
+	// name (or the target if not set), even if Insecure is true
+	VerifyHostname bool `json:"verify-hostname,omitempty" yaml:"verify-hostname,omitempty"`
+	// MinTLSVersion the minimum TLS version (1.0, 1.1, 1.2 or 1.3)
+	MinTLSVersion string `json:"min-tls-version,omitempty" yaml:"min-tls-version,omitempty"`
+	// MaxTLSVersion the maximum TLS version (1.0, 1.1, 1.2 or 1.3)
+	MaxTLSVersion string `json:"max-tls-version,omitempty" yaml:"max-tls-version,omitempty"`
+	// ExpectHandshakeFailure deprecated alias of ShouldFail
+	ExpectHandshakeFailure bool `json:"expect-handshake-failure,omitempty" yaml:"expect-handshake-failure,omitempty"`
+	// WarnResponseTime the result is degraded if the connection and the
+	// TLS handshake take more than this threshold
+	WarnResponseTime Duration `json:"warn-response-time,omitempty" yaml:"warn-response-time,omitempty"`
+	// VerifyCommand a command verifying the peer certificates after the
+	// handshake. The certificates chain is written in PEM format on the
+	// command standard input, and the certificates are valid if the command
+	// exits successfully.
+	VerifyCommand   string   `json:"verify-command,omitempty" yaml:"verify-command,omitempty"`
+	VerifyArguments []string `json:"verify-arguments,omitempty" yaml:"verify-arguments,omitempty"`
+	// ShouldFail the healthcheck is successful only if the connection, the
+	// handshake or the certificates verification fails, for example to
+	// verify that the server refuses the versions between MinTLSVersion and
+	// MaxTLSVersion. The handshake error is added to the annotations.
+	ShouldFail bool `json:"should-fail,omitempty" yaml:"should-fail,omitempty"`
 }
 
 // TLSHealthcheck defines a TLS healthcheck
 type TLSHealthcheck struct {
 	Logger    *zap.Logger
+	Resolver  *Resolver
 	Config    *TLSHealthcheckConfiguration
 	URL       string
 	TLSConfig *cryptotls.Config
@@ -41,11 +88,32 @@ type TLSHealthcheck struct {
 	t    tomb.Tomb
 }
 
+// shouldFail returns true if the healthcheck should fail, using the
+// deprecated ExpectHandshakeFailure alias
+func (config *TLSHealthcheckConfiguration) shouldFail() bool {
+	return config.ShouldFail || config.ExpectHandshakeFailure
+}
+
+// certificates returns the certificates of the healthcheck
+func (config *TLSHealthcheckConfiguration) certificates() *tls.Certificates {
+	return &tls.Certificates{
+		Key:       config.Key,
+		Cert:      config.Cert,
+		Cacert:    config.Cacert,
+		KeyPEM:    config.KeyPEM,
+		CertPEM:   config.CertPEM,
+		CacertPEM: config.CacertPEM,
+	}
+}
+
 // Validate validates the healthcheck configuration
 func (config *TLSHealthcheckConfiguration) Validate() error {
 	if config.Base.Name == "" {
 		return errors.New("The healthcheck name is missing")
 	}
+	if err := config.Base.validate(); err != nil {
+		return err
+	}
 	if config.Target == "" {
 		return errors.New("The healthcheck target is missing")
 	}
@@ -55,7 +123,7 @@ func (config *TLSHealthcheckConfiguration) Validate() error {
 	if config.Timeout == 0 {
 		return errors.New("The healthcheck timeout is missing")
 	}
-	if !config.Base.OneOff {
+	if !config.Base.OneOff && config.Base.Cron == "" {
 		if config.Base.Interval < Duration(2*time.Second) {
 			return errors.New("The healthcheck interval should be greater than 2 second")
 		}
@@ -63,9 +131,23 @@ func (config *TLSHealthcheckConfiguration) Validate() error {
 			return errors.New("The healthcheck interval should be greater than the timeout")
 		}
 	}
-	if !((config.Key != "" && config.Cert != "") ||
-		(config.Key == "" && config.Cert == "")) {
-		return errors.New("Invalid certificates")
+	if err := config.certificates().Validate(); err != nil {
+		return err
+	}
+	if err := tls.ValidateVersions(config.MinTLSVersion, config.MaxTLSVersion); err != nil {
+		return err
+	}
+	if err := validateWarnResponseTime(config.WarnResponseTime, 0); err != nil {
+		return err
+	}
+	if config.ExactSANs && len(config.ExpectedSANs) == 0 {
+		return errors.New("The expected SANs should be set when exact-sans is enabled")
+	}
+	if config.StartTLS != "" && !validStartTLS(config.StartTLS) {
+		return fmt.Errorf("Invalid STARTTLS protocol %s (supported: %s, %s, %s)", config.StartTLS, StartTLSSMTP, StartTLSIMAP, StartTLSPostgres)
+	}
+	if config.VerifyCommand == "" && len(config.VerifyArguments) != 0 {
+		return errors.New("The verify command should be set when verify arguments are set")
 	}
 	return nil
 }
@@ -80,6 +162,11 @@ func (h *TLSHealthcheck) SetSource(source string) {
 	h.Config.Base.Source = source
 }
 
+// SetResolver set the healthcheck resolver
+func (h *TLSHealthcheck) SetResolver(resolver *Resolver) {
+	h.Resolver = resolver
+}
+
 // Summary returns an healthcheck summary
 func (h *TLSHealthcheck) Summary() string {
 	summary := ""
@@ -90,6 +177,10 @@ func (h *TLSHealthcheck) Summary() string {
 		summary = fmt.Sprintf("TLS healthcheck on %s:%d", h.Config.Target, h.Config.Port)
 	}
 
+	if h.Config.shouldFail() {
+		summary = summary + shouldFailSummary
+	}
+
 	return summary
 }
 
@@ -102,7 +193,14 @@ func (h *TLSHealthcheck) buildURL() {
 // Initialize the healthcheck.
 func (h *TLSHealthcheck) Initialize() error {
 	h.buildURL()
-	tlsConfig, err := tls.GetTLSConfig(h.Config.Key, h.Config.Cert, h.Config.Cacert, h.Config.ServerName, h.Config.Insecure)
+	tlsConfig, err := tls.GetTLSConfigWithCertificates(h.Config.certificates(), h.Config.ServerName, h.Config.Insecure)
+	if err != nil {
+		return err
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = h.Config.Target
+	}
+	err = tls.SetVersions(tlsConfig, h.Config.MinTLSVersion, h.Config.MaxTLSVersion)
 	if err != nil {
 		return err
 	}
@@ -140,16 +238,26 @@ func (h *TLSHealthcheck) LogInfo(message string) {
 		zap.String("name", h.Config.Base.Name))
 }
 
-// Execute executes an healthcheck on the given target
-func (h *TLSHealthcheck) Execute() error {
+// Execute executes an healthcheck on the given target. The result is
+// inverted if the healthcheck should fail.
+func (h *TLSHealthcheck) Execute(ctx context.Context) (Annotations, error) {
 	h.LogDebug("start executing healthcheck")
+	annotations, err := h.execute(ctx)
+	if h.Config.shouldFail() {
+		return invertResult("TLS", h.URL, annotations, err)
+	}
+	return annotations, err
+}
+
+// execute establishes the TLS connection and verifies the certificates
+func (h *TLSHealthcheck) execute(ctx context.Context) (Annotations, error) {
+	annotations := Annotations{}
 	dialer := net.Dialer{}
-	ctx := h.t.Context(context.TODO())
 	if h.Config.SourceIP != nil {
 		srcIP := net.IP(h.Config.SourceIP).String()
 		addr, err := net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:0", srcIP))
 		if err != nil {
-			return errors.Wrapf(err, "Fail to set the source IP %s", srcIP)
+			return annotations, errors.Wrapf(err, "Fail to set the source IP %s", srcIP)
 		}
 		dialer = net.Dialer{
 			LocalAddr: addr,
@@ -159,31 +267,143 @@ func (h *TLSHealthcheck) Execute() error {
 
 	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
 	defer cancel()
-	conn, err := dialer.DialContext(timeoutCtx, "tcp", h.URL)
+	start := time.Now()
+	conn, err := h.Resolver.DialContext(&dialer)(timeoutCtx, "tcp", h.URL)
 	if err != nil {
-		return errors.Wrapf(err, "TLS connection failed on %s", h.URL)
+		return annotations, errors.Wrapf(err, "TLS connection failed on %s", h.URL)
 	}
 	defer conn.Close()
+	if h.Config.StartTLS != "" {
+		if deadline, ok := timeoutCtx.Deadline(); ok {
+			err = conn.SetDeadline(deadline)
+			if err != nil {
+				return annotations, errors.Wrapf(err, "Fail to set the connection deadline on %s", h.URL)
+			}
+		}
+		err = startTLS(conn, h.Config.StartTLS)
+		if err != nil {
+			return annotations, errors.Wrapf(err, "STARTTLS negotiation failed on %s", h.URL)
+		}
+	}
 	tlsConn := cryptotls.Client(conn, h.TLSConfig)
 	defer tlsConn.Close()
 	err = tlsConn.Handshake()
+	responseTime := time.Since(start)
+	// the reverse lookup is done once the response time is measured
+	if h.Config.ReverseDNS {
+		reverseDNS(timeoutCtx, h.Resolver, conn.RemoteAddr(), annotations)
+	}
 	if err != nil {
-		return errors.Wrapf(err, "TLS handshake failed on %s", h.URL)
+		if h.Config.shouldFail() {
+			annotations["handshake-error"] = err.Error()
+		}
+		var hostnameErr x509.HostnameError
+		if errors.As(err, &hostnameErr) {
+			annotations["hostname-mismatch"] = hostnameErr.Host
+		}
+		return annotations, errors.Wrapf(err, "TLS handshake failed on %s", h.URL)
 	}
-	if h.Config.ExpirationDelay != 0 {
-		state := tlsConn.ConnectionState()
-		expirationTime := time.Time{}
-		for _, cert := range state.PeerCertificates {
-			if (expirationTime.IsZero() || cert.NotAfter.Before(expirationTime)) && !cert.NotAfter.IsZero() {
-				expirationTime = cert.NotAfter
-			}
+	state := tlsConn.ConnectionState()
+	annotations["tls-version"] = tls.FormatVersion(state.Version)
+	if h.TLSConfig.MinVersion != 0 && state.Version < h.TLSConfig.MinVersion {
+		return annotations, fmt.Errorf("TLS %s negotiated on %s is lower than the minimum version %s", tls.FormatVersion(state.Version), h.URL, h.Config.MinTLSVersion)
+	}
+	if h.Config.VerifyHostname {
+		if len(state.PeerCertificates) == 0 {
+			return annotations, fmt.Errorf("No peer certificate for %s", h.URL)
+		}
+		err = state.PeerCertificates[0].VerifyHostname(h.TLSConfig.ServerName)
+		if err != nil {
+			annotations["hostname-mismatch"] = h.TLSConfig.ServerName
+			return annotations, errors.Wrapf(err, "Invalid certificate hostname for %s", h.URL)
+		}
+	}
+	expirationTime := time.Time{}
+	for _, cert := range state.PeerCertificates {
+		if (expirationTime.IsZero() || cert.NotAfter.Before(expirationTime)) && !cert.NotAfter.IsZero() {
+			expirationTime = cert.NotAfter
 		}
+	}
+	if !expirationTime.IsZero() {
+		reportMetrics(ctx, map[string]float64{
+			MetricCertificateExpiry: time.Until(expirationTime).Seconds(),
+		})
+	}
+	if h.Config.ExpirationDelay != 0 {
 		expirationTimeLimit := time.Now().Add(time.Duration(h.Config.ExpirationDelay))
 		if expirationTime.Before(expirationTimeLimit) {
-			return fmt.Errorf("The certificate for %s will expire at %s", h.URL, expirationTime.String())
+			return annotations, fmt.Errorf("The certificate for %s will expire at %s", h.URL, expirationTime.String())
+		}
+	}
+
+	if len(h.Config.ExpectedSANs) != 0 {
+		if len(state.PeerCertificates) == 0 {
+			return annotations, fmt.Errorf("No peer certificate for %s", h.URL)
+		}
+		err = verifySANs(h.Config.ExpectedSANs, h.Config.ExactSANs, state.PeerCertificates[0])
+		if err != nil {
+			return annotations, errors.Wrapf(err, "Invalid certificate for %s", h.URL)
+		}
+	}
+	if h.Config.VerifyCommand != "" {
+		err = h.verifyWithCommand(timeoutCtx, state.PeerCertificates)
+		if err != nil {
+			return annotations, errors.Wrapf(err, "Certificates verification command failed for %s", h.URL)
+		}
+	}
+
+	return annotations, checkWarnResponseTime(responseTime, h.Config.WarnResponseTime, annotations)
+}
+
+// verifyWithCommand executes the verification command, passing the
+// certificates chain in PEM format on its standard input
+func (h *TLSHealthcheck) verifyWithCommand(ctx context.Context, certificates []*x509.Certificate) error {
+	var chain bytes.Buffer
+	for _, cert := range certificates {
+		err := pem.Encode(&chain, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+		if err != nil {
+			return errors.Wrapf(err, "Fail to encode the certificate")
 		}
 	}
+	cmd := exec.CommandContext(ctx, h.Config.VerifyCommand, h.Config.VerifyArguments...)
+	cmd.Stdin = &chain
+	return runCommand(cmd)
+}
 
+// verifySANs verifies that the expected subject alternative names are
+// present in the certificate. If exact is true, the certificate should not
+// contain other subject alternative names.
+func verifySANs(expected []string, exact bool, cert *x509.Certificate) error {
+	sans := make(map[string]bool)
+	for _, name := range cert.DNSNames {
+		sans[name] = true
+	}
+	for _, ip := range cert.IPAddresses {
+		sans[ip.String()] = true
+	}
+	expectedSANs := make(map[string]bool)
+	missing := []string{}
+	for _, san := range expected {
+		expectedSANs[san] = true
+		if !sans[san] {
+			missing = append(missing, san)
+		}
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("The SANs %s were not found in the certificate", strings.Join(missing, ", "))
+	}
+	if exact {
+		unexpected := []string{}
+		for san := range sans {
+			if !expectedSANs[san] {
+				unexpected = append(unexpected, san)
+			}
+		}
+		if len(unexpected) != 0 {
+			sort.Strings(unexpected)
+			return fmt.Errorf("The certificate contains unexpected SANs %s", strings.Join(unexpected, ", "))
+		}
+	}
 	return nil
 }
 
@@ -195,9 +415,12 @@ func NewTLSHealthcheck(logger *zap.Logger, config *TLSHealthcheckConfiguration)
 	}
 }
 
-// MarshalJSON marshal to json a dns healthcheck
+// MarshalJSON marshal to json a TLS healthcheck. The inline private key is
+// omitted.
 func (h *TLSHealthcheck) MarshalJSON() ([]byte, error) {
-	return json.Marshal(h.Config)
+	config := h.Config.DeepCopy()
+	config.KeyPEM = ""
+	return json.Marshal(config)
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -209,6 +432,16 @@ func (in *TLSHealthcheckConfiguration) DeepCopyInto(out *TLSHealthcheckConfigura
 		*out = make(IP, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExpectedSANs != nil {
+		in, out := &in.ExpectedSANs, &out.ExpectedSANs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VerifyArguments != nil {
+		in, out := &in.VerifyArguments, &out.VerifyArguments
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSHealthcheckConfiguration.
@@ -1,8 +1,16 @@
 package healthcheck
 
 import (
+	"bufio"
+	"context"
+	cryptotls "crypto/tls"
+	"encoding/pem"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -44,7 +52,7 @@ func TestTLSExecuteError(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err = h.Execute()
+	_, err = h.Execute(context.Background())
 	if err == nil {
 		t.Fatalf("Was expecting an error")
 	}
@@ -60,8 +68,539 @@ func TestTLSExecuteErrorNoTarget(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err := h.Execute()
+	_, err := h.Execute(context.Background())
 	if err == nil {
 		t.Fatalf("Was expecting an error")
 	}
 }
+
+func TestTLSExecuteSANs(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		sans    []string
+		exact   bool
+		success bool
+	}{
+		{sans: []string{"example.com"}, exact: false, success: true},
+		{sans: []string{"example.com", "127.0.0.1"}, exact: false, success: true},
+		{sans: []string{"mcorbin.fr"}, exact: false, success: false},
+		{sans: []string{"example.com"}, exact: true, success: false},
+		{sans: []string{"example.com", "*.example.com", "127.0.0.1", "::1"}, exact: true, success: true},
+	}
+	for _, c := range cases {
+		h := TLSHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &TLSHealthcheckConfiguration{
+				Port:         uint(port),
+				Target:       "127.0.0.1",
+				Timeout:      Duration(time.Second * 2),
+				Insecure:     true,
+				ExpectedSANs: c.sans,
+				ExactSANs:    c.exact,
+			},
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Fail to initialize the healthcheck :\n%v", err)
+		}
+		_, err = h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %v:\n%v", c.sans, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %v", c.sans)
+		}
+	}
+}
+
+func TestTLSExecuteStartTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	negotiations := map[string]func(conn net.Conn, reader *bufio.Reader) error{
+		StartTLSSMTP: func(conn net.Conn, reader *bufio.Reader) error {
+			_, err := io.WriteString(conn, "220 localhost ESMTP\r\n")
+			if err != nil {
+				return err
+			}
+			if _, err := reader.ReadString('\n'); err != nil {
+				return err
+			}
+			_, err = io.WriteString(conn, "250-localhost\r\n250 STARTTLS\r\n")
+			if err != nil {
+				return err
+			}
+			if _, err := reader.ReadString('\n'); err != nil {
+				return err
+			}
+			_, err = io.WriteString(conn, "220 ready\r\n")
+			return err
+		},
+		StartTLSIMAP: func(conn net.Conn, reader *bufio.Reader) error {
+			_, err := io.WriteString(conn, "* OK IMAP ready\r\n")
+			if err != nil {
+				return err
+			}
+			if _, err := reader.ReadString('\n'); err != nil {
+				return err
+			}
+			_, err = io.WriteString(conn, "a001 OK Begin TLS negotiation\r\n")
+			return err
+		},
+		StartTLSPostgres: func(conn net.Conn, reader *bufio.Reader) error {
+			request := make([]byte, 8)
+			if _, err := io.ReadFull(reader, request); err != nil {
+				return err
+			}
+			_, err := conn.Write([]byte("S"))
+			return err
+		},
+	}
+	for protocol, negotiate := range negotiations {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("fail to listen :\n%v", err)
+		}
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			if err := negotiate(conn, bufio.NewReader(conn)); err != nil {
+				return
+			}
+			_ = cryptotls.Server(conn, ts.TLS).Handshake()
+		}()
+		h := TLSHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &TLSHealthcheckConfiguration{
+				Port:     uint(listener.Addr().(*net.TCPAddr).Port),
+				Target:   "127.0.0.1",
+				Timeout:  Duration(time.Second * 2),
+				Insecure: true,
+				StartTLS: protocol,
+			},
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Fail to initialize the healthcheck :\n%v", err)
+		}
+		_, err = h.Execute(context.Background())
+		if err != nil {
+			t.Fatalf("healthcheck error for %s:\n%v", protocol, err)
+		}
+		listener.Close()
+	}
+}
+
+func TestTLSValidateStartTLS(t *testing.T) {
+	config := TLSHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(time.Second * 10),
+		},
+		Target:   "127.0.0.1",
+		Port:     25,
+		Timeout:  Duration(time.Second * 2),
+		StartTLS: "smtp",
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("The configuration should be valid :\n%v", err)
+	}
+	config.StartTLS = "ftp"
+	if err := config.Validate(); err == nil {
+		t.Fatalf("Was expecting an error for an unsupported protocol")
+	}
+}
+
+func TestTLSExecuteCertificateExpiry(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := NewTLSHealthcheck(zap.NewExample(), &TLSHealthcheckConfiguration{
+		Port:     uint(port),
+		Target:   "127.0.0.1",
+		Timeout:  Duration(time.Second * 2),
+		Insecure: true,
+	})
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Fail to initialize the healthcheck :\n%v", err)
+	}
+	expected := time.Until(ts.Certificate().NotAfter).Seconds()
+	ctx, metrics := WithExecutionMetrics(context.Background())
+	_, err = h.Execute(ctx)
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	expiry, ok := metrics()[MetricCertificateExpiry]
+	if !ok || expiry <= 0 || expiry > expected {
+		t.Fatalf("Invalid certificate expiry metric %v (expected around %f)", metrics(), expected)
+	}
+	h.Config.Port = 1
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Fail to initialize the healthcheck :\n%v", err)
+	}
+	failedCtx, failedMetrics := WithExecutionMetrics(context.Background())
+	_, err = h.Execute(failedCtx)
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if len(failedMetrics()) != 0 {
+		t.Fatalf("The failed execution should not report metrics %v", failedMetrics())
+	}
+	if _, ok := metrics()[MetricCertificateExpiry]; !ok {
+		t.Fatalf("The metrics of the first execution were modified %v", metrics())
+	}
+}
+
+func TestTLSExecuteVerifyHostname(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cacert := filepath.Join(t.TempDir(), "ca.pem")
+	err = os.WriteFile(cacert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the CA certificate\n%v", err)
+	}
+	cases := []struct {
+		serverName     string
+		insecure       bool
+		verifyHostname bool
+		cacert         string
+		success        bool
+	}{
+		{serverName: "", cacert: cacert, success: true},
+		{serverName: "example.com", cacert: cacert, success: true},
+		{serverName: "foo.mcorbin.fr", cacert: cacert, success: false},
+		{serverName: "foo.mcorbin.fr", insecure: true, success: true},
+		{serverName: "foo.mcorbin.fr", insecure: true, verifyHostname: true, success: false},
+		{serverName: "example.com", insecure: true, verifyHostname: true, success: true},
+	}
+	for _, c := range cases {
+		h := NewTLSHealthcheck(zap.NewExample(), &TLSHealthcheckConfiguration{
+			Base: Base{
+				Name: "foo",
+			},
+			Port:           uint(port),
+			Target:         "127.0.0.1",
+			ServerName:     c.serverName,
+			Insecure:       c.insecure,
+			Cacert:         c.cacert,
+			VerifyHostname: c.verifyHostname,
+			Timeout:        Duration(time.Second * 2),
+		})
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		annotations, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %v :\n%v", c, err)
+		}
+		if !c.success {
+			if err == nil {
+				t.Fatalf("Was expecting an error for %v", c)
+			}
+			if annotations["hostname-mismatch"] != c.serverName {
+				t.Fatalf("Invalid annotations %v", annotations)
+			}
+		}
+	}
+}
+
+func TestTLSExecuteCacertPEM(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := NewTLSHealthcheck(zap.NewExample(), &TLSHealthcheckConfiguration{
+		Base: Base{
+			Name: "foo",
+		},
+		Port:      uint(port),
+		Target:    "127.0.0.1",
+		CacertPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})),
+		Timeout:   Duration(time.Second * 2),
+	})
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	_, err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	h.Config.CacertPEM = "invalid"
+	err = h.Initialize()
+	if err == nil {
+		t.Fatalf("Was expecting an error for an invalid ca certificate")
+	}
+	h.Config.CacertPEM = ""
+	h.Config.KeyPEM = "invalid"
+	h.Config.CertPEM = "invalid"
+	err = h.Initialize()
+	if err == nil {
+		t.Fatalf("Was expecting an error for an invalid key pair")
+	}
+}
+
+func TestTLSValidateCertificates(t *testing.T) {
+	cases := []struct {
+		config TLSHealthcheckConfiguration
+		valid  bool
+	}{
+		{config: TLSHealthcheckConfiguration{Key: "key.pem", Cert: "cert.pem", Cacert: "ca.pem"}, valid: true},
+		{config: TLSHealthcheckConfiguration{KeyPEM: "key", CertPEM: "cert", CacertPEM: "ca"}, valid: true},
+		{config: TLSHealthcheckConfiguration{Key: "key.pem", Cert: "cert.pem", CacertPEM: "ca"}, valid: true},
+		{config: TLSHealthcheckConfiguration{Key: "key.pem"}, valid: false},
+		{config: TLSHealthcheckConfiguration{CertPEM: "cert"}, valid: false},
+		{config: TLSHealthcheckConfiguration{Key: "key.pem", CertPEM: "cert"}, valid: false},
+		{config: TLSHealthcheckConfiguration{Key: "key.pem", Cert: "cert.pem", KeyPEM: "key", CertPEM: "cert"}, valid: false},
+		{config: TLSHealthcheckConfiguration{Cacert: "ca.pem", CacertPEM: "ca"}, valid: false},
+	}
+	for _, c := range cases {
+		c.config.Base = Base{Name: "foo", OneOff: true}
+		c.config.Target = "127.0.0.1"
+		c.config.Port = 443
+		c.config.Timeout = Duration(time.Second)
+		err := c.config.Validate()
+		if c.valid && err != nil {
+			t.Fatalf("The configuration should be valid\n%v", err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("The configuration should be invalid %+v", c.config)
+		}
+	}
+}
+
+func TestTLSExecuteVersions(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.TLS = &cryptotls.Config{MaxVersion: cryptotls.VersionTLS12}
+	ts.StartTLS()
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		minVersion string
+		maxVersion string
+		shouldFail bool
+		success    bool
+	}{
+		{success: true},
+		{minVersion: "1.2", maxVersion: "1.3", success: true},
+		{minVersion: "1.3", success: false},
+		{minVersion: "1.3", shouldFail: true, success: true},
+		{maxVersion: "1.2", shouldFail: true, success: false},
+	}
+	for _, c := range cases {
+		h := NewTLSHealthcheck(zap.NewExample(), &TLSHealthcheckConfiguration{
+			Base: Base{
+				Name: "foo",
+			},
+			Port:          uint(port),
+			Target:        "127.0.0.1",
+			Insecure:      true,
+			MinTLSVersion: c.minVersion,
+			MaxTLSVersion: c.maxVersion,
+			ShouldFail:    c.shouldFail,
+			Timeout:       Duration(time.Second * 2),
+		})
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		annotations, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %+v:\n%v", c, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %+v", c)
+		}
+		if c.success && !c.shouldFail && annotations["tls-version"] != "1.2" {
+			t.Fatalf("Invalid annotations %v", annotations)
+		}
+		if c.success && c.shouldFail && annotations["handshake-error"] == "" {
+			t.Fatalf("Invalid annotations %v", annotations)
+		}
+	}
+}
+
+func TestTLSExecuteShouldFail(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer secure.Close()
+	cases := []struct {
+		url     string
+		success bool
+	}{
+		{url: plain.URL, success: true},
+		{url: secure.URL, success: false},
+	}
+	for _, c := range cases {
+		port, err := strconv.ParseUint(strings.Split(c.url, ":")[2], 10, 16)
+		if err != nil {
+			t.Fatalf("error getting HTTP server port :\n%v", err)
+		}
+		h := NewTLSHealthcheck(zap.NewExample(), &TLSHealthcheckConfiguration{
+			Base: Base{
+				Name: "foo",
+			},
+			Port:       uint(port),
+			Target:     "127.0.0.1",
+			Insecure:   true,
+			ShouldFail: true,
+			Timeout:    Duration(time.Second * 2),
+		})
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		annotations, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %s:\n%v", c.url, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %s", c.url)
+		}
+		if c.success && annotations["expected-error"] == "" {
+			t.Fatalf("Invalid annotations %v", annotations)
+		}
+	}
+	// expect-handshake-failure is an alias of should-fail
+	port, err := strconv.ParseUint(strings.Split(plain.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := NewTLSHealthcheck(zap.NewExample(), &TLSHealthcheckConfiguration{
+		Base: Base{
+			Name: "foo",
+		},
+		Port:                   uint(port),
+		Target:                 "127.0.0.1",
+		Insecure:               true,
+		ExpectHandshakeFailure: true,
+		Timeout:                Duration(time.Second * 2),
+	})
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	annotations, err := h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	if annotations["handshake-error"] == "" {
+		t.Fatalf("Invalid annotations %v", annotations)
+	}
+}
+
+func TestTLSValidateVersions(t *testing.T) {
+	cases := []struct {
+		minVersion string
+		maxVersion string
+		valid      bool
+	}{
+		{minVersion: "1.0", maxVersion: "1.3", valid: true},
+		{minVersion: "1.2", valid: true},
+		{maxVersion: "1.1", valid: true},
+		{minVersion: "1.3", maxVersion: "1.2", valid: false},
+		{minVersion: "1.4", valid: false},
+		{maxVersion: "tls1.2", valid: false},
+	}
+	for _, c := range cases {
+		config := TLSHealthcheckConfiguration{
+			Base:          Base{Name: "foo", OneOff: true},
+			Target:        "127.0.0.1",
+			Port:          443,
+			Timeout:       Duration(time.Second),
+			MinTLSVersion: c.minVersion,
+			MaxTLSVersion: c.maxVersion,
+		}
+		err := config.Validate()
+		if c.valid && err != nil {
+			t.Fatalf("The configuration should be valid\n%v", err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("The configuration should be invalid %+v", c)
+		}
+	}
+}
+
+func TestTLSExecuteVerifyCommand(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		arguments []string
+		success   bool
+	}{
+		{arguments: []string{"-c", "grep -q 'BEGIN CERTIFICATE'"}, success: true},
+		{arguments: []string{"-c", "grep -q 'BEGIN PRIVATE KEY'"}, success: false},
+		{arguments: []string{"-c", "echo invalid policy >&2; exit 3"}, success: false},
+	}
+	for _, c := range cases {
+		h := NewTLSHealthcheck(zap.NewExample(), &TLSHealthcheckConfiguration{
+			Base: Base{
+				Name: "foo",
+			},
+			Port:            uint(port),
+			Target:          "127.0.0.1",
+			Insecure:        true,
+			VerifyCommand:   "/bin/sh",
+			VerifyArguments: c.arguments,
+			Timeout:         Duration(time.Second * 2),
+		})
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		_, err := h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for %v:\n%v", c.arguments, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %v", c.arguments)
+		}
+	}
+}
@@ -3,8 +3,10 @@ package healthcheck
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,27 +24,48 @@ func unQuote(text []byte) string {
 	return s
 }
 
+// parseDuration parses a duration. A number without unit is a number of
+// seconds.
+func parseDuration(s string) (Duration, error) {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		nanoseconds := seconds * float64(time.Second)
+		if math.IsNaN(nanoseconds) || nanoseconds >= math.MaxInt64 || nanoseconds < math.MinInt64 {
+			return 0, errors.Errorf("%s is not a valid duration", s)
+		}
+		return Duration(nanoseconds), nil
+	}
+	dur, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.Wrapf(err, "%s is not a duration", s)
+	}
+	return Duration(dur), nil
+}
+
 // UnmarshalText unmarshal a duration
 func (d *Duration) UnmarshalText(text []byte) error {
-	if len(text) < 2 {
+	if len(text) == 0 {
 		return errors.New(fmt.Sprintf("%s is not a duration", text))
 	}
-	t := unQuote(text)
-	dur, err := time.ParseDuration(string(t))
+	dur, err := parseDuration(unQuote(text))
 	if err != nil {
-		return errors.Wrapf(err, "%s is not a duration", text)
+		return err
 	}
-	*d = Duration(dur)
+	*d = dur
 	return nil
 }
 
-// UnmarshalYAML read a duration fom yaml
+// UnmarshalYAML read a duration fom yaml. A number without unit is a
+// number of seconds.
 func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	var raw time.Duration
+	var raw string
 	if err := unmarshal(&raw); err != nil {
 		return errors.Wrap(err, "Unable to read Cabourotte configuration")
 	}
-	*d = Duration(raw)
+	dur, err := parseDuration(raw)
+	if err != nil {
+		return errors.Wrap(err, "Unable to read Cabourotte configuration")
+	}
+	*d = dur
 	return nil
 }
 
@@ -57,6 +80,20 @@ func (d *Duration) MarshalJSON() ([]byte, error) {
 	return json.Marshal(duration.String())
 }
 
+// checkResponseTime returns an error if the response time is greater than the
+// maximum response time (if set). Both durations are added to the annotations.
+func checkResponseTime(responseTime time.Duration, max Duration, annotations Annotations) error {
+	if max == 0 {
+		return nil
+	}
+	annotations["response-time"] = responseTime.String()
+	annotations["max-response-time"] = time.Duration(max).String()
+	if responseTime > time.Duration(max) {
+		return fmt.Errorf("Response time %s is greater than the maximum response time %s", responseTime, time.Duration(max))
+	}
+	return nil
+}
+
 // Protocol is the healthcheck http protocol
 type Protocol int
 
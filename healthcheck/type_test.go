@@ -0,0 +1,54 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalDuration(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Duration
+	}{
+		{in: "10s", want: Duration(10 * time.Second)},
+		{in: "\"10s\"", want: Duration(10 * time.Second)},
+		{in: "10", want: Duration(10 * time.Second)},
+		{in: "\"10\"", want: Duration(10 * time.Second)},
+		{in: "1.5", want: Duration(1500 * time.Millisecond)},
+		{in: "500ms", want: Duration(500 * time.Millisecond)},
+	}
+	for _, c := range cases {
+		var yamlDuration Duration
+		err := yaml.Unmarshal([]byte(c.in), &yamlDuration)
+		if err != nil {
+			t.Fatalf("Fail to unmarshal YAML duration %s :\n%v", c.in, err)
+		}
+		if yamlDuration != c.want {
+			t.Fatalf("Invalid YAML duration for %s\nexpected: %v\nactual: %v", c.in, c.want, yamlDuration)
+		}
+	}
+	for _, c := range cases {
+		var jsonDuration Duration
+		in := c.in
+		if in == "10s" || in == "500ms" {
+			in = "\"" + in + "\""
+		}
+		err := json.Unmarshal([]byte(in), &jsonDuration)
+		if err != nil {
+			t.Fatalf("Fail to unmarshal JSON duration %s :\n%v", in, err)
+		}
+		if jsonDuration != c.want {
+			t.Fatalf("Invalid JSON duration for %s\nexpected: %v\nactual: %v", in, c.want, jsonDuration)
+		}
+	}
+	for _, in := range []string{"foo", "NaN", "Inf", "-Inf", "1e12", "1e400"} {
+		var d Duration
+		err := yaml.Unmarshal([]byte(in), &d)
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", in)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// udpMaxDatagramSize the maximum size of the datagrams read by the UDP
+// healthchecks
+const udpMaxDatagramSize = 65535
+
+// UDPHealthcheckConfiguration defines an UDP healthcheck configuration
+type UDPHealthcheckConfiguration struct {
+	Base `json:",inline" yaml:",inline"`
+	// can be an IP or a domain
+	Target   string   `json:"target"`
+	Port     uint     `json:"port"`
+	SourceIP IP       `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
+	Timeout  Duration `json:"timeout"`
+	// Send the payload sent to the target
+	Send string `json:"send,omitempty" yaml:"send,omitempty"`
+	// SendHex the payload sent to the target, hex encoded, as an
+	// alternative to Send for binary payloads
+	SendHex string `json:"send-hex,omitempty" yaml:"send-hex,omitempty"`
+	// ExpectRegexp the response datagram should match this regular
+	// expression. Any response is valid if not set.
+	ExpectRegexp *Regexp `json:"expect-regexp,omitempty" yaml:"expect-regexp,omitempty"`
+	// SocketOptions the options applied on the healthcheck socket
+	SocketOptions *SocketOptions `json:"socket-options,omitempty" yaml:"socket-options,omitempty"`
+}
+
+// Validate validates the healthcheck configuration
+func (config *UDPHealthcheckConfiguration) Validate() error {
+	if config.Base.Name == "" {
+		return errors.New("The healthcheck name is missing")
+	}
+	if err := config.Base.validate(); err != nil {
+		return err
+	}
+	if err := config.SocketOptions.Validate(); err != nil {
+		return err
+	}
+	if config.Target == "" {
+		return errors.New("The healthcheck target is missing")
+	}
+	if config.Port == 0 {
+		return errors.New("The healthcheck port is missing")
+	}
+	if config.Timeout == 0 {
+		return errors.New("The healthcheck timeout is missing")
+	}
+	if config.Send != "" && config.SendHex != "" {
+		return errors.New("The send and send-hex payloads can't be both set")
+	}
+	if config.Send == "" && config.SendHex == "" {
+		return errors.New("The payload to send is missing")
+	}
+	if _, err := hex.DecodeString(config.SendHex); err != nil {
+		return errors.Wrapf(err, "Invalid hex payload")
+	}
+	if !config.Base.OneOff && config.Base.Cron == "" {
+		if config.Base.Interval < Duration(2*time.Second) {
+			return errors.New("The healthcheck interval should be greater than 2 second")
+		}
+		if config.Base.Interval < config.Timeout {
+			return errors.New("The healthcheck interval should be greater than the timeout")
+		}
+	}
+	return nil
+}
+
+// UDPHealthcheck defines an UDP healthcheck
+type UDPHealthcheck struct {
+	Logger   *zap.Logger
+	Resolver *Resolver
+	Config   *UDPHealthcheckConfiguration
+	URL      string
+	payload  []byte
+}
+
+// buildURL build the target URL for the UDP healthcheck, depending of its
+// configuration
+func (h *UDPHealthcheck) buildURL() {
+	h.URL = net.JoinHostPort(h.Config.Target, fmt.Sprintf("%d", h.Config.Port))
+}
+
+// Summary returns an healthcheck summary
+func (h *UDPHealthcheck) Summary() string {
+	summary := ""
+	if h.Config.Base.Description != "" {
+		summary = fmt.Sprintf("UDP healthcheck %s on %s:%d", h.Config.Base.Description, h.Config.Target, h.Config.Port)
+
+	} else {
+		summary = fmt.Sprintf("UDP healthcheck on %s:%d", h.Config.Target, h.Config.Port)
+	}
+
+	return summary
+}
+
+// Initialize the healthcheck.
+func (h *UDPHealthcheck) Initialize() error {
+	h.buildURL()
+	h.payload = []byte(h.Config.Send)
+	if h.Config.SendHex != "" {
+		payload, err := hex.DecodeString(h.Config.SendHex)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid hex payload")
+		}
+		h.payload = payload
+	}
+	return nil
+}
+
+// GetConfig get the config
+func (h *UDPHealthcheck) GetConfig() interface{} {
+	return h.Config
+}
+
+// Base get the base configuration
+func (h *UDPHealthcheck) Base() Base {
+	return h.Config.Base
+}
+
+// SetSource set the healthcheck source
+func (h *UDPHealthcheck) SetSource(source string) {
+	h.Config.Base.Source = source
+}
+
+// SetResolver set the healthcheck resolver
+func (h *UDPHealthcheck) SetResolver(resolver *Resolver) {
+	h.Resolver = resolver
+}
+
+// LogError logs an error with context
+func (h *UDPHealthcheck) LogError(err error, message string) {
+	h.Logger.Error(err.Error(),
+		zap.String("extra", message),
+		zap.String("target", h.Config.Target),
+		zap.Uint("port", h.Config.Port),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogDebug logs a message with context
+func (h *UDPHealthcheck) LogDebug(message string) {
+	h.Logger.Debug(message,
+		zap.String("target", h.Config.Target),
+		zap.Uint("port", h.Config.Port),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogInfo logs a message with context
+func (h *UDPHealthcheck) LogInfo(message string) {
+	h.Logger.Info(message,
+		zap.String("target", h.Config.Target),
+		zap.Uint("port", h.Config.Port),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// Execute executes an healthcheck on the given target: the payload is sent
+// to the target, and the response datagram is verified
+func (h *UDPHealthcheck) Execute(ctx context.Context) (Annotations, error) {
+	h.LogDebug("start executing healthcheck")
+	annotations := Annotations{}
+	dialer := net.Dialer{}
+	if h.Config.SourceIP != nil {
+		srcIP := net.IP(h.Config.SourceIP).String()
+		addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:0", srcIP))
+		if err != nil {
+			return annotations, errors.Wrapf(err, "Fail to set the source IP %s", srcIP)
+		}
+		dialer = net.Dialer{
+			LocalAddr: addr,
+		}
+	}
+	dialer.Control = h.Config.SocketOptions.control()
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
+	defer cancel()
+	conn, err := h.Resolver.DialContext(&dialer)(timeoutCtx, "udp", h.URL)
+	if err != nil {
+		return annotations, errors.Wrapf(err, "UDP connection failed on %s", h.URL)
+	}
+	defer conn.Close()
+	if deadline, ok := timeoutCtx.Deadline(); ok {
+		err = conn.SetDeadline(deadline)
+		if err != nil {
+			return annotations, errors.Wrapf(err, "Fail to set the connection deadline on %s", h.URL)
+		}
+	}
+	_, err = conn.Write(h.payload)
+	if err != nil {
+		return annotations, errors.Wrapf(err, "Fail to send the payload to %s", h.URL)
+	}
+	buffer := make([]byte, udpMaxDatagramSize)
+	n, err := conn.Read(buffer)
+	if err != nil {
+		return annotations, errors.Wrapf(err, "Fail to read the response from %s", h.URL)
+	}
+	if h.Config.ExpectRegexp != nil {
+		r := regexp.Regexp(*h.Config.ExpectRegexp)
+		if !r.Match(buffer[:n]) {
+			return annotations, fmt.Errorf("The response from %s does not match the regexp %s", h.URL, r.String())
+		}
+	}
+	return annotations, nil
+}
+
+// NewUDPHealthcheck creates an UDP healthcheck from a logger and a
+// configuration
+func NewUDPHealthcheck(logger *zap.Logger, config *UDPHealthcheckConfiguration) *UDPHealthcheck {
+	return &UDPHealthcheck{
+		Logger: logger,
+		Config: config,
+	}
+}
+
+// MarshalJSON marshal to json an UDP healthcheck
+func (h *UDPHealthcheck) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Config)
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UDPHealthcheckConfiguration) DeepCopyInto(out *UDPHealthcheckConfiguration) {
+	*out = *in
+	in.Base.DeepCopyInto(&out.Base)
+	if in.SourceIP != nil {
+		in, out := &in.SourceIP, &out.SourceIP
+		*out = make(IP, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExpectRegexp != nil {
+		in, out := &in.ExpectRegexp, &out.ExpectRegexp
+		*out = (*in).DeepCopy()
+	}
+	if in.SocketOptions != nil {
+		in, out := &in.SocketOptions, &out.SocketOptions
+		*out = new(SocketOptions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UDPHealthcheckConfiguration.
+func (in *UDPHealthcheckConfiguration) DeepCopy() *UDPHealthcheckConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(UDPHealthcheckConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,127 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// startUDPEchoServer starts an UDP server answering "pong: " followed by the
+// received payload, and returns its port
+func startUDPEchoServer(t *testing.T, address string) uint {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		t.Skipf("Fail to start the UDP server on %s :\n%v", address, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			response := append([]byte("pong: "), buffer[:n]...)
+			_, _ = conn.WriteTo(response, addr)
+		}
+	}()
+	return uint(conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func TestUDPExecute(t *testing.T) {
+	cases := []struct {
+		address string
+		target  string
+	}{
+		{address: "127.0.0.1:0", target: "127.0.0.1"},
+		{address: "[::1]:0", target: "::1"},
+	}
+	for _, c := range cases {
+		port := startUDPEchoServer(t, c.address)
+		expected := Regexp(*regexp.MustCompile("^pong: ping$"))
+		h := NewUDPHealthcheck(zap.NewExample(), &UDPHealthcheckConfiguration{
+			Base: Base{
+				Name: "foo",
+			},
+			Target:       c.target,
+			Port:         port,
+			Send:         "ping",
+			ExpectRegexp: &expected,
+			Timeout:      Duration(time.Second * 2),
+		})
+		err := h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		_, err = h.Execute(context.Background())
+		if err != nil {
+			t.Fatalf("healthcheck error on %s:\n%v", c.target, err)
+		}
+		// hex encoded "pang"
+		h.Config.Send = ""
+		h.Config.SendHex = "70616e67"
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		_, err = h.Execute(context.Background())
+		if err == nil {
+			t.Fatalf("Was expecting an error on %s", c.target)
+		}
+	}
+}
+
+func TestUDPExecuteTimeout(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the UDP server :\n%v", err)
+	}
+	defer conn.Close()
+	h := NewUDPHealthcheck(zap.NewExample(), &UDPHealthcheckConfiguration{
+		Base: Base{
+			Name: "foo",
+		},
+		Target:  "127.0.0.1",
+		Port:    uint(conn.LocalAddr().(*net.UDPAddr).Port),
+		Send:    "ping",
+		Timeout: Duration(time.Millisecond * 200),
+	})
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	_, err = h.Execute(context.Background())
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
+func TestUDPValidate(t *testing.T) {
+	cases := []struct {
+		config UDPHealthcheckConfiguration
+		valid  bool
+	}{
+		{config: UDPHealthcheckConfiguration{Send: "ping"}, valid: true},
+		{config: UDPHealthcheckConfiguration{SendHex: "70696e67"}, valid: true},
+		{config: UDPHealthcheckConfiguration{}, valid: false},
+		{config: UDPHealthcheckConfiguration{Send: "ping", SendHex: "70696e67"}, valid: false},
+		{config: UDPHealthcheckConfiguration{SendHex: "invalid"}, valid: false},
+	}
+	for _, c := range cases {
+		c.config.Base = Base{Name: "foo", OneOff: true}
+		c.config.Target = "127.0.0.1"
+		c.config.Port = 8125
+		c.config.Timeout = Duration(time.Second)
+		err := c.config.Validate()
+		if c.valid && err != nil {
+			t.Fatalf("Invalid configuration %v:\n%v", c.config, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Was expecting an error for %v", c.config)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package healthcheck
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// windowTimeFormat the format of the active window start and end times
+const windowTimeFormat = "15:04"
+
+// ActiveWindow the time window during which an healthcheck is executed
+type ActiveWindow struct {
+	// Days the days of the week (monday, tuesday...) on which the window
+	// applies (every day if empty)
+	Days []string `json:"days,omitempty" yaml:"days,omitempty"`
+	// Start the start of the window, in the HH:MM format
+	Start string `json:"start"`
+	// End the end of the window, in the HH:MM format. The window spans
+	// midnight if the end is before the start.
+	End string `json:"end"`
+	// Timezone the timezone of the window (UTC if empty)
+	Timezone string `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+}
+
+// parseDay parses a day of the week
+func parseDay(day string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), day) {
+			return d, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("Invalid day %s", day)
+}
+
+// minutes parses a HH:MM time and returns the number of minutes since
+// midnight
+func minutes(value string) (int, error) {
+	t, err := time.Parse(windowTimeFormat, value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Invalid time %s (format HH:MM)", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Validate validates the active window
+func (w *ActiveWindow) Validate() error {
+	if w == nil {
+		return nil
+	}
+	for _, day := range w.Days {
+		if _, err := parseDay(day); err != nil {
+			return err
+		}
+	}
+	start, err := minutes(w.Start)
+	if err != nil {
+		return err
+	}
+	end, err := minutes(w.End)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return errors.New("The active window start and end should be different")
+	}
+	if _, err := time.LoadLocation(w.Timezone); err != nil {
+		return errors.Wrapf(err, "Invalid timezone %s", w.Timezone)
+	}
+	return nil
+}
+
+// Active returns true if the given time is in the window. A nil window is
+// always active.
+func (w *ActiveWindow) Active(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+	location, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return true
+	}
+	start, err := minutes(w.Start)
+	if err != nil {
+		return true
+	}
+	end, err := minutes(w.End)
+	if err != nil {
+		return true
+	}
+	t = t.In(location)
+	if len(w.Days) != 0 {
+		dayFound := false
+		for _, day := range w.Days {
+			if d, err := parseDay(day); err == nil && d == t.Weekday() {
+				dayFound = true
+				break
+			}
+		}
+		if !dayFound {
+			return false
+		}
+	}
+	current := t.Hour()*60 + t.Minute()
+	if start < end {
+		return current >= start && current < end
+	}
+	return current >= start || current < end
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActiveWindow) DeepCopyInto(out *ActiveWindow) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActiveWindow.
+func (in *ActiveWindow) DeepCopy() *ActiveWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(ActiveWindow)
+	in.DeepCopyInto(out)
+	return out
+}
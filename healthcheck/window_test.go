@@ -0,0 +1,56 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActiveWindow(t *testing.T) {
+	// 2024-01-08 is a monday
+	monday := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		window *ActiveWindow
+		time   time.Time
+		active bool
+	}{
+		{window: nil, time: monday, active: true},
+		{window: &ActiveWindow{Start: "08:00", End: "18:00"}, time: monday.Add(9 * time.Hour), active: true},
+		{window: &ActiveWindow{Start: "08:00", End: "18:00"}, time: monday.Add(18 * time.Hour), active: false},
+		{window: &ActiveWindow{Start: "08:00", End: "18:00"}, time: monday.Add(7 * time.Hour), active: false},
+		{window: &ActiveWindow{Start: "22:00", End: "02:00"}, time: monday.Add(23 * time.Hour), active: true},
+		{window: &ActiveWindow{Start: "22:00", End: "02:00"}, time: monday.Add(time.Hour), active: true},
+		{window: &ActiveWindow{Start: "22:00", End: "02:00"}, time: monday.Add(12 * time.Hour), active: false},
+		{window: &ActiveWindow{Days: []string{"Monday", "friday"}, Start: "08:00", End: "18:00"}, time: monday.Add(9 * time.Hour), active: true},
+		{window: &ActiveWindow{Days: []string{"tuesday"}, Start: "08:00", End: "18:00"}, time: monday.Add(9 * time.Hour), active: false},
+		{window: &ActiveWindow{Start: "08:00", End: "18:00", Timezone: "America/New_York"}, time: monday.Add(9 * time.Hour), active: false},
+		{window: &ActiveWindow{Start: "08:00", End: "18:00", Timezone: "America/New_York"}, time: monday.Add(14 * time.Hour), active: true},
+	}
+	for _, c := range cases {
+		if c.window.Active(c.time) != c.active {
+			t.Fatalf("Invalid active window result for %v at %s, expected %t", c.window, c.time, c.active)
+		}
+	}
+}
+
+func TestActiveWindowValidate(t *testing.T) {
+	cases := []struct {
+		window  ActiveWindow
+		success bool
+	}{
+		{window: ActiveWindow{Start: "08:00", End: "18:00"}, success: true},
+		{window: ActiveWindow{Days: []string{"sunday"}, Start: "08:00", End: "18:00", Timezone: "Europe/Paris"}, success: true},
+		{window: ActiveWindow{Start: "8h", End: "18:00"}, success: false},
+		{window: ActiveWindow{Start: "08:00", End: "08:00"}, success: false},
+		{window: ActiveWindow{Days: []string{"someday"}, Start: "08:00", End: "18:00"}, success: false},
+		{window: ActiveWindow{Start: "08:00", End: "18:00", Timezone: "Mars/Olympus"}, success: false},
+	}
+	for _, c := range cases {
+		err := c.window.Validate()
+		if c.success && err != nil {
+			t.Fatalf("The window %v should be valid\n%v", c.window, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("Was expecting an error for %v", c.window)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package healthcheck
 
 import (
+	"sync/atomic"
 	"time"
 
 	"gopkg.in/tomb.v2"
@@ -11,18 +12,68 @@ type Wrapper struct {
 	healthcheck Healthcheck
 	Tick        *time.Ticker
 	t           tomb.Tomb
+	events      *eventsBuffer
+
+	intervalOverride time.Duration
+	// enabled the healthcheck is executed only if enabled
+	enabled         atomic.Bool
+	enabledOverride bool
+	// interval the current execution interval, used to compute the
+	// scheduling drift
+	interval             atomic.Int64
+	executed             atomic.Bool
+	failed               bool
+	consecutiveFailures  uint
+	consecutiveSuccesses uint
 }
 
 // NewWrapper creates a new wrapper struct
 func NewWrapper(healthcheck Healthcheck) *Wrapper {
-	return &Wrapper{
+	wrapper := &Wrapper{
 		healthcheck: healthcheck,
+		events:      newEventsBuffer(DefaultMaxExecutionEvents),
+	}
+	base := healthcheck.Base()
+	wrapper.enabled.Store(base.IsEnabled())
+	return wrapper
+}
+
+// threshold returns the configured threshold, or 1 if not set
+func threshold(value uint) uint {
+	if value == 0 {
+		return 1
 	}
+	return value
+}
+
+// debounce updates the state of the healthcheck using the outcome of an
+// execution, and returns the debounced outcome.
+// The healthcheck is considered failed after FailureThreshold consecutive
+// failures, and successful again after SuccessThreshold consecutive
+// successes.
+func (w *Wrapper) debounce(success bool) bool {
+	base := w.healthcheck.Base()
+	if success {
+		w.consecutiveFailures = 0
+		w.consecutiveSuccesses++
+		if w.failed && w.consecutiveSuccesses >= threshold(base.SuccessThreshold) {
+			w.failed = false
+		}
+	} else {
+		w.consecutiveSuccesses = 0
+		w.consecutiveFailures++
+		if !w.failed && w.consecutiveFailures >= threshold(base.FailureThreshold) {
+			w.failed = true
+		}
+	}
+	return !w.failed
 }
 
 // Stop an Healthcheck wrapper
 func (w *Wrapper) Stop() error {
-	w.Tick.Stop()
+	if w.Tick != nil {
+		w.Tick.Stop()
+	}
 	w.t.Kill(nil)
 	err := w.t.Wait()
 	if err != nil {
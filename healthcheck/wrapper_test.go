@@ -0,0 +1,59 @@
+package healthcheck
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestWrapperDebounce(t *testing.T) {
+	wrapper := NewWrapper(NewTCPHealthcheck(
+		zap.NewExample(),
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:             "foo",
+				FailureThreshold: 3,
+				SuccessThreshold: 2,
+			},
+		},
+	))
+	cases := []struct {
+		success  bool
+		expected bool
+	}{
+		{false, true},
+		{false, true},
+		{true, true},
+		{false, true},
+		{false, true},
+		{false, false},
+		{true, false},
+		{false, false},
+		{true, false},
+		{true, true},
+		{false, true},
+	}
+	for i, c := range cases {
+		result := wrapper.debounce(c.success)
+		if result != c.expected {
+			t.Fatalf("Invalid debounced state for execution %d: %t", i, result)
+		}
+	}
+}
+
+func TestWrapperDebounceDefault(t *testing.T) {
+	wrapper := NewWrapper(NewTCPHealthcheck(
+		zap.NewExample(),
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name: "foo",
+			},
+		},
+	))
+	if wrapper.debounce(false) {
+		t.Fatalf("The healthcheck should fail immediately")
+	}
+	if !wrapper.debounce(true) {
+		t.Fatalf("The healthcheck should recover immediately")
+	}
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/mcorbin/corbierror"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// DefaultArchiveLimit the default maximum number of results returned by the
+// archive endpoint
+const DefaultArchiveLimit = 1000
+
+// CursorHeader the header containing the cursor of the next archive page
+const CursorHeader = "X-Cabourotte-Cursor"
+
+// archiveCursor the position of a result in the archive. Results are ordered
+// by timestamp, in nanoseconds, and name, so the results of an healthcheck
+// executed several times in the same second are not skipped.
+type archiveCursor struct {
+	Timestamp int64
+	Name      string
+}
+
+func (c archiveCursor) String() string {
+	return fmt.Sprintf("%d:%s", c.Timestamp, c.Name)
+}
+
+// parseCursor parses a cursor in the timestamp:name format
+func parseCursor(value string) (archiveCursor, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return archiveCursor{}, fmt.Errorf("Invalid cursor %s", value)
+	}
+	timestamp, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return archiveCursor{}, fmt.Errorf("Invalid cursor %s", value)
+	}
+	return archiveCursor{Timestamp: timestamp, Name: parts[1]}, nil
+}
+
+// archiveEntry a result of the archive and its position
+type archiveEntry struct {
+	position archiveCursor
+	result   *healthcheck.Result
+}
+
+// after returns true if the position is after the cursor
+func (c archiveCursor) after(position archiveCursor) bool {
+	if position.Timestamp != c.Timestamp {
+		return position.Timestamp > c.Timestamp
+	}
+	return position.Name > c.Name
+}
+
+// archivePage returns at most limit results newer than since and after the
+// cursor, and the cursor of the next page (nil if there is no next page)
+func archivePage(results []healthcheck.Result, since int64, cursor *archiveCursor, limit int) ([]healthcheck.Result, *archiveCursor) {
+	entries := make([]archiveEntry, 0, len(results))
+	for i := range results {
+		result := &results[i]
+		if result.HealthcheckTimestamp < since {
+			continue
+		}
+		entries = append(entries, archiveEntry{
+			position: archiveCursor{Timestamp: result.Time().UnixNano(), Name: result.Name},
+			result:   result,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].position.after(entries[j].position)
+	})
+	page := []healthcheck.Result{}
+	for i, entry := range entries {
+		if cursor != nil && !cursor.after(entry.position) {
+			continue
+		}
+		if len(page) == limit {
+			last := entries[i-1].position
+			return page, &last
+		}
+		page = append(page, *entry.result)
+	}
+	return page, nil
+}
+
+// archive streams the results in JSONL
+func (c *Component) archive(ec echo.Context) error {
+	var since int64
+	var err error
+	if value := ec.QueryParam("since"); value != "" {
+		since, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return corbierror.New(fmt.Sprintf("Invalid since parameter %s", value), corbierror.BadRequest, true)
+		}
+	}
+	limit := DefaultArchiveLimit
+	if value := ec.QueryParam("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return corbierror.New(fmt.Sprintf("Invalid limit parameter %s", value), corbierror.BadRequest, true)
+		}
+	}
+	var cursor *archiveCursor
+	if value := ec.QueryParam("cursor"); value != "" {
+		parsed, err := parseCursor(value)
+		if err != nil {
+			return corbierror.New(err.Error(), corbierror.BadRequest, true)
+		}
+		cursor = &parsed
+	}
+	page, next := archivePage(c.MemoryStore.ListHistory(), since, cursor, limit)
+	response := ec.Response()
+	if next != nil {
+		response.Header().Set(CursorHeader, next.String())
+	}
+	response.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	response.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(response)
+	for i := range page {
+		if err := encoder.Encode(&page[i]); err != nil {
+			return err
+		}
+	}
+	response.Flush()
+	return nil
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestArchivePage(t *testing.T) {
+	results := []healthcheck.Result{
+		{Name: "c", HealthcheckTimestamp: 20},
+		{Name: "a", HealthcheckTimestamp: 10},
+		{Name: "b", HealthcheckTimestamp: 20},
+		{Name: "d", HealthcheckTimestamp: 30},
+	}
+	page, next := archivePage(results, 15, nil, 2)
+	if len(page) != 2 || page[0].Name != "b" || page[1].Name != "c" {
+		t.Fatalf("Invalid archive page %v", page)
+	}
+	if next == nil || next.String() != "20000000000:c" {
+		t.Fatalf("Invalid cursor %v", next)
+	}
+	cursor, err := parseCursor(next.String())
+	if err != nil {
+		t.Fatalf("Fail to parse the cursor\n%v", err)
+	}
+	page, next = archivePage(results, 15, &cursor, 2)
+	if len(page) != 1 || page[0].Name != "d" {
+		t.Fatalf("Invalid archive page %v", page)
+	}
+	if next != nil {
+		t.Fatalf("The last page should not have a cursor")
+	}
+	// results of the same healthcheck executed in the same second
+	results = []healthcheck.Result{
+		{Name: "a", HealthcheckTimestamp: 10, HealthcheckTime: time.Unix(10, 200).Format(time.RFC3339Nano)},
+		{Name: "a", HealthcheckTimestamp: 10, HealthcheckTime: time.Unix(10, 100).Format(time.RFC3339Nano)},
+	}
+	page, next = archivePage(results, 0, nil, 1)
+	if len(page) != 1 || page[0].HealthcheckTime != results[1].HealthcheckTime || next == nil {
+		t.Fatalf("Invalid archive page %v", page)
+	}
+	page, next = archivePage(results, 0, next, 1)
+	if len(page) != 1 || page[0].HealthcheckTime != results[0].HealthcheckTime || next != nil {
+		t.Fatalf("The second result of the same second was skipped %v", page)
+	}
+	_, err = parseCursor("foo")
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
@@ -1,8 +1,10 @@
 package http
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -21,13 +23,63 @@ type Configuration struct {
 	Port                  uint32
 	DisableHealthcheckAPI bool `yaml:"disable-healthcheck-api,omitempty"`
 	DisableResultAPI      bool `yaml:"disable-result-api,omitempty"`
-	Key                   string
-	Cert                  string
-	BasicAuth             BasicAuth `yaml:"basic-auth"`
-	AllowedCN             []string  `yaml:"allowed-cn"`
-	Cacert                string
+	// Socket the path of the Unix socket the server listens on, instead of
+	// Host and Port
+	Socket string `yaml:"socket,omitempty"`
+	// DisableConfigAPI disables the endpoint returning the running
+	// configuration
+	DisableConfigAPI    bool `yaml:"disable-config-api,omitempty"`
+	Key                 string
+	Cert                string
+	BasicAuth           BasicAuth `yaml:"basic-auth"`
+	AllowedCN           []string  `yaml:"allowed-cn"`
+	Cacert              string
+	BulkParallelism     uint                 `yaml:"bulk-parallelism,omitempty"`
+	OneOffTimeout       healthcheck.Duration `yaml:"one-off-timeout,omitempty"`
+	AggregateCacheTTL   healthcheck.Duration `yaml:"aggregate-cache-ttl,omitempty"`
+	ForwardedHeaders    []string             `yaml:"forwarded-headers,omitempty"`
+	AggregationStrategy string               `yaml:"aggregation-strategy,omitempty"`
+	DegradedThreshold   float64              `yaml:"degraded-threshold,omitempty"`
+	// FrontendMode the frontend is rendered by the server (server) or is a
+	// static page fetching the results from the API (spa)
+	FrontendMode string `yaml:"frontend-mode,omitempty"`
+	// FrontendDirectory the directory containing a custom SPA frontend
+	// (the embedded SPA if not set)
+	FrontendDirectory string `yaml:"frontend-directory,omitempty"`
 }
 
+// DefaultBulkParallelism the default number of healthchecks added in parallel
+// by the bulk endpoint
+const DefaultBulkParallelism = 10
+
+// DefaultOneOffTimeout the default maximum execution time of one-off
+// healthchecks
+const DefaultOneOffTimeout = healthcheck.Duration(60 * time.Second)
+
+// DefaultAggregateCacheTTL the default duration during which aggregate
+// computations on the results are cached
+const DefaultAggregateCacheTTL = healthcheck.Duration(time.Second)
+
+const (
+	// AggregationWorstOf a group is unhealthy as soon as one of its
+	// healthchecks fails
+	AggregationWorstOf string = "worst-of"
+	// AggregationPercentage a group is degraded when the percentage of failed
+	// healthchecks is greater than the degraded threshold
+	AggregationPercentage string = "percentage"
+	// AggregationWeighted same as AggregationPercentage, but each healthcheck
+	// counts for its weight
+	AggregationWeighted string = "weighted"
+)
+
+const (
+	// FrontendServer the frontend is rendered by the server
+	FrontendServer string = "server"
+	// FrontendSPA the frontend is a static page fetching the results from
+	// the API
+	FrontendSPA string = "spa"
+)
+
 // UnmarshalYAML parses the configuration of the http component from YAML.
 func (c *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	type rawConfiguration Configuration
@@ -35,12 +87,18 @@ func (c *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if err := unmarshal(&raw); err != nil {
 		return errors.Wrap(err, "Unable to read HTTP configuration")
 	}
-	ip := net.ParseIP(raw.Host)
-	if ip == nil {
-		return errors.New("Invalid IP address for the HTTP server")
-	}
-	if raw.Port == 0 {
-		return errors.New("Invalid Port for the HTTP server")
+	if raw.Socket != "" {
+		if raw.Host != "" || raw.Port != 0 {
+			return errors.New("The HTTP server socket can't be set with the host and port")
+		}
+	} else {
+		ip := net.ParseIP(raw.Host)
+		if ip == nil {
+			return errors.New("Invalid IP address for the HTTP server")
+		}
+		if raw.Port == 0 {
+			return errors.New("Invalid Port for the HTTP server")
+		}
 	}
 	if (raw.Cert != "" && raw.Key == "") || (raw.Cert == "" && raw.Key != "") {
 		return errors.New("The cert and key options should be configured together")
@@ -53,17 +111,61 @@ func (c *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		(raw.BasicAuth.Username != "" && raw.BasicAuth.Password == "") {
 		return errors.New("Invalid Basic Auth configuration")
 	}
+	switch raw.AggregationStrategy {
+	case "", AggregationWorstOf, AggregationPercentage, AggregationWeighted:
+	default:
+		return fmt.Errorf("Invalid aggregation strategy %s", raw.AggregationStrategy)
+	}
+	if raw.DegradedThreshold < 0 || raw.DegradedThreshold > 100 {
+		return errors.New("The degraded threshold should be between 0 and 100")
+	}
+	switch raw.FrontendMode {
+	case "", FrontendServer, FrontendSPA:
+	default:
+		return fmt.Errorf("Invalid frontend mode %s", raw.FrontendMode)
+	}
+	if raw.FrontendDirectory != "" && raw.FrontendMode != FrontendSPA {
+		return errors.New("The frontend directory can only be set in the spa frontend mode")
+	}
 	*c = Configuration(raw)
 	return nil
 }
 
+// ClonePayload the payload for requests cloning an healthcheck
+type ClonePayload struct {
+	Name      string          `json:"name"`
+	Overrides json.RawMessage `json:"overrides"`
+}
+
+// IntervalPayload the payload for requests overriding the interval of an
+// healthcheck
+type IntervalPayload struct {
+	Interval healthcheck.Duration `json:"interval"`
+}
+
+// QueryResultsPayload the payload for requests fetching the results of
+// several healthchecks
+type QueryResultsPayload struct {
+	Names []string `json:"names"`
+}
+
 // BulkPayload the paylaod for bulk requests fo healthchecks
 type BulkPayload struct {
-	DNSChecks     []healthcheck.DNSHealthcheckConfiguration     `json:"dns-checks"`
-	CommandChecks []healthcheck.CommandHealthcheckConfiguration `json:"command-checks"`
-	TCPChecks     []healthcheck.TCPHealthcheckConfiguration     `json:"tcp-checks"`
-	HTTPChecks    []healthcheck.HTTPHealthcheckConfiguration    `json:"http-checks"`
-	TLSChecks     []healthcheck.TLSHealthcheckConfiguration     `json:"tls-checks"`
+	DNSChecks      []healthcheck.DNSHealthcheckConfiguration      `json:"dns-checks"`
+	CommandChecks  []healthcheck.CommandHealthcheckConfiguration  `json:"command-checks"`
+	TCPChecks      []healthcheck.TCPHealthcheckConfiguration      `json:"tcp-checks"`
+	HTTPChecks     []healthcheck.HTTPHealthcheckConfiguration     `json:"http-checks"`
+	TLSChecks      []healthcheck.TLSHealthcheckConfiguration      `json:"tls-checks"`
+	GRPCChecks     []healthcheck.GRPCHealthcheckConfiguration     `json:"grpc-checks"`
+	PostgresChecks []healthcheck.PostgresHealthcheckConfiguration `json:"postgres-checks"`
+	UDPChecks      []healthcheck.UDPHealthcheckConfiguration      `json:"udp-checks"`
+}
+
+// PreviewPayload the payload for the healthchecks preview requests
+type PreviewPayload struct {
+	BulkPayload
+	// Labels the labels added to all healthchecks
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // Validate validates the payload for bulk requests
@@ -119,5 +221,35 @@ func (p *BulkPayload) Validate() error {
 			return errors.New(msg)
 		}
 	}
+	for _, config := range p.GRPCChecks {
+		err := config.Validate()
+		if config.Base.OneOff {
+			return errors.New(oneOffErrorMsg)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+			return errors.New(msg)
+		}
+	}
+	for _, config := range p.PostgresChecks {
+		err := config.Validate()
+		if config.Base.OneOff {
+			return errors.New(oneOffErrorMsg)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+			return errors.New(msg)
+		}
+	}
+	for _, config := range p.UDPChecks {
+		err := config.Validate()
+		if config.Base.OneOff {
+			return errors.New(oneOffErrorMsg)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+			return errors.New(msg)
+		}
+	}
 	return nil
 }
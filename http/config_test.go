@@ -37,6 +37,7 @@ basic-auth:
 allowed-cn:
   - "mcorbin"
   - "aaa"
+bulk-parallelism: 5
 `,
 			want: Configuration{
 				Host:                  "127.0.0.1",
@@ -47,12 +48,35 @@ allowed-cn:
 				DisableResultAPI:      true,
 				DisableHealthcheckAPI: true,
 				AllowedCN:             []string{"mcorbin", "aaa"},
+				BulkParallelism:       5,
 				BasicAuth: BasicAuth{
 					Username: "foo",
 					Password: "bar",
 				},
 			},
 		},
+		{
+			in: `
+host: "127.0.0.1"
+port: 2000
+frontend-mode: spa
+frontend-directory: /tmp/frontend
+`,
+			want: Configuration{
+				Host:              "127.0.0.1",
+				Port:              2000,
+				FrontendMode:      FrontendSPA,
+				FrontendDirectory: "/tmp/frontend",
+			},
+		},
+		{
+			in: `
+socket: /tmp/cabourotte.sock
+`,
+			want: Configuration{
+				Socket: "/tmp/cabourotte.sock",
+			},
+		},
 	}
 	for _, c := range cases {
 		var result Configuration
@@ -119,6 +143,24 @@ host: "127.0.0.1"
 port: 2000
 basic-auth:
   username: "foo"
+`},
+		{
+			in: `
+host: "127.0.0.1"
+port: 2000
+frontend-mode: foo
+`},
+		{
+			in: `
+host: "127.0.0.1"
+port: 2000
+frontend-directory: /tmp/frontend
+`},
+		{
+			in: `
+host: "127.0.0.1"
+port: 2000
+socket: /tmp/cabourotte.sock
 `},
 	}
 	for _, c := range cases {
@@ -2,12 +2,18 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"crypto/subtle"
 	"embed"
+	"encoding/json"
 	"fmt"
 	"io/fs"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"text/template"
@@ -17,17 +23,103 @@ import (
 	"github.com/labstack/echo/middleware"
 
 	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/memorystore"
 	"github.com/mcorbin/corbierror"
+	"github.com/pkg/errors"
 )
 
 type ListResultsOutput struct {
 	Result []healthcheck.Result `json:"result"`
+	// Total the number of results matching the filters, set when the
+	// results are filtered or paginated
+	Total *int `json:"total,omitempty"`
+}
+
+type QueryResultsOutput struct {
+	Result []healthcheck.Result `json:"result"`
+	// NotFound the names of the healthchecks without result
+	NotFound []string `json:"not-found"`
+}
+
+type ListHistoryOutput struct {
+	Result []healthcheck.Result `json:"result"`
+	// Tiers the down-sampled results, set when retention tiers are configured
+	Tiers []memorystore.TierHistory `json:"tiers,omitempty"`
 }
 
 type ListHealthchecksOutput struct {
 	Result []healthcheck.Healthcheck `json:"result"`
 }
 
+// healthcheckOutput an healthcheck with its enabled state and its next
+// execution time, for healthchecks scheduled using a cron expression
+type healthcheckOutput struct {
+	healthcheck.Healthcheck
+	enabled bool
+}
+
+// MarshalJSON marshal to json an healthcheck, adding the enabled field and
+// the next-run field for healthchecks scheduled using a cron expression
+func (o healthcheckOutput) MarshalJSON() ([]byte, error) {
+	body, err := json.Marshal(o.Healthcheck)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	fields["enabled"], err = json.Marshal(o.enabled)
+	if err != nil {
+		return nil, err
+	}
+	base := o.Base()
+	nextRun := base.NextRun(time.Now())
+	if nextRun != nil && o.enabled {
+		fields["next-run"], err = json.Marshal(nextRun)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// newHealthcheckOutput wraps an healthcheck in order to expose its enabled
+// state and its next execution time
+func (c *Component) newHealthcheckOutput(check healthcheck.Healthcheck) healthcheckOutput {
+	return healthcheckOutput{
+		Healthcheck: check,
+		enabled:     c.healthcheck.IsEnabled(check),
+	}
+}
+
+// newHealthcheckOutputs wraps healthchecks in order to expose their enabled
+// state and their next execution time
+func (c *Component) newHealthcheckOutputs(checks []healthcheck.Healthcheck) []healthcheck.Healthcheck {
+	result := make([]healthcheck.Healthcheck, 0, len(checks))
+	for _, check := range checks {
+		result = append(result, c.newHealthcheckOutput(check))
+	}
+	return result
+}
+
+// ListSourcesOutput the output of the healthchecks sources listing
+type ListSourcesOutput struct {
+	Result []healthcheck.SourceStats `json:"result"`
+}
+
+type ListExecutionEventsOutput struct {
+	Result []healthcheck.ExecutionEvent `json:"result"`
+}
+
+type RemoveSourceOutput struct {
+	Removed int `json:"removed"`
+}
+
+type IntervalOutput struct {
+	Interval string `json:"interval"`
+}
+
 // BasicResponse a type for HTTP responses
 type BasicResponse struct {
 	Messages []string `json:"messages"`
@@ -49,18 +141,115 @@ func (c *Component) addCheck(ec echo.Context, check healthcheck.Healthcheck) err
 	return nil
 }
 
+// addChecks adds several periodic healthchecks to the healthcheck component.
+// Checks are added in parallel, by at most BulkParallelism workers.
+// Returns an error message for each check which failed to be added.
+func (c *Component) addChecks(ec echo.Context, checks []healthcheck.Healthcheck) []string {
+	parallelism := int(c.Config.BulkParallelism)
+	if parallelism == 0 {
+		parallelism = DefaultBulkParallelism
+	}
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	errorMessages := []string{}
+	checksChan := make(chan healthcheck.Healthcheck)
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for check := range checksChan {
+				err := c.addCheck(ec, check)
+				if err != nil {
+					lock.Lock()
+					errorMessages = append(errorMessages, fmt.Sprintf("healthcheck %s: %s", check.Base().Name, err.Error()))
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+	for _, check := range checks {
+		checksChan <- check
+	}
+	close(checksChan)
+	wg.Wait()
+	sort.Strings(errorMessages)
+	return errorMessages
+}
+
 //go:embed assets
 var embededFiles embed.FS
 
+// forwardHeaders adds the allowed headers of the API request to the headers
+// of an one-off HTTP healthcheck. Headers set in the healthcheck configuration
+// are not overridden.
+func (c *Component) forwardHeaders(ec echo.Context, check healthcheck.Healthcheck) {
+	httpCheck, ok := check.(*healthcheck.HTTPHealthcheck)
+	if !ok {
+		return
+	}
+	for _, header := range c.Config.ForwardedHeaders {
+		value := ec.Request().Header.Get(header)
+		if value == "" {
+			continue
+		}
+		if httpCheck.Config.Headers == nil {
+			httpCheck.Config.Headers = make(map[string]string)
+		}
+		if !hasHeader(httpCheck.Config.Headers, header) {
+			httpCheck.Config.Headers[header] = value
+		}
+	}
+}
+
+// hasHeader returns true if the header is in the headers, header names
+// being case-insensitive
+func hasHeader(headers map[string]string, header string) bool {
+	for name := range headers {
+		if strings.EqualFold(name, header) {
+			return true
+		}
+	}
+	return false
+}
+
 // oneOff executes an one-off healthcheck and returns its result
 func (c *Component) oneOff(ec echo.Context, healthcheck healthcheck.Healthcheck) error {
 	c.Logger.Info(fmt.Sprintf("Executing one-off healthcheck %s", healthcheck.Base().Name))
+	c.forwardHeaders(ec, healthcheck)
+	healthcheck.SetResolver(c.healthcheck.Resolver)
 	err := healthcheck.Initialize()
 	if err != nil {
 		msg := fmt.Sprintf("Fail to initialize one off healthcheck %s: %s", healthcheck.Base().Name, err.Error())
 		return corbierror.New(msg, corbierror.Internal, true)
 	}
-	err = healthcheck.Execute()
+	timeout := time.Duration(c.Config.OneOffTimeout)
+	if timeout == 0 {
+		timeout = time.Duration(DefaultOneOffTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ec.Request().Context(), timeout)
+	defer cancel()
+	release, err := c.healthcheck.Acquire(ctx)
+	if err != nil {
+		msg := fmt.Sprintf("Execution of one off healthcheck %s exceeded the maximum execution time of %s while waiting for the concurrent executions limit", healthcheck.Base().Name, timeout.String())
+		c.Logger.Error(msg)
+		return ec.JSON(http.StatusGatewayTimeout, newResponse(msg))
+	}
+	errChan := make(chan error, 1)
+	go func() {
+		defer release()
+		_, err := healthcheck.Execute(ctx)
+		errChan <- err
+	}()
+	select {
+	case err = <-errChan:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		msg := fmt.Sprintf("Execution of one off healthcheck %s exceeded the maximum execution time of %s", healthcheck.Base().Name, timeout.String())
+		c.Logger.Error(msg)
+		return ec.JSON(http.StatusGatewayTimeout, newResponse(msg))
+	}
 	if err != nil {
 		msg := fmt.Sprintf("Execution of one off healthcheck %s failed: %s", healthcheck.Base().Name, err.Error())
 		c.Logger.Error(msg)
@@ -71,6 +260,140 @@ func (c *Component) oneOff(ec echo.Context, healthcheck healthcheck.Healthcheck)
 	return ec.JSON(http.StatusCreated, newResponse(msg))
 }
 
+// setEnabled enables or disables an existing healthcheck
+func (c *Component) setEnabled(ec echo.Context, enabled bool) error {
+	name := ec.Param("name")
+	err := c.healthcheck.SetEnabled(name, enabled)
+	if err != nil {
+		return corbierror.New(fmt.Sprintf("Healthcheck %s not found", name), corbierror.NotFound, true)
+	}
+	state := "disabled"
+	if enabled {
+		state = "enabled"
+	}
+	return ec.JSON(http.StatusOK, newResponse(fmt.Sprintf("Healthcheck %s %s", name, state)))
+}
+
+// execute executes an existing healthcheck once and returns its result. The
+// result is not exported and the healthcheck metrics are not updated.
+func (c *Component) execute(ec echo.Context) error {
+	name := ec.Param("name")
+	check := c.healthcheck.GetCheck(name)
+	if check == nil {
+		return corbierror.New(fmt.Sprintf("Healthcheck %s not found", name), corbierror.NotFound, true)
+	}
+	c.Logger.Info(fmt.Sprintf("Executing healthcheck %s on demand", name))
+	timeout := time.Duration(c.Config.OneOffTimeout)
+	if timeout == 0 {
+		timeout = time.Duration(DefaultOneOffTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ec.Request().Context(), timeout)
+	defer cancel()
+	release, err := c.healthcheck.Acquire(ctx)
+	if err != nil {
+		msg := fmt.Sprintf("Execution of healthcheck %s exceeded the maximum execution time of %s while waiting for the concurrent executions limit", name, timeout.String())
+		c.Logger.Error(msg)
+		return ec.JSON(http.StatusGatewayTimeout, newResponse(msg))
+	}
+	ctx, metrics := healthcheck.WithExecutionMetrics(ctx)
+	type execution struct {
+		annotations healthcheck.Annotations
+		err         error
+	}
+	executionChan := make(chan execution, 1)
+	start := time.Now()
+	go func() {
+		defer release()
+		annotations, err := check.Execute(ctx)
+		executionChan <- execution{annotations: annotations, err: err}
+	}()
+	select {
+	case result := <-executionChan:
+		duration := time.Since(start)
+		checkResult := healthcheck.NewResult(check, duration.Milliseconds(), result.annotations, result.err)
+		checkResult.LimitAnnotations(c.healthcheck.AnnotationsLimits())
+		checkResult.Metrics = metrics()
+		return ec.JSON(http.StatusOK, checkResult)
+	case <-ctx.Done():
+		msg := fmt.Sprintf("Execution of healthcheck %s exceeded the maximum execution time of %s", name, timeout.String())
+		c.Logger.Error(msg)
+		return ec.JSON(http.StatusGatewayTimeout, newResponse(msg))
+	}
+}
+
+// cloneCheck creates a new healthcheck from a copy of the configuration of
+// an existing one. The overrides are applied on the copied configuration.
+func (c *Component) cloneCheck(check healthcheck.Healthcheck, payload ClonePayload) (healthcheck.Healthcheck, error) {
+	switch config := check.GetConfig().(type) {
+	case *healthcheck.DNSHealthcheckConfiguration:
+		newConfig := config.DeepCopy()
+		if err := cloneConfig(&newConfig.Base, newConfig, payload); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewDNSHealthcheck(c.Logger, newConfig), nil
+	case *healthcheck.TCPHealthcheckConfiguration:
+		newConfig := config.DeepCopy()
+		if err := cloneConfig(&newConfig.Base, newConfig, payload); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewTCPHealthcheck(c.Logger, newConfig), nil
+	case *healthcheck.TLSHealthcheckConfiguration:
+		newConfig := config.DeepCopy()
+		if err := cloneConfig(&newConfig.Base, newConfig, payload); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewTLSHealthcheck(c.Logger, newConfig), nil
+	case *healthcheck.HTTPHealthcheckConfiguration:
+		newConfig := config.DeepCopy()
+		if err := cloneConfig(&newConfig.Base, newConfig, payload); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewHTTPHealthcheck(c.Logger, newConfig), nil
+	case *healthcheck.CommandHealthcheckConfiguration:
+		newConfig := config.DeepCopy()
+		if err := cloneConfig(&newConfig.Base, newConfig, payload); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewCommandHealthcheck(c.Logger, newConfig), nil
+	case *healthcheck.GRPCHealthcheckConfiguration:
+		newConfig := config.DeepCopy()
+		if err := cloneConfig(&newConfig.Base, newConfig, payload); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewGRPCHealthcheck(c.Logger, newConfig), nil
+	case *healthcheck.PostgresHealthcheckConfiguration:
+		newConfig := config.DeepCopy()
+		if err := cloneConfig(&newConfig.Base, newConfig, payload); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewPostgresHealthcheck(c.Logger, newConfig), nil
+	case *healthcheck.UDPHealthcheckConfiguration:
+		newConfig := config.DeepCopy()
+		if err := cloneConfig(&newConfig.Base, newConfig, payload); err != nil {
+			return nil, err
+		}
+		return healthcheck.NewUDPHealthcheck(c.Logger, newConfig), nil
+	}
+	return nil, fmt.Errorf("Unsupported healthcheck type for %s", check.Base().Name)
+}
+
+// validable a healthcheck configuration which can be validated
+type validable interface {
+	Validate() error
+}
+
+// cloneConfig applies the clone payload on a copied configuration and
+// validates the result
+func cloneConfig(base *healthcheck.Base, config validable, payload ClonePayload) error {
+	if len(payload.Overrides) != 0 {
+		if err := json.Unmarshal(payload.Overrides, config); err != nil {
+			return fmt.Errorf("Invalid overrides: %s", err.Error())
+		}
+	}
+	base.Name = payload.Name
+	return config.Validate()
+}
+
 func (c *Component) addCheckError(ec echo.Context, healthcheck healthcheck.Healthcheck, err error) error {
 	msg := fmt.Sprintf("Fail to start the healthcheck %s: %s", healthcheck.Base().Name, err.Error())
 	return corbierror.New(msg, corbierror.Internal, true)
@@ -81,13 +404,31 @@ func (c *Component) handleCheck(ec echo.Context, healthcheck healthcheck.Healthc
 	if healthcheck.Base().OneOff {
 		return c.oneOff(ec, healthcheck)
 	}
-	err := c.addCheck(ec, healthcheck)
+	err := c.healthcheck.ValidateExporters(healthcheck.Base())
+	if err != nil {
+		msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+		return corbierror.New(msg, corbierror.BadRequest, true)
+	}
+	err = c.addCheck(ec, healthcheck)
 	if err != nil {
 		return c.addCheckError(ec, healthcheck, err)
 	}
 	return ec.JSON(http.StatusCreated, newResponse("Healthcheck successfully added"))
 }
 
+// frontend returns the files served by the frontend and the page served as
+// index. In the SPA mode, the page fetches the results from the API instead
+// of being rendered by the server.
+func (c *Component) frontend(assets fs.FS) (fs.FS, string) {
+	if c.Config.FrontendMode != FrontendSPA {
+		return assets, "index.html"
+	}
+	if c.Config.FrontendDirectory != "" {
+		return os.DirFS(c.Config.FrontendDirectory), "index.html"
+	}
+	return assets, "spa.html"
+}
+
 // handlers configures the handlers for the http server component
 func (c *Component) handlers() {
 	c.Server.HTTPErrorHandler = errorHandler(c.Logger)
@@ -102,7 +443,7 @@ func (c *Component) handlers() {
 				return true, nil
 			}
 			c.Logger.Error("Invalid Basic Auth credentials")
-			return true, nil
+			return false, nil
 		}))
 	}
 	echo.NotFoundHandler = func(ec echo.Context) error {
@@ -186,6 +527,51 @@ func (c *Component) handlers() {
 			return c.handleCheck(ec, healthcheck)
 		})
 
+		apiGroup.POST("/healthcheck/grpc", func(ec echo.Context) error {
+			var config healthcheck.GRPCHealthcheckConfiguration
+			if err := ec.Bind(&config); err != nil {
+				msg := fmt.Sprintf("Fail to create the gRPC healthcheck. Invalid JSON: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			err := config.Validate()
+			if err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			healthcheck := healthcheck.NewGRPCHealthcheck(c.Logger, &config)
+			return c.handleCheck(ec, healthcheck)
+		})
+
+		apiGroup.POST("/healthcheck/postgres", func(ec echo.Context) error {
+			var config healthcheck.PostgresHealthcheckConfiguration
+			if err := ec.Bind(&config); err != nil {
+				msg := fmt.Sprintf("Fail to create the PostgreSQL healthcheck. Invalid JSON: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			err := config.Validate()
+			if err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			healthcheck := healthcheck.NewPostgresHealthcheck(c.Logger, &config)
+			return c.handleCheck(ec, healthcheck)
+		})
+
+		apiGroup.POST("/healthcheck/udp", func(ec echo.Context) error {
+			var config healthcheck.UDPHealthcheckConfiguration
+			if err := ec.Bind(&config); err != nil {
+				msg := fmt.Sprintf("Fail to create the UDP healthcheck. Invalid JSON: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			err := config.Validate()
+			if err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			healthcheck := healthcheck.NewUDPHealthcheck(c.Logger, &config)
+			return c.handleCheck(ec, healthcheck)
+		})
+
 		apiGroup.POST("/healthcheck/bulk", func(ec echo.Context) error {
 			bulkLock.Lock()
 			defer bulkLock.Unlock()
@@ -201,50 +587,39 @@ func (c *Component) handlers() {
 				msg := fmt.Sprintf("Fail to validate healthchecks configuration: %s", err.Error())
 				return corbierror.New(msg, corbierror.BadRequest, true)
 			}
-			for i := range payload.HTTPChecks {
-				config := payload.HTTPChecks[i]
-				healthcheck := healthcheck.NewHTTPHealthcheck(c.Logger, &config)
-				err := c.addCheck(ec, healthcheck)
-				if err != nil {
-					return c.addCheckError(ec, healthcheck, err)
-				}
-				newChecks[config.Base.Name] = true
-			}
-			for i := range payload.TCPChecks {
-				config := payload.TCPChecks[i]
-				healthcheck := healthcheck.NewTCPHealthcheck(c.Logger, &config)
-				err := c.addCheck(ec, healthcheck)
-				if err != nil {
-					return c.addCheckError(ec, healthcheck, err)
-				}
-				newChecks[config.Base.Name] = true
+			checks, err := c.healthcheck.BuildChecks(
+				healthcheck.SourceAPI,
+				nil,
+				payload.CommandChecks,
+				payload.DNSChecks,
+				payload.TCPChecks,
+				payload.HTTPChecks,
+				payload.TLSChecks,
+				payload.GRPCChecks,
+				payload.PostgresChecks,
+				payload.UDPChecks)
+			if err != nil {
+				msg := fmt.Sprintf("Fail to validate healthchecks configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
 			}
-			for i := range payload.DNSChecks {
-				config := payload.DNSChecks[i]
-				healthcheck := healthcheck.NewDNSHealthcheck(c.Logger, &config)
-				err := c.addCheck(ec, healthcheck)
-				if err != nil {
-					return c.addCheckError(ec, healthcheck, err)
+			for _, check := range checks {
+				// the checks are added in parallel, the last one would
+				// not always win
+				if newChecks[check.Base().Name] {
+					msg := fmt.Sprintf("Fail to validate healthchecks configuration: the healthcheck %s is defined several times", check.Base().Name)
+					return corbierror.New(msg, corbierror.BadRequest, true)
 				}
-				newChecks[config.Base.Name] = true
-			}
-			for i := range payload.TLSChecks {
-				config := payload.TLSChecks[i]
-				healthcheck := healthcheck.NewTLSHealthcheck(c.Logger, &config)
-				err := c.addCheck(ec, healthcheck)
+				newChecks[check.Base().Name] = true
+				err := c.healthcheck.ValidateExporters(check.Base())
 				if err != nil {
-					return c.addCheckError(ec, healthcheck, err)
+					msg := fmt.Sprintf("Fail to validate healthchecks configuration: %s", err.Error())
+					return corbierror.New(msg, corbierror.BadRequest, true)
 				}
-				newChecks[config.Base.Name] = true
 			}
-			for i := range payload.CommandChecks {
-				config := payload.CommandChecks[i]
-				healthcheck := healthcheck.NewCommandHealthcheck(c.Logger, &config)
-				err := c.addCheck(ec, healthcheck)
-				if err != nil {
-					return c.addCheckError(ec, healthcheck, err)
-				}
-				newChecks[config.Base.Name] = true
+			errorMessages := c.addChecks(ec, checks)
+			if len(errorMessages) != 0 {
+				msg := fmt.Sprintf("Fail to add healthchecks: %s", strings.Join(errorMessages, ", "))
+				return corbierror.New(msg, corbierror.Internal, true)
 			}
 			err = c.healthcheck.RemoveNonConfiguredHealthchecks(oldChecks, newChecks)
 			if err != nil {
@@ -253,9 +628,45 @@ func (c *Component) handlers() {
 			return ec.JSON(http.StatusCreated, newResponse("Healthchecks successfully added"))
 		})
 
+		apiGroup.POST("/healthcheck/preview", func(ec echo.Context) error {
+			var payload PreviewPayload
+			if err := ec.Bind(&payload); err != nil {
+				msg := fmt.Sprintf("Fail to preview healthchecks. Invalid JSON: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			checks, err := c.healthcheck.BuildChecks(
+				healthcheck.SourceAPI,
+				payload.Labels,
+				payload.CommandChecks,
+				payload.DNSChecks,
+				payload.TCPChecks,
+				payload.HTTPChecks,
+				payload.TLSChecks,
+				payload.GRPCChecks,
+				payload.PostgresChecks,
+				payload.UDPChecks)
+			if err != nil {
+				msg := fmt.Sprintf("Fail to validate healthchecks configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			return ec.JSON(http.StatusOK, ListHealthchecksOutput{
+				Result: c.newHealthcheckOutputs(checks),
+			})
+		})
+
 		apiGroup.GET("/healthcheck", func(ec echo.Context) error {
+			labels, err := parseLabelsQuery(ec)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.BadRequest, true)
+			}
+			checks := c.healthcheck.ListChecksFiltered(ec.QueryParam("source"), labels)
 			return ec.JSON(http.StatusOK, ListHealthchecksOutput{
-				Result: c.healthcheck.ListChecks(),
+				Result: c.newHealthcheckOutputs(checks),
+			})
+		})
+		apiGroup.GET("/sources", func(ec echo.Context) error {
+			return ec.JSON(http.StatusOK, ListSourcesOutput{
+				Result: c.healthcheck.Sources(),
 			})
 		})
 		apiGroup.GET("/healthcheck/:name", func(ec echo.Context) error {
@@ -264,9 +675,104 @@ func (c *Component) handlers() {
 			if healthcheck == nil {
 				return corbierror.New("Healthcheck not found", corbierror.NotFound, true)
 			}
-			return ec.JSON(http.StatusOK, healthcheck)
+			return ec.JSON(http.StatusOK, c.newHealthcheckOutput(healthcheck))
+		})
+
+		apiGroup.GET("/healthcheck/:name/logs", func(ec echo.Context) error {
+			name := ec.Param("name")
+			events, err := c.healthcheck.GetExecutionEvents(name)
+			if err != nil {
+				return corbierror.New("Healthcheck not found", corbierror.NotFound, true)
+			}
+			return ec.JSON(http.StatusOK, ListExecutionEventsOutput{
+				Result: events,
+			})
+		})
+
+		apiGroup.POST("/healthcheck/:name/clone", func(ec echo.Context) error {
+			name := ec.Param("name")
+			var payload ClonePayload
+			if err := ec.Bind(&payload); err != nil {
+				msg := fmt.Sprintf("Fail to clone the healthcheck. Invalid JSON: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			if payload.Name == "" {
+				return corbierror.New("The name of the new healthcheck is missing", corbierror.BadRequest, true)
+			}
+			check := c.healthcheck.GetCheck(name)
+			if check == nil {
+				return corbierror.New("Healthcheck not found", corbierror.NotFound, true)
+			}
+			if c.healthcheck.GetCheck(payload.Name) != nil {
+				msg := fmt.Sprintf("The healthcheck %s already exists", payload.Name)
+				return corbierror.New(msg, corbierror.Conflict, true)
+			}
+			newCheck, err := c.cloneCheck(check, payload)
+			if err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			c.Logger.Info(fmt.Sprintf("Cloning healthcheck %s to %s", name, payload.Name))
+			return c.handleCheck(ec, newCheck)
+		})
+
+		apiGroup.PUT("/healthcheck/:name/interval", func(ec echo.Context) error {
+			name := ec.Param("name")
+			var payload IntervalPayload
+			if err := ec.Bind(&payload); err != nil {
+				msg := fmt.Sprintf("Fail to override the healthcheck interval. Invalid JSON: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			if c.healthcheck.GetCheck(name) == nil {
+				return corbierror.New("Healthcheck not found", corbierror.NotFound, true)
+			}
+			err := c.healthcheck.SetInterval(name, payload.Interval)
+			if err != nil {
+				msg := fmt.Sprintf("Fail to override the healthcheck interval: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			return ec.JSON(http.StatusOK, IntervalOutput{
+				Interval: time.Duration(payload.Interval).String(),
+			})
+		})
+
+		apiGroup.POST("/healthcheck/:name/enable", func(ec echo.Context) error {
+			return c.setEnabled(ec, true)
+		})
+
+		apiGroup.POST("/healthcheck/:name/disable", func(ec echo.Context) error {
+			return c.setEnabled(ec, false)
+		})
+
+		apiGroup.POST("/maintenance/on", func(ec echo.Context) error {
+			c.healthcheck.SetMaintenance(true)
+			return ec.JSON(http.StatusOK, newResponse("Global maintenance enabled"))
+		})
+
+		apiGroup.POST("/maintenance/off", func(ec echo.Context) error {
+			c.healthcheck.SetMaintenance(false)
+			return ec.JSON(http.StatusOK, newResponse("Global maintenance disabled"))
+		})
+
+		apiGroup.DELETE("/healthcheck/source/:source", func(ec echo.Context) error {
+			source := ec.Param("source")
+			c.Logger.Info(fmt.Sprintf("Deleting healthchecks from source %s", source))
+			removed := 0
+			for name := range c.healthcheck.SourceChecksNames(source) {
+				err := c.healthcheck.RemoveCheck(name)
+				if err != nil {
+					msg := fmt.Sprintf("Fail to remove the healthcheck %s: %s", name, err.Error())
+					return corbierror.New(msg, corbierror.Internal, true)
+				}
+				removed++
+			}
+			return ec.JSON(http.StatusOK, RemoveSourceOutput{
+				Removed: removed,
+			})
 		})
 
+		apiGroup.POST("/healthcheck/:name/execute", c.execute)
+
 		apiGroup.DELETE("/healthcheck/:name", func(ec echo.Context) error {
 			name := ec.Param("name")
 			c.Logger.Info(fmt.Sprintf("Deleting healthcheck %s", name))
@@ -280,9 +786,31 @@ func (c *Component) handlers() {
 	}
 
 	if !c.Config.DisableResultAPI {
-		apiGroup.GET("/result", func(ec echo.Context) error {
-			return ec.JSON(http.StatusOK, ListResultsOutput{
-				Result: c.MemoryStore.List(),
+		apiGroup.GET("/result", c.listResults)
+		apiGroup.GET("/result/archive", c.archive)
+		apiGroup.POST("/result/query", c.queryResults)
+		apiGroup.GET("/stats", func(ec echo.Context) error {
+			return ec.JSON(http.StatusOK, StatsOutput{
+				Stats:       c.stats(),
+				Maintenance: c.healthcheck.Maintenance(),
+			})
+		})
+		apiGroup.GET("/status", func(ec echo.Context) error {
+			return ec.JSON(http.StatusOK, c.status())
+		})
+		apiGroup.GET("/result/:name/history", func(ec echo.Context) error {
+			name := ec.Param("name")
+			results, err := c.MemoryStore.GetHistory(ec.Request().Context(), name)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.NotFound, true)
+			}
+			tiers, err := c.MemoryStore.GetTiers(name)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.NotFound, true)
+			}
+			return ec.JSON(http.StatusOK, ListHistoryOutput{
+				Result: results,
+				Tiers:  tiers,
 			})
 		})
 		apiGroup.GET("/result/:name", func(ec echo.Context) error {
@@ -298,29 +826,22 @@ func (c *Component) handlers() {
 			err := ec.Redirect(http.StatusFound, "/frontend/index.html")
 			return err
 		})
+		frontendFS, index := c.frontend(fsys)
 		c.Server.GET("/frontend/*", func(ec echo.Context) error {
 			path := strings.TrimPrefix(ec.Request().URL.Path, "/frontend/")
 
-			if path == "" {
-				path = "index.html"
+			if path == "" || path == "index.html" {
+				path = index
 			}
 
-			c.Logger.Info(path)
-			file, err := fsys.Open(path)
-			if err != nil {
-				return corbierror.Wrap(err, "Internal error", corbierror.Internal, true)
+			buffer, err := fs.ReadFile(frontendFS, path)
+			if errors.Is(err, fs.ErrNotExist) {
+				return corbierror.New(fmt.Sprintf("File %s not found", path), corbierror.NotFound, true)
 			}
-			stat, err := file.Stat()
 			if err != nil {
 				return corbierror.Wrap(err, "Internal error", corbierror.Internal, true)
 			}
-			size := stat.Size()
-			buffer := make([]byte, size)
-			_, err = file.Read(buffer)
-			if err != nil {
-				return corbierror.Wrap(err, "Internal error", corbierror.Internal, true)
-			}
-			if path == "index.html" {
+			if c.Config.FrontendMode != FrontendSPA && path == "index.html" {
 				tmpl := template.New("frontend")
 				tmpl.Funcs(template.FuncMap{
 					"last": func(x int, a interface{}) bool {
@@ -344,12 +865,20 @@ func (c *Component) handlers() {
 				return ec.HTML(http.StatusOK, tmplBytes.String())
 
 			} else {
-				return ec.HTML(http.StatusOK, string(buffer))
+				contentType := mime.TypeByExtension(filepath.Ext(path))
+				if contentType == "" {
+					contentType = echo.MIMETextHTMLCharsetUTF8
+				}
+				return ec.Blob(http.StatusOK, contentType, buffer)
 			}
 
 		})
 	}
 
+	if !c.Config.DisableConfigAPI {
+		apiGroup.GET("/config", c.runningConfig)
+	}
+
 	c.Server.GET("/health", func(ec echo.Context) error {
 		return ec.JSON(http.StatusOK, "ok")
 	})
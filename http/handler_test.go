@@ -3,13 +3,17 @@ package http
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -97,6 +101,34 @@ func TestHandlers(t *testing.T) {
 	if !strings.Contains(body, `"name":"baz"`) {
 		t.Fatalf("Invalid body\n")
 	}
+	// filter the healthchecks
+	filters := []struct {
+		query  string
+		status int
+		name   bool
+	}{
+		{query: "source=api", status: http.StatusOK, name: true},
+		{query: "source=configuration", status: http.StatusOK, name: false},
+		{query: "label=env:prod", status: http.StatusOK, name: false},
+		{query: "label=invalid", status: http.StatusBadRequest, name: false},
+	}
+	for _, f := range filters {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:2001/api/v1/healthcheck?%s", f.query))
+		if err != nil {
+			t.Fatalf("Fail to get the healthchecks\n%v", err)
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Fail to read the body\n%v", err)
+		}
+		if resp.StatusCode != f.status {
+			t.Fatalf("Invalid status %d for %s", resp.StatusCode, f.query)
+		}
+		if strings.Contains(string(bodyBytes), `"name":"foo"`) != f.name {
+			t.Fatalf("Invalid body for %s\n%s", f.query, string(bodyBytes))
+		}
+	}
 	// get one healthcheck
 	resp, err = http.Get("http://127.0.0.1:2001/api/v1/healthcheck/foo")
 	if err != nil {
@@ -226,6 +258,121 @@ func TestOneOffCheck(t *testing.T) {
 	}
 }
 
+func TestOneOffCheckForwardedHeaders(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	config := &Configuration{
+		Host:             "127.0.0.1",
+		Port:             2001,
+		ForwardedHeaders: []string{"X-Correlation-Id", "X-Tenant"},
+	}
+	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger), prom, config, checkComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Correlation-Id") != "abc" || r.Header.Get("X-Secret") != "" ||
+			r.Header.Get("X-Tenant") != "configured" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	client := &http.Client{}
+	reqBody := fmt.Sprintf(`{"name":"baz","description":"bar","interval":"10m","one-off":true,"target":"127.0.0.1","port":%d,"timeout":"10s","protocol":"http","valid-status":[200],"headers":{"x-tenant":"configured"}}`, port)
+	req, err := http.NewRequest("POST", "http://127.0.0.1:2001/api/v1/healthcheck/http", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		t.Fatalf("Fail to build the HTTP request\n%v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Correlation-Id", "abc")
+	req.Header.Set("X-Tenant", "forwarded")
+	req.Header.Set("X-Secret", "secret")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("HTTP request failed, status %d", resp.StatusCode)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestOneOffCheckTimeout(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	config := &Configuration{
+		Host:          "127.0.0.1",
+		Port:          2001,
+		OneOffTimeout: healthcheck.Duration(200 * time.Millisecond),
+	}
+	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger), prom, config, checkComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(2 * time.Second)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	client := &http.Client{}
+	reqBody := fmt.Sprintf(`{"name":"baz","description":"bar","interval":"10m","one-off":true,"target":"127.0.0.1","port":%d,"timeout":"10s","protocol":"http","valid-status":[200]}`, port)
+	req, err := http.NewRequest("POST", "http://127.0.0.1:2001/api/v1/healthcheck/http", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		t.Fatalf("Fail to build the HTTP request\n%v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("Expected a gateway timeout, got status %d", resp.StatusCode)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
 func TestBulkEndpoint(t *testing.T) {
 	logger := zap.NewExample()
 	prom, err := prometheus.New()
@@ -246,7 +393,7 @@ func TestBulkEndpoint(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	reqBody := `{"http-checks": [{"name":"baz","description":"bar","interval":"10m","target":"127.0.0.1","port":3000,"timeout":"10s","protocol":"http","valid-status":[200],"body-regexp":["test*"]}]}`
+	reqBody := `{"http-checks": [{"name":"baz","description":"bar","interval":"10m","target":"127.0.0.1","port":3000,"timeout":"10s","protocol":"http","valid-status":[200],"body-regexp":["test*"]}], "tcp-checks": [{"name":"tcp1","interval":"10m","target":"127.0.0.1","port":3000,"timeout":"10s"},{"name":"tcp2","interval":"10m","target":"127.0.0.1","port":3001,"timeout":"10s"}]}`
 	req, err := http.NewRequest("POST", "http://127.0.0.1:2001/api/v1/healthcheck/bulk", bytes.NewBuffer([]byte(reqBody)))
 	req.Header.Set("Content-Type", "application/json")
 	if err != nil {
@@ -268,7 +415,7 @@ func TestBulkEndpoint(t *testing.T) {
 	if !strings.Contains(body, "Healthchecks successfully added") {
 		t.Fatalf("Invalid body %s", body)
 	}
-	if len(checkComponent.Healthchecks) != 1 {
+	if len(checkComponent.Healthchecks) != 3 {
 		t.Fatalf("Healthchecks were not successfully created: %d", len(checkComponent.Healthchecks))
 	}
 	check := checkComponent.GetCheck("baz")
@@ -280,6 +427,172 @@ func TestBulkEndpoint(t *testing.T) {
 	if len(httpConfig.BodyRegexp) != 1 {
 		t.Fatalf("Invalid regexp configuration")
 	}
+	cases := []struct {
+		body     string
+		status   int
+		messages []string
+	}{
+		{
+			body:     `{"tcp-checks": [{"name":"tcp1","interval":"10m","target":"127.0.0.1","port":3000,"timeout":"10s"},{"name":"tcp1","interval":"10m","target":"127.0.0.1","port":3001,"timeout":"10s"}]}`,
+			status:   http.StatusBadRequest,
+			messages: []string{"the healthcheck tcp1 is defined several times"},
+		},
+		{
+			body:   `{"tcp-checks": [{"name":"tcp1","interval":"10m","target":"127.0.0.1","port":3000,"timeout":"10s"}], "tls-checks": [{"name":"tls1","interval":"10m","target":"127.0.0.1","port":3000,"timeout":"10s","cacert":"/does/not/exist"},{"name":"tls2","interval":"10m","target":"127.0.0.1","port":3000,"timeout":"10s","cacert":"/does/not/exist"}]}`,
+			status: http.StatusInternalServerError,
+			messages: []string{
+				"healthcheck tls1: Fail to initialize healthcheck tls1",
+				"healthcheck tls2: Fail to initialize healthcheck tls2",
+			},
+		},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest("POST", "http://127.0.0.1:2001/api/v1/healthcheck/bulk", bytes.NewBuffer([]byte(c.body)))
+		if err != nil {
+			t.Fatalf("Fail to build the HTTP request\n%v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Fail to read the body\n%v", err)
+		}
+		if resp.StatusCode != c.status {
+			t.Fatalf("Invalid status %d for %s: %s", resp.StatusCode, c.body, string(bodyBytes))
+		}
+		for _, message := range c.messages {
+			if !strings.Contains(string(bodyBytes), message) {
+				t.Fatalf("Invalid body %s, expected %s", string(bodyBytes), message)
+			}
+		}
+	}
+	if checkComponent.GetCheck("tls1") != nil || checkComponent.GetCheck("tcp1") == nil {
+		t.Fatalf("Invalid healthchecks after the failed requests")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestCloneEndpoint(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	checkComponent.ExporterNames = []string{"kafka"}
+	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger), prom, &Configuration{Host: "127.0.0.1", Port: 2001}, checkComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	err = checkComponent.AddCheck(healthcheck.NewTCPHealthcheck(
+		logger,
+		&healthcheck.TCPHealthcheckConfiguration{
+			Base: healthcheck.Base{
+				Name:     "foo",
+				Interval: healthcheck.Duration(time.Minute * 10),
+				Labels:   map[string]string{"env": "prod"},
+			},
+			Target:  "127.0.0.1",
+			Port:    3000,
+			Timeout: healthcheck.Duration(time.Second * 3),
+		},
+	))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	client := &http.Client{}
+	cases := []struct {
+		path   string
+		body   string
+		status int
+	}{
+		{path: "foo", body: `{"name":"bar","overrides":{"port":3001}}`, status: http.StatusCreated},
+		{path: "notfound", body: `{"name":"baz"}`, status: http.StatusNotFound},
+		{path: "foo", body: `{}`, status: http.StatusBadRequest},
+		{path: "foo", body: `{"name":"baz","overrides":{"timeout":"0s"}}`, status: http.StatusBadRequest},
+		{path: "foo", body: `{"name":"baz","overrides":{"exporters":["unknown"]}}`, status: http.StatusBadRequest},
+		{path: "foo", body: `{"name":"bar","overrides":{"port":3002}}`, status: http.StatusConflict},
+		{path: "foo", body: `{"name":"foo"}`, status: http.StatusConflict},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest("POST", fmt.Sprintf("http://127.0.0.1:2001/api/v1/healthcheck/%s/clone", c.path), bytes.NewBuffer([]byte(c.body)))
+		if err != nil {
+			t.Fatalf("Fail to build the HTTP request\n%v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Fatalf("Invalid status for %s, expected %d, got %d", c.body, c.status, resp.StatusCode)
+		}
+	}
+	if len(checkComponent.Healthchecks) != 2 {
+		t.Fatalf("The healthcheck was not cloned: %d", len(checkComponent.Healthchecks))
+	}
+	config := checkComponent.GetCheck("bar").GetConfig().(*healthcheck.TCPHealthcheckConfiguration)
+	if config.Port != 3001 || config.Target != "127.0.0.1" || config.Labels["env"] != "prod" {
+		t.Fatalf("Invalid cloned configuration %v", config)
+	}
+	original := checkComponent.GetCheck("foo").GetConfig().(*healthcheck.TCPHealthcheckConfiguration)
+	if original.Port != 3000 {
+		t.Fatalf("The original healthcheck was modified")
+	}
+	err = checkComponent.AddCheck(healthcheck.NewHTTPHealthcheck(
+		logger,
+		&healthcheck.HTTPHealthcheckConfiguration{
+			Base: healthcheck.Base{
+				Name:     "web",
+				Interval: healthcheck.Duration(time.Minute * 10),
+			},
+			ValidStatus: []uint{200},
+			Target:      "127.0.0.1",
+			Port:        3000,
+			Protocol:    healthcheck.HTTP,
+			Query:       map[string]string{"a": "1"},
+			Timeout:     healthcheck.Duration(time.Second * 3),
+		},
+	))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	req, err := http.NewRequest("POST", "http://127.0.0.1:2001/api/v1/healthcheck/web/clone", bytes.NewBuffer([]byte(`{"name":"web2","overrides":{"query":{"b":"2"}}}`)))
+	if err != nil {
+		t.Fatalf("Fail to build the HTTP request\n%v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Invalid status %d", resp.StatusCode)
+	}
+	cloned := checkComponent.GetCheck("web2").GetConfig().(*healthcheck.HTTPHealthcheckConfiguration)
+	if cloned.Query["a"] != "1" || cloned.Query["b"] != "2" {
+		t.Fatalf("Invalid cloned query %v", cloned.Query)
+	}
+	source := checkComponent.GetCheck("web").GetConfig().(*healthcheck.HTTPHealthcheckConfiguration)
+	if len(source.Query) != 1 || source.Query["a"] != "1" {
+		t.Fatalf("The source healthcheck query was modified: %v", source.Query)
+	}
 	err = component.Stop()
 	if err != nil {
 		t.Fatalf("Fail to stop the component\n%v", err)
@@ -324,6 +637,7 @@ func TestBasicAuth(t *testing.T) {
 	if err != nil {
 		t.Fatalf("HTTP request failed\n%v", err)
 	}
+	resp.Body.Close()
 	if resp.StatusCode != 401 {
 		t.Fatalf("Expected 401, got status %d", resp.StatusCode)
 	}
@@ -337,7 +651,682 @@ func TestBasicAuth(t *testing.T) {
 	if err != nil {
 		t.Fatalf("HTTP request failed\n%v", err)
 	}
+	resp.Body.Close()
 	if resp.StatusCode != 200 {
 		t.Fatalf("Expected 200, got status %d", resp.StatusCode)
 	}
+	req, err = http.NewRequest("GET", "http://127.0.0.1:2001/api/v1/result", nil)
+	if err != nil {
+		t.Fatalf("Fail to build the request\n%v", err)
+	}
+	req.Header.Add("Authorization", "Basic "+basicAuth("foobar", "wrongpassword"))
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 401 {
+		t.Fatalf("Expected 401 with wrong credentials, got status %d", resp.StatusCode)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestIntervalEndpoint(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger), prom, &Configuration{Host: "127.0.0.1", Port: 2001}, checkComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	err = checkComponent.AddCheck(healthcheck.NewTCPHealthcheck(
+		logger,
+		&healthcheck.TCPHealthcheckConfiguration{
+			Base: healthcheck.Base{
+				Name:     "foo",
+				Interval: healthcheck.Duration(time.Minute * 10),
+			},
+			Target:  "127.0.0.1",
+			Port:    3000,
+			Timeout: healthcheck.Duration(time.Second * 3),
+		},
+	))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	client := &http.Client{}
+	cases := []struct {
+		path   string
+		body   string
+		status int
+	}{
+		{path: "foo", body: `{"interval":"5s"}`, status: http.StatusOK},
+		{path: "foo", body: `{"interval":"1s"}`, status: http.StatusBadRequest},
+		{path: "notfound", body: `{"interval":"5s"}`, status: http.StatusNotFound},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest("PUT", fmt.Sprintf("http://127.0.0.1:2001/api/v1/healthcheck/%s/interval", c.path), bytes.NewBuffer([]byte(c.body)))
+		if err != nil {
+			t.Fatalf("Fail to build the HTTP request\n%v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		if c.status == http.StatusOK {
+			var output IntervalOutput
+			err = json.NewDecoder(resp.Body).Decode(&output)
+			if err != nil {
+				t.Fatalf("Fail to read the response\n%v", err)
+			}
+			if output.Interval != "5s" {
+				t.Fatalf("Invalid effective interval %s", output.Interval)
+			}
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Fatalf("Invalid status for %s, expected %d, got %d", c.body, c.status, resp.StatusCode)
+		}
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestEnableDisableHandler(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger), prom, &Configuration{Host: "127.0.0.1", Port: 2001}, checkComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	err = checkComponent.AddCheck(healthcheck.NewTCPHealthcheck(
+		logger,
+		&healthcheck.TCPHealthcheckConfiguration{
+			Base: healthcheck.Base{
+				Name:     "foo",
+				Interval: healthcheck.Duration(time.Minute * 10),
+			},
+			Target:  "127.0.0.1",
+			Port:    3000,
+			Timeout: healthcheck.Duration(time.Second * 3),
+		},
+	))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	client := &http.Client{}
+	cases := []struct {
+		path    string
+		action  string
+		status  int
+		enabled bool
+	}{
+		{path: "foo", action: "disable", status: http.StatusOK, enabled: false},
+		{path: "foo", action: "enable", status: http.StatusOK, enabled: true},
+		{path: "notfound", action: "disable", status: http.StatusNotFound},
+	}
+	for _, c := range cases {
+		resp, err := client.Post(fmt.Sprintf("http://127.0.0.1:2001/api/v1/healthcheck/%s/%s", c.path, c.action), "application/json", nil)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Fatalf("Invalid status for %s %s, expected %d, got %d", c.action, c.path, c.status, resp.StatusCode)
+		}
+		if c.status != http.StatusOK {
+			continue
+		}
+		resp, err = client.Get("http://127.0.0.1:2001/api/v1/healthcheck/foo")
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		var output map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&output)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Fail to read the response\n%v", err)
+		}
+		if output["enabled"] != c.enabled {
+			t.Fatalf("Invalid enabled state %v", output["enabled"])
+		}
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestMaintenanceHandler(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger), prom, &Configuration{Host: "127.0.0.1", Port: 2001}, checkComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	client := &http.Client{}
+	for _, state := range []string{"on", "off"} {
+		resp, err := client.Post(fmt.Sprintf("http://127.0.0.1:2001/api/v1/maintenance/%s", state), "application/json", nil)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Invalid status for maintenance %s: %d", state, resp.StatusCode)
+		}
+		if checkComponent.Maintenance() != (state == "on") {
+			t.Fatalf("Invalid maintenance state %t", checkComponent.Maintenance())
+		}
+		resp, err = client.Get("http://127.0.0.1:2001/api/v1/stats")
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		var output map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&output)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Fail to read the response\n%v", err)
+		}
+		if output["maintenance"] != (state == "on") {
+			t.Fatalf("Invalid maintenance state in the stats %v", output)
+		}
+		if _, ok := output["total"]; !ok {
+			t.Fatalf("The stats are missing %v", output)
+		}
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestFrontendSPA(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	directory := t.TempDir()
+	err = os.WriteFile(filepath.Join(directory, "index.html"), []byte("custom frontend"), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the frontend\n%v", err)
+	}
+	err = os.WriteFile(filepath.Join(directory, "app.js"), []byte("console.log(1)"), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the frontend\n%v", err)
+	}
+	cases := []struct {
+		config      Configuration
+		path        string
+		contains    string
+		contentType string
+		status      int
+	}{
+		{config: Configuration{FrontendMode: FrontendSPA}, path: "index.html", contains: "/api/v1/result", contentType: "text/html"},
+		{config: Configuration{FrontendMode: FrontendSPA}, path: "", contains: "/api/v1/result", contentType: "text/html"},
+		{config: Configuration{FrontendMode: FrontendSPA, FrontendDirectory: directory}, path: "index.html", contains: "custom frontend", contentType: "text/html"},
+		{config: Configuration{FrontendMode: FrontendSPA, FrontendDirectory: directory}, path: "app.js", contains: "console.log", contentType: "javascript"},
+		{config: Configuration{FrontendMode: FrontendSPA, FrontendDirectory: directory}, path: "missing.js", contains: "not found", contentType: "json", status: http.StatusNotFound},
+		{config: Configuration{}, path: "index.html", contains: "Healthchecks", contentType: "text/html"},
+	}
+	for _, c := range cases {
+		if c.status == 0 {
+			c.status = http.StatusOK
+		}
+		c.config.Host = "127.0.0.1"
+		c.config.Port = 2001
+		component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger), prom, &c.config, checkComponent)
+		if err != nil {
+			t.Fatalf("Fail to create the component\n%v", err)
+		}
+		err = component.Start()
+		if err != nil {
+			t.Fatalf("Fail to start the component\n%v", err)
+		}
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:2001/frontend/%s", c.path))
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Fail to read the response\n%v", err)
+		}
+		if resp.StatusCode != c.status {
+			t.Fatalf("Invalid status %d for %s", resp.StatusCode, c.path)
+		}
+		if !strings.Contains(string(body), c.contains) {
+			t.Fatalf("Invalid frontend for %s: %s", c.path, string(body))
+		}
+		if !strings.Contains(resp.Header.Get("Content-Type"), c.contentType) {
+			t.Fatalf("Invalid content type %s for %s", resp.Header.Get("Content-Type"), c.path)
+		}
+		err = component.Stop()
+		if err != nil {
+			t.Fatalf("Fail to stop the component\n%v", err)
+		}
+	}
+}
+
+func TestExecutionEventsEndpoint(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger), prom, &Configuration{Host: "127.0.0.1", Port: 2001}, checkComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	err = checkComponent.AddCheck(healthcheck.NewTCPHealthcheck(
+		logger,
+		&healthcheck.TCPHealthcheckConfiguration{
+			Base: healthcheck.Base{
+				Name:     "foo",
+				Interval: healthcheck.Duration(time.Minute * 10),
+			},
+			Target:  "127.0.0.1",
+			Port:    3000,
+			Timeout: healthcheck.Duration(time.Second * 3),
+		},
+	))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	cases := []struct {
+		path   string
+		status int
+	}{
+		{path: "foo", status: http.StatusOK},
+		{path: "notfound", status: http.StatusNotFound},
+	}
+	for _, c := range cases {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:2001/api/v1/healthcheck/%s/logs", c.path))
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Fatalf("Invalid status for %s, expected %d, got %d", c.path, c.status, resp.StatusCode)
+		}
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestRemoveSourceEndpoint(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger), prom, &Configuration{Host: "127.0.0.1", Port: 2001}, checkComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	for _, check := range []struct {
+		name   string
+		source string
+	}{
+		{name: "foo", source: "discovery"},
+		{name: "bar", source: "discovery"},
+		{name: "baz", source: healthcheck.SourceAPI},
+	} {
+		err = checkComponent.AddCheck(healthcheck.NewTCPHealthcheck(
+			logger,
+			&healthcheck.TCPHealthcheckConfiguration{
+				Base: healthcheck.Base{
+					Name:     check.name,
+					Source:   check.source,
+					Interval: healthcheck.Duration(time.Minute * 10),
+				},
+				Target:  "127.0.0.1",
+				Port:    3000,
+				Timeout: healthcheck.Duration(time.Second * 3),
+			},
+		))
+		if err != nil {
+			t.Fatalf("Fail to add the healthcheck\n%v", err)
+		}
+	}
+	req, err := http.NewRequest("DELETE", "http://127.0.0.1:2001/api/v1/healthcheck/source/discovery", nil)
+	if err != nil {
+		t.Fatalf("Fail to build the HTTP request\n%v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Invalid status %d", resp.StatusCode)
+	}
+	var output RemoveSourceOutput
+	err = json.NewDecoder(resp.Body).Decode(&output)
+	if err != nil {
+		t.Fatalf("Fail to read the response\n%v", err)
+	}
+	if output.Removed != 2 {
+		t.Fatalf("Invalid number of removed healthchecks %d", output.Removed)
+	}
+	checks := checkComponent.ListChecks()
+	if len(checks) != 1 || checks[0].Base().Name != "baz" {
+		t.Fatalf("Invalid healthchecks after the source removal")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestExecuteEndpoint(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger), prom, &Configuration{Host: "127.0.0.1", Port: 2001}, checkComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	err = checkComponent.AddCheck(healthcheck.NewTCPHealthcheck(
+		logger,
+		&healthcheck.TCPHealthcheckConfiguration{
+			Base: healthcheck.Base{
+				Name:     "foo",
+				Interval: healthcheck.Duration(time.Minute * 10),
+			},
+			Target:  "127.0.0.1",
+			Port:    2001,
+			Timeout: healthcheck.Duration(time.Second * 3),
+		},
+	))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	resp, err := http.Post("http://127.0.0.1:2001/api/v1/healthcheck/foo/execute", "application/json", nil)
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Invalid status %d", resp.StatusCode)
+	}
+	var result healthcheck.Result
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		t.Fatalf("Fail to decode the result\n%v", err)
+	}
+	if result.Name != "foo" || !result.Success || result.Type != healthcheck.TypeTCP {
+		t.Fatalf("Invalid result %v", result)
+	}
+	notFound, err := http.Post("http://127.0.0.1:2001/api/v1/healthcheck/notfound/execute", "application/json", nil)
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	notFound.Body.Close()
+	if notFound.StatusCode != http.StatusNotFound {
+		t.Fatalf("Invalid status %d", notFound.StatusCode)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestPreviewEndpoint(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger), prom, &Configuration{Host: "127.0.0.1", Port: 2001}, checkComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	reqBody := `{"labels": {"env": "prod"}, "tcp-checks": [{"name":"tcp1","interval":"10m","target":"127.0.0.1","port":3000,"timeout":"10s","labels":{"team":"infra"}}]}`
+	resp, err := http.Post("http://127.0.0.1:2001/api/v1/healthcheck/preview", "application/json", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("HTTP request failed, status %d", resp.StatusCode)
+	}
+	var result struct {
+		Result []healthcheck.TCPHealthcheckConfiguration `json:"result"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		t.Fatalf("Fail to decode the response\n%v", err)
+	}
+	if len(result.Result) != 1 {
+		t.Fatalf("Invalid preview %v", result)
+	}
+	base := result.Result[0].Base
+	if base.Name != "tcp1" || base.Labels["env"] != "prod" || base.Labels["team"] != "infra" || base.Source != healthcheck.SourceAPI {
+		t.Fatalf("Invalid preview %v", result)
+	}
+	if len(checkComponent.ListChecks()) != 0 {
+		t.Fatalf("The healthchecks should not be registered")
+	}
+	invalid, err := http.Post("http://127.0.0.1:2001/api/v1/healthcheck/preview", "application/json", bytes.NewBuffer([]byte(`{"tcp-checks": [{"name":"tcp1"}]}`)))
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	invalid.Body.Close()
+	if invalid.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Invalid status %d", invalid.StatusCode)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestHealthcheckNextRun(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger), prom, &Configuration{Host: "127.0.0.1", Port: 2001}, checkComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	check := healthcheck.NewTCPHealthcheck(logger, &healthcheck.TCPHealthcheckConfiguration{
+		Base: healthcheck.Base{
+			Name: "foo",
+			Cron: "@daily",
+		},
+		Target:  "127.0.0.1",
+		Port:    9000,
+		Timeout: healthcheck.Duration(time.Second * 2),
+	})
+	err = checkComponent.AddCheck(check)
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	resp, err := http.Get("http://127.0.0.1:2001/api/v1/healthcheck/foo")
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("HTTP request failed, status %d", resp.StatusCode)
+	}
+	var result map[string]interface{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		t.Fatalf("Fail to decode the response\n%v", err)
+	}
+	if result["name"] != "foo" || result["cron"] != "@daily" {
+		t.Fatalf("Invalid healthcheck %v", result)
+	}
+	nextRun, ok := result["next-run"].(string)
+	if !ok {
+		t.Fatalf("The next run is missing %v", result)
+	}
+	_, err = time.Parse(time.RFC3339, nextRun)
+	if err != nil {
+		t.Fatalf("Invalid next run %s\n%v", nextRun, err)
+	}
+	err = checkComponent.RemoveCheck("foo")
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestListEndpointRedactsSecrets(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(zap.NewExample(), memorystore.NewMemoryStore(logger), prom, &Configuration{Host: "127.0.0.1", Port: 2001}, checkComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	payloads := []string{
+		`{"name":"basic","interval":"10m","target":"127.0.0.1","port":9999,"timeout":"1s","protocol":"http","valid-status":[200],"basic-auth":{"username":"admin","password":"basicsecret"},"headers":{"Authorization":"headersecret"}}`,
+		`{"name":"bearer","interval":"10m","target":"127.0.0.1","port":9999,"timeout":"1s","protocol":"http","valid-status":[200],"bearer-token":"tokensecret"}`,
+	}
+	for _, payload := range payloads {
+		resp, err := http.Post("http://127.0.0.1:2001/api/v1/healthcheck/http", "application/json", bytes.NewBuffer([]byte(payload)))
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("HTTP request failed, status %d", resp.StatusCode)
+		}
+	}
+	resp, err := http.Get("http://127.0.0.1:2001/api/v1/healthcheck")
+	if err != nil {
+		t.Fatalf("Fail to get the healthchecks\n%v", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Fail to read the body\n%v", err)
+	}
+	body := string(bodyBytes)
+	for _, secret := range []string{"basicsecret", "tokensecret", "headersecret"} {
+		if strings.Contains(body, secret) {
+			t.Fatalf("The secret %s was not redacted\n%s", secret, body)
+		}
+	}
+	if !strings.Contains(body, `"username":"admin"`) || !strings.Contains(body, "redacted") {
+		t.Fatalf("Invalid body\n%s", body)
+	}
+	// the running healthcheck keeps its credentials
+	for _, c := range checkComponent.ListChecks() {
+		check := c.(*healthcheck.HTTPHealthcheck)
+		if check.Config.BasicAuth != nil && check.Config.BasicAuth.Password != "basicsecret" {
+			t.Fatalf("The healthcheck configuration was modified")
+		}
+		if check.Config.BasicAuth == nil && check.Config.BearerToken != "tokensecret" {
+			t.Fatalf("The healthcheck configuration was modified")
+		}
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
 }
@@ -0,0 +1,159 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/mcorbin/corbierror"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// resultsQuery the filters and pagination parameters of the results API
+type resultsQuery struct {
+	// limit the maximum number of results (0 for no limit)
+	limit  int
+	offset int
+	// status success, degraded or failure (all results if empty). Degraded
+	// results are also successful.
+	status string
+	labels map[string]string
+}
+
+// empty returns true if no parameter was set
+func (q resultsQuery) empty() bool {
+	return q.limit == 0 && q.offset == 0 && q.status == "" && len(q.labels) == 0
+}
+
+// parseResultsQuery parses the results API query parameters
+func parseResultsQuery(ec echo.Context) (resultsQuery, error) {
+	query := resultsQuery{}
+	var err error
+	if value := ec.QueryParam("limit"); value != "" {
+		query.limit, err = strconv.Atoi(value)
+		if err != nil || query.limit <= 0 {
+			return query, fmt.Errorf("Invalid limit parameter %s", value)
+		}
+	}
+	if value := ec.QueryParam("offset"); value != "" {
+		query.offset, err = strconv.Atoi(value)
+		if err != nil || query.offset < 0 {
+			return query, fmt.Errorf("Invalid offset parameter %s", value)
+		}
+	}
+	if value := ec.QueryParam("status"); value != "" {
+		if value != healthcheck.StatusSuccess && value != healthcheck.StatusDegraded && value != healthcheck.StatusFailure {
+			return query, fmt.Errorf("Invalid status parameter %s (should be success, degraded or failure)", value)
+		}
+		query.status = value
+	}
+	query.labels, err = parseLabelsQuery(ec)
+	if err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// parseLabelsQuery parses the label query parameters (format key:value)
+func parseLabelsQuery(ec echo.Context) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, label := range ec.QueryParams()["label"] {
+		parts := strings.SplitN(label, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid label parameter %s (format key:value)", label)
+		}
+		labels[parts[0]] = parts[1]
+	}
+	return labels, nil
+}
+
+// match returns true if the result matches the query filters
+func (q resultsQuery) match(result *healthcheck.Result) bool {
+	if q.status == "success" && !result.Success {
+		return false
+	}
+	if q.status == "failure" && result.Success {
+		return false
+	}
+	if q.status == "degraded" && !result.Degraded {
+		return false
+	}
+	for k, v := range q.labels {
+		if value, ok := result.Labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// filterResults filters the results and returns the requested page, and
+// the number of results matching the filters
+func filterResults(results []healthcheck.Result, query resultsQuery) ([]healthcheck.Result, int) {
+	filtered := []healthcheck.Result{}
+	for i := range results {
+		if query.match(&results[i]) {
+			filtered = append(filtered, results[i])
+		}
+	}
+	total := len(filtered)
+	if query.offset >= total {
+		return []healthcheck.Result{}, total
+	}
+	filtered = filtered[query.offset:]
+	if query.limit != 0 && query.limit < len(filtered) {
+		filtered = filtered[:query.limit]
+	}
+	return filtered, total
+}
+
+// listResults returns the latest healthchecks results
+func (c *Component) listResults(ec echo.Context) error {
+	query, err := parseResultsQuery(ec)
+	if err != nil {
+		return corbierror.New(err.Error(), corbierror.BadRequest, true)
+	}
+	results := c.MemoryStore.List()
+	if query.empty() {
+		return ec.JSON(http.StatusOK, ListResultsOutput{
+			Result: results,
+		})
+	}
+	page, total := filterResults(results, query)
+	return ec.JSON(http.StatusOK, ListResultsOutput{
+		Result: page,
+		Total:  &total,
+	})
+}
+
+// queryResults returns the latest results of the requested healthchecks
+func (c *Component) queryResults(ec echo.Context) error {
+	var payload QueryResultsPayload
+	if err := ec.Bind(&payload); err != nil {
+		msg := fmt.Sprintf("Fail to query results. Invalid JSON: %s", err.Error())
+		return corbierror.New(msg, corbierror.BadRequest, true)
+	}
+	if len(payload.Names) == 0 {
+		return corbierror.New("The healthchecks names are missing", corbierror.BadRequest, true)
+	}
+	output := QueryResultsOutput{
+		Result:   []healthcheck.Result{},
+		NotFound: []string{},
+	}
+	seen := make(map[string]bool)
+	for _, name := range payload.Names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		result, err := c.MemoryStore.Get(name)
+		if err != nil {
+			output.NotFound = append(output.NotFound, name)
+			continue
+		}
+		output.Result = append(output.Result, result)
+	}
+	return ec.JSON(http.StatusOK, output)
+}
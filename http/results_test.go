@@ -0,0 +1,100 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/memorystore"
+)
+
+func TestFilterResults(t *testing.T) {
+	results := []healthcheck.Result{
+		{Name: "a", Success: true, Labels: map[string]string{"env": "prod"}},
+		{Name: "b", Success: false, Labels: map[string]string{"env": "prod"}},
+		{Name: "c", Success: false, Labels: map[string]string{"env": "dev"}},
+		{Name: "d", Success: false},
+		{Name: "e", Success: true, Degraded: true},
+	}
+	e := echo.New()
+	cases := []struct {
+		query    string
+		expected []string
+		total    int
+		success  bool
+	}{
+		{query: "", expected: []string{"a", "b", "c", "d", "e"}, total: 5, success: true},
+		{query: "?status=failure", expected: []string{"b", "c", "d"}, total: 3, success: true},
+		{query: "?status=failure&limit=2&offset=1", expected: []string{"c", "d"}, total: 3, success: true},
+		{query: "?label=env:prod", expected: []string{"a", "b"}, total: 2, success: true},
+		{query: "?label=env:prod&status=success", expected: []string{"a"}, total: 1, success: true},
+		{query: "?offset=10", expected: []string{}, total: 5, success: true},
+		{query: "?status=degraded", expected: []string{"e"}, total: 1, success: true},
+		{query: "?status=success", expected: []string{"a", "e"}, total: 2, success: true},
+		{query: "?status=unknown", success: false},
+		{query: "?limit=-1", success: false},
+		{query: "?label=env", success: false},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/api/v1/result"+c.query, nil)
+		query, err := parseResultsQuery(e.NewContext(req, httptest.NewRecorder()))
+		if !c.success {
+			if err == nil {
+				t.Fatalf("Was expecting an error for %s", c.query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Fail to parse the query %s\n%v", c.query, err)
+		}
+		page, total := filterResults(results, query)
+		if total != c.total || len(page) != len(c.expected) {
+			t.Fatalf("Invalid results for %s: %v (total %d)", c.query, page, total)
+		}
+		for i := range page {
+			if page[i].Name != c.expected[i] {
+				t.Fatalf("Invalid results for %s: %v", c.query, page)
+			}
+		}
+	}
+}
+
+func TestQueryResults(t *testing.T) {
+	store := memorystore.NewMemoryStore(zap.NewExample())
+	now := time.Now().Unix()
+	store.Add(&healthcheck.Result{Name: "a", Success: true, HealthcheckTimestamp: now})
+	store.Add(&healthcheck.Result{Name: "b", Success: false, HealthcheckTimestamp: now})
+	store.Add(&healthcheck.Result{Name: "c", Success: true, HealthcheckTimestamp: now})
+	component := Component{MemoryStore: store}
+	e := echo.New()
+	req := httptest.NewRequest("POST", "/api/v1/result/query", strings.NewReader(`{"names": ["b", "unknown", "a", "b"]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	err := component.queryResults(e.NewContext(req, rec))
+	if err != nil {
+		t.Fatalf("Fail to query the results\n%v", err)
+	}
+	var output QueryResultsOutput
+	err = json.Unmarshal(rec.Body.Bytes(), &output)
+	if err != nil {
+		t.Fatalf("Fail to decode the response\n%v", err)
+	}
+	if len(output.Result) != 2 || output.Result[0].Name != "b" || output.Result[1].Name != "a" {
+		t.Fatalf("Invalid results %v", output.Result)
+	}
+	if len(output.NotFound) != 1 || output.NotFound[0] != "unknown" {
+		t.Fatalf("Invalid not found healthchecks %v", output.NotFound)
+	}
+	req = httptest.NewRequest("POST", "/api/v1/result/query", strings.NewReader(`{"names": []}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	err = component.queryResults(e.NewContext(req, httptest.NewRecorder()))
+	if err == nil {
+		t.Fatalf("Was expecting an error because the names are missing")
+	}
+}
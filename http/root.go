@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"sync"
@@ -30,7 +31,12 @@ type Component struct {
 	Prometheus       *prometheus.Prometheus
 	requestHistogram *prom.HistogramVec
 	responseCounter  *prom.CounterVec
-	wg               sync.WaitGroup
+	statsCache       aggregateCache
+	statusCache      aggregateCache
+	// RunningConfig returns the configuration currently loaded by the
+	// daemon, exposed by the configuration endpoint
+	RunningConfig func() interface{}
+	wg            sync.WaitGroup
 }
 
 // New creates a new HTTP component
@@ -75,8 +81,9 @@ func New(logger *zap.Logger, memstore *memorystore.MemoryStore, promComponent *p
 
 	respCounter := prom.NewCounterVec(
 		prom.CounterOpts{
-			Name: "http_responses_total",
-			Help: "Count the number of HTTP responses.",
+			Namespace: promComponent.Namespace(),
+			Name:      "http_responses_total",
+			Help:      "Count the number of HTTP responses.",
 		},
 		[]string{"method", "status", "path"})
 
@@ -86,9 +93,10 @@ func New(logger *zap.Logger, memstore *memorystore.MemoryStore, promComponent *p
 
 	reqHistogram := prom.NewHistogramVec(
 		prom.HistogramOpts{
-			Name:    "http_requests_duration_second",
-			Help:    "Time to execute http requests",
-			Buckets: buckets,
+			Namespace: promComponent.Namespace(),
+			Name:      "http_requests_duration_second",
+			Help:      "Time to execute http requests",
+			Buckets:   buckets,
 		},
 		[]string{"method", "path"})
 
@@ -108,7 +116,21 @@ func New(logger *zap.Logger, memstore *memorystore.MemoryStore, promComponent *p
 // Start starts the http server
 func (c *Component) Start() error {
 	address := fmt.Sprintf("[%s]:%d", c.Config.Host, c.Config.Port)
+	if c.Config.Socket != "" {
+		address = c.Config.Socket
+	}
 	c.Logger.Info(fmt.Sprintf("Starting the HTTP server component on %s", address))
+	if c.Config.Socket != "" {
+		listener, err := c.listenSocket()
+		if err != nil {
+			return err
+		}
+		if c.Config.Cert != "" {
+			c.Server.TLSListener = tls.NewListener(listener, c.Server.TLSServer.TLSConfig)
+		} else {
+			c.Server.Listener = listener
+		}
+	}
 	c.handlers()
 	err := c.Prometheus.Register(c.responseCounter)
 	if err != nil {
@@ -156,6 +178,35 @@ func (c *Component) Stop() error {
 		c.Logger.Error(err.Error())
 		return err
 	}
+	if c.Config.Socket != "" {
+		err = os.Remove(c.Config.Socket)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "fail to remove the socket %s", c.Config.Socket)
+		}
+	}
 	c.Logger.Info("HTTP server stopped")
 	return nil
 }
+
+// listenSocket listens on the Unix socket of the configuration. A socket
+// left by a previous execution is removed, but other files are never
+// overwritten.
+func (c *Component) listenSocket() (net.Listener, error) {
+	info, err := os.Lstat(c.Config.Socket)
+	if err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("fail to listen on %s: the file exists and is not a socket", c.Config.Socket)
+		}
+		err = os.Remove(c.Config.Socket)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to remove the socket %s", c.Config.Socket)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "fail to check the socket %s", c.Config.Socket)
+	}
+	listener, err := net.Listen("unix", c.Config.Socket)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to listen on the socket %s", c.Config.Socket)
+	}
+	return listener, nil
+}
@@ -1,11 +1,14 @@
 package http
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"io"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -47,6 +50,82 @@ func TestStartStop(t *testing.T) {
 	}
 }
 
+func TestStartStopSocket(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	healthcheck, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	socket := filepath.Join(t.TempDir(), "cabourotte.sock")
+	// a socket left by a previous execution should be replaced
+	previous, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Fail to create the socket\n%v", err)
+	}
+	previous.(*net.UnixListener).SetUnlinkOnClose(false)
+	previous.Close()
+	component, err := New(logger, memorystore.NewMemoryStore(logger), prom, &Configuration{Socket: socket}, healthcheck)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dialer := net.Dialer{}
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	resp, err := client.Get("http://localhost/metrics")
+	if err != nil {
+		t.Fatalf("HTTP error\n%v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("Was expected a 200 status")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Fatalf("The socket was not removed")
+	}
+}
+
+func TestStartSocketExistingFile(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	healthcheck, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom, []string{})
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	path := filepath.Join(t.TempDir(), "cabourotte.sock")
+	err = os.WriteFile(path, []byte("foo"), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the file\n%v", err)
+	}
+	component, err := New(logger, memorystore.NewMemoryStore(logger), prom, &Configuration{Socket: path}, healthcheck)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
 func TestStartStopTLS(t *testing.T) {
 	logger := zap.NewExample()
 	prom, err := prometheus.New()
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/mcorbin/corbierror"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// redacted the value replacing the secrets in the running configuration
+const redacted = healthcheck.Redacted
+
+// secretKeys the configuration keys containing secrets, normalized
+// (lowercase, without dashes and underscores)
+var secretKeys = []string{"password", "token", "secret", "keypem", "dsn", "authorization", "apikey"}
+
+// isSecretKey returns true if the value of a configuration key is a secret
+func isSecretKey(key string) bool {
+	normalized := strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(key))
+	for _, secret := range secretKeys {
+		if strings.Contains(normalized, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact replaces recursively the non-empty secrets of a decoded JSON value
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if isSecretKey(key) {
+				if inner != nil && inner != "" {
+					v[key] = redacted
+				}
+				continue
+			}
+			v[key] = redact(inner)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+	return value
+}
+
+// redactedConfig returns the configuration with its secrets redacted
+func redactedConfig(config interface{}) (interface{}, error) {
+	content, err := json.Marshal(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to serialize the configuration")
+	}
+	var result interface{}
+	err = json.Unmarshal(content, &result)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to read the configuration")
+	}
+	return redact(result), nil
+}
+
+// runningConfig returns the configuration currently loaded by the daemon,
+// in JSON or in YAML if the format query parameter is yaml
+func (c *Component) runningConfig(ec echo.Context) error {
+	if c.RunningConfig == nil {
+		return corbierror.New("The running configuration is not available", corbierror.NotFound, true)
+	}
+	config, err := redactedConfig(c.RunningConfig())
+	if err != nil {
+		return corbierror.Wrap(err, "Internal error", corbierror.Internal, true)
+	}
+	switch ec.QueryParam("format") {
+	case "", "json":
+		return ec.JSON(http.StatusOK, config)
+	case "yaml":
+		content, err := yaml.Marshal(config)
+		if err != nil {
+			return corbierror.Wrap(err, "Internal error", corbierror.Internal, true)
+		}
+		return ec.Blob(http.StatusOK, "application/yaml", content)
+	}
+	return corbierror.New("Invalid format (json or yaml expected)", corbierror.BadRequest, true)
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedactedConfig(t *testing.T) {
+	config := map[string]interface{}{
+		"Host": "127.0.0.1",
+		"BasicAuth": map[string]string{
+			"Username": "admin",
+			"Password": "secret",
+		},
+		"checks": []map[string]interface{}{
+			{
+				"name":         "foo",
+				"bearer-token": "token",
+				"key-pem":      "",
+				"headers":      map[string]string{"Authorization": "Bearer token", "Accept": "text/plain"},
+			},
+		},
+	}
+	result, err := redactedConfig(config)
+	if err != nil {
+		t.Fatalf("Fail to redact the configuration\n%v", err)
+	}
+	expected := map[string]interface{}{
+		"Host": "127.0.0.1",
+		"BasicAuth": map[string]interface{}{
+			"Username": "admin",
+			"Password": redacted,
+		},
+		"checks": []interface{}{
+			map[string]interface{}{
+				"name":         "foo",
+				"bearer-token": redacted,
+				"key-pem":      "",
+				"headers":      map[string]interface{}{"Authorization": redacted, "Accept": "text/plain"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("Invalid redacted configuration %v", result)
+	}
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"sync"
+	"time"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// StatusStats counts the successful and failed healthchecks. Degraded
+// healthchecks are also counted as successful.
+type StatusStats struct {
+	Total    int `json:"total"`
+	Success  int `json:"success"`
+	Degraded int `json:"degraded"`
+	Failure  int `json:"failure"`
+}
+
+func (s *StatusStats) add(result *healthcheck.Result) {
+	s.Total++
+	if result.Success {
+		s.Success++
+		if result.Degraded {
+			s.Degraded++
+		}
+	} else {
+		s.Failure++
+	}
+}
+
+// Stats aggregated statistics about the healthchecks results
+type Stats struct {
+	StatusStats `json:",inline"`
+	Sources     map[string]*StatusStats `json:"sources"`
+	Types       map[string]*StatusStats `json:"types"`
+}
+
+// StatsOutput the statistics about the healthchecks results and the global
+// maintenance state
+type StatsOutput struct {
+	*Stats      `json:",inline"`
+	Maintenance bool `json:"maintenance"`
+}
+
+// resultGroups the results grouped by source and by type
+type resultGroups struct {
+	all     []*healthcheck.Result
+	sources map[string][]*healthcheck.Result
+	types   map[string][]*healthcheck.Result
+}
+
+// groupResults groups a list of results by source and by type. The stats
+// and the status reports are both built from these groups.
+func groupResults(results []healthcheck.Result) *resultGroups {
+	groups := &resultGroups{
+		all:     make([]*healthcheck.Result, 0, len(results)),
+		sources: make(map[string][]*healthcheck.Result),
+		types:   make(map[string][]*healthcheck.Result),
+	}
+	for i := range results {
+		result := &results[i]
+		groups.all = append(groups.all, result)
+		groups.sources[result.Source] = append(groups.sources[result.Source], result)
+		groups.types[result.Type] = append(groups.types[result.Type], result)
+	}
+	return groups
+}
+
+// newStatusStats counts the successful and failed results of a group
+func newStatusStats(results []*healthcheck.Result) *StatusStats {
+	stats := &StatusStats{}
+	for _, result := range results {
+		stats.add(result)
+	}
+	return stats
+}
+
+// computeStats computes statistics about a list of results
+func computeStats(results []healthcheck.Result) *Stats {
+	groups := groupResults(results)
+	stats := &Stats{
+		StatusStats: *newStatusStats(groups.all),
+		Sources:     make(map[string]*StatusStats, len(groups.sources)),
+		Types:       make(map[string]*StatusStats, len(groups.types)),
+	}
+	for source, group := range groups.sources {
+		stats.Sources[source] = newStatusStats(group)
+	}
+	for resultType, group := range groups.types {
+		stats.Types[resultType] = newStatusStats(group)
+	}
+	return stats
+}
+
+// aggregateCache caches the result of an aggregate computation. The cached
+// value is invalidated after a TTL or when the memory store changes.
+type aggregateCache struct {
+	lock       sync.Mutex
+	value      interface{}
+	generation uint64
+	expiration time.Time
+}
+
+// get returns the cached value, or computes and caches a new value
+func (a *aggregateCache) get(ttl time.Duration, generation uint64, compute func() interface{}) interface{} {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	now := time.Now()
+	if a.value != nil && a.generation == generation && now.Before(a.expiration) {
+		return a.value
+	}
+	a.value = compute()
+	a.generation = generation
+	a.expiration = now.Add(ttl)
+	return a.value
+}
+
+// stats returns statistics about the current healthchecks results
+func (c *Component) stats() *Stats {
+	ttl := time.Duration(c.Config.AggregateCacheTTL)
+	if ttl == 0 {
+		ttl = time.Duration(DefaultAggregateCacheTTL)
+	}
+	value := c.statsCache.get(ttl, c.MemoryStore.Generation(), func() interface{} {
+		return computeStats(c.MemoryStore.List())
+	})
+	return value.(*Stats)
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+	"github.com/appclacks/cabourotte/memorystore"
+)
+
+func TestStats(t *testing.T) {
+	store := memorystore.NewMemoryStore(zap.NewExample())
+	component := &Component{
+		MemoryStore: store,
+		Config: &Configuration{
+			AggregateCacheTTL: healthcheck.Duration(time.Hour),
+		},
+	}
+	now := time.Now().Unix()
+	store.Add(&healthcheck.Result{Name: "foo", Type: healthcheck.TypeHTTP, Source: "api", Success: true, HealthcheckTimestamp: now})
+	store.Add(&healthcheck.Result{Name: "bar", Type: healthcheck.TypeTCP, Source: "api", Success: false, HealthcheckTimestamp: now})
+	stats := component.stats()
+	if stats.Total != 2 || stats.Success != 1 || stats.Failure != 1 {
+		t.Fatalf("Invalid stats %v", stats)
+	}
+	if stats.Sources["api"].Total != 2 || stats.Types[healthcheck.TypeTCP].Failure != 1 {
+		t.Fatalf("Invalid stats %v", stats)
+	}
+	if component.stats() != stats {
+		t.Fatalf("The stats should be cached")
+	}
+	store.Add(&healthcheck.Result{Name: "baz", Type: healthcheck.TypeTCP, Source: "api", Success: true, HealthcheckTimestamp: now})
+	stats = component.stats()
+	if stats.Total != 3 {
+		t.Fatalf("The cache was not invalidated by a new result %v", stats)
+	}
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"time"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+const (
+	// StatusHealthy all the healthchecks of the group are successful
+	StatusHealthy string = "healthy"
+	// StatusDegraded some healthchecks of the group are failing or degraded
+	StatusDegraded string = "degraded"
+	// StatusUnhealthy the group is considered down
+	StatusUnhealthy string = "unhealthy"
+)
+
+// GroupStatus the aggregated status of a group of healthchecks
+type GroupStatus struct {
+	Status        string  `json:"status"`
+	Total         int     `json:"total"`
+	Failure       int     `json:"failure"`
+	Degraded      int     `json:"degraded"`
+	Weight        int     `json:"weight"`
+	FailureWeight int     `json:"failure-weight"`
+	FailureRatio  float64 `json:"failure-ratio"`
+}
+
+// StatusReport the aggregated status of all healthchecks, by source and by type
+type StatusReport struct {
+	GroupStatus `json:",inline"`
+	Strategy    string                  `json:"strategy"`
+	Sources     map[string]*GroupStatus `json:"sources"`
+	Types       map[string]*GroupStatus `json:"types"`
+}
+
+// resultWeight returns the weight of a result in the aggregate status
+func resultWeight(result *healthcheck.Result) int {
+	if result.Weight <= 0 {
+		return 1
+	}
+	return result.Weight
+}
+
+func (g *GroupStatus) add(result *healthcheck.Result) {
+	weight := resultWeight(result)
+	g.Total++
+	g.Weight += weight
+	if !result.Success {
+		g.Failure++
+		g.FailureWeight += weight
+	} else if result.Degraded {
+		g.Degraded++
+	}
+}
+
+// newGroupStatus computes the status of a group of results using the given
+// strategy
+func newGroupStatus(results []*healthcheck.Result, strategy string, threshold float64) *GroupStatus {
+	group := &GroupStatus{}
+	for _, result := range results {
+		group.add(result)
+	}
+	group.aggregate(strategy, threshold)
+	return group
+}
+
+// aggregate computes the status of the group using the given strategy.
+// Without failures, the group is degraded if any result is degraded for the
+// worst-of strategy, or if all results are degraded for the other strategies.
+func (g *GroupStatus) aggregate(strategy string, threshold float64) {
+	if g.Total == 0 {
+		g.Status = StatusHealthy
+		return
+	}
+	switch strategy {
+	case AggregationWeighted:
+		g.FailureRatio = float64(g.FailureWeight) * 100 / float64(g.Weight)
+	default:
+		g.FailureRatio = float64(g.Failure) * 100 / float64(g.Total)
+	}
+	switch {
+	case g.Failure == 0 && g.Degraded > 0 && (strategy == AggregationWorstOf || g.Degraded == g.Total):
+		g.Status = StatusDegraded
+	case g.Failure == 0:
+		g.Status = StatusHealthy
+	case g.Failure == g.Total:
+		g.Status = StatusUnhealthy
+	case strategy == AggregationPercentage || strategy == AggregationWeighted:
+		if g.FailureRatio > threshold {
+			g.Status = StatusDegraded
+		} else {
+			g.Status = StatusHealthy
+		}
+	default:
+		g.Status = StatusUnhealthy
+	}
+}
+
+// computeStatus computes the aggregated status of a list of results
+func computeStatus(results []healthcheck.Result, strategy string, threshold float64) *StatusReport {
+	if strategy == "" {
+		strategy = AggregationWorstOf
+	}
+	groups := groupResults(results)
+	report := &StatusReport{
+		GroupStatus: *newGroupStatus(groups.all, strategy, threshold),
+		Strategy:    strategy,
+		Sources:     make(map[string]*GroupStatus, len(groups.sources)),
+		Types:       make(map[string]*GroupStatus, len(groups.types)),
+	}
+	for source, group := range groups.sources {
+		report.Sources[source] = newGroupStatus(group, strategy, threshold)
+	}
+	for resultType, group := range groups.types {
+		report.Types[resultType] = newGroupStatus(group, strategy, threshold)
+	}
+	return report
+}
+
+// status returns the aggregated status of the current healthchecks results
+func (c *Component) status() *StatusReport {
+	ttl := time.Duration(c.Config.AggregateCacheTTL)
+	if ttl == 0 {
+		ttl = time.Duration(DefaultAggregateCacheTTL)
+	}
+	value := c.statusCache.get(ttl, c.MemoryStore.Generation(), func() interface{} {
+		return computeStatus(c.MemoryStore.List(), c.Config.AggregationStrategy, c.Config.DegradedThreshold)
+	})
+	return value.(*StatusReport)
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+func TestComputeStatus(t *testing.T) {
+	results := []healthcheck.Result{
+		{Name: "a", Type: healthcheck.TypeHTTP, Source: "api", Success: true, Weight: 5},
+		{Name: "b", Type: healthcheck.TypeHTTP, Source: "api", Success: true},
+		{Name: "c", Type: healthcheck.TypeHTTP, Source: "api", Success: true, Weight: 3},
+		{Name: "d", Type: healthcheck.TypeTCP, Source: "api", Success: false},
+	}
+	cases := []struct {
+		strategy  string
+		threshold float64
+		status    string
+	}{
+		{"", 0, StatusUnhealthy},
+		{AggregationWorstOf, 20, StatusUnhealthy},
+		{AggregationPercentage, 20, StatusDegraded},
+		{AggregationPercentage, 30, StatusHealthy},
+		{AggregationWeighted, 20, StatusHealthy},
+		{AggregationWeighted, 5, StatusDegraded},
+	}
+	for _, c := range cases {
+		report := computeStatus(results, c.strategy, c.threshold)
+		if report.Status != c.status {
+			t.Fatalf("Invalid status for strategy %s and threshold %f: %s", c.strategy, c.threshold, report.Status)
+		}
+		if report.Types[healthcheck.TypeTCP].Status != StatusUnhealthy {
+			t.Fatalf("The tcp group should be unhealthy")
+		}
+		if report.Types[healthcheck.TypeHTTP].Status != StatusHealthy {
+			t.Fatalf("The http group should be healthy")
+		}
+	}
+	report := computeStatus(results, AggregationWeighted, 5)
+	if report.Weight != 10 || report.FailureWeight != 1 || report.FailureRatio != 10 {
+		t.Fatalf("Invalid weighted report %v", report)
+	}
+}
+
+func TestComputeStatusDegraded(t *testing.T) {
+	results := []healthcheck.Result{
+		{Name: "a", Type: healthcheck.TypeHTTP, Source: "api", Success: true, Degraded: true},
+		{Name: "b", Type: healthcheck.TypeHTTP, Source: "api", Success: true, Degraded: true},
+		{Name: "c", Type: healthcheck.TypeTCP, Source: "api", Success: true},
+	}
+	cases := []struct {
+		strategy string
+		status   string
+	}{
+		{AggregationWorstOf, StatusDegraded},
+		{AggregationPercentage, StatusHealthy},
+		{AggregationWeighted, StatusHealthy},
+	}
+	for _, c := range cases {
+		report := computeStatus(results, c.strategy, 20)
+		if report.Status != c.status {
+			t.Fatalf("Invalid status for strategy %s: %s", c.strategy, report.Status)
+		}
+		if report.Degraded != 2 || report.Failure != 0 {
+			t.Fatalf("Invalid degraded report %v", report)
+		}
+		if report.Types[healthcheck.TypeHTTP].Status != StatusDegraded {
+			t.Fatalf("The http group should be degraded with the strategy %s", c.strategy)
+		}
+		if report.Types[healthcheck.TypeTCP].Status != StatusHealthy {
+			t.Fatalf("The tcp group should be healthy with the strategy %s", c.strategy)
+		}
+	}
+}
@@ -4,6 +4,17 @@ import (
 	"github.com/appclacks/cabourotte/cmd"
 )
 
+// set at build time using ldflags
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
 func main() {
-	cmd.Main()
+	cmd.Main(cmd.BuildInfo{
+		Version: version,
+		Commit:  commit,
+		Date:    date,
+	})
 }
@@ -0,0 +1,101 @@
+package memorystore
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// persistInterval the interval between two saves of the store on disk
+const persistInterval = 5 * time.Second
+
+// persistedStore the content of the persistence file
+type persistedStore struct {
+	Results []healthcheck.Result `json:"results"`
+}
+
+// load loads the results saved in the persistence file. The expired
+// results are ignored.
+func (m *MemoryStore) load() error {
+	content, err := os.ReadFile(m.PersistPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "Fail to read the persistence file %s", m.PersistPath)
+	}
+	var store persistedStore
+	err = json.Unmarshal(content, &store)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to parse the persistence file %s", m.PersistPath)
+	}
+	// results are added from the oldest to the most recent in order to
+	// rebuild the history of each healthcheck
+	sort.SliceStable(store.Results, func(i, j int) bool {
+		return store.Results[i].HealthcheckTimestamp < store.Results[j].HealthcheckTimestamp
+	})
+	now := time.Now()
+	for i := range store.Results {
+		result := store.Results[i]
+		checkTimestamp := time.Unix(result.HealthcheckTimestamp, 0)
+		if now.After(checkTimestamp.Add(m.TTL)) {
+			continue
+		}
+		m.add(&result)
+	}
+	m.persistedGeneration = m.generation
+	m.Logger.Info("results loaded from the persistence file",
+		zap.String("path", m.PersistPath),
+		zap.Int("count", len(m.Results)))
+	return nil
+}
+
+// save writes the results history in the persistence file if the store
+// changed since the last save. The file is replaced atomically.
+func (m *MemoryStore) save() error {
+	generation := m.Generation()
+	if generation == m.persistedGeneration {
+		return nil
+	}
+	content, err := json.Marshal(persistedStore{Results: m.ListHistory()})
+	if err != nil {
+		return errors.Wrapf(err, "Fail to serialize the results")
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(m.PersistPath), filepath.Base(m.PersistPath)+".tmp")
+	if err != nil {
+		return errors.Wrapf(err, "Fail to create the persistence file")
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(content)
+	if err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "Fail to write the persistence file")
+	}
+	err = tmp.Close()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to write the persistence file")
+	}
+	err = os.Rename(tmp.Name(), m.PersistPath)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to replace the persistence file %s", m.PersistPath)
+	}
+	m.persistedGeneration = generation
+	return nil
+}
+
+// persist saves the store on disk, logging errors
+func (m *MemoryStore) persist() {
+	err := m.save()
+	if err != nil {
+		m.Logger.Error("fail to persist the results",
+			zap.String("error", err.Error()),
+			zap.String("path", m.PersistPath))
+	}
+}
@@ -1,6 +1,7 @@
 package memorystore
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"sync"
@@ -12,37 +13,106 @@ import (
 	"github.com/appclacks/cabourotte/healthcheck"
 )
 
+// DefaultHistorySize the default number of results kept for each healthcheck
+const DefaultHistorySize = 1
+
 // MemoryStore A store containing the latest healthchecks results
 type MemoryStore struct {
 	TTL     time.Duration
 	Logger  *zap.Logger
 	Results map[string]*healthcheck.Result
 	Tick    *time.Ticker
+	// HistorySize the number of results kept for each healthcheck
+	HistorySize int
+	// RetentionTiers the down-sampled retention tiers of the results
+	RetentionTiers []RetentionTier
+	// PersistPath the file where the results are persisted, in order to be
+	// restored at startup. The results are only kept in memory if empty.
+	PersistPath string
+
+	t                   tomb.Tomb
+	persistedGeneration uint64
+	lock                sync.RWMutex
+	generation          uint64
+	history             map[string]*history
+	tiers               map[string][]*tier
+}
+
+// history a ring buffer of the last results of an healthcheck
+type history struct {
+	results []healthcheck.Result
+	next    int
+	full    bool
+}
 
-	t    tomb.Tomb
-	lock sync.RWMutex
+// add adds a result to the history, replacing the oldest one if the history
+// is full
+func (h *history) add(result *healthcheck.Result) {
+	h.results[h.next] = *result
+	h.next = (h.next + 1) % len(h.results)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list returns the results, from the oldest to the most recent
+func (h *history) list() []healthcheck.Result {
+	if !h.full {
+		result := make([]healthcheck.Result, h.next)
+		copy(result, h.results[:h.next])
+		return result
+	}
+	result := make([]healthcheck.Result, 0, len(h.results))
+	result = append(result, h.results[h.next:]...)
+	result = append(result, h.results[:h.next]...)
+	return result
 }
 
 // NewMemoryStore creates a new memory store
 func NewMemoryStore(logger *zap.Logger) *MemoryStore {
 	return &MemoryStore{
-		Logger:  logger,
-		TTL:     time.Second * 120,
-		Results: make(map[string]*healthcheck.Result),
+		Logger:      logger,
+		TTL:         time.Second * 120,
+		Results:     make(map[string]*healthcheck.Result),
+		HistorySize: DefaultHistorySize,
+		history:     make(map[string]*history),
+		tiers:       make(map[string][]*tier),
 	}
 }
 
-// Start starts the memory store
+// Start starts the memory store. The persisted results are loaded if the
+// persistence is enabled: the store falls back to memory only if the
+// persistence file can't be read.
 func (m *MemoryStore) Start() {
 	m.lock.Lock()
 	defer m.lock.Unlock()
+	if m.PersistPath != "" {
+		err := m.load()
+		if err != nil {
+			m.Logger.Error("fail to load the persisted results, the results will only be kept in memory",
+				zap.String("error", err.Error()),
+				zap.String("path", m.PersistPath))
+			m.PersistPath = ""
+		}
+	}
 	m.Tick = time.NewTicker(time.Second * 30)
 	m.t.Go(func() error {
+		var persistC <-chan time.Time
+		if m.PersistPath != "" {
+			persistTick := time.NewTicker(persistInterval)
+			defer persistTick.Stop()
+			persistC = persistTick.C
+		}
 		for {
 			select {
 			case <-m.Tick.C:
 				m.Purge()
+			case <-persistC:
+				m.persist()
 			case <-m.t.Dying():
+				if m.PersistPath != "" {
+					m.persist()
+				}
 				return nil
 			}
 		}
@@ -64,10 +134,49 @@ func (m *MemoryStore) Stop() error {
 func (m *MemoryStore) Add(result *healthcheck.Result) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
+	m.add(result)
+}
+
+// add adds a result to the store. The lock should be held by the caller.
+func (m *MemoryStore) add(result *healthcheck.Result) {
 	m.Results[result.Name] = result
+	resultHistory, ok := m.history[result.Name]
+	if !ok || len(resultHistory.results) != m.historySize() {
+		resultHistory = &history{
+			results: make([]healthcheck.Result, m.historySize()),
+		}
+		m.history[result.Name] = resultHistory
+	}
+	resultHistory.add(result)
+	resultTiers, ok := m.tiers[result.Name]
+	if !ok || len(resultTiers) != len(m.RetentionTiers) {
+		resultTiers = newTiers(m.RetentionTiers)
+		m.tiers[result.Name] = resultTiers
+	}
+	for _, t := range resultTiers {
+		t.add(result)
+	}
+	m.generation++
 }
 
-// Purge the expired results
+// Generation returns a counter incremented each time the store content changes
+func (m *MemoryStore) Generation() uint64 {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.generation
+}
+
+// historySize returns the number of results to keep for each healthcheck
+func (m *MemoryStore) historySize() int {
+	if m.HistorySize <= 0 {
+		return DefaultHistorySize
+	}
+	return m.HistorySize
+}
+
+// Purge the expired results. The history of an healthcheck expires when its
+// latest result is expired, the retention tiers summaries expire after their
+// retention.
 func (m *MemoryStore) Purge() {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -79,6 +188,14 @@ func (m *MemoryStore) Purge() {
 			m.Logger.Info("expire healthcheck",
 				zap.String("name", result.Name))
 			delete(m.Results, result.Name)
+			delete(m.history, result.Name)
+			delete(m.tiers, result.Name)
+			m.generation++
+		}
+	}
+	for _, resultTiers := range m.tiers {
+		for _, t := range resultTiers {
+			t.purge(now)
 		}
 	}
 }
@@ -107,3 +224,25 @@ func (m *MemoryStore) Get(name string) (healthcheck.Result, error) {
 	}
 	return healthcheck.Result{}, fmt.Errorf("Result not found for healthcheck %s", name)
 }
+
+// GetHistory returns the last results of an healthcheck, from the oldest to
+// the most recent
+func (m *MemoryStore) GetHistory(ctx context.Context, name string) ([]healthcheck.Result, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if resultHistory, ok := m.history[name]; ok {
+		return resultHistory.list(), nil
+	}
+	return nil, fmt.Errorf("Result not found for healthcheck %s", name)
+}
+
+// ListHistory returns the history of all healthchecks
+func (m *MemoryStore) ListHistory() []healthcheck.Result {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	result := []healthcheck.Result{}
+	for _, resultHistory := range m.history {
+		result = append(result, resultHistory.list()...)
+	}
+	return result
+}
@@ -1,6 +1,9 @@
 package memorystore
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -47,3 +50,165 @@ func TestMemoryExporter(t *testing.T) {
 		t.Fatalf("Invalid result list size: %d", len(resultList))
 	}
 }
+
+func TestMemoryStoreHistory(t *testing.T) {
+	store := NewMemoryStore(zap.NewExample())
+	store.HistorySize = 3
+	now := time.Now().Unix()
+	for i := int64(0); i < 5; i++ {
+		store.Add(&healthcheck.Result{
+			Name:                 "foo",
+			HealthcheckTimestamp: now + i,
+		})
+	}
+	history, err := store.GetHistory(context.Background(), "foo")
+	if err != nil {
+		t.Fatalf("Fail to get the history\n%v", err)
+	}
+	if len(history) != 3 || history[0].HealthcheckTimestamp != now+2 || history[2].HealthcheckTimestamp != now+4 {
+		t.Fatalf("Invalid history %v", history)
+	}
+	latest, err := store.Get("foo")
+	if err != nil {
+		t.Fatalf("Fail to get the result\n%v", err)
+	}
+	if latest.HealthcheckTimestamp != now+4 || len(store.List()) != 1 {
+		t.Fatalf("Invalid latest result %v", latest)
+	}
+	if len(store.ListHistory()) != 3 {
+		t.Fatalf("Invalid history list size")
+	}
+	_, err = store.GetHistory(context.Background(), "bar")
+	if err == nil {
+		t.Fatalf("Was expecting an error for an unknown healthcheck")
+	}
+	store.Add(&healthcheck.Result{
+		Name:                 "foo",
+		HealthcheckTimestamp: time.Now().Add(time.Minute * time.Duration(-5)).Unix(),
+	})
+	store.Purge()
+	_, err = store.GetHistory(context.Background(), "foo")
+	if err == nil {
+		t.Fatalf("The history should be expired")
+	}
+}
+
+func TestMemoryStoreTiers(t *testing.T) {
+	store := NewMemoryStore(zap.NewExample())
+	store.RetentionTiers = []RetentionTier{
+		{
+			Resolution: healthcheck.Duration(time.Minute),
+			Retention:  healthcheck.Duration(time.Hour),
+		},
+	}
+	now := time.Now().Unix()
+	start := now - now%60
+	store.Add(&healthcheck.Result{Name: "foo", HealthcheckTimestamp: start - 7200, Success: true, Duration: 10})
+	store.Add(&healthcheck.Result{Name: "foo", HealthcheckTimestamp: start - 60, Success: true, Duration: 10})
+	store.Add(&healthcheck.Result{Name: "foo", HealthcheckTimestamp: start, Success: true, Duration: 10})
+	store.Add(&healthcheck.Result{Name: "foo", HealthcheckTimestamp: start - 30, Success: false, Duration: 30})
+	store.Add(&healthcheck.Result{Name: "foo", HealthcheckTimestamp: start + 1, Success: false, Duration: 30})
+	tiers, err := store.GetTiers("foo")
+	if err != nil {
+		t.Fatalf("Fail to get the tiers\n%v", err)
+	}
+	if len(tiers) != 1 || len(tiers[0].Summaries) != 3 {
+		t.Fatalf("Invalid tiers %v", tiers)
+	}
+	if tiers[0].Resolution != "1m0s" || tiers[0].Retention != "1h0m0s" {
+		t.Fatalf("Invalid tier %v", tiers[0])
+	}
+	summary := tiers[0].Summaries[1]
+	if summary.Timestamp != start-60 || summary.Count != 2 || summary.Successes != 1 || summary.Failures != 1 || summary.AverageDuration != 20 {
+		t.Fatalf("Invalid summary %v", summary)
+	}
+	summary = tiers[0].Summaries[2]
+	if summary.Timestamp != start || summary.Count != 2 || summary.AverageDuration != 20 {
+		t.Fatalf("Invalid summary %v", summary)
+	}
+	store.Purge()
+	tiers, err = store.GetTiers("foo")
+	if err != nil {
+		t.Fatalf("Fail to get the tiers\n%v", err)
+	}
+	if len(tiers[0].Summaries) != 2 || tiers[0].Summaries[0].Timestamp != start-60 {
+		t.Fatalf("The oldest summary should be purged %v", tiers)
+	}
+	invalid := RetentionTier{
+		Resolution: healthcheck.Duration(time.Hour),
+		Retention:  healthcheck.Duration(time.Minute),
+	}
+	if invalid.Validate() == nil {
+		t.Fatalf("Was expecting an error because the retention is lower than the resolution")
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+	store := NewMemoryStore(zap.NewExample())
+	store.HistorySize = 3
+	store.PersistPath = path
+	store.Start()
+	now := time.Now()
+	for i := 2; i >= 0; i-- {
+		store.Add(&healthcheck.Result{
+			Name:                 "foo",
+			Success:              i != 1,
+			HealthcheckTimestamp: now.Add(time.Duration(-i) * time.Second).Unix(),
+			Message:              "message",
+		})
+	}
+	store.Add(&healthcheck.Result{
+		Name:                 "bar",
+		Success:              true,
+		HealthcheckTimestamp: now.Add(-5 * time.Minute).Unix(),
+		Message:              "message",
+	})
+	err := store.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the store: %s", err.Error())
+	}
+
+	restored := NewMemoryStore(zap.NewExample())
+	restored.HistorySize = 3
+	restored.PersistPath = path
+	restored.Start()
+	defer restored.Stop()
+	results := restored.List()
+	if len(results) != 1 {
+		t.Fatalf("Invalid result list size: %d", len(results))
+	}
+	if !results[0].Equals(store.List()[1]) {
+		t.Fatalf("Invalid result content: %v", results[0])
+	}
+	history, err := restored.GetHistory(context.Background(), "foo")
+	if err != nil {
+		t.Fatalf("Fail to get the history: %s", err.Error())
+	}
+	if len(history) != 3 || !history[0].Success || history[1].Success || !history[2].Success {
+		t.Fatalf("Invalid history: %v", history)
+	}
+}
+
+func TestPersistenceInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+	err := os.WriteFile(path, []byte("invalid"), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the file: %s", err.Error())
+	}
+	store := NewMemoryStore(zap.NewExample())
+	store.PersistPath = path
+	store.Start()
+	defer store.Stop()
+	if store.PersistPath != "" {
+		t.Fatalf("The persistence should be disabled")
+	}
+	store.Add(&healthcheck.Result{
+		Name:                 "foo",
+		Success:              true,
+		HealthcheckTimestamp: time.Now().Unix(),
+	})
+	if len(store.List()) != 1 {
+		t.Fatalf("Invalid result list size")
+	}
+}
@@ -0,0 +1,139 @@
+package memorystore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/appclacks/cabourotte/healthcheck"
+)
+
+// RetentionTier a down-sampled retention tier: the results are aggregated
+// in summaries covering Resolution, which are kept for Retention
+type RetentionTier struct {
+	Resolution healthcheck.Duration `json:"resolution" yaml:"resolution"`
+	Retention  healthcheck.Duration `json:"retention" yaml:"retention"`
+}
+
+// Validate validates the retention tier
+func (t *RetentionTier) Validate() error {
+	if t.Resolution < healthcheck.Duration(time.Second) {
+		return errors.New("The retention tier resolution should be greater than 1 second")
+	}
+	if t.Retention < t.Resolution {
+		return errors.New("The retention tier retention should be greater than its resolution")
+	}
+	return nil
+}
+
+// Summary the aggregation of the results of an healthcheck during a period
+type Summary struct {
+	// Timestamp the start of the period
+	Timestamp int64 `json:"timestamp"`
+	Count     int   `json:"count"`
+	Successes int   `json:"successes"`
+	Failures  int   `json:"failures"`
+	// AverageDuration the average duration of the executions, in milliseconds
+	AverageDuration int64 `json:"average-duration"`
+
+	totalDuration int64
+}
+
+// add adds a result to the summary
+func (s *Summary) add(result *healthcheck.Result) {
+	s.Count++
+	if result.Success {
+		s.Successes++
+	} else {
+		s.Failures++
+	}
+	s.totalDuration += result.Duration
+	s.AverageDuration = s.totalDuration / int64(s.Count)
+}
+
+// TierHistory the summaries of an healthcheck for a retention tier, from
+// the oldest to the most recent
+type TierHistory struct {
+	Resolution string    `json:"resolution"`
+	Retention  string    `json:"retention"`
+	Summaries  []Summary `json:"summaries"`
+}
+
+// tier the summaries of an healthcheck for a retention tier
+type tier struct {
+	config    RetentionTier
+	summaries []*Summary
+}
+
+// resolution returns the resolution of the tier in seconds
+func (t *tier) resolution() int64 {
+	return int64(time.Duration(t.config.Resolution) / time.Second)
+}
+
+// add aggregates a result in the summary of its period
+func (t *tier) add(result *healthcheck.Result) {
+	start := result.HealthcheckTimestamp - result.HealthcheckTimestamp%t.resolution()
+	// results are most of the time added in order, so the summary is
+	// searched from the most recent one
+	i := len(t.summaries) - 1
+	for i >= 0 && t.summaries[i].Timestamp > start {
+		i--
+	}
+	if i >= 0 && t.summaries[i].Timestamp == start {
+		t.summaries[i].add(result)
+		return
+	}
+	summary := &Summary{Timestamp: start}
+	summary.add(result)
+	t.summaries = append(t.summaries, nil)
+	copy(t.summaries[i+2:], t.summaries[i+1:])
+	t.summaries[i+1] = summary
+}
+
+// purge removes the summaries of periods ended before the retention
+func (t *tier) purge(now time.Time) {
+	limit := now.Add(-time.Duration(t.config.Retention)).Unix()
+	i := 0
+	for i < len(t.summaries) && t.summaries[i].Timestamp+t.resolution() <= limit {
+		i++
+	}
+	t.summaries = t.summaries[i:]
+}
+
+// history returns the tier history
+func (t *tier) history() TierHistory {
+	summaries := make([]Summary, 0, len(t.summaries))
+	for _, summary := range t.summaries {
+		summaries = append(summaries, *summary)
+	}
+	return TierHistory{
+		Resolution: time.Duration(t.config.Resolution).String(),
+		Retention:  time.Duration(t.config.Retention).String(),
+		Summaries:  summaries,
+	}
+}
+
+// newTiers creates the tiers of an healthcheck
+func newTiers(configs []RetentionTier) []*tier {
+	result := make([]*tier, 0, len(configs))
+	for _, config := range configs {
+		result = append(result, &tier{config: config})
+	}
+	return result
+}
+
+// GetTiers returns the retention tiers history of an healthcheck
+func (m *MemoryStore) GetTiers(name string) ([]TierHistory, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	tiers, ok := m.tiers[name]
+	if !ok {
+		return nil, fmt.Errorf("Result not found for healthcheck %s", name)
+	}
+	result := make([]TierHistory, 0, len(tiers))
+	for _, t := range tiers {
+		result = append(result, t.history())
+	}
+	return result, nil
+}
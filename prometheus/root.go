@@ -1,11 +1,13 @@
 package prometheus
 
 import (
+	"io"
 	"net/http"
 
 	prom "github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
 	"go.uber.org/zap"
 )
 
@@ -33,6 +35,14 @@ func New() (*Prometheus, error) {
 	return p, nil
 }
 
+// Namespace returns the namespace prefixing the metrics names
+func (p *Prometheus) Namespace() string {
+	if p.Config == nil {
+		return ""
+	}
+	return p.Config.Namespace
+}
+
 // Register adds a metric to the component
 func (p *Prometheus) Register(collector prom.Collector) error {
 	return p.Registry.Register(collector)
@@ -47,3 +57,33 @@ func (p *Prometheus) Unregister(collector prom.Collector) {
 func (p *Prometheus) Handler() http.Handler {
 	return promhttp.HandlerFor(p.Registry, promhttp.HandlerOpts{})
 }
+
+// Write writes the metrics to w using the Prometheus text format
+func (p *Prometheus) Write(w io.Writer) error {
+	families, err := p.Registry.Gather()
+	if err != nil {
+		return err
+	}
+	for _, family := range families {
+		_, err := expfmt.MetricFamilyToText(w, family)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterBuildInfo registers a gauge exposing the Cabourotte build information
+func (p *Prometheus) RegisterBuildInfo(version string, commit string, date string) error {
+	gauge := prom.NewGaugeVec(prom.GaugeOpts{
+		Namespace: p.Namespace(),
+		Name:      "cabourotte_build_info",
+		Help:      "Cabourotte build information.",
+	}, []string{"version", "commit", "date"})
+	err := p.Register(gauge)
+	if err != nil {
+		return err
+	}
+	gauge.With(prom.Labels{"version": version, "commit": commit, "date": date}).Set(1)
+	return nil
+}
@@ -9,28 +9,88 @@ import (
 	"github.com/pkg/errors"
 )
 
-// GetTLSConfig returns a tls configuration
+// Certificates the certificates used to build a tls configuration. Each
+// certificate is either a file path or its PEM content.
+type Certificates struct {
+	Key       string
+	Cert      string
+	Cacert    string
+	KeyPEM    string
+	CertPEM   string
+	CacertPEM string
+}
+
+// Validate validates the certificates. The key and the certificate should
+// be both set, and each certificate can't be set both as a file and as PEM
+// content.
+func (c *Certificates) Validate() error {
+	if c.Key != "" && c.KeyPEM != "" {
+		return errors.New("The key file and the key PEM content can't be both set")
+	}
+	if c.Cert != "" && c.CertPEM != "" {
+		return errors.New("The certificate file and the certificate PEM content can't be both set")
+	}
+	if c.Cacert != "" && c.CacertPEM != "" {
+		return errors.New("The ca certificate file and the ca certificate PEM content can't be both set")
+	}
+	if (c.Key != "") != (c.Cert != "") || (c.KeyPEM != "") != (c.CertPEM != "") {
+		return errors.New("Invalid certificates")
+	}
+	return nil
+}
+
+// GetTLSConfig returns a tls configuration.
+// The serverName is sent using SNI, and the server certificate should be
+// valid for this name. If insecure is true, neither the certificate chain
+// nor the certificate hostname are verified: the TLS healthchecks
+// VerifyHostname option can be used to verify the hostname only.
 func GetTLSConfig(keyPath string, certPath string, cacertPath string, serverName string, insecure bool) (*tls.Config, error) {
+	certificates := Certificates{
+		Key:    keyPath,
+		Cert:   certPath,
+		Cacert: cacertPath,
+	}
+	return GetTLSConfigWithCertificates(&certificates, serverName, insecure)
+}
+
+// GetTLSConfigWithCertificates returns a tls configuration using
+// certificates provided as files or as PEM content.
+// See GetTLSConfig for the serverName and insecure parameters.
+func GetTLSConfigWithCertificates(certificates *Certificates, serverName string, insecure bool) (*tls.Config, error) {
 	tlsConfig := &tls.Config{}
-	if keyPath != "" {
-		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if certificates.Key != "" {
+		cert, err := tls.LoadX509KeyPair(certificates.Cert, certificates.Key)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Fail to load certificates")
 		}
 		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
-	if cacertPath != "" {
-		caCert, err := os.ReadFile(cacertPath)
+	if certificates.KeyPEM != "" {
+		cert, err := tls.X509KeyPair([]byte(certificates.CertPEM), []byte(certificates.KeyPEM))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to load certificates")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if certificates.Cacert != "" {
+		caCert, err := os.ReadFile(certificates.Cacert)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Fail to load the ca certificate")
 		}
 		caCertPool := x509.NewCertPool()
 		result := caCertPool.AppendCertsFromPEM(caCert)
 		if !result {
-			return nil, fmt.Errorf("fail to read ca certificate on %s", certPath)
+			return nil, fmt.Errorf("fail to read ca certificate on %s", certificates.Cacert)
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+	if certificates.CacertPEM != "" {
+		caCertPool := x509.NewCertPool()
+		result := caCertPool.AppendCertsFromPEM([]byte(certificates.CacertPEM))
+		if !result {
+			return nil, errors.New("fail to read the ca certificate PEM content")
 		}
 		tlsConfig.RootCAs = caCertPool
-
 	}
 	if serverName != "" {
 		tlsConfig.ServerName = serverName
@@ -38,3 +98,68 @@ func GetTLSConfig(keyPath string, certPath string, cacertPath string, serverName
 	tlsConfig.InsecureSkipVerify = insecure
 	return tlsConfig, nil
 }
+
+// ParseVersion parses a TLS version (1.0, 1.1, 1.2 or 1.3). It returns 0
+// if the version is empty.
+func ParseVersion(version string) (uint16, error) {
+	switch version {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("Invalid TLS version %s (supported: 1.0, 1.1, 1.2, 1.3)", version)
+}
+
+// FormatVersion formats a TLS version (1.0, 1.1, 1.2 or 1.3)
+func FormatVersion(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "1.0"
+	case tls.VersionTLS11:
+		return "1.1"
+	case tls.VersionTLS12:
+		return "1.2"
+	case tls.VersionTLS13:
+		return "1.3"
+	}
+	return fmt.Sprintf("0x%04X", version)
+}
+
+// ValidateVersions validates the minimum and maximum TLS versions
+func ValidateVersions(minVersion string, maxVersion string) error {
+	minimum, err := ParseVersion(minVersion)
+	if err != nil {
+		return err
+	}
+	maximum, err := ParseVersion(maxVersion)
+	if err != nil {
+		return err
+	}
+	if minimum != 0 && maximum != 0 && minimum > maximum {
+		return errors.New("The minimum TLS version should be lower than the maximum TLS version")
+	}
+	return nil
+}
+
+// SetVersions sets the minimum and maximum TLS versions of a tls
+// configuration. The versions should be already validated.
+func SetVersions(tlsConfig *tls.Config, minVersion string, maxVersion string) error {
+	minimum, err := ParseVersion(minVersion)
+	if err != nil {
+		return err
+	}
+	maximum, err := ParseVersion(maxVersion)
+	if err != nil {
+		return err
+	}
+	tlsConfig.MinVersion = minimum
+	tlsConfig.MaxVersion = maximum
+	return nil
+}